@@ -1,16 +1,27 @@
 package cmd
 
 import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
-	settingsfactory "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/factory"
-	paymentfactory "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/factory"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/lock"
 )
 
 var (
-	batchSize int
-	dryRun    bool
+	batchSize   int
+	dryRun      bool
+	maxItems    int
+	maxDuration time.Duration
+	concurrency int
+	lockMode    string
+	lockWait    time.Duration
 )
 
 var cronUpdatePaymentCmd = &cobra.Command{
@@ -22,10 +33,26 @@ This command is designed to be executed by a cron scheduler to perform
 batch updates on payments. The actual business logic resides in the
 payment module's cron adapter (hexagonal architecture).
 
+Pending payments are walked oldest-first in pages of --batch-size until none
+are left or the --max-items/--max-duration budget is spent. The payments of a
+page are claimed with a lease and processed --concurrency at a time; a claimed
+payment is not claimed again before its lease expires. A payment that fails to
+process is deferred for CRON_RETRY_DELAY, so the next run resumes with the
+payments an interrupted run left behind instead of the ones that keep failing.
+
+Only one instance runs the job at a time: the run takes a Postgres advisory
+lock named after the job, shared with the scheduler command. With --lock-mode
+skip (default) a run that finds the lock held exits successfully without doing
+anything; with --lock-mode wait it waits for the lock, up to
+--lock-wait-timeout.
+
 Example:
   payment-app cron-update-payment
   payment-app cron-update-payment --dry-run
   payment-app cron-update-payment --batch-size 100
+  payment-app cron-update-payment --max-items 5000 --max-duration 10m
+  payment-app cron-update-payment --concurrency 8
+  payment-app cron-update-payment --lock-mode wait --lock-wait-timeout 5m
 
 Cron schedule example (runs every hour):
   0 * * * * /path/to/payment-app cron-update-payment >> /var/log/payment-cron.log 2>&1`,
@@ -37,6 +64,11 @@ func init() {
 
 	cronUpdatePaymentCmd.Flags().IntVar(&batchSize, "batch-size", 0, "Number of payments to process in one batch (overrides CRON_BATCH_SIZE)")
 	cronUpdatePaymentCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Run in dry-run mode (overrides CRON_DRY_RUN)")
+	cronUpdatePaymentCmd.Flags().IntVar(&maxItems, "max-items", 0, "Maximum number of payments to process in one run (overrides CRON_MAX_ITEMS)")
+	cronUpdatePaymentCmd.Flags().DurationVar(&maxDuration, "max-duration", 0, "Maximum duration of one run (overrides CRON_MAX_DURATION)")
+	cronUpdatePaymentCmd.Flags().IntVar(&concurrency, "concurrency", 0, "Number of payments processed in parallel (overrides CRON_CONCURRENCY)")
+	cronUpdatePaymentCmd.Flags().StringVar(&lockMode, "lock-mode", "", "What to do when another instance runs the job: skip or wait (overrides CRON_LOCK_MODE)")
+	cronUpdatePaymentCmd.Flags().DurationVar(&lockWait, "lock-wait-timeout", 0, "Maximum time to wait for the job lock in wait mode (overrides CRON_LOCK_WAIT_TIMEOUT)")
 }
 
 func runCronUpdatePayment(cmd *cobra.Command, args []string) (err error) {
@@ -49,24 +81,60 @@ func runCronUpdatePayment(cmd *cobra.Command, args []string) (err error) {
 	if !dryRun {
 		dryRun = cfg.Cron.DryRun
 	}
+	if maxItems == 0 {
+		maxItems = cfg.Cron.MaxItems
+	}
+	if maxDuration == 0 {
+		maxDuration = cfg.Cron.MaxDuration
+	}
+	if concurrency == 0 {
+		concurrency = cfg.Cron.Concurrency
+	}
+	if lockMode == "" {
+		lockMode = cfg.Cron.LockMode
+	}
+	if lockWait == 0 {
+		lockWait = cfg.Cron.LockWaitTimeout
+	}
+	mode, err := lock.ParseMode(lockMode)
+	if err != nil {
+		return err
+	}
 
 	log.Info().
 		Int("batch_size", batchSize).
 		Bool("dry_run", dryRun).
+		Int("max_items", maxItems).
+		Dur("max_duration", maxDuration).
+		Int("concurrency", concurrency).
+		Str("lock_mode", string(mode)).
 		Msg("Starting payment update cron job")
 
-	paymentSettingsModule := settingsfactory.NewModule(settingsfactory.ModuleConfig{
-		DB: db,
-	})
+	// The flags override the cron configuration of this run only
+	runCfg := *cfg
+	runCfg.Cron.BatchSize = batchSize
+	runCfg.Cron.DryRun = dryRun
+	runCfg.Cron.MaxItems = maxItems
+	runCfg.Cron.MaxDuration = maxDuration
+	runCfg.Cron.Concurrency = concurrency
+	modules, err := NewModules(&runCfg)
+	if err != nil {
+		return err
+	}
 
-	paymentModule := paymentfactory.NewModule(paymentfactory.ModuleConfig{
-		DB:                  db,
-		PaymentSettingsPort: paymentSettingsModule.Service,
-		CronBatchSize:       batchSize,
-		CronDryRun:          dryRun,
+	// The run holds the job lock and is recorded in the job run history like a scheduled one
+	locker := lock.NewPostgresLocker(db, "")
+	guarded := lock.Guard(locker, payment.PaymentUpdaterJobName, modules.Payment.PaymentUpdater, lock.Options{
+		Mode:        mode,
+		WaitTimeout: lockWait,
 	})
+	updater := jobs.NewTracker(modules.JobRuns.Service, payment.PaymentUpdaterJobName, guarded)
+
+	// SIGINT/SIGTERM cancel the job so in-flight queries are aborted instead of outliving the process
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	result, err := paymentModule.PaymentUpdater.Execute()
+	result, err := updater.Execute(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("Cron job failed")
 		return err
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs"
+	jobsfactory "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs/factory"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
+	settingsfactory "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/factory"
+	paymentfactory "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/factory"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+	webhooksfactory "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/factory"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/config"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+// Modules are the modules of the monolith, wired the same way for every command.
+type Modules struct {
+	PaymentSettings *paymentsettings.Module
+	Webhooks        *webhooks.Module
+	Payment         *payment.Module
+	// JobRuns is the job run history every job is recorded in.
+	JobRuns *jobs.Module
+}
+
+// Jobs returns the jobs contributed by every module, for the scheduler to register at once.
+func (m *Modules) Jobs() []scheduler.Job {
+	var all []scheduler.Job
+	all = append(all, m.Payment.Jobs...)
+	all = append(all, m.PaymentSettings.Jobs...)
+	all = append(all, m.Webhooks.Jobs...)
+	return all
+}
+
+// NewModules assembles every module from cfg on the shared database and event bus. Modules are built in
+// dependency order: the payment module is handed the ports of the payment settings and webhooks modules.
+// A command that overrides part of the configuration, e.g. from its flags, passes a modified copy of cfg.
+func NewModules(cfg *config.Config) (modules *Modules, err error) {
+	db := GetDB()
+
+	paymentSettingsModule := settingsfactory.NewModule(settingsfactory.ModuleConfig{
+		DB:                db,
+		EventBus:          GetEventBus(),
+		EnableIdempotency: cfg.Idempotency.PaymentSettingsEnabled,
+		IdempotencyKeyTTL: cfg.Idempotency.KeyTTL,
+		RejectUnknownKeys: cfg.Settings.RejectUnknownKeys,
+	})
+
+	webhooksModule := webhooksfactory.NewModule(webhooksfactory.ModuleConfig{
+		DB:             db,
+		MaxAttempts:    cfg.Webhooks.MaxAttempts,
+		InitialBackoff: cfg.Webhooks.InitialBackoff,
+		MaxBackoff:     cfg.Webhooks.MaxBackoff,
+		Timeout:        cfg.Webhooks.Timeout,
+	})
+
+	paymentModule, err := paymentfactory.NewModule(paymentfactory.ModuleConfig{
+		DB:                      db,
+		PaymentSettingsPort:     paymentSettingsModule.Service,
+		EventBus:                GetEventBus(),
+		WebhookPort:             webhooksModule.Service,
+		DisableSettingsCache:    !cfg.SettingsCache.Enabled,
+		SettingsCacheTTL:        cfg.SettingsCache.TTL,
+		SettingsCacheMaxEntries: cfg.SettingsCache.MaxEntries,
+		IdempotencyKeyTTL:       cfg.Idempotency.KeyTTL,
+		CronBatchSize:           cfg.Cron.BatchSize,
+		CronDryRun:              cfg.Cron.DryRun,
+		CronMaxItems:            cfg.Cron.MaxItems,
+		CronMaxDuration:         cfg.Cron.MaxDuration,
+		CronConcurrency:         cfg.Cron.Concurrency,
+		CronLeaseDuration:       cfg.Cron.LeaseDuration,
+		CronRetryDelay:          cfg.Cron.RetryDelay,
+		GatewaySimulatorRules:   cfg.Gateway.SimulatorRules,
+		GatewaySimulatorLatency: cfg.Gateway.SimulatorLatency,
+		WebhookSecrets:          cfg.Gateway.WebhookSecrets,
+		WebhookTolerance:        cfg.Gateway.WebhookTolerance,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Every job run is recorded in the job run history
+	jobsModule := jobsfactory.NewModule(jobsfactory.ModuleConfig{
+		DB: db,
+	})
+
+	return &Modules{
+		PaymentSettings: paymentSettingsModule,
+		Webhooks:        webhooksModule,
+		Payment:         paymentModule,
+		JobRuns:         jobsModule,
+	}, nil
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/lock"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/outbox"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+var (
+	outboxModule     string
+	outboxReplayFrom int64
+)
+
+var outboxRelayCmd = &cobra.Command{
+	Use:   "outbox-relay",
+	Short: "Publish the domain events of the module outboxes",
+	Long: `Publish the domain events recorded in the module outboxes.
+
+The payment and payment-settings modules record their domain events in an
+outbox table in their own schema, in the same transaction as the change they
+describe. The relay reads the unpublished events in position order, hands them
+to the event publisher and marks them published. The scheduler command runs
+the same relays every 10 seconds; this command runs them once.
+
+With --replay-from the relay publishes every event from that position on
+again, published or not, e.g. to rebuild a consumer after data loss.
+
+Each relay runs under the lock of its scheduler job and is recorded in the job
+run history, so it never runs next to the scheduled relay of the same outbox.
+
+Example:
+  payment-app outbox-relay
+  payment-app outbox-relay --module payment
+  payment-app outbox-relay --module payment-settings --replay-from 1200`,
+	RunE: runOutboxRelay,
+}
+
+func init() {
+	rootCmd.AddCommand(outboxRelayCmd)
+
+	outboxRelayCmd.Flags().StringVar(&outboxModule, "module", "all", "Outbox to relay: payment, payment-settings or all")
+	outboxRelayCmd.Flags().Int64Var(&outboxReplayFrom, "replay-from", 0, "Publish every event from this position on again, published or not")
+}
+
+// outboxReplay runs a replay of relay as a scheduler.Runner, so it can be guarded and tracked like a relay run.
+type outboxReplay struct {
+	relay    *outbox.Relay
+	position int64
+}
+
+func (r outboxReplay) Execute(ctx context.Context) (interface{}, error) {
+	return r.relay.Replay(ctx, r.position)
+}
+
+func runOutboxRelay(cmd *cobra.Command, args []string) (err error) {
+	cfg := GetConfig()
+	db := GetDB()
+
+	replay := cmd.Flags().Changed("replay-from")
+	if replay && outboxReplayFrom < 1 {
+		return fmt.Errorf("--replay-from must be a positive outbox position")
+	}
+
+	modules, err := NewModules(cfg)
+	if err != nil {
+		return err
+	}
+
+	relays := map[string]*outbox.Relay{
+		payment.OutboxRelayJobName:         modules.Payment.OutboxRelay,
+		paymentsettings.OutboxRelayJobName: modules.PaymentSettings.OutboxRelay,
+	}
+	var jobNames []string
+	switch outboxModule {
+	case "payment":
+		jobNames = []string{payment.OutboxRelayJobName}
+	case "payment-settings":
+		jobNames = []string{paymentsettings.OutboxRelayJobName}
+	case "all":
+		if replay {
+			// Positions are per outbox, so a position only means something for one module
+			return fmt.Errorf("--replay-from requires --module payment or --module payment-settings")
+		}
+		jobNames = []string{payment.OutboxRelayJobName, paymentsettings.OutboxRelayJobName}
+	default:
+		return fmt.Errorf("unknown outbox module %q: use payment, payment-settings or all", outboxModule)
+	}
+
+	locker := lock.NewPostgresLocker(db, "")
+
+	// SIGINT/SIGTERM cancel the relay so in-flight queries are aborted instead of outliving the process
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, jobName := range jobNames {
+		var runner scheduler.Runner = relays[jobName]
+		if replay {
+			runner = outboxReplay{relay: relays[jobName], position: outboxReplayFrom}
+		}
+		guarded := lock.Guard(locker, jobName, runner, lock.Options{Mode: lock.ModeWait, WaitTimeout: cfg.Cron.LockWaitTimeout})
+
+		log.Info().Str("job", jobName).Bool("replay", replay).Int64("replay_from", outboxReplayFrom).Msg("Starting outbox relay")
+		result, err := jobs.NewTracker(modules.JobRuns.Service, jobName, guarded).Execute(ctx)
+		if err != nil {
+			log.Error().Err(err).Str("job", jobName).Msg("Outbox relay failed")
+			return err
+		}
+		log.Info().Str("job", jobName).Interface("result", result).Msg("Outbox relay completed")
+	}
+	return nil
+}
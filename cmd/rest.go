@@ -12,8 +12,6 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
-	settingsfactory "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/factory"
-	paymentfactory "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/factory"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/middlewares"
 )
 
@@ -37,18 +35,13 @@ func init() {
 
 func runREST(cmd *cobra.Command, args []string) (err error) {
 	cfg := GetConfig()
-	db := GetDB()
 
 	log.Info().Msg("Initializing REST API server")
 
-	paymentSettingsModule := settingsfactory.NewModule(settingsfactory.ModuleConfig{
-		DB: db,
-	})
-
-	paymentModule := paymentfactory.NewModule(paymentfactory.ModuleConfig{
-		DB:                  db,
-		PaymentSettingsPort: paymentSettingsModule.Service,
-	})
+	modules, err := NewModules(cfg)
+	if err != nil {
+		return err
+	}
 
 	e := echo.New()
 	e.HideBanner = true
@@ -61,6 +54,7 @@ func runREST(cmd *cobra.Command, args []string) (err error) {
 	e.Use(middleware.Recover())
 	e.Use(middlewares.CORS())
 	e.Use(middlewares.SetRequestContextWithTimeout(cfg.Server.ReadTimeout))
+	e.Use(middlewares.Tenant(cfg.Tenancy.APIKeys, cfg.Tenancy.AdminAPIKey))
 
 	e.GET("/health", func(c echo.Context) error {
 		return c.JSON(200, map[string]string{
@@ -71,8 +65,10 @@ func runREST(cmd *cobra.Command, args []string) (err error) {
 	})
 
 	api := e.Group("/api/v1")
-	paymentModule.RegisterHTTPHandlers(api)
-	paymentSettingsModule.RegisterHTTPHandlers(api)
+	modules.Payment.RegisterHTTPHandlers(api)
+	modules.PaymentSettings.RegisterHTTPHandlers(api)
+	modules.Webhooks.RegisterHTTPHandlers(api)
+	modules.JobRuns.RegisterHTTPHandlers(api)
 
 	go func() {
 		log.Info().
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -10,13 +11,15 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/config"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/eventbus"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/logger"
 )
 
 var (
-	cfgFile string
-	cfg     *config.Config
-	db      *sql.DB
+	cfgFile  string
+	cfg      *config.Config
+	db       *sql.DB
+	eventBus *eventbus.Bus
 )
 
 var rootCmd = &cobra.Command{
@@ -81,10 +84,22 @@ func initApp(cmd *cobra.Command, args []string) (err error) {
 		Int("max_idle_conns", cfg.Database.MaxIdleConns).
 		Msg("Successfully connected to PostgreSQL database")
 
+	// Modules of this process communicate through one in-process event bus
+	eventBus = eventbus.New(eventbus.Config{})
+
 	return nil
 }
 
 func cleanupApp(cmd *cobra.Command, args []string) (err error) {
+	// Asynchronous event handlers may still use the database
+	if eventBus != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err = eventBus.Close(ctx); err != nil {
+			log.Error().Err(err).Msg("Event bus shutdown failed")
+		}
+	}
+
 	if db != nil {
 		log.Info().Msg("Closing database connection")
 		return db.Close()
@@ -96,6 +111,10 @@ func GetDB() *sql.DB {
 	return db
 }
 
+func GetEventBus() *eventbus.Bus {
+	return eventBus
+}
+
 func GetConfig() *config.Config {
 	return cfg
 }
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/lock"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+var schedulerCmd = &cobra.Command{
+	Use:   "scheduler",
+	Short: "Run all module jobs on their cron schedules",
+	Long: `Run a long-lived scheduler hosting the jobs contributed by every module.
+
+Each module exposes its cron adapters as named jobs with a default cron
+expression. Schedules can be overridden per job with SCHEDULER_JOBS, e.g.
+  SCHEDULER_JOBS="payment-updater=*/10 * * * *"
+An override naming no job stops the scheduler at startup.
+
+A job never overlaps with itself: when a run is still in progress at the next
+activation, that activation is skipped. On SIGINT/SIGTERM no new runs start and
+running jobs get SCHEDULER_SHUTDOWN_TIMEOUT to finish before they are cancelled.
+Every run takes a Postgres advisory lock named after the job, so a job runs on
+a single host at a time (CRON_LOCK_MODE=skip|wait). Every run is recorded and
+listed by GET /api/v1/jobs/runs.
+
+Example:
+  payment-app scheduler`,
+	RunE: runScheduler,
+}
+
+func init() {
+	rootCmd.AddCommand(schedulerCmd)
+}
+
+func runScheduler(cmd *cobra.Command, args []string) (err error) {
+	cfg := GetConfig()
+	db := GetDB()
+
+	modules, err := NewModules(cfg)
+	if err != nil {
+		return err
+	}
+
+	mode, err := lock.ParseMode(cfg.Cron.LockMode)
+	if err != nil {
+		return err
+	}
+	// Jobs are single-instance across hosts, on top of the in-process overlap protection
+	locker := lock.NewPostgresLocker(db, "")
+	lockOptions := lock.Options{Mode: mode, WaitTimeout: cfg.Cron.LockWaitTimeout}
+
+	s := scheduler.New(scheduler.Config{
+		Schedules:       cfg.Scheduler.Schedules,
+		ShutdownTimeout: cfg.Scheduler.ShutdownTimeout,
+	})
+	if err = s.Register(modules.JobRuns.Track(lock.GuardJobs(locker, lockOptions, modules.Jobs()...)...)...); err != nil {
+		return err
+	}
+
+	for _, job := range s.Jobs() {
+		log.Info().Str("job", job.Name).Str("schedule", job.Schedule).Msg("Job registered")
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Info().Int("jobs", len(s.Jobs())).Msg("Scheduler started")
+	return s.Run(ctx)
+}
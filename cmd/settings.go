@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+var (
+	settingsFormat string
+	settingsOutput string
+	settingsDryRun bool
+	settingsPrune  bool
+	settingsActor  string
+	settingsReason string
+	settingsTenant string
+)
+
+var settingsCmd = &cobra.Command{
+	Use:   "settings",
+	Short: "Export and import payment settings",
+	Long: `Export the payment settings in force and import them into another environment.
+
+A settings file lists settings by key and currency, in JSON, YAML or CSV. IDs,
+versions and validity ranges are environment-specific and are not part of a
+file: imported updates keep the validity of the setting they change, and
+imported settings are created in force from now on.
+
+The commands work on the platform defaults every tenant falls back to, or with
+--tenant on the settings of one tenant.`,
+}
+
+var settingsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the payment settings in force to a file",
+	Long: `Write the payment settings in force, ordered by key and currency.
+
+The format is taken from --format, or from the extension of --output
+(.json, .yaml, .yml, .csv). Without --output the settings are written to
+stdout, as JSON by default.
+
+Example:
+  payment-app settings export --output settings.yaml
+  payment-app settings export --format csv > settings.csv
+  payment-app settings export --tenant merchant_a --output merchant_a.yaml`,
+	Args: cobra.NoArgs,
+	RunE: runSettingsExport,
+}
+
+var settingsImportCmd = &cobra.Command{
+	Use:   "import <file|->",
+	Short: "Apply a settings file to the payment settings in force",
+	Long: `Apply a settings file, matched to the settings in force by key and currency.
+
+Settings missing from the environment are created and settings with another
+value or status are updated. With --prune, settings in force that the file does
+not list are deleted. The plan is printed before it is applied, and the whole
+plan is applied in one transaction: when a change fails, none is applied. When
+the settings change in between, the plan that was applied is printed as well.
+Every entry is validated against the settings schema first, and invalid or
+duplicate entries are all reported at once.
+
+The format is taken from --format, or from the extension of the file. Use -
+to read the file from stdin, with --format.
+
+Example:
+  payment-app settings import settings.yaml --dry-run
+  payment-app settings import settings.csv --prune --reason "Sync from staging"
+  cat settings.json | payment-app settings import - --format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSettingsImport,
+}
+
+func init() {
+	settingsCmd.PersistentFlags().StringVar(&settingsTenant, "tenant", tenant.Platform, "Tenant whose settings to export or import (default the platform defaults)")
+
+	settingsExportCmd.Flags().StringVar(&settingsFormat, "format", "", "File format: json, yaml or csv (default from the --output extension, or json)")
+	settingsExportCmd.Flags().StringVarP(&settingsOutput, "output", "o", "", "File to write, stdout when empty")
+
+	settingsImportCmd.Flags().StringVar(&settingsFormat, "format", "", "File format: json, yaml or csv (default from the file extension)")
+	settingsImportCmd.Flags().BoolVar(&settingsDryRun, "dry-run", false, "Print the plan without applying it")
+	settingsImportCmd.Flags().BoolVar(&settingsPrune, "prune", false, "Delete the settings in force the file does not list")
+	settingsImportCmd.Flags().StringVar(&settingsActor, "actor", "cli", "Actor recorded in the revisions of the changes")
+	settingsImportCmd.Flags().StringVar(&settingsReason, "reason", "", "Reason recorded in the revisions of the changes (default \"Imported from <file>\")")
+
+	settingsCmd.AddCommand(settingsExportCmd, settingsImportCmd)
+	rootCmd.AddCommand(settingsCmd)
+}
+
+func newSettingsService() (paymentsettings.IPaymentSettingsService, error) {
+	modules, err := NewModules(GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	return modules.PaymentSettings.Service, nil
+}
+
+// settingsContext returns the command context acting for the --tenant tenant.
+func settingsContext(cmd *cobra.Command) (ctx context.Context, err error) {
+	if settingsTenant != tenant.Platform {
+		if err = tenant.Validate(settingsTenant); err != nil {
+			return nil, err
+		}
+	}
+	return tenant.WithID(cmd.Context(), settingsTenant), nil
+}
+
+func runSettingsExport(cmd *cobra.Command, args []string) (err error) {
+	format := settingsFormat
+	if format == "" {
+		format = paymentsettings.FileFormatJSON
+		if settingsOutput != "" {
+			if format, err = paymentsettings.FileFormatFromPath(settingsOutput); err != nil {
+				return err
+			}
+		}
+	}
+
+	ctx, err := settingsContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	service, err := newSettingsService()
+	if err != nil {
+		return err
+	}
+	var settings []paymentsettings.PaymentSetting
+	params := paymentsettings.PaymentSettingFetchParams{Limit: 100}
+	for {
+		page, nextCursor, err := service.FetchPaymentSettings(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to fetch payment settings: %w", err)
+		}
+		settings = append(settings, page...)
+		if nextCursor == "" {
+			break
+		}
+		params.Cursor = nextCursor
+	}
+
+	var w io.Writer = cmd.OutOrStdout()
+	if settingsOutput != "" {
+		file, err := os.Create(settingsOutput)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = file
+	}
+	if err = paymentsettings.EncodeSettingsFile(w, format, settings); err != nil {
+		return fmt.Errorf("failed to write payment settings: %w", err)
+	}
+	if settingsOutput != "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d payment settings to %s\n", len(settings), settingsOutput)
+	}
+	return nil
+}
+
+func runSettingsImport(cmd *cobra.Command, args []string) (err error) {
+	path := args[0]
+	format := settingsFormat
+	if format == "" {
+		if path == "-" {
+			return fmt.Errorf("--format is required to read from stdin")
+		}
+		if format, err = paymentsettings.FileFormatFromPath(path); err != nil {
+			return err
+		}
+	}
+
+	var r io.Reader = cmd.InOrStdin()
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	desired, err := paymentsettings.DecodeSettingsFile(r, format)
+	if err != nil {
+		return err
+	}
+
+	reason := settingsReason
+	if reason == "" {
+		reason = "Imported from " + path
+	}
+	ctx, err := settingsContext(cmd)
+	if err != nil {
+		return err
+	}
+	ctx = paymentsettings.WithChangeInfo(ctx, paymentsettings.ChangeInfo{Actor: settingsActor, Reason: reason})
+
+	service, err := newSettingsService()
+	if err != nil {
+		return err
+	}
+	// The plan is printed from a dry run first, so it is shown even when applying it fails
+	preview, err := service.ApplyPaymentSettings(ctx, desired, paymentsettings.ApplyOptions{
+		DryRun: true,
+		Prune:  settingsPrune,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import payment settings, no change was applied: %w", err)
+	}
+	printed := formatSettingsPlan(preview)
+	fmt.Fprint(cmd.OutOrStdout(), printed)
+	if settingsDryRun {
+		fmt.Fprintln(cmd.OutOrStdout(), "Dry run, no change was applied.")
+		return nil
+	}
+	if len(preview.Changes) == 0 {
+		return nil
+	}
+
+	// The changes are planned again inside the transaction applying them
+	plan, err := service.ApplyPaymentSettings(ctx, desired, paymentsettings.ApplyOptions{
+		Prune: settingsPrune,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import payment settings, no change was applied: %w", err)
+	}
+	if applied := formatSettingsPlan(plan); applied != printed {
+		fmt.Fprint(cmd.OutOrStdout(), "The settings changed since the plan was printed, applied instead:\n"+applied)
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Applied.")
+	return nil
+}
+
+// formatSettingsPlan renders one line per change, then the totals.
+func formatSettingsPlan(plan paymentsettings.SettingsPlan) string {
+	w := &strings.Builder{}
+	for _, change := range plan.Changes {
+		switch change.Action {
+		case paymentsettings.PlanActionCreate:
+			fmt.Fprintf(w, "+ create %s %s = %q (%s)\n", change.After.SettingKey, change.After.Currency, change.After.SettingValue, change.After.Status)
+		case paymentsettings.PlanActionUpdate:
+			fmt.Fprintf(w, "~ update %s %s: %q (%s) -> %q (%s)\n", change.After.SettingKey, change.After.Currency,
+				change.Before.SettingValue, change.Before.Status, change.After.SettingValue, change.After.Status)
+		case paymentsettings.PlanActionDelete:
+			fmt.Fprintf(w, "- delete %s %s = %q (%s)\n", change.Before.SettingKey, change.Before.Currency, change.Before.SettingValue, change.Before.Status)
+		}
+	}
+	fmt.Fprintf(w, "Plan: %d to create, %d to update, %d to delete, %d unchanged.\n",
+		plan.Count(paymentsettings.PlanActionCreate), plan.Count(paymentsettings.PlanActionUpdate),
+		plan.Count(paymentsettings.PlanActionDelete), plan.Unchanged)
+	return w.String()
+}
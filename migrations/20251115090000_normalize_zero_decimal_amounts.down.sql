@@ -0,0 +1,8 @@
+-- Restores the original amounts of the payments that were not changed since they were corrected
+UPDATE payment_module.payments p
+SET amount = c.original_amount, updated_at = c.original_updated_at
+FROM payment_module.payment_amount_corrections c
+WHERE c.payment_id = p.id
+  AND p.amount = c.corrected_amount;
+
+DROP TABLE IF EXISTS payment_module.payment_amount_corrections;
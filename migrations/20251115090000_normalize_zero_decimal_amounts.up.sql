@@ -0,0 +1,54 @@
+-- Amounts are exact money values scaled by the ISO 4217 exponent of their currency (see pkg/money), so an
+-- amount with more decimals than its currency has (e.g. 8200.75 JPY) can no longer be read. Such amounts
+-- are rounded to the exponent; the original amount is kept in payment_amount_corrections, which the down
+-- migration restores from and which lists the payments to review.
+CREATE TABLE IF NOT EXISTS payment_module.payment_amount_corrections (
+    payment_id VARCHAR(255) PRIMARY KEY,
+    currency VARCHAR(3) NOT NULL,
+    original_amount DECIMAL(19, 4) NOT NULL,
+    original_updated_at TIMESTAMP NOT NULL,
+    corrected_amount DECIMAL(19, 4) NOT NULL,
+    corrected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TEMPORARY TABLE IF NOT EXISTS currency_exponents (code VARCHAR(3) PRIMARY KEY, exponent INT NOT NULL);
+INSERT INTO currency_exponents (code, exponent) VALUES
+    ('AED', 2), ('AUD', 2), ('BHD', 3), ('BRL', 2), ('CAD', 2), ('CHF', 2), ('CLP', 0), ('CNY', 2),
+    ('CZK', 2), ('DKK', 2), ('EUR', 2), ('GBP', 2), ('HKD', 2), ('HUF', 2), ('IDR', 2), ('ILS', 2),
+    ('INR', 2), ('ISK', 0), ('JOD', 3), ('JPY', 0), ('KRW', 0), ('KWD', 3), ('MXN', 2), ('MYR', 2),
+    ('NOK', 2), ('NZD', 2), ('OMR', 3), ('PHP', 2), ('PLN', 2), ('SAR', 2), ('SEK', 2), ('SGD', 2),
+    ('THB', 2), ('TND', 3), ('TRY', 2), ('TWD', 2), ('USD', 2), ('VND', 0), ('ZAR', 2)
+ON CONFLICT (code) DO NOTHING;
+
+-- A payment in a currency without a known exponent cannot be read at all; it needs a decision, not rounding
+DO $$
+DECLARE
+    unsupported BIGINT;
+    example VARCHAR;
+BEGIN
+    SELECT COUNT(*), MIN(p.id || ' (' || p.currency || ')') INTO unsupported, example
+    FROM payment_module.payments p
+    LEFT JOIN currency_exponents c ON c.code = UPPER(TRIM(p.currency))
+    WHERE c.code IS NULL;
+
+    IF unsupported > 0 THEN
+        RAISE EXCEPTION '% payments are in currencies pkg/money does not support, e.g. %; correct or remove them and migrate again',
+            unsupported, example;
+    END IF;
+END
+$$;
+
+INSERT INTO payment_module.payment_amount_corrections (payment_id, currency, original_amount, original_updated_at, corrected_amount)
+SELECT p.id, p.currency, p.amount, p.updated_at, ROUND(p.amount, c.exponent)
+FROM payment_module.payments p
+JOIN currency_exponents c ON c.code = UPPER(TRIM(p.currency))
+WHERE p.amount <> ROUND(p.amount, c.exponent)
+ON CONFLICT (payment_id) DO NOTHING;
+
+UPDATE payment_module.payments p
+SET amount = c.corrected_amount, updated_at = c.corrected_at
+FROM payment_module.payment_amount_corrections c
+WHERE c.payment_id = p.id
+  AND p.amount = c.original_amount;
+
+DROP TABLE currency_exponents;
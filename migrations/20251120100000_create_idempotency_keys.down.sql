@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS payment_settings_module.idempotency_keys;
+DROP TABLE IF EXISTS payment_module.idempotency_keys;
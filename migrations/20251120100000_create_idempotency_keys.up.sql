@@ -0,0 +1,26 @@
+-- Idempotency keys are stored per module schema; status_code stays NULL while the first request is in flight.
+CREATE TABLE IF NOT EXISTS payment_module.idempotency_keys (
+    idempotency_key VARCHAR(255) PRIMARY KEY,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INT,
+    content_type VARCHAR(255),
+    response_body BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_payment_idempotency_keys_expires_at ON payment_module.idempotency_keys(expires_at);
+
+CREATE TABLE IF NOT EXISTS payment_settings_module.idempotency_keys (
+    idempotency_key VARCHAR(255) PRIMARY KEY,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INT,
+    content_type VARCHAR(255),
+    response_body BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_payment_settings_idempotency_keys_expires_at ON payment_settings_module.idempotency_keys(expires_at);
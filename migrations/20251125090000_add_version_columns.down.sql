@@ -0,0 +1,2 @@
+ALTER TABLE payment_settings_module.payment_settings DROP COLUMN IF EXISTS version;
+ALTER TABLE payment_module.payments DROP COLUMN IF EXISTS version;
@@ -0,0 +1,3 @@
+-- Row version for optimistic concurrency control; every update increments it.
+ALTER TABLE payment_module.payments ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE payment_settings_module.payment_settings ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,6 @@
+-- Last processed cursor of long-running jobs, used to resume an interrupted run.
+CREATE TABLE IF NOT EXISTS payment_module.job_checkpoints (
+    job_name VARCHAR(100) PRIMARY KEY,
+    last_cursor TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS payment_module.idx_payments_pending_claims;
+ALTER TABLE payment_module.payments DROP COLUMN IF EXISTS lease_expires_at;
+ALTER TABLE payment_module.payments DROP COLUMN IF EXISTS claimed_by;
@@ -0,0 +1,7 @@
+-- Lease columns used by cron workers to claim disjoint batches of pending payments.
+ALTER TABLE payment_module.payments ADD COLUMN IF NOT EXISTS claimed_by VARCHAR(255);
+ALTER TABLE payment_module.payments ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_payments_pending_claims
+    ON payment_module.payments (id, lease_expires_at)
+    WHERE status = 'pending';
@@ -0,0 +1 @@
+DROP SCHEMA IF EXISTS jobs_module CASCADE;
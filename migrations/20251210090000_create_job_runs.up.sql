@@ -0,0 +1,19 @@
+CREATE SCHEMA IF NOT EXISTS jobs_module;
+
+-- History of scheduled job runs, one row per run of a module cron adapter.
+CREATE TABLE IF NOT EXISTS jobs_module.job_runs (
+    id VARCHAR(255) PRIMARY KEY,
+    job_name VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP,
+    processed_count INTEGER NOT NULL DEFAULT 0,
+    success_count INTEGER NOT NULL DEFAULT 0,
+    error_count INTEGER NOT NULL DEFAULT 0,
+    errors TEXT[] NOT NULL DEFAULT '{}',
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    host VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+-- Serves "last runs of a job", optionally filtered by status
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_name_status ON jobs_module.job_runs (job_name, status, id DESC);
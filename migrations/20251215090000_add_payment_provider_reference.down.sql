@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS payment_module.idx_payments_provider_reference;
+
+ALTER TABLE payment_module.payments
+    DROP COLUMN IF EXISTS provider_reference,
+    DROP COLUMN IF EXISTS provider;
@@ -0,0 +1,7 @@
+-- Identity of the payment at the payment gateway, set once the payment was authorized.
+ALTER TABLE payment_module.payments
+    ADD COLUMN IF NOT EXISTS provider VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS provider_reference VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_payments_provider_reference ON payment_module.payments (provider, provider_reference)
+    WHERE provider_reference <> '';
@@ -0,0 +1,12 @@
+-- Provider webhook events already applied, so a redelivered event is applied once.
+CREATE TABLE IF NOT EXISTS payment_module.provider_events (
+    provider VARCHAR(50) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    payment_id VARCHAR(255) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    occurred_at TIMESTAMP,
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_provider_events_payment_id ON payment_module.provider_events (payment_id);
@@ -0,0 +1 @@
+DROP SCHEMA IF EXISTS webhooks_module CASCADE;
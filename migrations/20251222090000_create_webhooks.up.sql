@@ -0,0 +1,36 @@
+CREATE SCHEMA IF NOT EXISTS webhooks_module;
+
+-- Merchant URLs receiving domain events. event_types may contain '*' for every event type.
+CREATE TABLE IF NOT EXISTS webhooks_module.endpoints (
+    id VARCHAR(255) PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    event_types TEXT[] NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Delivery log: one row per event and endpoint, with the outcome of the latest attempt.
+CREATE TABLE IF NOT EXISTS webhooks_module.deliveries (
+    id VARCHAR(255) PRIMARY KEY,
+    endpoint_id VARCHAR(255) NOT NULL REFERENCES webhooks_module.endpoints (id) ON DELETE CASCADE,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_attempt_at TIMESTAMP,
+    last_status_code INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Serves the dispatcher looking for due deliveries
+CREATE INDEX IF NOT EXISTS idx_deliveries_due ON webhooks_module.deliveries (next_attempt_at)
+    WHERE status = 'pending';
+
+-- Serves the delivery log of an endpoint, newest first
+CREATE INDEX IF NOT EXISTS idx_deliveries_endpoint_id ON webhooks_module.deliveries (endpoint_id, id DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS payment_settings_module.outbox;
+DROP TABLE IF EXISTS payment_module.outbox;
@@ -0,0 +1,25 @@
+-- Domain events are appended per module schema in the same transaction as the change they describe.
+-- position orders the events; published_at stays NULL until the outbox relay published the event.
+CREATE TABLE IF NOT EXISTS payment_module.outbox (
+    position BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(255) NOT NULL UNIQUE,
+    event_type VARCHAR(255) NOT NULL,
+    aggregate_id VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX idx_payment_outbox_unpublished ON payment_module.outbox(position) WHERE published_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS payment_settings_module.outbox (
+    position BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(255) NOT NULL UNIQUE,
+    event_type VARCHAR(255) NOT NULL,
+    aggregate_id VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX idx_payment_settings_outbox_unpublished ON payment_settings_module.outbox(position) WHERE published_at IS NULL;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS payment_settings_module.payment_setting_revisions;
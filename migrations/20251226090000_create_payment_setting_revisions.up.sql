@@ -0,0 +1,28 @@
+-- Every change of a payment setting is recorded as an immutable revision in the transaction of the change.
+-- The key, currency, status and version describe the setting after the change, or before it for a deletion;
+-- old_value is NULL for a creation and new_value is NULL for a deletion. There is no foreign key to
+-- payment_settings, so the history of a deleted setting is kept.
+CREATE TABLE IF NOT EXISTS payment_settings_module.payment_setting_revisions (
+    id VARCHAR(255) PRIMARY KEY,
+    setting_id VARCHAR(255) NOT NULL,
+    version BIGINT NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    setting_key VARCHAR(100) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    old_value VARCHAR(255),
+    new_value VARCHAR(255),
+    actor VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_payment_setting_revisions_setting ON payment_settings_module.payment_setting_revisions(setting_id, id);
+CREATE INDEX idx_payment_setting_revisions_created_at ON payment_settings_module.payment_setting_revisions(created_at);
+
+-- Existing settings start their history with their current state, in force since their last update
+INSERT INTO payment_settings_module.payment_setting_revisions
+    (id, setting_id, version, action, setting_key, currency, status, old_value, new_value, actor, reason, created_at)
+SELECT 'psrev-' || substring(id FROM 6), id, version, 'created', setting_key, currency, status, NULL, setting_value,
+       'system', 'Recorded when the revision history was introduced', updated_at
+FROM payment_settings_module.payment_settings;
@@ -0,0 +1,11 @@
+-- Fails when a key and currency have several rows; delete the scheduled or expired ones first
+ALTER TABLE payment_settings_module.payment_setting_revisions DROP COLUMN IF EXISTS valid_to;
+ALTER TABLE payment_settings_module.payment_setting_revisions DROP COLUMN IF EXISTS valid_from;
+
+DROP INDEX IF EXISTS payment_settings_module.idx_payment_settings_valid_from;
+ALTER TABLE payment_settings_module.payment_settings DROP CONSTRAINT IF EXISTS payment_settings_no_overlap;
+ALTER TABLE payment_settings_module.payment_settings DROP CONSTRAINT IF EXISTS payment_settings_valid_range;
+ALTER TABLE payment_settings_module.payment_settings
+    ADD CONSTRAINT payment_settings_setting_key_currency_key UNIQUE (setting_key, currency);
+ALTER TABLE payment_settings_module.payment_settings DROP COLUMN IF EXISTS valid_to;
+ALTER TABLE payment_settings_module.payment_settings DROP COLUMN IF EXISTS valid_from;
@@ -0,0 +1,33 @@
+-- A setting is in force from valid_from until valid_to (exclusive), or indefinitely when valid_to is NULL.
+-- Several rows of a key and currency may exist as long as their validity ranges do not overlap, so a
+-- change can be scheduled ahead of time. The ranges are instants supplied by clients, hence TIMESTAMPTZ.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+ALTER TABLE payment_settings_module.payment_settings ADD COLUMN IF NOT EXISTS valid_from TIMESTAMPTZ;
+ALTER TABLE payment_settings_module.payment_settings ADD COLUMN IF NOT EXISTS valid_to TIMESTAMPTZ;
+UPDATE payment_settings_module.payment_settings SET valid_from = created_at WHERE valid_from IS NULL;
+ALTER TABLE payment_settings_module.payment_settings ALTER COLUMN valid_from SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE payment_settings_module.payment_settings ALTER COLUMN valid_from SET NOT NULL;
+
+ALTER TABLE payment_settings_module.payment_settings
+    ADD CONSTRAINT payment_settings_valid_range CHECK (valid_to IS NULL OR valid_to > valid_from);
+ALTER TABLE payment_settings_module.payment_settings DROP CONSTRAINT IF EXISTS payment_settings_setting_key_currency_key;
+ALTER TABLE payment_settings_module.payment_settings
+    ADD CONSTRAINT payment_settings_no_overlap EXCLUDE USING gist (
+        setting_key WITH =,
+        currency WITH =,
+        tstzrange(valid_from, valid_to) WITH &&
+    );
+
+CREATE INDEX idx_payment_settings_valid_from ON payment_settings_module.payment_settings(valid_from);
+
+-- Revisions record the validity range of the setting, so as-of reads return what was in force at the instant
+ALTER TABLE payment_settings_module.payment_setting_revisions ADD COLUMN IF NOT EXISTS valid_from TIMESTAMPTZ;
+ALTER TABLE payment_settings_module.payment_setting_revisions ADD COLUMN IF NOT EXISTS valid_to TIMESTAMPTZ;
+UPDATE payment_settings_module.payment_setting_revisions r
+SET valid_from = s.valid_from, valid_to = s.valid_to
+FROM payment_settings_module.payment_settings s
+WHERE s.id = r.setting_id;
+-- Revisions of deleted settings were in force since they were recorded
+UPDATE payment_settings_module.payment_setting_revisions SET valid_from = created_at WHERE valid_from IS NULL;
+ALTER TABLE payment_settings_module.payment_setting_revisions ALTER COLUMN valid_from SET NOT NULL;
@@ -0,0 +1,2 @@
+DELETE FROM payment_settings_module.payment_setting_revisions WHERE setting_id = 'pset-01JGF6Q0T0A7K3M9P2R5W8Y4XZ';
+DELETE FROM payment_settings_module.payment_settings WHERE id = 'pset-01JGF6Q0T0A7K3M9P2R5W8Y4XZ';
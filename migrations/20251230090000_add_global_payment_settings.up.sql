@@ -0,0 +1,15 @@
+-- Settings of currency '*' apply to every currency without a setting of its own key. The payment timeout
+-- is the same for every currency, so it gets a global default; the currency rows still override it.
+INSERT INTO payment_settings_module.payment_settings (id, setting_key, setting_value, currency, status, valid_from, created_at, updated_at)
+SELECT 'pset-01JGF6Q0T0A7K3M9P2R5W8Y4XZ', 'payment_timeout_seconds', '300', '*', 'active', '2024-11-01 10:00:00+00', '2024-11-01 10:00:00', '2024-11-01 10:00:00'
+WHERE NOT EXISTS (
+    SELECT 1 FROM payment_settings_module.payment_settings WHERE setting_key = 'payment_timeout_seconds' AND currency = '*'
+);
+
+INSERT INTO payment_settings_module.payment_setting_revisions
+    (id, setting_id, version, action, setting_key, currency, status, valid_from, valid_to, old_value, new_value, actor, reason, created_at)
+SELECT 'psrev-' || substring(id FROM 6), id, version, 'created', setting_key, currency, status, valid_from, valid_to, NULL, setting_value,
+       'system', 'Global default', updated_at
+FROM payment_settings_module.payment_settings
+WHERE id = 'pset-01JGF6Q0T0A7K3M9P2R5W8Y4XZ'
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,25 @@
+-- Idempotency keys expire; the ones scoped to a tenant may not fit anymore
+DELETE FROM payment_settings_module.idempotency_keys WHERE length(idempotency_key) > 255;
+ALTER TABLE payment_settings_module.idempotency_keys ALTER COLUMN idempotency_key TYPE VARCHAR(255);
+DELETE FROM payment_module.idempotency_keys WHERE length(idempotency_key) > 255;
+ALTER TABLE payment_module.idempotency_keys ALTER COLUMN idempotency_key TYPE VARCHAR(255);
+
+ALTER TABLE webhooks_module.deliveries DROP COLUMN IF EXISTS tenant_id;
+DROP INDEX IF EXISTS webhooks_module.idx_endpoints_tenant_id;
+ALTER TABLE webhooks_module.endpoints DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE payment_settings_module.payment_setting_revisions DROP COLUMN IF EXISTS tenant_id;
+
+-- Fails when tenants have overlapping settings of the same key and currency; delete the tenant settings first
+DROP INDEX IF EXISTS payment_settings_module.idx_payment_settings_tenant_id;
+ALTER TABLE payment_settings_module.payment_settings DROP CONSTRAINT IF EXISTS payment_settings_no_overlap;
+ALTER TABLE payment_settings_module.payment_settings
+    ADD CONSTRAINT payment_settings_no_overlap EXCLUDE USING gist (
+        setting_key WITH =,
+        currency WITH =,
+        tstzrange(valid_from, valid_to) WITH &&
+    );
+ALTER TABLE payment_settings_module.payment_settings DROP COLUMN IF EXISTS tenant_id;
+
+DROP INDEX IF EXISTS payment_module.idx_payments_tenant_id;
+ALTER TABLE payment_module.payments DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,33 @@
+-- Payments, payment settings and webhook endpoints belong to a tenant (merchant), and every query of the
+-- repositories is scoped to the tenant of the request. Payments and endpoints created before tenancy
+-- belong to the tenant 'default'.
+ALTER TABLE payment_module.payments ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE payment_module.payments ALTER COLUMN tenant_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_payments_tenant_id ON payment_module.payments (tenant_id, id);
+
+-- Settings of tenant '' are the platform defaults every tenant falls back to; the existing ones become them.
+ALTER TABLE payment_settings_module.payment_settings ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE payment_settings_module.payment_settings ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE payment_settings_module.payment_settings DROP CONSTRAINT IF EXISTS payment_settings_no_overlap;
+ALTER TABLE payment_settings_module.payment_settings
+    ADD CONSTRAINT payment_settings_no_overlap EXCLUDE USING gist (
+        tenant_id WITH =,
+        setting_key WITH =,
+        currency WITH =,
+        tstzrange(valid_from, valid_to) WITH &&
+    );
+CREATE INDEX IF NOT EXISTS idx_payment_settings_tenant_id ON payment_settings_module.payment_settings (tenant_id, id);
+
+ALTER TABLE payment_settings_module.payment_setting_revisions ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE payment_settings_module.payment_setting_revisions ALTER COLUMN tenant_id DROP DEFAULT;
+
+-- A delivery belongs to the tenant of its endpoint
+ALTER TABLE webhooks_module.endpoints ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE webhooks_module.endpoints ALTER COLUMN tenant_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_endpoints_tenant_id ON webhooks_module.endpoints (tenant_id, id);
+ALTER TABLE webhooks_module.deliveries ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE webhooks_module.deliveries ALTER COLUMN tenant_id DROP DEFAULT;
+
+-- Idempotency keys are prefixed with the tenant of the request
+ALTER TABLE payment_module.idempotency_keys ALTER COLUMN idempotency_key TYPE VARCHAR(320);
+ALTER TABLE payment_settings_module.idempotency_keys ALTER COLUMN idempotency_key TYPE VARCHAR(320);
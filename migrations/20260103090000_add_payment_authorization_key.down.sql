@@ -0,0 +1,2 @@
+ALTER TABLE payment_module.payments
+    DROP COLUMN IF EXISTS authorization_key;
@@ -0,0 +1,4 @@
+-- Idempotency key of the authorization at the payment gateway, stored before the gateway is called so a
+-- retried authorization is deduplicated by the provider instead of authorizing the payment twice.
+ALTER TABLE payment_module.payments
+    ADD COLUMN IF NOT EXISTS authorization_key VARCHAR(64) NOT NULL DEFAULT '';
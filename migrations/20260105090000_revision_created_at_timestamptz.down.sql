@@ -0,0 +1,2 @@
+ALTER TABLE payment_settings_module.payment_setting_revisions
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE current_setting('TimeZone');
@@ -0,0 +1,5 @@
+-- Revisions are read as of an instant, so their creation time is stored as one. Existing values hold the
+-- wall clock of the application and are interpreted in the TimeZone of the session running the migration;
+-- SET TimeZone to the zone the application ran in first if the two differ.
+ALTER TABLE payment_settings_module.payment_setting_revisions
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE current_setting('TimeZone');
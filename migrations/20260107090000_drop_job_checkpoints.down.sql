@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS payment_module.job_checkpoints (
+    job_name VARCHAR(100) PRIMARY KEY,
+    last_cursor TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- The payment updater tracks its progress with claim leases instead of a shared checkpoint.
+DROP TABLE IF EXISTS payment_module.job_checkpoints;
@@ -0,0 +1 @@
+ALTER TABLE payment_module.payments DROP COLUMN IF EXISTS next_attempt_at;
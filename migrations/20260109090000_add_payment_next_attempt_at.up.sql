@@ -0,0 +1,3 @@
+-- Payments that failed to process are deferred until next_attempt_at, so the cron updater resumes with
+-- the payments it has not attempted yet instead of spending every run on the ones that keep failing.
+ALTER TABLE payment_module.payments ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP;
@@ -0,0 +1,34 @@
+// Package factory provides module initialization and dependency wiring.
+package factory
+
+import (
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs/internal/adapter/controller"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs/internal/adapter/repository"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs/internal/service"
+)
+
+// ModuleConfig contains all external dependencies required to initialize the Jobs module.
+type ModuleConfig struct {
+	DB *sql.DB
+}
+
+// NewModule assembles and wires the Jobs module using dependency injection.
+func NewModule(config ModuleConfig) *jobs.Module {
+	// Wire up outbound adapters (repositories)
+	jobRunRepo := repository.NewJobRunRepository(config.DB)
+
+	// Wire up the hexagon core (service)
+	jobRunService := service.NewJobRunService(jobRunRepo)
+
+	return &jobs.Module{
+		Service: jobRunService,
+		RegisterController: func(e *echo.Group) {
+			controller.NewJobRunController(e, jobRunService)
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs"
+)
+
+type JobRunResponse struct {
+	ID             string     `json:"id"`
+	JobName        string     `json:"jobName"`
+	Status         string     `json:"status"`
+	StartedAt      time.Time  `json:"startedAt"`
+	FinishedAt     *time.Time `json:"finishedAt"`
+	ProcessedCount int        `json:"processedCount"`
+	SuccessCount   int        `json:"successCount"`
+	ErrorCount     int        `json:"errorCount"`
+	Errors         []string   `json:"errors"`
+	DryRun         bool       `json:"dryRun"`
+	Host           string     `json:"host"`
+}
+
+func FromJobRunToResponse(run jobs.JobRun) JobRunResponse {
+	errs := run.Errors
+	if errs == nil {
+		errs = []string{}
+	}
+	return JobRunResponse{
+		ID:             run.ID,
+		JobName:        run.JobName,
+		Status:         run.Status,
+		StartedAt:      run.StartedAt,
+		FinishedAt:     run.FinishedAt,
+		ProcessedCount: run.ProcessedCount,
+		SuccessCount:   run.SuccessCount,
+		ErrorCount:     run.ErrorCount,
+		Errors:         errs,
+		DryRun:         run.DryRun,
+		Host:           run.Host,
+	}
+}
+
+func FromJobRunListToResponse(runs []jobs.JobRun) []JobRunResponse {
+	response := make([]JobRunResponse, len(runs))
+	for i, run := range runs {
+		response[i] = FromJobRunToResponse(run)
+	}
+	return response
+}
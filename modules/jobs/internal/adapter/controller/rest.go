@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs/internal/adapter/controller/dto"
+	pkgerrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+type jobRunController struct {
+	jobRunService jobs.IJobRunService
+}
+
+func NewJobRunController(e *echo.Group, jobRunService jobs.IJobRunService) (controller *jobRunController) {
+	controller = &jobRunController{jobRunService: jobRunService}
+	e.GET("/jobs/runs", controller.FetchJobRuns, requirePlatform)
+	return controller
+}
+
+// FetchJobRuns lists runs newest first, filtered by ?job= and ?status=.
+// The last successful run of a job is GET /jobs/runs?job=<name>&status=succeeded&limit=1.
+func (c *jobRunController) FetchJobRuns(ctx echo.Context) (err error) {
+	cursor := ctx.QueryParam("cursor")
+	limit := 10
+	if limitParam := ctx.QueryParam("limit"); limitParam != "" {
+		if val, convErr := strconv.Atoi(limitParam); convErr == nil && val > 0 {
+			limit = val
+		}
+	}
+
+	result, nextCursor, err := c.jobRunService.FetchJobRuns(ctx.Request().Context(), jobs.JobRunFetchParams{
+		Cursor:  cursor,
+		Limit:   limit,
+		JobName: ctx.QueryParam("job"),
+		Status:  ctx.QueryParam("status"),
+	})
+	if err != nil {
+		return err
+	}
+	ctx.Response().Header().Set("X-Next-Cursor", nextCursor)
+	return ctx.JSON(http.StatusOK, dto.FromJobRunListToResponse(result))
+}
+
+// requirePlatform rejects every request but those of the admin API key. Job runs are platform-wide and
+// their errors name the payments of every tenant, so no merchant may read them.
+func requirePlatform(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		id, ok := tenant.FromContext(ctx.Request().Context())
+		if !ok {
+			return tenant.ErrRequired
+		}
+		if id != tenant.Platform {
+			return pkgerrors.NewForbiddenError(fmt.Errorf("job runs are only available to the admin API key"))
+		}
+		return next(ctx)
+	}
+}
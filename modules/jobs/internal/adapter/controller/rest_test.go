@@ -0,0 +1,102 @@
+//go:build e2e
+
+package controller_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs"
+	jobsfactory "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs/factory"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs/internal/adapter/controller/dto"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/testutils"
+)
+
+type JobsControllerE2ETestSuite struct {
+	suite.Suite
+	pgContainer *testutils.PostgresContainer
+	echo        *echo.Echo
+	module      *jobs.Module
+}
+
+func (s *JobsControllerE2ETestSuite) SetupSuite() {
+	if testing.Short() {
+		s.T().Skip("Skipping E2E test in short mode")
+	}
+
+	s.pgContainer = testutils.SetupPostgres(s.T())
+	s.pgContainer.RunMigrations(s.T(), "../../../../../migrations")
+
+	s.echo = testutils.NewEchoForTest()
+	apiGroup := s.echo.Group("/api/v1")
+
+	s.module = jobsfactory.NewModule(jobsfactory.ModuleConfig{
+		DB: s.pgContainer.DB,
+	})
+	s.module.RegisterHTTPHandlers(apiGroup)
+}
+
+func (s *JobsControllerE2ETestSuite) TearDownSuite() {
+	s.pgContainer.Teardown(s.T())
+}
+
+func (s *JobsControllerE2ETestSuite) SetupTest() {
+	s.pgContainer.TruncateTables(s.T(), "jobs_module.job_runs")
+}
+
+type stubRunner struct {
+	err error
+}
+
+func (r stubRunner) Execute(context.Context) (interface{}, error) {
+	return nil, r.err
+}
+
+func (s *JobsControllerE2ETestSuite) TestE2E_FetchJobRuns_LastSuccessfulRun() {
+	succeeding := jobs.NewTracker(s.module.Service, "payment-updater", stubRunner{})
+	failing := jobs.NewTracker(s.module.Service, "payment-updater", stubRunner{err: assert.AnError})
+
+	_, err := succeeding.Execute(context.Background())
+	require.NoError(s.T(), err)
+	// IDs are only ordered across milliseconds
+	time.Sleep(2 * time.Millisecond)
+	_, err = failing.Execute(context.Background())
+	require.Error(s.T(), err)
+
+	rec := testutils.MakeAdminRequest(s.T(), s.echo, http.MethodGet, "/api/v1/jobs/runs?job=payment-updater&status=succeeded&limit=1", nil)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusOK)
+
+	var response []dto.JobRunResponse
+	testutils.ParseJSONResponse(s.T(), rec, &response)
+	require.Len(s.T(), response, 1)
+	assert.Equal(s.T(), jobs.StatusSucceeded, response[0].Status)
+	assert.NotNil(s.T(), response[0].FinishedAt)
+
+	rec = testutils.MakeAdminRequest(s.T(), s.echo, http.MethodGet, "/api/v1/jobs/runs?job=payment-updater", nil)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusOK)
+	testutils.ParseJSONResponse(s.T(), rec, &response)
+	require.Len(s.T(), response, 2)
+	assert.Equal(s.T(), jobs.StatusFailed, response[0].Status, "newest run first")
+	assert.Equal(s.T(), []string{assert.AnError.Error()}, response[0].Errors)
+}
+
+func (s *JobsControllerE2ETestSuite) TestE2E_FetchJobRuns_InvalidStatus() {
+	rec := testutils.MakeAdminRequest(s.T(), s.echo, http.MethodGet, "/api/v1/jobs/runs?status=done", nil)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusBadRequest)
+}
+
+func (s *JobsControllerE2ETestSuite) TestE2E_FetchJobRuns_MerchantForbidden() {
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, "/api/v1/jobs/runs", nil)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusForbidden)
+}
+
+func TestE2E_JobsControllerTestSuite(t *testing.T) {
+	suite.Run(t, new(JobsControllerE2ETestSuite))
+}
@@ -0,0 +1,59 @@
+package controller_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs/internal/adapter/controller"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs/mocks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/middlewares"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+func TestJobRunController_RequiresAdminKey(t *testing.T) {
+	apiKeys := map[string]string{"key_a": "merchant_a"}
+	const adminKey = "key_admin"
+
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "without a key", expectedStatus: http.StatusUnauthorized},
+		{name: "merchant key", authorization: "Bearer key_a", expectedStatus: http.StatusForbidden},
+		{name: "admin key", authorization: "Bearer " + adminKey, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The service is only reached by the admin key
+			service := mocks.NewMockIJobRunService(t)
+			if tt.expectedStatus == http.StatusOK {
+				service.On("FetchJobRuns", mock.MatchedBy(func(ctx context.Context) bool {
+					id, ok := tenant.FromContext(ctx)
+					return ok && id == tenant.Platform
+				}), mock.AnythingOfType("jobs.JobRunFetchParams")).Return([]jobs.JobRun{}, "", nil)
+			}
+			e := echo.New()
+			e.HTTPErrorHandler = middlewares.ErrorHandler
+			e.Use(middlewares.Tenant(apiKeys, adminKey))
+			controller.NewJobRunController(e.Group("/api/v1"), service)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs/runs", nil)
+			if tt.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+		})
+	}
+}
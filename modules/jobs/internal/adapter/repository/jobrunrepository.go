@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs/internal/ports"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/dbutils"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/transaction"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/uniqueid"
+)
+
+type jobRunRepository struct {
+	db *sql.DB
+}
+
+func NewJobRunRepository(db *sql.DB) ports.IJobRunRepository {
+	return &jobRunRepository{
+		db: db,
+	}
+}
+
+func (r *jobRunRepository) qb() sq.StatementBuilderType {
+	return sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+}
+
+func (r *jobRunRepository) conn(ctx context.Context) transaction.DBTX {
+	return transaction.Executor(ctx, r.db)
+}
+
+var jobRunColumns = []string{
+	"id", "job_name", "status", "started_at", "finished_at",
+	"processed_count", "success_count", "error_count", "errors", "dry_run", "host",
+}
+
+func (r *jobRunRepository) FetchJobRuns(ctx context.Context, params jobs.JobRunFetchParams) (result []jobs.JobRun, nextCursor string, err error) {
+	query := r.qb().Select(jobRunColumns...).
+		From("jobs_module.job_runs").
+		OrderBy("id DESC")
+
+	if params.Cursor != "" {
+		cursorID, decodeErr := dbutils.DecodeCursor(params.Cursor)
+		if decodeErr != nil {
+			return nil, "", decodeErr
+		}
+		query = query.Where(sq.Lt{"id": cursorID})
+	}
+
+	if params.JobName != "" {
+		query = query.Where(sq.Eq{"job_name": params.JobName})
+	}
+
+	if params.Status != "" {
+		query = query.Where(sq.Eq{"status": params.Status})
+	}
+
+	// Fetch one extra to determine if there's a next page
+	query = query.Limit(uint64(params.Limit + 1))
+
+	rows, err := query.RunWith(r.conn(ctx)).QueryContext(ctx)
+	if err != nil {
+		return nil, "", dbutils.HandlePostgresError(err)
+	}
+	defer func() {
+		errClose := rows.Close()
+		if errClose != nil {
+			log.Error().Err(errClose).Msg("failed to close rows")
+		}
+	}()
+
+	result = make([]jobs.JobRun, 0)
+	for rows.Next() {
+		var (
+			run        jobs.JobRun
+			finishedAt sql.NullTime
+		)
+		err := rows.Scan(&run.ID, &run.JobName, &run.Status, &run.StartedAt, &finishedAt,
+			&run.ProcessedCount, &run.SuccessCount, &run.ErrorCount, pq.Array(&run.Errors), &run.DryRun, &run.Host)
+		if err != nil {
+			return nil, "", err
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		result = append(result, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", dbutils.HandlePostgresError(err)
+	}
+
+	if len(result) > params.Limit {
+		result = result[:params.Limit]
+		nextCursor = dbutils.EncodeCursor(result[len(result)-1].ID)
+	}
+
+	return result, nextCursor, nil
+}
+
+func (r *jobRunRepository) CreateJobRun(ctx context.Context, run *jobs.JobRun) (err error) {
+	run.ID, err = uniqueid.GeneratePK("jrun")
+	if err != nil {
+		return err
+	}
+
+	_, err = r.qb().Insert("jobs_module.job_runs").
+		Columns(jobRunColumns...).
+		Values(run.ID, run.JobName, run.Status, run.StartedAt, run.FinishedAt,
+			run.ProcessedCount, run.SuccessCount, run.ErrorCount, pq.Array(nonNil(run.Errors)), run.DryRun, run.Host).
+		RunWith(r.conn(ctx)).
+		ExecContext(ctx)
+	if err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+
+	return nil
+}
+
+func (r *jobRunRepository) UpdateJobRun(ctx context.Context, run *jobs.JobRun) (err error) {
+	res, err := r.qb().Update("jobs_module.job_runs").
+		Set("status", run.Status).
+		Set("finished_at", run.FinishedAt).
+		Set("processed_count", run.ProcessedCount).
+		Set("success_count", run.SuccessCount).
+		Set("error_count", run.ErrorCount).
+		Set("errors", pq.Array(nonNil(run.Errors))).
+		Set("dry_run", run.DryRun).
+		Where(sq.Eq{"id": run.ID}).
+		RunWith(r.conn(ctx)).
+		ExecContext(ctx)
+	if err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+	if rowsAffected == 0 {
+		return errors.ErrDataNotFound
+	}
+
+	return nil
+}
+
+// nonNil stores an empty array rather than NULL for runs without errors.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs"
+	pkgerrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/testutils"
+)
+
+type JobRunRepositoryTestSuite struct {
+	suite.Suite
+	pgContainer *testutils.PostgresContainer
+	repo        *jobRunRepository
+}
+
+func (s *JobRunRepositoryTestSuite) SetupSuite() {
+	if testing.Short() {
+		s.T().Skip("Skipping repository integration test in short mode")
+	}
+	s.pgContainer = testutils.SetupPostgres(s.T())
+	s.pgContainer.RunMigrations(s.T(), "../../../../../migrations")
+	s.repo = &jobRunRepository{db: s.pgContainer.DB}
+}
+
+func (s *JobRunRepositoryTestSuite) TearDownSuite() {
+	s.pgContainer.Teardown(s.T())
+}
+
+func (s *JobRunRepositoryTestSuite) SetupTest() {
+	s.pgContainer.TruncateTables(s.T(), "jobs_module.job_runs")
+}
+
+func (s *JobRunRepositoryTestSuite) createRun(jobName, status string) jobs.JobRun {
+	// IDs are only ordered across milliseconds
+	time.Sleep(2 * time.Millisecond)
+	run := &jobs.JobRun{JobName: jobName, Status: jobs.StatusRunning, StartedAt: time.Now(), Host: "host-1"}
+	require.NoError(s.T(), s.repo.CreateJobRun(context.Background(), run))
+	if status != jobs.StatusRunning {
+		finishedAt := time.Now()
+		run.Status = status
+		run.FinishedAt = &finishedAt
+		run.ProcessedCount = 3
+		run.SuccessCount = 2
+		run.ErrorCount = 1
+		run.Errors = []string{"failed to update payment"}
+		require.NoError(s.T(), s.repo.UpdateJobRun(context.Background(), run))
+	}
+	return *run
+}
+
+func (s *JobRunRepositoryTestSuite) TestJobRunLifecycle() {
+	created := s.createRun("payment-updater", jobs.StatusFailed)
+
+	runs, nextCursor, err := s.repo.FetchJobRuns(context.Background(), jobs.JobRunFetchParams{Limit: 10})
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), nextCursor)
+	require.Len(s.T(), runs, 1)
+
+	run := runs[0]
+	assert.Equal(s.T(), created.ID, run.ID)
+	assert.Equal(s.T(), jobs.StatusFailed, run.Status)
+	assert.NotNil(s.T(), run.FinishedAt)
+	assert.Equal(s.T(), 3, run.ProcessedCount)
+	assert.Equal(s.T(), 1, run.ErrorCount)
+	assert.Equal(s.T(), []string{"failed to update payment"}, run.Errors)
+	assert.Equal(s.T(), "host-1", run.Host)
+}
+
+func (s *JobRunRepositoryTestSuite) TestFetchJobRuns_Filters() {
+	s.createRun("payment-updater", jobs.StatusSucceeded)
+	s.createRun("payment-updater", jobs.StatusFailed)
+	lastSuccess := s.createRun("payment-updater", jobs.StatusSucceeded)
+	s.createRun("payment-updater", jobs.StatusRunning)
+	s.createRun("other-job", jobs.StatusSucceeded)
+
+	runs, nextCursor, err := s.repo.FetchJobRuns(context.Background(), jobs.JobRunFetchParams{
+		JobName: "payment-updater",
+		Status:  jobs.StatusSucceeded,
+		Limit:   1,
+	})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), runs, 1)
+	assert.Equal(s.T(), lastSuccess.ID, runs[0].ID, "newest run first")
+	assert.NotEmpty(s.T(), nextCursor)
+
+	runs, _, err = s.repo.FetchJobRuns(context.Background(), jobs.JobRunFetchParams{JobName: "payment-updater", Limit: 10})
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), runs, 4)
+
+	runs, _, err = s.repo.FetchJobRuns(context.Background(), jobs.JobRunFetchParams{Status: jobs.StatusRunning, Limit: 10})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), runs, 1)
+	assert.Nil(s.T(), runs[0].FinishedAt)
+}
+
+func (s *JobRunRepositoryTestSuite) TestUpdateJobRun_NotFound() {
+	err := s.repo.UpdateJobRun(context.Background(), &jobs.JobRun{ID: "jrun_missing", Status: jobs.StatusSucceeded})
+	assert.Equal(s.T(), pkgerrors.ErrDataNotFound, err)
+}
+
+func TestJobRunRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(JobRunRepositoryTestSuite))
+}
@@ -0,0 +1,20 @@
+// Package ports defines the interfaces (ports) for external dependencies in hexagonal architecture.
+//
+// Ports are contracts that adapters must implement. By defining ports in the module:
+//   - The core domain remains independent of external systems (databases, APIs, etc.)
+//   - Adapters (implementations) can be swapped without changing business logic
+//   - Testing becomes easier through mock implementations
+package ports
+
+import (
+	"context"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs"
+)
+
+// IJobRunRepository is an outbound port for job run persistence.
+type IJobRunRepository interface {
+	FetchJobRuns(ctx context.Context, params jobs.JobRunFetchParams) (result []jobs.JobRun, nextCursor string, err error)
+	CreateJobRun(ctx context.Context, run *jobs.JobRun) error
+	UpdateJobRun(ctx context.Context, run *jobs.JobRun) error
+}
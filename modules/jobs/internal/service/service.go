@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs/internal/ports"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+)
+
+type JobRunService struct {
+	repo ports.IJobRunRepository
+}
+
+func NewJobRunService(repo ports.IJobRunRepository) (service *JobRunService) {
+	return &JobRunService{repo: repo}
+}
+
+func (s *JobRunService) FetchJobRuns(ctx context.Context, params jobs.JobRunFetchParams) (result []jobs.JobRun, nextCursor string, err error) {
+	switch params.Status {
+	case "", jobs.StatusRunning, jobs.StatusSucceeded, jobs.StatusFailed, jobs.StatusSkipped:
+	default:
+		return nil, "", errors.NewValidationError(fmt.Errorf("unknown job run status %q", params.Status))
+	}
+	return s.repo.FetchJobRuns(ctx, params)
+}
+
+func (s *JobRunService) StartJobRun(ctx context.Context, run *jobs.JobRun) (err error) {
+	if run.JobName == "" {
+		return errors.NewValidationError(fmt.Errorf("job name is required"))
+	}
+	run.Status = jobs.StatusRunning
+	run.StartedAt = time.Now()
+	run.FinishedAt = nil
+	return s.repo.CreateJobRun(ctx, run)
+}
+
+func (s *JobRunService) FinishJobRun(ctx context.Context, run *jobs.JobRun) (err error) {
+	switch run.Status {
+	case jobs.StatusSucceeded, jobs.StatusFailed, jobs.StatusSkipped:
+	default:
+		return errors.NewValidationError(fmt.Errorf("a finished job run must be %s, %s or %s, got %q", jobs.StatusSucceeded, jobs.StatusFailed, jobs.StatusSkipped, run.Status))
+	}
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	return s.repo.UpdateJobRun(ctx, run)
+}
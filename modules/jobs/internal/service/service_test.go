@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs/internal/ports/mocks"
+)
+
+func TestJobRunService_FetchJobRuns(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		callsRepo   bool
+		expectError bool
+	}{
+		{name: "all statuses", status: "", callsRepo: true},
+		{name: "succeeded runs", status: jobs.StatusSucceeded, callsRepo: true},
+		{name: "unknown status", status: "done", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := mocks.NewMockIJobRunRepository(t)
+			params := jobs.JobRunFetchParams{JobName: "payment-updater", Status: tt.status, Limit: 10}
+			if tt.callsRepo {
+				mockRepo.On("FetchJobRuns", mock.Anything, params).Return([]jobs.JobRun{{ID: "jrun_1"}}, "", nil)
+			}
+
+			service := NewJobRunService(mockRepo)
+			result, _, err := service.FetchJobRuns(context.Background(), params)
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, result, 1)
+			}
+		})
+	}
+}
+
+func TestJobRunService_StartAndFinishJobRun(t *testing.T) {
+	mockRepo := mocks.NewMockIJobRunRepository(t)
+	mockRepo.On("CreateJobRun", mock.Anything, mock.MatchedBy(func(run *jobs.JobRun) bool {
+		return run.Status == jobs.StatusRunning && !run.StartedAt.IsZero() && run.FinishedAt == nil
+	})).Return(nil)
+	mockRepo.On("UpdateJobRun", mock.Anything, mock.MatchedBy(func(run *jobs.JobRun) bool {
+		return run.Status == jobs.StatusSucceeded && run.FinishedAt != nil
+	})).Return(nil)
+
+	service := NewJobRunService(mockRepo)
+	run := &jobs.JobRun{JobName: "payment-updater"}
+	assert.NoError(t, service.StartJobRun(context.Background(), run))
+
+	// A run can only finish as succeeded or failed
+	assert.Error(t, service.FinishJobRun(context.Background(), run))
+
+	run.Status = jobs.StatusSucceeded
+	assert.NoError(t, service.FinishJobRun(context.Background(), run))
+
+	assert.Error(t, service.StartJobRun(context.Background(), &jobs.JobRun{}), "job name is required")
+}
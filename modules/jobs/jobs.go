@@ -0,0 +1,58 @@
+// Package jobs implements the Jobs module in a modular monolith architecture.
+//
+// This module keeps the run history of scheduled jobs:
+//   - Domain model (JobRun) records one run of a module CronAdapter
+//   - Service interface (IJobRunService) exposes the module's public API
+//   - Module.Track wraps the jobs of other modules so every run is recorded
+//
+// On-call engineers query the history (e.g. the last successful run of a job) through
+// GET /api/v1/jobs/runs instead of searching log files.
+package jobs
+
+import (
+	"context"
+	"time"
+)
+
+// Statuses of a job run.
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	// StatusSkipped marks a run that did no work because the job was running on another instance.
+	StatusSkipped = "skipped"
+)
+
+// JobRun represents one run of a scheduled job in the domain model.
+// FinishedAt is nil while the run is in progress.
+type JobRun struct {
+	ID             string     `json:"id"`
+	JobName        string     `json:"jobName"`
+	Status         string     `json:"status"`
+	StartedAt      time.Time  `json:"startedAt"`
+	FinishedAt     *time.Time `json:"finishedAt"`
+	ProcessedCount int        `json:"processedCount"`
+	SuccessCount   int        `json:"successCount"`
+	ErrorCount     int        `json:"errorCount"`
+	Errors         []string   `json:"errors"`
+	DryRun         bool       `json:"dryRun"`
+	Host           string     `json:"host"`
+}
+
+// JobRunFetchParams contains filtering and pagination parameters for querying job runs.
+// Runs are returned newest first.
+type JobRunFetchParams struct {
+	JobName string `json:"jobName"`
+	Status  string `json:"status"`
+	Limit   int    `json:"limit"`
+	Cursor  string `json:"cursor"`
+}
+
+// IJobRunService defines the public API of the Jobs module.
+type IJobRunService interface {
+	FetchJobRuns(ctx context.Context, params JobRunFetchParams) (result []JobRun, nextCursor string, err error)
+	// StartJobRun records a run as running and assigns its ID and start time.
+	StartJobRun(ctx context.Context, run *JobRun) error
+	// FinishJobRun stores the final status, counts and errors of a started run.
+	FinishJobRun(ctx context.Context, run *JobRun) error
+}
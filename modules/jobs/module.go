@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+// Module encapsulates the Jobs module following hexagonal architecture.
+//
+// Structure:
+//   - Service: The hexagon core (run history)
+//   - RegisterController: Inbound adapter (REST API)
+//
+// Other modules never depend on this module. The composition root passes their jobs
+// through Track before handing them to the scheduler or running them once.
+type Module struct {
+	Service            IJobRunService
+	RegisterController func(*echo.Group)
+}
+
+// RegisterHTTPHandlers registers all HTTP endpoints for this module.
+// This allows the monolith to compose multiple modules by registering their routes
+// into the main HTTP router, maintaining module encapsulation.
+func (m *Module) RegisterHTTPHandlers(e *echo.Group) {
+	if m.RegisterController != nil {
+		m.RegisterController(e)
+	}
+}
+
+// Track returns the jobs with runners that record every run in the run history.
+func (m *Module) Track(jobs ...scheduler.Job) []scheduler.Job {
+	tracked := make([]scheduler.Job, len(jobs))
+	for i, job := range jobs {
+		job.Runner = NewTracker(m.Service, job.Name, job.Runner)
+		tracked[i] = job
+	}
+	return tracked
+}
@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+// maxRecordedErrors caps the error messages stored per run; ErrorCount keeps the real total.
+const maxRecordedErrors = 50
+
+// Tracker is a scheduler.Runner decorator recording every run of the wrapped runner.
+// Counts are taken from results implementing scheduler.Summarizer. Failing to record a run
+// is logged and never fails the job itself. A runner that panics is recorded as failed before
+// the panic goes on to the caller, so its run is never left running.
+type Tracker struct {
+	service IJobRunService
+	jobName string
+	runner  scheduler.Runner
+	host    string
+}
+
+// NewTracker wraps runner so its runs are recorded under jobName.
+func NewTracker(service IJobRunService, jobName string, runner scheduler.Runner) *Tracker {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return &Tracker{
+		service: service,
+		jobName: jobName,
+		runner:  runner,
+		host:    host,
+	}
+}
+
+func (t *Tracker) Execute(ctx context.Context) (result interface{}, err error) {
+	run := &JobRun{JobName: t.jobName, Host: t.host}
+	started := true
+	if errStart := t.service.StartJobRun(ctx, run); errStart != nil {
+		log.Error().Err(errStart).Str("job", t.jobName).Msg("failed to record job run start")
+		started = false
+	}
+
+	if started {
+		defer func() {
+			if p := recover(); p != nil {
+				run.Status = StatusFailed
+				run.Errors = append(run.Errors, fmt.Sprintf("panic: %v", p))
+				t.finish(ctx, run)
+				panic(p)
+			}
+		}()
+	}
+
+	result, err = t.runner.Execute(ctx)
+	if !started {
+		return result, err
+	}
+
+	run.Status = StatusSucceeded
+	if summarizer, ok := result.(scheduler.Summarizer); ok {
+		summary := summarizer.RunSummary()
+		if summary.Skipped {
+			run.Status = StatusSkipped
+		}
+		run.ProcessedCount = summary.ProcessedCount
+		run.SuccessCount = summary.SuccessCount
+		run.ErrorCount = summary.ErrorCount
+		run.Errors = summary.Errors
+		run.DryRun = summary.DryRun
+	}
+
+	if err != nil {
+		run.Status = StatusFailed
+		if !slices.Contains(run.Errors, err.Error()) {
+			run.Errors = append(run.Errors, err.Error())
+		}
+	}
+	if len(run.Errors) > maxRecordedErrors {
+		run.Errors = run.Errors[:maxRecordedErrors]
+	}
+
+	t.finish(ctx, run)
+	return result, err
+}
+
+// finish records the result of run. The run is recorded even when it stopped because ctx was cancelled.
+func (t *Tracker) finish(ctx context.Context, run *JobRun) {
+	if errFinish := t.service.FinishJobRun(context.WithoutCancel(ctx), run); errFinish != nil {
+		log.Error().Err(errFinish).Str("job", t.jobName).Str("run_id", run.ID).Msg("failed to record job run result")
+	}
+}
@@ -0,0 +1,134 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/jobs/mocks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+type summaryResult struct {
+	summary scheduler.RunSummary
+}
+
+func (r summaryResult) RunSummary() scheduler.RunSummary {
+	return r.summary
+}
+
+type runnerFunc func(ctx context.Context) (interface{}, error)
+
+func (f runnerFunc) Execute(ctx context.Context) (interface{}, error) {
+	return f(ctx)
+}
+
+func TestTracker_Execute(t *testing.T) {
+	errJob := errors.New("failed to load checkpoint")
+
+	tests := []struct {
+		name           string
+		result         interface{}
+		err            error
+		expectedStatus string
+		expectedErrors []string
+		expectedCount  int
+	}{
+		{
+			name:           "successful run with summary",
+			result:         summaryResult{scheduler.RunSummary{ProcessedCount: 5, SuccessCount: 5, DryRun: true}},
+			expectedStatus: jobs.StatusSucceeded,
+			expectedCount:  5,
+		},
+		{
+			name:           "skipped run",
+			result:         summaryResult{scheduler.RunSummary{Skipped: true, Errors: []string{"lock held by host-a"}}},
+			expectedStatus: jobs.StatusSkipped,
+			expectedErrors: []string{"lock held by host-a"},
+		},
+		{
+			name:           "failed run without result",
+			err:            errJob,
+			expectedStatus: jobs.StatusFailed,
+			expectedErrors: []string{errJob.Error()},
+		},
+		{
+			name:           "failed run keeps item errors",
+			result:         summaryResult{scheduler.RunSummary{ProcessedCount: 2, ErrorCount: 1, Errors: []string{"payment pay_1 failed"}}},
+			err:            errJob,
+			expectedStatus: jobs.StatusFailed,
+			expectedErrors: []string{"payment pay_1 failed", errJob.Error()},
+			expectedCount:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := mocks.NewMockIJobRunService(t)
+			mockService.On("StartJobRun", mock.Anything, mock.MatchedBy(func(run *jobs.JobRun) bool {
+				return run.JobName == "payment-updater" && run.Host != ""
+			})).Return(nil)
+
+			var finished jobs.JobRun
+			mockService.On("FinishJobRun", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				finished = *args.Get(1).(*jobs.JobRun)
+			}).Return(nil)
+
+			runner := runnerFunc(func(context.Context) (interface{}, error) { return tt.result, tt.err })
+			tracker := jobs.NewTracker(mockService, "payment-updater", runner)
+			result, err := tracker.Execute(context.Background())
+
+			assert.Equal(t, tt.result, result)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.expectedStatus, finished.Status)
+			assert.Equal(t, tt.expectedErrors, finished.Errors)
+			assert.Equal(t, tt.expectedCount, finished.ProcessedCount)
+		})
+	}
+}
+
+func TestTracker_Execute_RecordingFailureDoesNotFailJob(t *testing.T) {
+	mockService := mocks.NewMockIJobRunService(t)
+	mockService.On("StartJobRun", mock.Anything, mock.Anything).Return(errors.New("connection refused"))
+
+	ran := false
+	runner := runnerFunc(func(context.Context) (interface{}, error) {
+		ran = true
+		return nil, nil
+	})
+
+	_, err := jobs.NewTracker(mockService, "payment-updater", runner).Execute(context.Background())
+
+	assert.NoError(t, err)
+	assert.True(t, ran)
+	mockService.AssertNotCalled(t, "FinishJobRun", mock.Anything, mock.Anything)
+}
+
+func TestTracker_Execute_PanicRecordsFailedRun(t *testing.T) {
+	mockService := mocks.NewMockIJobRunService(t)
+	mockService.On("StartJobRun", mock.Anything, mock.Anything).Return(nil)
+
+	var finished jobs.JobRun
+	mockService.On("FinishJobRun", mock.MatchedBy(func(ctx context.Context) bool {
+		return ctx.Err() == nil
+	}), mock.Anything).Run(func(args mock.Arguments) {
+		finished = *args.Get(1).(*jobs.JobRun)
+	}).Return(nil).Once()
+
+	runner := runnerFunc(func(context.Context) (interface{}, error) {
+		panic("nil map")
+	})
+
+	// The run is recorded with a cancelled context too, and the panic still reaches the caller
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.PanicsWithValue(t, "nil map", func() {
+		_, _ = jobs.NewTracker(mockService, "payment-updater", runner).Execute(ctx)
+	})
+	assert.Equal(t, jobs.StatusFailed, finished.Status)
+	assert.Equal(t, []string{"panic: nil map"}, finished.Errors)
+}
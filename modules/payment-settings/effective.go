@@ -0,0 +1,98 @@
+package paymentsettings
+
+import (
+	"time"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+// GlobalCurrency is the currency of the settings that apply to every currency without a setting of its own,
+// e.g. a payment_timeout_seconds shared by all currencies.
+const GlobalCurrency = "*"
+
+// Scopes an effective setting is resolved from.
+const (
+	ScopeGlobal   = "global"
+	ScopeCurrency = "currency"
+)
+
+// EffectiveSetting is the value a key resolves to for a currency, and the setting it comes from.
+// TenantID is empty when the value is a platform default.
+type EffectiveSetting struct {
+	Value     string     `json:"value"`
+	Scope     string     `json:"scope"`
+	TenantID  string     `json:"tenantId"`
+	SettingID string     `json:"settingId"`
+	ValidTo   *time.Time `json:"validTo"`
+}
+
+// EffectiveSettingsParams selects the currency to resolve the settings of, at EffectiveAt; the zero time means now.
+type EffectiveSettingsParams struct {
+	Currency    string    `json:"currency"`
+	EffectiveAt time.Time `json:"effectiveAt"`
+}
+
+// EffectiveSettings is the merged view of the active settings of a currency for a tenant: every key of the
+// currency scope, and every key of the global scope the currency does not override, the tenant's own
+// settings taking precedence over the platform defaults.
+type EffectiveSettings struct {
+	TenantID    string                      `json:"tenantId"`
+	Currency    string                      `json:"currency"`
+	EffectiveAt time.Time                   `json:"effectiveAt"`
+	Settings    map[string]EffectiveSetting `json:"settings"`
+}
+
+// Lookup returns the effective setting of key.
+func (e EffectiveSettings) Lookup(key string) (EffectiveSetting, bool) {
+	setting, ok := e.Settings[key]
+	return setting, ok
+}
+
+// ResolveEffectiveSettings merges the settings of currency and of GlobalCurrency of tenantID and of the
+// platform. A key resolves to the first of: the tenant's currency setting, the tenant's global setting,
+// the platform's currency setting and the platform's global setting. Settings of other currencies and
+// other tenants are ignored.
+func ResolveEffectiveSettings(tenantID, currency string, settings []PaymentSetting) map[string]EffectiveSetting {
+	result := make(map[string]EffectiveSetting, len(settings))
+	ranks := make(map[string]int, len(settings))
+	for _, setting := range settings {
+		rank := precedence(tenantID, currency, setting)
+		if rank == 0 || rank <= ranks[setting.SettingKey] {
+			continue
+		}
+		ranks[setting.SettingKey] = rank
+
+		scope := ScopeCurrency
+		if setting.Currency == GlobalCurrency {
+			scope = ScopeGlobal
+		}
+		result[setting.SettingKey] = EffectiveSetting{
+			Value:     setting.SettingValue,
+			Scope:     scope,
+			TenantID:  setting.TenantID,
+			SettingID: setting.ID,
+			ValidTo:   setting.ValidTo,
+		}
+	}
+	return result
+}
+
+// precedence ranks a setting among the ones its key may resolve to, higher first; 0 means it does not apply.
+func precedence(tenantID, currency string, setting PaymentSetting) (rank int) {
+	switch setting.Currency {
+	case currency:
+		rank = 2
+	case GlobalCurrency:
+		rank = 1
+	default:
+		return 0
+	}
+	switch setting.TenantID {
+	case tenant.Platform:
+	case tenantID:
+		rank += 2
+	default:
+		return 0
+	}
+	return rank
+}
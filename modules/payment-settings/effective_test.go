@@ -0,0 +1,112 @@
+package paymentsettings_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+func TestResolveEffectiveSettings(t *testing.T) {
+	settings := []paymentsettings.PaymentSetting{
+		{ID: "pset_usd_timeout", SettingKey: paymentsettings.SettingKeyPaymentTimeoutSeconds, SettingValue: "600", Currency: "USD"},
+		{ID: "pset_global_timeout", SettingKey: paymentsettings.SettingKeyPaymentTimeoutSeconds, SettingValue: "300", Currency: paymentsettings.GlobalCurrency},
+		{ID: "pset_global_max", SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "10000.00", Currency: paymentsettings.GlobalCurrency},
+		{ID: "pset_eur_min", SettingKey: paymentsettings.SettingKeyMinTransactionAmount, SettingValue: "1.00", Currency: "EUR"},
+	}
+
+	tests := []struct {
+		name     string
+		currency string
+		expected map[string]paymentsettings.EffectiveSetting
+	}{
+		{
+			name:     "currency overrides the global default",
+			currency: "USD",
+			expected: map[string]paymentsettings.EffectiveSetting{
+				paymentsettings.SettingKeyPaymentTimeoutSeconds: {Value: "600", Scope: paymentsettings.ScopeCurrency, SettingID: "pset_usd_timeout"},
+				paymentsettings.SettingKeyMaxTransactionAmount:  {Value: "10000.00", Scope: paymentsettings.ScopeGlobal, SettingID: "pset_global_max"},
+			},
+		},
+		{
+			name:     "currency without overrides",
+			currency: "EUR",
+			expected: map[string]paymentsettings.EffectiveSetting{
+				paymentsettings.SettingKeyPaymentTimeoutSeconds: {Value: "300", Scope: paymentsettings.ScopeGlobal, SettingID: "pset_global_timeout"},
+				paymentsettings.SettingKeyMaxTransactionAmount:  {Value: "10000.00", Scope: paymentsettings.ScopeGlobal, SettingID: "pset_global_max"},
+				paymentsettings.SettingKeyMinTransactionAmount:  {Value: "1.00", Scope: paymentsettings.ScopeCurrency, SettingID: "pset_eur_min"},
+			},
+		},
+		{
+			name:     "global scope only",
+			currency: paymentsettings.GlobalCurrency,
+			expected: map[string]paymentsettings.EffectiveSetting{
+				paymentsettings.SettingKeyPaymentTimeoutSeconds: {Value: "300", Scope: paymentsettings.ScopeGlobal, SettingID: "pset_global_timeout"},
+				paymentsettings.SettingKeyMaxTransactionAmount:  {Value: "10000.00", Scope: paymentsettings.ScopeGlobal, SettingID: "pset_global_max"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, paymentsettings.ResolveEffectiveSettings("merchant_a", tt.currency, settings))
+		})
+	}
+}
+
+func TestResolveEffectiveSettings_TenantOverrides(t *testing.T) {
+	settings := []paymentsettings.PaymentSetting{
+		{ID: "pset_platform_usd_min", SettingKey: paymentsettings.SettingKeyMinTransactionAmount, SettingValue: "10.00", Currency: "USD"},
+		{ID: "pset_platform_global_min", SettingKey: paymentsettings.SettingKeyMinTransactionAmount, SettingValue: "5.00", Currency: paymentsettings.GlobalCurrency},
+		{ID: "pset_platform_usd_max", SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "10000.00", Currency: "USD"},
+		{ID: "pset_platform_timeout", SettingKey: paymentsettings.SettingKeyPaymentTimeoutSeconds, SettingValue: "300", Currency: paymentsettings.GlobalCurrency},
+		{ID: "pset_a_global_min", TenantID: "merchant_a", SettingKey: paymentsettings.SettingKeyMinTransactionAmount, SettingValue: "1.00", Currency: paymentsettings.GlobalCurrency},
+		{ID: "pset_a_usd_timeout", TenantID: "merchant_a", SettingKey: paymentsettings.SettingKeyPaymentTimeoutSeconds, SettingValue: "600", Currency: "USD"},
+		{ID: "pset_a_global_timeout", TenantID: "merchant_a", SettingKey: paymentsettings.SettingKeyPaymentTimeoutSeconds, SettingValue: "900", Currency: paymentsettings.GlobalCurrency},
+		{ID: "pset_b_usd_max", TenantID: "merchant_b", SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "50.00", Currency: "USD"},
+	}
+
+	tests := []struct {
+		name     string
+		tenantID string
+		expected map[string]paymentsettings.EffectiveSetting
+	}{
+		{
+			name:     "tenant settings override the platform defaults",
+			tenantID: "merchant_a",
+			expected: map[string]paymentsettings.EffectiveSetting{
+				// A global setting of the tenant wins over a currency setting of the platform
+				paymentsettings.SettingKeyMinTransactionAmount: {Value: "1.00", Scope: paymentsettings.ScopeGlobal, TenantID: "merchant_a", SettingID: "pset_a_global_min"},
+				paymentsettings.SettingKeyMaxTransactionAmount: {Value: "10000.00", Scope: paymentsettings.ScopeCurrency, SettingID: "pset_platform_usd_max"},
+				// and a currency setting of the tenant over its global one
+				paymentsettings.SettingKeyPaymentTimeoutSeconds: {Value: "600", Scope: paymentsettings.ScopeCurrency, TenantID: "merchant_a", SettingID: "pset_a_usd_timeout"},
+			},
+		},
+		{
+			name:     "tenant without settings",
+			tenantID: "merchant_c",
+			expected: map[string]paymentsettings.EffectiveSetting{
+				paymentsettings.SettingKeyMinTransactionAmount:  {Value: "10.00", Scope: paymentsettings.ScopeCurrency, SettingID: "pset_platform_usd_min"},
+				paymentsettings.SettingKeyMaxTransactionAmount:  {Value: "10000.00", Scope: paymentsettings.ScopeCurrency, SettingID: "pset_platform_usd_max"},
+				paymentsettings.SettingKeyPaymentTimeoutSeconds: {Value: "300", Scope: paymentsettings.ScopeGlobal, SettingID: "pset_platform_timeout"},
+			},
+		},
+		{
+			name:     "platform",
+			tenantID: tenant.Platform,
+			expected: map[string]paymentsettings.EffectiveSetting{
+				paymentsettings.SettingKeyMinTransactionAmount:  {Value: "10.00", Scope: paymentsettings.ScopeCurrency, SettingID: "pset_platform_usd_min"},
+				paymentsettings.SettingKeyMaxTransactionAmount:  {Value: "10000.00", Scope: paymentsettings.ScopeCurrency, SettingID: "pset_platform_usd_max"},
+				paymentsettings.SettingKeyPaymentTimeoutSeconds: {Value: "300", Scope: paymentsettings.ScopeGlobal, SettingID: "pset_platform_timeout"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, paymentsettings.ResolveEffectiveSettings(tt.tenantID, "USD", settings))
+		})
+	}
+}
@@ -11,6 +11,7 @@ package factory
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
@@ -18,11 +19,35 @@ import (
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/internal/adapter/controller"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/internal/adapter/repository"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/internal/service"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/eventbus"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/idempotency"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/middlewares"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/outbox"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/transaction"
 )
 
 // ModuleConfig contains all external dependencies required to initialize the Payment Settings module.
 type ModuleConfig struct {
 	DB *sql.DB
+	// EnableIdempotency opts POST /payment-settings into Idempotency-Key handling.
+	EnableIdempotency bool
+	IdempotencyKeyTTL time.Duration
+	// Schema declares the type and constraints of the known setting keys (default: paymentsettings.DefaultSchema()).
+	Schema *paymentsettings.Schema
+	// RejectUnknownKeys refuses to create or update settings whose key the schema does not declare.
+	RejectUnknownKeys bool
+	// EventBus is the in-process bus shared by all modules, on which committed setting changes are
+	// published as PaymentSettingChanged. Other modules subscribe to it in their own factory.
+	EventBus *eventbus.Bus
+	// TxManager runs a change and its outbox event in one transaction (default: a manager on DB).
+	TxManager transaction.Manager
+	// EventPublisher receives the domain events of the module outbox (default: outbox.LogPublisher).
+	EventPublisher outbox.Publisher
+	// OutboxSchedule is the default cron expression of the outbox relay in the scheduler (default every 10 seconds).
+	OutboxSchedule string
+	// IdempotencyPurgeSchedule is the default cron expression of the expired idempotency key purge (default hourly).
+	IdempotencyPurgeSchedule string
 }
 
 // NewModule assembles and wires the complete Payment Settings module using dependency injection.
@@ -37,14 +62,57 @@ type ModuleConfig struct {
 func NewModule(config ModuleConfig) *paymentsettings.Module {
 	// Wire up outbound adapters (repositories)
 	settingsRepo := repository.NewPaymentSettingsRepository(config.DB)
+	revisionRepo := repository.NewRevisionRepository(config.DB)
+	// Domain events are recorded in the payment settings module schema
+	outboxStore := outbox.NewPostgresStore(config.DB, "payment_settings_module.outbox")
+
+	if config.TxManager == nil {
+		config.TxManager = transaction.NewManager(config.DB, transaction.Config{})
+	}
+	// Without a shared bus nobody hears about setting changes
+	if config.EventBus == nil {
+		config.EventBus = eventbus.New(eventbus.Config{})
+	}
+
+	if config.Schema == nil {
+		config.Schema = paymentsettings.DefaultSchema()
+	}
+	if config.RejectUnknownKeys {
+		config.Schema.RejectUnknownKeys = true
+	}
 
 	// Wire up the hexagon core (service)
-	settingsService := service.NewPaymentSettingsService(settingsRepo)
+	settingsService := service.NewPaymentSettingsService(settingsRepo, revisionRepo, config.Schema, config.TxManager, outboxStore, config.EventBus)
+
+	if config.EventPublisher == nil {
+		config.EventPublisher = outbox.LogPublisher{}
+	}
+	outboxRelay := outbox.NewRelay(outboxStore, config.EventPublisher, outbox.RelayConfig{Name: paymentsettings.OutboxRelayJobName})
+	if config.OutboxSchedule == "" {
+		config.OutboxSchedule = "@every 10s"
+	}
+
+	// Opt-in idempotency keys are persisted in the payment settings module schema
+	idempotencyStore := idempotency.NewPostgresStore(config.DB, "payment_settings_module.idempotency_keys")
+	var createMiddlewares []echo.MiddlewareFunc
+	if config.EnableIdempotency {
+		createMiddlewares = append(createMiddlewares, middlewares.Idempotency(idempotencyStore, config.IdempotencyKeyTTL))
+	}
+	// Expired keys are purged even when idempotency is disabled, so keys stored while it was enabled go too
+	idempotencyPurger := idempotency.NewPurger(idempotencyStore, paymentsettings.IdempotencyPurgeJobName)
+	if config.IdempotencyPurgeSchedule == "" {
+		config.IdempotencyPurgeSchedule = "@hourly"
+	}
 
 	return &paymentsettings.Module{
 		Service: settingsService,
 		RegisterController: func(e *echo.Group) {
-			controller.NewPaymentSettingController(e, settingsService)
+			controller.NewPaymentSettingController(e, settingsService, createMiddlewares...)
+		},
+		OutboxRelay: outboxRelay,
+		Jobs: []scheduler.Job{
+			{Name: paymentsettings.OutboxRelayJobName, Schedule: config.OutboxSchedule, Runner: outboxRelay},
+			{Name: paymentsettings.IdempotencyPurgeJobName, Schedule: config.IdempotencyPurgeSchedule, Runner: idempotencyPurger},
 		},
 	}
 }
@@ -0,0 +1,178 @@
+package paymentsettings
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of a settings file.
+const (
+	FileFormatJSON = "json"
+	FileFormatYAML = "yaml"
+	FileFormatCSV  = "csv"
+)
+
+// csvHeader is the first row of a CSV settings file.
+var csvHeader = []string{"setting_key", "currency", "setting_value", "status"}
+
+// SettingsFileEntry is a setting as exported and imported. Settings are identified by key and currency, as
+// IDs differ between environments; validity ranges are environment-specific too and are not part of a file.
+type SettingsFileEntry struct {
+	SettingKey   string `json:"settingKey" yaml:"settingKey"`
+	Currency     string `json:"currency" yaml:"currency"`
+	SettingValue string `json:"settingValue" yaml:"settingValue"`
+	Status       string `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// SettingsFile is the document of a JSON or YAML settings file.
+type SettingsFile struct {
+	Settings []SettingsFileEntry `json:"settings" yaml:"settings"`
+}
+
+// FileFormatFromPath returns the format of a settings file from its extension.
+func FileFormatFromPath(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return FileFormatJSON, nil
+	case ".yaml", ".yml":
+		return FileFormatYAML, nil
+	case ".csv":
+		return FileFormatCSV, nil
+	default:
+		return "", fmt.Errorf("cannot tell the format of %q, expected a .json, .yaml, .yml or .csv file", path)
+	}
+}
+
+// EncodeSettingsFile writes the settings in format, ordered by key and currency.
+func EncodeSettingsFile(w io.Writer, format string, settings []PaymentSetting) error {
+	entries := make([]SettingsFileEntry, 0, len(settings))
+	for _, setting := range settings {
+		entries = append(entries, SettingsFileEntry{
+			SettingKey:   setting.SettingKey,
+			Currency:     setting.Currency,
+			SettingValue: setting.SettingValue,
+			Status:       setting.Status,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].SettingKey != entries[j].SettingKey {
+			return entries[i].SettingKey < entries[j].SettingKey
+		}
+		return entries[i].Currency < entries[j].Currency
+	})
+
+	switch format {
+	case FileFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(SettingsFile{Settings: entries})
+	case FileFormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(SettingsFile{Settings: entries}); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case FileFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := writer.Write([]string{entry.SettingKey, entry.Currency, entry.SettingValue, entry.Status}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("unsupported settings file format %q", format)
+	}
+}
+
+// DecodeSettingsFile reads the settings of a file in format. Unknown fields are rejected, so a typo does not
+// silently drop a value, and an empty status means SettingStatusActive.
+func DecodeSettingsFile(r io.Reader, format string) ([]PaymentSetting, error) {
+	var entries []SettingsFileEntry
+	switch format {
+	case FileFormatJSON:
+		var file SettingsFile
+		decoder := json.NewDecoder(r)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, fmt.Errorf("invalid JSON settings file: %w", err)
+		}
+		entries = file.Settings
+	case FileFormatYAML:
+		var file SettingsFile
+		decoder := yaml.NewDecoder(r)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("invalid YAML settings file: %w", err)
+		}
+		entries = file.Settings
+	case FileFormatCSV:
+		var err error
+		if entries, err = decodeCSVEntries(r); err != nil {
+			return nil, fmt.Errorf("invalid CSV settings file: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported settings file format %q", format)
+	}
+
+	settings := make([]PaymentSetting, 0, len(entries))
+	for _, entry := range entries {
+		status := entry.Status
+		if status == "" {
+			status = SettingStatusActive
+		}
+		settings = append(settings, PaymentSetting{
+			SettingKey:   entry.SettingKey,
+			Currency:     entry.Currency,
+			SettingValue: entry.SettingValue,
+			Status:       status,
+		})
+	}
+	return settings, nil
+}
+
+func decodeCSVEntries(r io.Reader) ([]SettingsFileEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvHeader)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, column := range csvHeader {
+		if strings.TrimSpace(header[i]) != column {
+			return nil, fmt.Errorf("expected header %s", strings.Join(csvHeader, ","))
+		}
+	}
+
+	var entries []SettingsFileEntry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, SettingsFileEntry{
+			SettingKey:   record[0],
+			Currency:     record[1],
+			SettingValue: record[2],
+			Status:       record[3],
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package paymentsettings_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
+)
+
+func TestSettingsFileRoundTrip(t *testing.T) {
+	settings := []paymentsettings.PaymentSetting{
+		{ID: "pset_usd_max", SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "10000.00", Currency: "USD", Status: paymentsettings.SettingStatusActive, Version: 3},
+		{ID: "pset_global_timeout", SettingKey: paymentsettings.SettingKeyPaymentTimeoutSeconds, SettingValue: "300", Currency: paymentsettings.GlobalCurrency, Status: paymentsettings.SettingStatusActive},
+		{ID: "pset_eur_max", SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "5,000", Currency: "EUR", Status: "inactive"},
+	}
+	// Ordered by key and currency, without IDs, versions and validity
+	expected := []paymentsettings.PaymentSetting{
+		{SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "5,000", Currency: "EUR", Status: "inactive"},
+		{SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "10000.00", Currency: "USD", Status: paymentsettings.SettingStatusActive},
+		{SettingKey: paymentsettings.SettingKeyPaymentTimeoutSeconds, SettingValue: "300", Currency: paymentsettings.GlobalCurrency, Status: paymentsettings.SettingStatusActive},
+	}
+
+	for _, format := range []string{paymentsettings.FileFormatJSON, paymentsettings.FileFormatYAML, paymentsettings.FileFormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, paymentsettings.EncodeSettingsFile(&buf, format, settings))
+
+			decoded, err := paymentsettings.DecodeSettingsFile(&buf, format)
+			require.NoError(t, err)
+			assert.Equal(t, expected, decoded)
+		})
+	}
+}
+
+func TestDecodeSettingsFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		content     string
+		expected    []paymentsettings.PaymentSetting
+		expectedErr string
+	}{
+		{
+			name:     "status defaults to active",
+			format:   paymentsettings.FileFormatYAML,
+			content:  "settings:\n  - settingKey: payment_timeout_seconds\n    currency: USD\n    settingValue: \"600\"\n",
+			expected: []paymentsettings.PaymentSetting{{SettingKey: "payment_timeout_seconds", Currency: "USD", SettingValue: "600", Status: paymentsettings.SettingStatusActive}},
+		},
+		{
+			name:     "empty YAML file",
+			format:   paymentsettings.FileFormatYAML,
+			content:  "",
+			expected: []paymentsettings.PaymentSetting{},
+		},
+		{
+			name:        "unknown JSON field",
+			format:      paymentsettings.FileFormatJSON,
+			content:     `{"settings":[{"settingKey":"payment_timeout_seconds","currency":"USD","value":"600"}]}`,
+			expectedErr: "unknown field",
+		},
+		{
+			name:        "unknown YAML field",
+			format:      paymentsettings.FileFormatYAML,
+			content:     "settings:\n  - settingKey: payment_timeout_seconds\n    currency: USD\n    value: \"600\"\n",
+			expectedErr: "not found in type",
+		},
+		{
+			name:        "unexpected CSV header",
+			format:      paymentsettings.FileFormatCSV,
+			content:     "key,currency,value,status\n",
+			expectedErr: "expected header",
+		},
+		{
+			name:        "unsupported format",
+			format:      "xml",
+			expectedErr: "unsupported settings file format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := paymentsettings.DecodeSettingsFile(strings.NewReader(tt.content), tt.format)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, decoded)
+		})
+	}
+}
+
+func TestFileFormatFromPath(t *testing.T) {
+	format, err := paymentsettings.FileFormatFromPath("settings/prod.YML")
+	require.NoError(t, err)
+	assert.Equal(t, paymentsettings.FileFormatYAML, format)
+
+	_, err = paymentsettings.FileFormatFromPath("settings.txt")
+	assert.Error(t, err)
+}
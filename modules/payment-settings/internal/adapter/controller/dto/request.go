@@ -1,40 +1,49 @@
 package dto
 
 import (
+	"encoding/json"
 	"time"
 
 	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
 )
 
 type CreatePaymentSettingRequest struct {
-	SettingKey   string    `json:"settingKey"`
-	SettingValue string    `json:"settingValue"`
-	Currency     string    `json:"currency"`
-	Status       string    `json:"status"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	SettingKey   string     `json:"settingKey"`
+	SettingValue string     `json:"settingValue"`
+	Currency     string     `json:"currency"`
+	Status       string     `json:"status"`
+	ValidFrom    *time.Time `json:"validFrom"`
+	ValidTo      *time.Time `json:"validTo"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
 }
 
+// ToPaymentSetting leaves ValidFrom zero when it is omitted, so the setting is in force from its creation.
 func (r *CreatePaymentSettingRequest) ToPaymentSetting() paymentsettings.PaymentSetting {
 	return paymentsettings.PaymentSetting{
 		SettingKey:   r.SettingKey,
 		SettingValue: r.SettingValue,
 		Currency:     r.Currency,
 		Status:       r.Status,
+		ValidFrom:    timeOrZero(r.ValidFrom),
+		ValidTo:      r.ValidTo,
 		CreatedAt:    r.CreatedAt,
 		UpdatedAt:    r.UpdatedAt,
 	}
 }
 
 type UpdatePaymentSettingRequest struct {
-	SettingKey   string    `json:"settingKey"`
-	SettingValue string    `json:"settingValue"`
-	Currency     string    `json:"currency"`
-	Status       string    `json:"status"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	SettingKey   string       `json:"settingKey"`
+	SettingValue string       `json:"settingValue"`
+	Currency     string       `json:"currency"`
+	Status       string       `json:"status"`
+	ValidFrom    *time.Time   `json:"validFrom"`
+	ValidTo      OptionalTime `json:"validTo,omitzero"`
+	CreatedAt    time.Time    `json:"createdAt"`
+	UpdatedAt    time.Time    `json:"updatedAt"`
 }
 
+// ToPaymentSetting leaves ValidFrom and ValidTo unset when they are omitted, so the stored ones are kept.
 func (r *UpdatePaymentSettingRequest) ToPaymentSetting(id string) paymentsettings.PaymentSetting {
 	return paymentsettings.PaymentSetting{
 		ID:           id,
@@ -42,7 +51,67 @@ func (r *UpdatePaymentSettingRequest) ToPaymentSetting(id string) paymentsetting
 		SettingValue: r.SettingValue,
 		Currency:     r.Currency,
 		Status:       r.Status,
+		ValidFrom:    timeOrZero(r.ValidFrom),
+		ValidTo:      r.ValidTo.ValidTo(),
 		CreatedAt:    r.CreatedAt,
 		UpdatedAt:    r.UpdatedAt,
 	}
 }
+
+// UpsertPaymentSettingRequest is the body of a setting addressed by its key and currency.
+type UpsertPaymentSettingRequest struct {
+	SettingValue string       `json:"settingValue"`
+	Status       string       `json:"status"`
+	ValidFrom    *time.Time   `json:"validFrom"`
+	ValidTo      OptionalTime `json:"validTo,omitzero"`
+}
+
+// ToPaymentSetting defaults the status to active, so a declarative configuration only lists values.
+// An existing setting keeps the validity the request omits.
+func (r *UpsertPaymentSettingRequest) ToPaymentSetting(key, currency string) paymentsettings.PaymentSetting {
+	status := r.Status
+	if status == "" {
+		status = paymentsettings.SettingStatusActive
+	}
+	return paymentsettings.PaymentSetting{
+		SettingKey:   key,
+		SettingValue: r.SettingValue,
+		Currency:     currency,
+		Status:       status,
+		ValidFrom:    timeOrZero(r.ValidFrom),
+		ValidTo:      r.ValidTo.ValidTo(),
+	}
+}
+
+// OptionalTime tells an omitted time from an explicit null, which removes the end of a validity.
+type OptionalTime struct {
+	Set  bool
+	Time *time.Time
+}
+
+func (t OptionalTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Time)
+}
+
+func (t *OptionalTime) UnmarshalJSON(data []byte) error {
+	t.Set = true
+	return json.Unmarshal(data, &t.Time)
+}
+
+// ValidTo maps the time to PaymentSetting.ValidTo: nil keeps the stored end, the zero time removes it.
+func (t OptionalTime) ValidTo() *time.Time {
+	if !t.Set {
+		return nil
+	}
+	if t.Time == nil {
+		return &time.Time{}
+	}
+	return t.Time
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
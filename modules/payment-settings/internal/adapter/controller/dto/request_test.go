@@ -0,0 +1,41 @@
+package dto_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/internal/adapter/controller/dto"
+)
+
+func TestUpdatePaymentSettingRequest_ValidTo(t *testing.T) {
+	validTo := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected *time.Time
+	}{
+		{name: "omitted keeps the stored end", body: `{"settingValue":"1"}`},
+		{name: "null removes the end", body: `{"settingValue":"1","validTo":null}`, expected: &time.Time{}},
+		{name: "time sets the end", body: `{"settingValue":"1","validTo":"2025-02-01T00:00:00Z"}`, expected: &validTo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req dto.UpdatePaymentSettingRequest
+			require.NoError(t, json.Unmarshal([]byte(tt.body), &req))
+
+			setting := req.ToPaymentSetting("pset_123")
+			if tt.expected == nil {
+				assert.Nil(t, setting.ValidTo)
+				return
+			}
+			require.NotNil(t, setting.ValidTo)
+			assert.True(t, tt.expected.Equal(*setting.ValidTo))
+		})
+	}
+}
@@ -7,22 +7,30 @@ import (
 )
 
 type PaymentSettingResponse struct {
-	ID           string    `json:"id"`
-	SettingKey   string    `json:"settingKey"`
-	SettingValue string    `json:"settingValue"`
-	Currency     string    `json:"currency"`
-	Status       string    `json:"status"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	ID           string     `json:"id"`
+	TenantID     string     `json:"tenantId"`
+	SettingKey   string     `json:"settingKey"`
+	SettingValue string     `json:"settingValue"`
+	Currency     string     `json:"currency"`
+	Status       string     `json:"status"`
+	ValidFrom    time.Time  `json:"validFrom"`
+	ValidTo      *time.Time `json:"validTo"`
+	Version      int64      `json:"version"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
 }
 
 func FromPaymentSettingToResponse(setting paymentsettings.PaymentSetting) PaymentSettingResponse {
 	return PaymentSettingResponse{
 		ID:           setting.ID,
+		TenantID:     setting.TenantID,
 		SettingKey:   setting.SettingKey,
 		SettingValue: setting.SettingValue,
 		Currency:     setting.Currency,
 		Status:       setting.Status,
+		ValidFrom:    setting.ValidFrom,
+		ValidTo:      setting.ValidTo,
+		Version:      setting.Version,
 		CreatedAt:    setting.CreatedAt,
 		UpdatedAt:    setting.UpdatedAt,
 	}
@@ -35,3 +43,75 @@ func FromPaymentSettingListToResponse(settings []paymentsettings.PaymentSetting)
 	}
 	return response
 }
+
+type SchemaResponse struct {
+	RejectUnknownKeys bool                            `json:"rejectUnknownKeys"`
+	Settings          []paymentsettings.SettingSchema `json:"settings"`
+}
+
+func FromSchemaToResponse(schema *paymentsettings.Schema) SchemaResponse {
+	return SchemaResponse{
+		RejectUnknownKeys: schema.RejectUnknownKeys,
+		Settings:          schema.Settings(),
+	}
+}
+
+type EffectiveSettingsResponse struct {
+	TenantID    string                                      `json:"tenantId"`
+	Currency    string                                      `json:"currency"`
+	EffectiveAt time.Time                                   `json:"effectiveAt"`
+	Settings    map[string]paymentsettings.EffectiveSetting `json:"settings"`
+}
+
+func FromEffectiveSettingsToResponse(effective paymentsettings.EffectiveSettings) EffectiveSettingsResponse {
+	return EffectiveSettingsResponse{
+		TenantID:    effective.TenantID,
+		Currency:    effective.Currency,
+		EffectiveAt: effective.EffectiveAt,
+		Settings:    effective.Settings,
+	}
+}
+
+type RevisionResponse struct {
+	ID         string     `json:"id"`
+	SettingID  string     `json:"settingId"`
+	Version    int64      `json:"version"`
+	Action     string     `json:"action"`
+	SettingKey string     `json:"settingKey"`
+	Currency   string     `json:"currency"`
+	Status     string     `json:"status"`
+	ValidFrom  time.Time  `json:"validFrom"`
+	ValidTo    *time.Time `json:"validTo"`
+	OldValue   *string    `json:"oldValue"`
+	NewValue   *string    `json:"newValue"`
+	Actor      string     `json:"actor"`
+	Reason     string     `json:"reason"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func FromRevisionToResponse(revision paymentsettings.SettingRevision) RevisionResponse {
+	return RevisionResponse{
+		ID:         revision.ID,
+		SettingID:  revision.SettingID,
+		Version:    revision.Version,
+		Action:     revision.Action,
+		SettingKey: revision.SettingKey,
+		Currency:   revision.Currency,
+		Status:     revision.Status,
+		ValidFrom:  revision.ValidFrom,
+		ValidTo:    revision.ValidTo,
+		OldValue:   revision.OldValue,
+		NewValue:   revision.NewValue,
+		Actor:      revision.Actor,
+		Reason:     revision.Reason,
+		CreatedAt:  revision.CreatedAt,
+	}
+}
+
+func FromRevisionListToResponse(revisions []paymentsettings.SettingRevision) []RevisionResponse {
+	response := make([]RevisionResponse, len(revisions))
+	for i, revision := range revisions {
+		response[i] = FromRevisionToResponse(revision)
+	}
+	return response
+}
@@ -1,45 +1,75 @@
 package controller
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
 	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/internal/adapter/controller/dto"
+	pkgerrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/etag"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+// Headers recorded in the revision of a change. The actor is taken as given; there is no authentication yet.
+const (
+	HeaderActor        = "X-Actor"
+	HeaderChangeReason = "X-Change-Reason"
 )
 
 type paymentSettingController struct {
 	paymentSettingsService paymentsettings.IPaymentSettingsService
 }
 
-func NewPaymentSettingController(e *echo.Group, paymentSettingsService paymentsettings.IPaymentSettingsService) (controller *paymentSettingController) {
+// NewPaymentSettingController registers the payment settings routes. createMiddlewares are applied to
+// POST /payment-settings only, e.g. the opt-in Idempotency-Key middleware. Every change requires a tenant.
+func NewPaymentSettingController(e *echo.Group, paymentSettingsService paymentsettings.IPaymentSettingsService, createMiddlewares ...echo.MiddlewareFunc) (controller *paymentSettingController) {
 	controller = &paymentSettingController{paymentSettingsService: paymentSettingsService}
 	e.GET("/payment-settings", controller.FetchPaymentSettings)
-	e.POST("/payment-settings", controller.CreatePaymentSetting)
+	// Registered before /payment-settings/:id; echo prefers the static segment either way
+	e.GET("/payment-settings/schema", controller.GetSchema)
+	e.GET("/payment-settings/upcoming", controller.FetchUpcomingPaymentSettings)
+	e.GET("/payment-settings/effective", controller.GetEffectiveSettings)
+	e.GET("/payment-settings/keys/:key/currencies/:currency", controller.GetPaymentSettingByKey)
+	e.PUT("/payment-settings/keys/:key/currencies/:currency", controller.UpsertPaymentSetting, requireTenant)
+	e.POST("/payment-settings", controller.CreatePaymentSetting, append([]echo.MiddlewareFunc{requireTenant}, createMiddlewares...)...)
 	e.GET("/payment-settings/:id", controller.GetPaymentSetting)
-	e.PUT("/payment-settings/:id", controller.UpdatePaymentSetting)
-	e.DELETE("/payment-settings/:id", controller.DeletePaymentSetting)
+	e.PUT("/payment-settings/:id", controller.UpdatePaymentSetting, requireTenant)
+	e.DELETE("/payment-settings/:id", controller.DeletePaymentSetting, requireTenant)
+	e.GET("/payment-settings/:id/revisions", controller.FetchSettingRevisions)
+	e.POST("/payment-settings/:id/revisions/:revisionId/rollback", controller.RollbackPaymentSetting, requireTenant)
 	return controller
 }
 
+// FetchPaymentSettings returns the settings in force now, or with ?effectiveAt=<RFC 3339 time> the ones in
+// force at that instant. With ?asOf=<RFC 3339 time> it returns the settings in force at that instant as
+// they were configured then, e.g. the limits a past payment was accepted with.
 func (c *paymentSettingController) FetchPaymentSettings(ctx echo.Context) (err error) {
-	cursor := ctx.QueryParam("cursor")
-	limit := 10
-	if limitParam := ctx.QueryParam("limit"); limitParam != "" {
-		if val, convErr := strconv.Atoi(limitParam); convErr == nil && val > 0 {
-			limit = val
-		}
+	params, err := fetchParams(ctx)
+	if err != nil {
+		return err
 	}
+	params.Cursor = ctx.QueryParam("cursor")
 
-	result, nextCursor, err := c.paymentSettingsService.FetchPaymentSettings(paymentsettings.PaymentSettingFetchParams{
-		Cursor:     cursor,
-		Limit:      limit,
-		Currency:   ctx.QueryParam("currency"),
-		SettingKey: ctx.QueryParam("settingKey"),
-		Status:     ctx.QueryParam("status"),
-	})
+	var result []paymentsettings.PaymentSetting
+	var nextCursor string
+	if ctx.QueryParam("asOf") != "" {
+		if !params.EffectiveAt.IsZero() {
+			return pkgerrors.NewValidationError(fmt.Errorf("asOf and effectiveAt cannot be combined"))
+		}
+		var at time.Time
+		if at, err = timeParam(ctx, "asOf"); err != nil {
+			return err
+		}
+		result, nextCursor, err = c.paymentSettingsService.FetchPaymentSettingsAsOf(ctx.Request().Context(), at, params)
+	} else {
+		result, nextCursor, err = c.paymentSettingsService.FetchPaymentSettings(ctx.Request().Context(), params)
+	}
 	if err != nil {
 		return err
 	}
@@ -47,47 +77,216 @@ func (c *paymentSettingController) FetchPaymentSettings(ctx echo.Context) (err e
 	return ctx.JSON(http.StatusOK, dto.FromPaymentSettingListToResponse(result))
 }
 
+// FetchUpcomingPaymentSettings returns the settings scheduled to come into force, soonest first.
+func (c *paymentSettingController) FetchUpcomingPaymentSettings(ctx echo.Context) (err error) {
+	params, err := fetchParams(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := c.paymentSettingsService.FetchUpcomingPaymentSettings(ctx.Request().Context(), params)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, dto.FromPaymentSettingListToResponse(result))
+}
+
+// GetEffectiveSettings returns the settings of ?currency=, falling back to the global defaults, in force now
+// or at ?effectiveAt=<RFC 3339 time>.
+func (c *paymentSettingController) GetEffectiveSettings(ctx echo.Context) (err error) {
+	params := paymentsettings.EffectiveSettingsParams{Currency: ctx.QueryParam("currency")}
+	if ctx.QueryParam("effectiveAt") != "" {
+		if params.EffectiveAt, err = timeParam(ctx, "effectiveAt"); err != nil {
+			return err
+		}
+	}
+	result, err := c.paymentSettingsService.GetEffectiveSettings(ctx.Request().Context(), params)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, dto.FromEffectiveSettingsToResponse(result))
+}
+
+// GetSchema publishes the declared setting keys and whether undeclared keys are rejected.
+func (c *paymentSettingController) GetSchema(ctx echo.Context) (err error) {
+	return ctx.JSON(http.StatusOK, dto.FromSchemaToResponse(c.paymentSettingsService.Schema()))
+}
+
 func (c *paymentSettingController) CreatePaymentSetting(ctx echo.Context) (err error) {
 	var paymentSettingRequest *dto.CreatePaymentSettingRequest
 	if err = ctx.Bind(&paymentSettingRequest); err != nil {
 		return err
 	}
 	paymentSetting := paymentSettingRequest.ToPaymentSetting()
-	err = c.paymentSettingsService.CreatePaymentSetting(&paymentSetting)
+	err = c.paymentSettingsService.CreatePaymentSetting(changeContext(ctx), &paymentSetting)
 	if err != nil {
 		return err
 	}
+	ctx.Response().Header().Set(etag.HeaderETag, etag.Format(paymentSetting.Version))
 	return ctx.JSON(http.StatusCreated, dto.FromPaymentSettingToResponse(paymentSetting))
 }
 
 func (c *paymentSettingController) GetPaymentSetting(ctx echo.Context) (err error) {
 	id := ctx.Param("id")
-	paymentSetting, err := c.paymentSettingsService.GetPaymentSetting(id)
+	paymentSetting, err := c.paymentSettingsService.GetPaymentSetting(ctx.Request().Context(), id)
 	if err != nil {
 		return err
 	}
+	ctx.Response().Header().Set(etag.HeaderETag, etag.Format(paymentSetting.Version))
 	return ctx.JSON(http.StatusOK, dto.FromPaymentSettingToResponse(paymentSetting))
 }
 
+// UpdatePaymentSetting requires an If-Match header carrying the ETag of the version being updated.
 func (c *paymentSettingController) UpdatePaymentSetting(ctx echo.Context) (err error) {
 	id := ctx.Param("id")
+	version, err := etag.ParseIfMatch(ctx.Request().Header.Get(etag.HeaderIfMatch))
+	if err != nil {
+		return err
+	}
 	var paymentSettingRequest *dto.UpdatePaymentSettingRequest
 	if err = ctx.Bind(&paymentSettingRequest); err != nil {
 		return err
 	}
 	paymentSetting := paymentSettingRequest.ToPaymentSetting(id)
-	err = c.paymentSettingsService.UpdatePaymentSetting(&paymentSetting)
+	paymentSetting.Version = version
+	err = c.paymentSettingsService.UpdatePaymentSetting(changeContext(ctx), &paymentSetting)
 	if err != nil {
 		return err
 	}
+	ctx.Response().Header().Set(etag.HeaderETag, etag.Format(paymentSetting.Version))
 	return ctx.JSON(http.StatusOK, dto.FromPaymentSettingToResponse(paymentSetting))
 }
 
+// GetPaymentSettingByKey returns the setting of a key and currency in force now, with its ETag.
+func (c *paymentSettingController) GetPaymentSettingByKey(ctx echo.Context) (err error) {
+	result, err := c.paymentSettingsService.GetPaymentSettingByKey(ctx.Request().Context(), ctx.Param("key"), ctx.Param("currency"))
+	if err != nil {
+		return err
+	}
+	ctx.Response().Header().Set(etag.HeaderETag, etag.Format(result.Version))
+	return ctx.JSON(http.StatusOK, dto.FromPaymentSettingToResponse(result))
+}
+
+// UpsertPaymentSetting creates or updates the setting of a key and currency, answering 201 or 200. Unlike
+// the updates by ID it takes If-Match as an option, so configuration tooling can apply its desired state
+// without reading it first.
+func (c *paymentSettingController) UpsertPaymentSetting(ctx echo.Context) (err error) {
+	version := etag.AnyVersion
+	if header := ctx.Request().Header.Get(etag.HeaderIfMatch); header != "" {
+		if version, err = etag.ParseIfMatch(header); err != nil {
+			return err
+		}
+	}
+	var upsertRequest *dto.UpsertPaymentSettingRequest
+	if err = ctx.Bind(&upsertRequest); err != nil {
+		return err
+	}
+	paymentSetting := upsertRequest.ToPaymentSetting(ctx.Param("key"), ctx.Param("currency"))
+	paymentSetting.Version = version
+	created, err := c.paymentSettingsService.UpsertPaymentSetting(changeContext(ctx), &paymentSetting)
+	if err != nil {
+		return err
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	ctx.Response().Header().Set(etag.HeaderETag, etag.Format(paymentSetting.Version))
+	return ctx.JSON(status, dto.FromPaymentSettingToResponse(paymentSetting))
+}
+
+// DeletePaymentSetting requires an If-Match header carrying the ETag of the version being deleted.
 func (c *paymentSettingController) DeletePaymentSetting(ctx echo.Context) (err error) {
 	id := ctx.Param("id")
-	err = c.paymentSettingsService.DeletePaymentSetting(id)
+	version, err := etag.ParseIfMatch(ctx.Request().Header.Get(etag.HeaderIfMatch))
+	if err != nil {
+		return err
+	}
+	err = c.paymentSettingsService.DeletePaymentSetting(changeContext(ctx), id, version)
 	if err != nil {
 		return err
 	}
 	return ctx.NoContent(http.StatusNoContent)
 }
+
+// FetchSettingRevisions returns the revisions of a setting, newest first, also after the setting was deleted.
+func (c *paymentSettingController) FetchSettingRevisions(ctx echo.Context) (err error) {
+	result, nextCursor, err := c.paymentSettingsService.FetchSettingRevisions(ctx.Request().Context(), paymentsettings.RevisionFetchParams{
+		SettingID: ctx.Param("id"),
+		Cursor:    ctx.QueryParam("cursor"),
+		Limit:     limitParam(ctx),
+	})
+	if err != nil {
+		return err
+	}
+	ctx.Response().Header().Set("X-Next-Cursor", nextCursor)
+	return ctx.JSON(http.StatusOK, dto.FromRevisionListToResponse(result))
+}
+
+// RollbackPaymentSetting requires an If-Match header carrying the ETag of the version being rolled back.
+func (c *paymentSettingController) RollbackPaymentSetting(ctx echo.Context) (err error) {
+	version, err := etag.ParseIfMatch(ctx.Request().Header.Get(etag.HeaderIfMatch))
+	if err != nil {
+		return err
+	}
+	paymentSetting, err := c.paymentSettingsService.RollbackPaymentSetting(changeContext(ctx), ctx.Param("id"), ctx.Param("revisionId"), version)
+	if err != nil {
+		return err
+	}
+	ctx.Response().Header().Set(etag.HeaderETag, etag.Format(paymentSetting.Version))
+	return ctx.JSON(http.StatusOK, dto.FromPaymentSettingToResponse(paymentSetting))
+}
+
+// requireTenant rejects changes made without a tenant. Only the admin API key acts for tenant.Platform,
+// so the defaults every tenant falls back to cannot be changed by an unauthenticated request.
+func requireTenant(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		if _, ok := tenant.FromContext(ctx.Request().Context()); !ok {
+			return tenant.ErrRequired
+		}
+		return next(ctx)
+	}
+}
+
+// changeContext returns the request context carrying the actor and reason of a change.
+func changeContext(ctx echo.Context) context.Context {
+	return paymentsettings.WithChangeInfo(ctx.Request().Context(), paymentsettings.ChangeInfo{
+		Actor:  ctx.Request().Header.Get(HeaderActor),
+		Reason: ctx.Request().Header.Get(HeaderChangeReason),
+	})
+}
+
+// fetchParams returns the filters shared by the list endpoints.
+func fetchParams(ctx echo.Context) (params paymentsettings.PaymentSettingFetchParams, err error) {
+	params = paymentsettings.PaymentSettingFetchParams{
+		Limit:      limitParam(ctx),
+		Currency:   ctx.QueryParam("currency"),
+		SettingKey: ctx.QueryParam("settingKey"),
+		Status:     ctx.QueryParam("status"),
+	}
+	if ctx.QueryParam("effectiveAt") != "" {
+		if params.EffectiveAt, err = timeParam(ctx, "effectiveAt"); err != nil {
+			return params, err
+		}
+	}
+	return params, nil
+}
+
+// timeParam parses an RFC 3339 query parameter.
+func timeParam(ctx echo.Context, name string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, ctx.QueryParam(name))
+	if err != nil {
+		return time.Time{}, pkgerrors.NewValidationError(fmt.Errorf("%s must be an RFC 3339 time, e.g. 2025-01-31T12:00:00Z", name))
+	}
+	return t, nil
+}
+
+// limitParam returns the page size of the request, 10 unless ?limit= is a positive number.
+func limitParam(ctx echo.Context) int {
+	limit := 10
+	if limitParam := ctx.QueryParam("limit"); limitParam != "" {
+		if val, convErr := strconv.Atoi(limitParam); convErr == nil && val > 0 {
+			limit = val
+		}
+	}
+	return limit
+}
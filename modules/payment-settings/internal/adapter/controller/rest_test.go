@@ -5,7 +5,9 @@ package controller_test
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -13,7 +15,9 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	paymentsettingsfactory "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/factory"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/internal/adapter/controller"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/internal/adapter/controller/dto"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/etag"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/testutils"
 )
 
@@ -46,7 +50,7 @@ func (s *PaymentSettingsControllerE2ETestSuite) TearDownSuite() {
 }
 
 func (s *PaymentSettingsControllerE2ETestSuite) SetupTest() {
-	s.pgContainer.TruncateTables(s.T(), "payment_settings", "payments")
+	s.pgContainer.TruncateTables(s.T(), "payment_settings", "payments", "payment_settings_module.outbox", "payment_settings_module.payment_setting_revisions")
 }
 
 func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_CreatePaymentSetting_Success() {
@@ -240,9 +244,11 @@ func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_UpdatePaymentSetting_Suc
 		Status:       "inactive",
 	}
 
-	updateRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPut, fmt.Sprintf("/api/v1/payment-settings/%s", createResponse.ID), updateReq)
+	ifMatch := map[string]string{etag.HeaderIfMatch: createRec.Header().Get(etag.HeaderETag)}
+	updateRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPut, fmt.Sprintf("/api/v1/payment-settings/%s", createResponse.ID), updateReq, ifMatch)
 
 	testutils.AssertStatusCode(s.T(), updateRec, http.StatusOK)
+	assert.Equal(s.T(), etag.Format(2), updateRec.Header().Get(etag.HeaderETag))
 
 	var updateResponse dto.PaymentSettingResponse
 	testutils.ParseJSONResponse(s.T(), updateRec, &updateResponse)
@@ -252,6 +258,35 @@ func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_UpdatePaymentSetting_Suc
 	assert.Equal(s.T(), updateReq.SettingValue, updateResponse.SettingValue)
 	assert.Equal(s.T(), updateReq.Currency, updateResponse.Currency)
 	assert.Equal(s.T(), updateReq.Status, updateResponse.Status)
+	assert.Equal(s.T(), int64(2), updateResponse.Version)
+
+	staleRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPut, fmt.Sprintf("/api/v1/payment-settings/%s", createResponse.ID), updateReq, ifMatch)
+	testutils.AssertStatusCode(s.T(), staleRec, http.StatusPreconditionFailed)
+}
+
+func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_UpdatePaymentSetting_MissingIfMatch() {
+	createReq := dto.CreatePaymentSettingRequest{
+		SettingKey:   "rate",
+		SettingValue: "1.0",
+		Currency:     "USD",
+		Status:       "active",
+	}
+	createRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payment-settings", createReq)
+	require.Equal(s.T(), http.StatusCreated, createRec.Code)
+
+	var createResponse dto.PaymentSettingResponse
+	testutils.ParseJSONResponse(s.T(), createRec, &createResponse)
+
+	updateReq := dto.UpdatePaymentSettingRequest{
+		SettingKey:   "rate",
+		SettingValue: "2.0",
+		Currency:     "USD",
+		Status:       "active",
+	}
+
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPut, fmt.Sprintf("/api/v1/payment-settings/%s", createResponse.ID), updateReq)
+
+	testutils.AssertStatusCode(s.T(), rec, http.StatusPreconditionRequired)
 }
 
 func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_UpdatePaymentSetting_NotFound() {
@@ -262,7 +297,8 @@ func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_UpdatePaymentSetting_Not
 		Status:       "inactive",
 	}
 
-	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPut, "/api/v1/payment-settings/pset_nonexistent", updateReq)
+	ifMatch := map[string]string{etag.HeaderIfMatch: "*"}
+	rec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPut, "/api/v1/payment-settings/pset_nonexistent", updateReq, ifMatch)
 
 	testutils.AssertStatusCode(s.T(), rec, http.StatusNotFound)
 }
@@ -280,7 +316,8 @@ func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_DeletePaymentSetting_Suc
 	var createResponse dto.PaymentSettingResponse
 	testutils.ParseJSONResponse(s.T(), createRec, &createResponse)
 
-	deleteRec := testutils.MakeRequest(s.T(), s.echo, http.MethodDelete, fmt.Sprintf("/api/v1/payment-settings/%s", createResponse.ID), nil)
+	ifMatch := map[string]string{etag.HeaderIfMatch: createRec.Header().Get(etag.HeaderETag)}
+	deleteRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodDelete, fmt.Sprintf("/api/v1/payment-settings/%s", createResponse.ID), nil, ifMatch)
 
 	testutils.AssertStatusCode(s.T(), deleteRec, http.StatusNoContent)
 
@@ -289,7 +326,8 @@ func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_DeletePaymentSetting_Suc
 }
 
 func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_DeletePaymentSetting_NotFound() {
-	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodDelete, "/api/v1/payment-settings/pset_nonexistent", nil)
+	ifMatch := map[string]string{etag.HeaderIfMatch: "*"}
+	rec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodDelete, "/api/v1/payment-settings/pset_nonexistent", nil, ifMatch)
 
 	testutils.AssertStatusCode(s.T(), rec, http.StatusNotFound)
 }
@@ -300,3 +338,190 @@ func TestE2E_PaymentSettingsControllerTestSuite(t *testing.T) {
 	}
 	suite.Run(t, new(PaymentSettingsControllerE2ETestSuite))
 }
+
+func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_CreatePaymentSetting_InvalidTypedValue() {
+	createReq := dto.CreatePaymentSettingRequest{
+		SettingKey:   "max_transaction_amount",
+		SettingValue: "1O000",
+		Currency:     "USD",
+		Status:       "active",
+	}
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payment-settings", createReq)
+
+	testutils.AssertStatusCode(s.T(), rec, http.StatusBadRequest)
+}
+
+func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_GetSchema() {
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, "/api/v1/payment-settings/schema", nil)
+
+	testutils.AssertStatusCode(s.T(), rec, http.StatusOK)
+
+	var response dto.SchemaResponse
+	testutils.ParseJSONResponse(s.T(), rec, &response)
+
+	assert.False(s.T(), response.RejectUnknownKeys)
+	keys := make([]string, len(response.Settings))
+	for i, setting := range response.Settings {
+		keys[i] = setting.Key
+	}
+	assert.Equal(s.T(), []string{"max_transaction_amount", "min_transaction_amount", "payment_timeout_seconds"}, keys)
+}
+
+func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_Revisions_AsOfAndRollback() {
+	createReq := dto.CreatePaymentSettingRequest{
+		SettingKey:   "max_transaction_amount",
+		SettingValue: "10000.00",
+		Currency:     "USD",
+		Status:       "active",
+	}
+	createRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPost, "/api/v1/payment-settings", createReq, map[string]string{controller.HeaderActor: "alice"})
+	require.Equal(s.T(), http.StatusCreated, createRec.Code)
+	var created dto.PaymentSettingResponse
+	testutils.ParseJSONResponse(s.T(), createRec, &created)
+	afterCreate := time.Now()
+	time.Sleep(5 * time.Millisecond)
+
+	updateReq := dto.UpdatePaymentSettingRequest{SettingKey: "max_transaction_amount", SettingValue: "1.00", Currency: "USD", Status: "active"}
+	updateRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPut, fmt.Sprintf("/api/v1/payment-settings/%s", created.ID), updateReq, map[string]string{
+		etag.HeaderIfMatch:            createRec.Header().Get(etag.HeaderETag),
+		controller.HeaderActor:        "bob",
+		controller.HeaderChangeReason: "typo",
+	})
+	require.Equal(s.T(), http.StatusOK, updateRec.Code)
+
+	revisionsRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, fmt.Sprintf("/api/v1/payment-settings/%s/revisions", created.ID), nil)
+	testutils.AssertStatusCode(s.T(), revisionsRec, http.StatusOK)
+	var revisions []dto.RevisionResponse
+	testutils.ParseJSONResponse(s.T(), revisionsRec, &revisions)
+	require.Len(s.T(), revisions, 2)
+	assert.Equal(s.T(), "updated", revisions[0].Action)
+	assert.Equal(s.T(), "10000.00", *revisions[0].OldValue)
+	assert.Equal(s.T(), "1.00", *revisions[0].NewValue)
+	assert.Equal(s.T(), "bob", revisions[0].Actor)
+	assert.Equal(s.T(), "typo", revisions[0].Reason)
+	assert.Equal(s.T(), "created", revisions[1].Action)
+	assert.Equal(s.T(), "alice", revisions[1].Actor)
+
+	asOfRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, "/api/v1/payment-settings?currency=USD&asOf="+url.QueryEscape(afterCreate.Format(time.RFC3339Nano)), nil)
+	testutils.AssertStatusCode(s.T(), asOfRec, http.StatusOK)
+	var asOf []dto.PaymentSettingResponse
+	testutils.ParseJSONResponse(s.T(), asOfRec, &asOf)
+	require.Len(s.T(), asOf, 1)
+	assert.Equal(s.T(), "10000.00", asOf[0].SettingValue)
+
+	rollbackRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPost, fmt.Sprintf("/api/v1/payment-settings/%s/revisions/%s/rollback", created.ID, revisions[1].ID), nil, map[string]string{
+		etag.HeaderIfMatch: updateRec.Header().Get(etag.HeaderETag),
+	})
+	testutils.AssertStatusCode(s.T(), rollbackRec, http.StatusOK)
+	var rolledBack dto.PaymentSettingResponse
+	testutils.ParseJSONResponse(s.T(), rollbackRec, &rolledBack)
+	assert.Equal(s.T(), "10000.00", rolledBack.SettingValue)
+	assert.Equal(s.T(), int64(3), rolledBack.Version)
+
+	invalidRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, "/api/v1/payment-settings?asOf=yesterday", nil)
+	testutils.AssertStatusCode(s.T(), invalidRec, http.StatusBadRequest)
+}
+
+func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_ScheduledSettings() {
+	switchAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	currentReq := dto.CreatePaymentSettingRequest{SettingKey: "max_transaction_amount", SettingValue: "10000.00", Currency: "USD", Status: "active", ValidTo: &switchAt}
+	currentRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payment-settings", currentReq)
+	testutils.AssertStatusCode(s.T(), currentRec, http.StatusCreated)
+	var current dto.PaymentSettingResponse
+	testutils.ParseJSONResponse(s.T(), currentRec, &current)
+	assert.NotZero(s.T(), current.ValidFrom)
+
+	scheduledReq := dto.CreatePaymentSettingRequest{SettingKey: "max_transaction_amount", SettingValue: "5000.00", Currency: "USD", Status: "active", ValidFrom: &switchAt}
+	scheduledRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payment-settings", scheduledReq)
+	testutils.AssertStatusCode(s.T(), scheduledRec, http.StatusCreated)
+	var scheduled dto.PaymentSettingResponse
+	testutils.ParseJSONResponse(s.T(), scheduledRec, &scheduled)
+
+	overlappingReq := dto.CreatePaymentSettingRequest{SettingKey: "max_transaction_amount", SettingValue: "1.00", Currency: "USD", Status: "active", ValidFrom: &switchAt}
+	overlappingRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payment-settings", overlappingReq)
+	testutils.AssertStatusCode(s.T(), overlappingRec, http.StatusConflict)
+
+	nowRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, "/api/v1/payment-settings?currency=USD", nil)
+	testutils.AssertStatusCode(s.T(), nowRec, http.StatusOK)
+	var inForce []dto.PaymentSettingResponse
+	testutils.ParseJSONResponse(s.T(), nowRec, &inForce)
+	require.Len(s.T(), inForce, 1)
+	assert.Equal(s.T(), current.ID, inForce[0].ID)
+
+	laterRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, "/api/v1/payment-settings?currency=USD&effectiveAt="+url.QueryEscape(switchAt.Format(time.RFC3339)), nil)
+	testutils.AssertStatusCode(s.T(), laterRec, http.StatusOK)
+	var later []dto.PaymentSettingResponse
+	testutils.ParseJSONResponse(s.T(), laterRec, &later)
+	require.Len(s.T(), later, 1)
+	assert.Equal(s.T(), scheduled.ID, later[0].ID)
+
+	upcomingRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, "/api/v1/payment-settings/upcoming?currency=USD", nil)
+	testutils.AssertStatusCode(s.T(), upcomingRec, http.StatusOK)
+	var upcoming []dto.PaymentSettingResponse
+	testutils.ParseJSONResponse(s.T(), upcomingRec, &upcoming)
+	require.Len(s.T(), upcoming, 1)
+	assert.Equal(s.T(), scheduled.ID, upcoming[0].ID)
+	assert.True(s.T(), switchAt.Equal(upcoming[0].ValidFrom))
+
+	emptyRange := dto.CreatePaymentSettingRequest{SettingKey: "fee", SettingValue: "1.0", Currency: "USD", Status: "active", ValidFrom: &switchAt, ValidTo: &switchAt}
+	emptyRangeRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payment-settings", emptyRange)
+	testutils.AssertStatusCode(s.T(), emptyRangeRec, http.StatusBadRequest)
+}
+
+func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_EffectiveSettings() {
+	for _, req := range []dto.CreatePaymentSettingRequest{
+		{SettingKey: "payment_timeout_seconds", SettingValue: "300", Currency: "*", Status: "active"},
+		{SettingKey: "max_transaction_amount", SettingValue: "10000.00", Currency: "*", Status: "active"},
+		{SettingKey: "max_transaction_amount", SettingValue: "1000000", Currency: "JPY", Status: "active"},
+	} {
+		rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payment-settings", req)
+		testutils.AssertStatusCode(s.T(), rec, http.StatusCreated)
+	}
+
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, "/api/v1/payment-settings/effective?currency=JPY", nil)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusOK)
+	var response dto.EffectiveSettingsResponse
+	testutils.ParseJSONResponse(s.T(), rec, &response)
+
+	assert.Equal(s.T(), "JPY", response.Currency)
+	require.Len(s.T(), response.Settings, 2)
+	assert.Equal(s.T(), "1000000", response.Settings["max_transaction_amount"].Value)
+	assert.Equal(s.T(), "currency", response.Settings["max_transaction_amount"].Scope)
+	assert.Equal(s.T(), "300", response.Settings["payment_timeout_seconds"].Value)
+	assert.Equal(s.T(), "global", response.Settings["payment_timeout_seconds"].Scope)
+
+	missingCurrencyRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, "/api/v1/payment-settings/effective", nil)
+	testutils.AssertStatusCode(s.T(), missingCurrencyRec, http.StatusBadRequest)
+}
+
+func (s *PaymentSettingsControllerE2ETestSuite) TestE2E_PaymentSettingByKey() {
+	path := "/api/v1/payment-settings/keys/max_transaction_amount/currencies/USD"
+
+	missingRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, path, nil)
+	testutils.AssertStatusCode(s.T(), missingRec, http.StatusNotFound)
+
+	createRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPut, path, dto.UpsertPaymentSettingRequest{SettingValue: "10000.00"})
+	testutils.AssertStatusCode(s.T(), createRec, http.StatusCreated)
+	var created dto.PaymentSettingResponse
+	testutils.ParseJSONResponse(s.T(), createRec, &created)
+	assert.Equal(s.T(), "max_transaction_amount", created.SettingKey)
+	assert.Equal(s.T(), "USD", created.Currency)
+	assert.Equal(s.T(), "active", created.Status)
+
+	updateRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPut, path, dto.UpsertPaymentSettingRequest{SettingValue: "5000.00"})
+	testutils.AssertStatusCode(s.T(), updateRec, http.StatusOK)
+	assert.Equal(s.T(), `"2"`, updateRec.Header().Get(etag.HeaderETag))
+
+	getRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, path, nil)
+	testutils.AssertStatusCode(s.T(), getRec, http.StatusOK)
+	var fetched dto.PaymentSettingResponse
+	testutils.ParseJSONResponse(s.T(), getRec, &fetched)
+	assert.Equal(s.T(), created.ID, fetched.ID)
+	assert.Equal(s.T(), "5000.00", fetched.SettingValue)
+
+	staleRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPut, path, dto.UpsertPaymentSettingRequest{SettingValue: "1.00"}, map[string]string{
+		etag.HeaderIfMatch: createRec.Header().Get(etag.HeaderETag),
+	})
+	testutils.AssertStatusCode(s.T(), staleRec, http.StatusPreconditionFailed)
+}
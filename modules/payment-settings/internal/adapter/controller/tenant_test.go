@@ -0,0 +1,75 @@
+package controller_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/internal/adapter/controller"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/mocks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/etag"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/middlewares"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+func TestPaymentSettingController_ChangesRequireTenant(t *testing.T) {
+	apiKeys := map[string]string{"key_a": "merchant_a"}
+	const adminKey = "key_admin"
+	body := `{"settingKey":"max_transaction_amount","settingValue":"999999.00","currency":"USD","status":"active"}`
+
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodPost, path: "/api/v1/payment-settings"},
+		{method: http.MethodPut, path: "/api/v1/payment-settings/pset_1"},
+		{method: http.MethodPut, path: "/api/v1/payment-settings/keys/max_transaction_amount/currencies/USD"},
+		{method: http.MethodDelete, path: "/api/v1/payment-settings/pset_1"},
+		{method: http.MethodPost, path: "/api/v1/payment-settings/pset_1/revisions/psrev_1/rollback"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			// The service must not be reached without a tenant
+			service := mocks.NewMockIPaymentSettingsService(t)
+			e := echo.New()
+			e.HTTPErrorHandler = middlewares.ErrorHandler
+			e.Use(middlewares.Tenant(apiKeys, adminKey))
+			controller.NewPaymentSettingController(e.Group("/api/v1"), service)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(etag.HeaderIfMatch, etag.Format(1))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code, rec.Body.String())
+		})
+	}
+
+	t.Run("admin key changes the platform defaults", func(t *testing.T) {
+		service := mocks.NewMockIPaymentSettingsService(t)
+		service.On("CreatePaymentSetting", mock.MatchedBy(func(ctx context.Context) bool {
+			id, ok := tenant.FromContext(ctx)
+			return ok && id == tenant.Platform
+		}), mock.AnythingOfType("*paymentsettings.PaymentSetting")).Return(nil)
+		e := echo.New()
+		e.HTTPErrorHandler = middlewares.ErrorHandler
+		e.Use(middlewares.Tenant(apiKeys, adminKey))
+		controller.NewPaymentSettingController(e.Group("/api/v1"), service)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/payment-settings", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+adminKey)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	})
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
@@ -10,6 +11,8 @@ import (
 	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/dbutils"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/transaction"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/uniqueid"
 )
 
@@ -27,9 +30,44 @@ func (r *PaymentSettingsRepository) qb() sq.StatementBuilderType {
 	return sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 }
 
-func (r *PaymentSettingsRepository) FetchPaymentSettings(params paymentsettings.PaymentSettingFetchParams) (result []paymentsettings.PaymentSetting, nextCursor string, err error) {
-	query := r.qb().Select("id", "setting_key", "setting_value", "currency", "status", "created_at", "updated_at").
+// conn returns the transaction carried by ctx, so the repository joins a unit of work when there is one.
+func (r *PaymentSettingsRepository) conn(ctx context.Context) transaction.DBTX {
+	return transaction.Executor(ctx, r.db)
+}
+
+var paymentSettingColumns = []string{"id", "tenant_id", "setting_key", "setting_value", "currency", "status", "valid_from", "valid_to", "version", "created_at", "updated_at"}
+
+// tenantScope restricts a query to the settings of the tenant in ctx. Settings are managed one tenant at a
+// time, the platform defaults under tenant.Platform, so a context acting for every tenant is rejected.
+func tenantScope(ctx context.Context) (scope sq.Eq, err error) {
+	id, err := tenant.Required(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sq.Eq{dbutils.TenantColumn: id}, nil
+}
+
+func scanPaymentSetting(row sq.RowScanner, setting *paymentsettings.PaymentSetting) error {
+	return row.Scan(&setting.ID, &setting.TenantID, &setting.SettingKey, &setting.SettingValue, &setting.Currency, &setting.Status,
+		&setting.ValidFrom, &setting.ValidTo, &setting.Version, &setting.CreatedAt, &setting.UpdatedAt)
+}
+
+// FetchPaymentSettings returns the settings in force at params.EffectiveAt, by default now.
+func (r *PaymentSettingsRepository) FetchPaymentSettings(ctx context.Context, params paymentsettings.PaymentSettingFetchParams) (result []paymentsettings.PaymentSetting, nextCursor string, err error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	effectiveAt := params.EffectiveAt
+	if effectiveAt.IsZero() {
+		effectiveAt = time.Now()
+	}
+
+	query := r.qb().Select(paymentSettingColumns...).
 		From("payment_settings_module.payment_settings").
+		Where(scope).
+		Where(sq.LtOrEq{"valid_from": effectiveAt}).
+		Where(sq.Or{sq.Eq{"valid_to": nil}, sq.Gt{"valid_to": effectiveAt}}).
 		OrderBy("id DESC")
 
 	// Apply cursor-based pagination using ULID
@@ -56,10 +94,128 @@ func (r *PaymentSettingsRepository) FetchPaymentSettings(params paymentsettings.
 	// Fetch one extra to determine if there's a next page
 	query = query.Limit(uint64(params.Limit + 1))
 
-	rows, err := query.RunWith(r.db).Query()
+	rows, err := query.RunWith(r.conn(ctx)).QueryContext(ctx)
+	if err != nil {
+		return nil, "", dbutils.HandlePostgresError(err)
+	}
+	defer func() {
+		errClose := rows.Close()
+		if errClose != nil {
+			log.Error().Err(errClose).Msg("failed to close rows")
+		}
+	}()
+
+	result, err = scanPaymentSettings(rows)
 	if err != nil {
 		return nil, "", err
 	}
+
+	// Check if there are more results
+	if len(result) > params.Limit {
+		// Remove the extra item
+		result = result[:params.Limit]
+		// Set next cursor to the last item's ID
+		nextCursor = dbutils.EncodeCursor(result[len(result)-1].ID)
+	}
+
+	return result, nextCursor, nil
+}
+
+// FetchUpcomingPaymentSettings returns the settings coming into force after params.EffectiveAt, by default now, soonest first.
+func (r *PaymentSettingsRepository) FetchUpcomingPaymentSettings(ctx context.Context, params paymentsettings.PaymentSettingFetchParams) (result []paymentsettings.PaymentSetting, err error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	after := params.EffectiveAt
+	if after.IsZero() {
+		after = time.Now()
+	}
+
+	query := r.qb().Select(paymentSettingColumns...).
+		From("payment_settings_module.payment_settings").
+		Where(scope).
+		Where(sq.Gt{"valid_from": after}).
+		OrderBy("valid_from", "id").
+		Limit(uint64(params.Limit))
+
+	if params.Currency != "" {
+		query = query.Where(sq.Eq{"currency": params.Currency})
+	}
+
+	if params.SettingKey != "" {
+		query = query.Where(sq.Eq{"setting_key": params.SettingKey})
+	}
+
+	if params.Status != "" {
+		query = query.Where(sq.Eq{"status": params.Status})
+	}
+
+	rows, err := query.RunWith(r.conn(ctx)).QueryContext(ctx)
+	if err != nil {
+		return nil, dbutils.HandlePostgresError(err)
+	}
+	defer func() {
+		errClose := rows.Close()
+		if errClose != nil {
+			log.Error().Err(errClose).Msg("failed to close rows")
+		}
+	}()
+
+	return scanPaymentSettings(rows)
+}
+
+// FetchActivePaymentSettings returns the active settings of the tenant in ctx and the platform defaults,
+// which the tenant's settings override.
+func (r *PaymentSettingsRepository) FetchActivePaymentSettings(ctx context.Context, currencies []string, at time.Time) (result []paymentsettings.PaymentSetting, err error) {
+	tenantID, err := tenant.Required(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.qb().Select(paymentSettingColumns...).
+		From("payment_settings_module.payment_settings").
+		Where(sq.Eq{dbutils.TenantColumn: []string{tenantID, tenant.Platform}}).
+		Where(sq.Eq{"currency": currencies, "status": paymentsettings.SettingStatusActive}).
+		Where(sq.LtOrEq{"valid_from": at}).
+		Where(sq.Or{sq.Eq{"valid_to": nil}, sq.Gt{"valid_to": at}}).
+		OrderBy("setting_key", "currency").
+		RunWith(r.conn(ctx)).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, dbutils.HandlePostgresError(err)
+	}
+	defer func() {
+		errClose := rows.Close()
+		if errClose != nil {
+			log.Error().Err(errClose).Msg("failed to close rows")
+		}
+	}()
+
+	return scanPaymentSettings(rows)
+}
+
+func (r *PaymentSettingsRepository) FetchOverlappingPaymentSettings(ctx context.Context, setting paymentsettings.PaymentSetting) (result []paymentsettings.PaymentSetting, err error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.qb().Select(paymentSettingColumns...).
+		From("payment_settings_module.payment_settings").
+		Where(scope).
+		Where(sq.Eq{"setting_key": setting.SettingKey, "currency": setting.Currency}).
+		Where(sq.NotEq{"id": setting.ID}).
+		Where(sq.Or{sq.Eq{"valid_to": nil}, sq.Gt{"valid_to": setting.ValidFrom}}).
+		OrderBy("valid_from")
+	if setting.ValidTo != nil {
+		query = query.Where(sq.Lt{"valid_from": *setting.ValidTo})
+	}
+
+	rows, err := query.RunWith(r.conn(ctx)).QueryContext(ctx)
+	if err != nil {
+		return nil, dbutils.HandlePostgresError(err)
+	}
 	defer func() {
 		errClose := rows.Close()
 		if errClose != nil {
@@ -67,37 +223,82 @@ func (r *PaymentSettingsRepository) FetchPaymentSettings(params paymentsettings.
 		}
 	}()
 
+	return scanPaymentSettings(rows)
+}
+
+func scanPaymentSettings(rows *sql.Rows) (result []paymentsettings.PaymentSetting, err error) {
 	result = make([]paymentsettings.PaymentSetting, 0)
 	for rows.Next() {
 		var setting paymentsettings.PaymentSetting
-		if err := rows.Scan(&setting.ID, &setting.SettingKey, &setting.SettingValue, &setting.Currency, &setting.Status, &setting.CreatedAt, &setting.UpdatedAt); err != nil {
-			return nil, "", err
+		if err := scanPaymentSetting(rows, &setting); err != nil {
+			return nil, err
 		}
 		result = append(result, setting)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, "", err
+		return nil, dbutils.HandlePostgresError(err)
 	}
+	return result, nil
+}
 
-	// Check if there are more results
-	if len(result) > params.Limit {
-		// Remove the extra item
-		result = result[:params.Limit]
-		// Set next cursor to the last item's ID
-		nextCursor = dbutils.EncodeCursor(result[len(result)-1].ID)
+func (r *PaymentSettingsRepository) GetPaymentSetting(ctx context.Context, id string) (result paymentsettings.PaymentSetting, err error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return result, err
 	}
 
-	return result, nextCursor, nil
+	row := r.qb().Select(paymentSettingColumns...).
+		From("payment_settings_module.payment_settings").
+		Where(sq.Eq{"id": id}).
+		Where(scope).
+		RunWith(r.conn(ctx)).
+		QueryRowContext(ctx)
+	err = scanPaymentSetting(row, &result)
+	if err != nil {
+		return result, dbutils.HandlePostgresError(err)
+	}
+
+	return result, nil
 }
 
-func (r *PaymentSettingsRepository) GetPaymentSetting(id string) (result paymentsettings.PaymentSetting, err error) {
-	err = r.qb().Select("id", "setting_key", "setting_value", "currency", "status", "created_at", "updated_at").
+func (r *PaymentSettingsRepository) GetPaymentSettingByKey(ctx context.Context, key, currency string) (result paymentsettings.PaymentSetting, err error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	now := time.Now()
+	row := r.qb().Select(paymentSettingColumns...).
+		From("payment_settings_module.payment_settings").
+		Where(scope).
+		Where(sq.Eq{"setting_key": key, "currency": currency}).
+		Where(sq.LtOrEq{"valid_from": now}).
+		Where(sq.Or{sq.Eq{"valid_to": nil}, sq.Gt{"valid_to": now}}).
+		RunWith(r.conn(ctx)).
+		QueryRowContext(ctx)
+	err = scanPaymentSetting(row, &result)
+	if err != nil {
+		return result, dbutils.HandlePostgresError(err)
+	}
+
+	return result, nil
+}
+
+func (r *PaymentSettingsRepository) GetPaymentSettingForUpdate(ctx context.Context, id string) (result paymentsettings.PaymentSetting, err error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	row := r.qb().Select(paymentSettingColumns...).
 		From("payment_settings_module.payment_settings").
 		Where(sq.Eq{"id": id}).
-		RunWith(r.db).
-		QueryRow().
-		Scan(&result.ID, &result.SettingKey, &result.SettingValue, &result.Currency, &result.Status, &result.CreatedAt, &result.UpdatedAt)
+		Where(scope).
+		Suffix("FOR UPDATE").
+		RunWith(r.conn(ctx)).
+		QueryRowContext(ctx)
+	err = scanPaymentSetting(row, &result)
 	if err != nil {
 		return result, dbutils.HandlePostgresError(err)
 	}
@@ -105,7 +306,11 @@ func (r *PaymentSettingsRepository) GetPaymentSetting(id string) (result payment
 	return result, nil
 }
 
-func (r *PaymentSettingsRepository) CreatePaymentSetting(settings *paymentsettings.PaymentSetting) (err error) {
+func (r *PaymentSettingsRepository) CreatePaymentSetting(ctx context.Context, settings *paymentsettings.PaymentSetting) (err error) {
+	settings.TenantID, err = tenant.Required(ctx)
+	if err != nil {
+		return err
+	}
 	settings.ID, err = uniqueid.GeneratePK("pset")
 	if err != nil {
 		return err
@@ -114,12 +319,16 @@ func (r *PaymentSettingsRepository) CreatePaymentSetting(settings *paymentsettin
 	now := time.Now()
 	settings.CreatedAt = now
 	settings.UpdatedAt = now
+	settings.Version = 1
+	if settings.ValidFrom.IsZero() {
+		settings.ValidFrom = now
+	}
 
 	_, err = r.qb().Insert("payment_settings_module.payment_settings").
-		Columns("id", "setting_key", "setting_value", "currency", "status", "created_at", "updated_at").
-		Values(settings.ID, settings.SettingKey, settings.SettingValue, settings.Currency, settings.Status, settings.CreatedAt, settings.UpdatedAt).
-		RunWith(r.db).
-		Exec()
+		Columns(paymentSettingColumns...).
+		Values(settings.ID, settings.TenantID, settings.SettingKey, settings.SettingValue, settings.Currency, settings.Status, settings.ValidFrom, settings.ValidTo, settings.Version, settings.CreatedAt, settings.UpdatedAt).
+		RunWith(r.conn(ctx)).
+		ExecContext(ctx)
 	if err != nil {
 		return dbutils.HandlePostgresError(err)
 	}
@@ -127,39 +336,56 @@ func (r *PaymentSettingsRepository) CreatePaymentSetting(settings *paymentsettin
 	return nil
 }
 
-func (r *PaymentSettingsRepository) UpdatePaymentSetting(settings *paymentsettings.PaymentSetting) (err error) {
+// UpdatePaymentSetting compare-and-swaps on the version: the row is only updated when settings.Version
+// still matches (unless it is 0) and settings.Version is set to the new version.
+func (r *PaymentSettingsRepository) UpdatePaymentSetting(ctx context.Context, settings *paymentsettings.PaymentSetting) (err error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return err
+	}
 	settings.UpdatedAt = time.Now()
 
-	result, err := r.qb().Update("payment_settings_module.payment_settings").
+	query := r.qb().Update("payment_settings_module.payment_settings").
 		Set("setting_key", settings.SettingKey).
 		Set("setting_value", settings.SettingValue).
 		Set("currency", settings.Currency).
 		Set("status", settings.Status).
+		Set("valid_from", settings.ValidFrom).
+		Set("valid_to", settings.ValidTo).
+		Set("version", sq.Expr("version + 1")).
 		Set("updated_at", settings.UpdatedAt).
 		Where(sq.Eq{"id": settings.ID}).
-		RunWith(r.db).
-		Exec()
+		Where(scope).
+		Suffix("RETURNING version")
+	if settings.Version != 0 {
+		query = query.Where(sq.Eq{"version": settings.Version})
+	}
+
+	err = query.RunWith(r.conn(ctx)).QueryRowContext(ctx).Scan(&settings.Version)
+	if err == sql.ErrNoRows {
+		return r.noRowsAffectedError(ctx, settings.ID)
+	}
 	if err != nil {
 		return dbutils.HandlePostgresError(err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	return nil
+}
+
+func (r *PaymentSettingsRepository) DeletePaymentSetting(ctx context.Context, id string, expectedVersion int64) (err error) {
+	scope, err := tenantScope(ctx)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return errors.ErrDataNotFound
+	query := r.qb().Delete("payment_settings_module.payment_settings").
+		Where(sq.Eq{"id": id}).
+		Where(scope)
+	if expectedVersion != 0 {
+		query = query.Where(sq.Eq{"version": expectedVersion})
 	}
 
-	return nil
-}
-
-func (r *PaymentSettingsRepository) DeletePaymentSetting(id string) (err error) {
-	result, err := r.qb().Delete("payment_settings_module.payment_settings").
-		Where(sq.Eq{"id": id}).
-		RunWith(r.db).
-		Exec()
+	result, err := query.RunWith(r.conn(ctx)).ExecContext(ctx)
 	if err != nil {
 		return dbutils.HandlePostgresError(err)
 	}
@@ -170,8 +396,33 @@ func (r *PaymentSettingsRepository) DeletePaymentSetting(id string) (err error)
 	}
 
 	if rowsAffected == 0 {
-		return errors.ErrDataNotFound
+		return r.noRowsAffectedError(ctx, id)
 	}
 
 	return nil
 }
+
+// noRowsAffectedError tells a missing setting apart from a stale version after a versioned write matched nothing.
+func (r *PaymentSettingsRepository) noRowsAffectedError(ctx context.Context, id string) (err error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	var count int
+	err = r.qb().Select("COUNT(1)").
+		From("payment_settings_module.payment_settings").
+		Where(sq.Eq{"id": id}).
+		Where(scope).
+		RunWith(r.conn(ctx)).
+		QueryRowContext(ctx).
+		Scan(&count)
+	if err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+
+	if count == 0 {
+		return errors.ErrDataNotFound
+	}
+	return errors.ErrPreconditionFailed
+}
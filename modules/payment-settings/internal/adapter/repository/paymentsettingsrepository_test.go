@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -9,6 +11,7 @@ import (
 
 	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
 	pkgerrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/testutils"
 )
 
@@ -65,7 +68,7 @@ func (s *PaymentSettingsRepositoryTestSuite) TestCreatePaymentSetting() {
 
 	for _, tt := range tests {
 		s.Run(tt.name, func() {
-			err := s.repo.CreatePaymentSetting(tt.setting)
+			err := s.repo.CreatePaymentSetting(testutils.TenantContext(), tt.setting)
 
 			if tt.expectError {
 				assert.Error(s.T(), err)
@@ -79,6 +82,29 @@ func (s *PaymentSettingsRepositoryTestSuite) TestCreatePaymentSetting() {
 	}
 }
 
+func (s *PaymentSettingsRepositoryTestSuite) TestContextCancelled() {
+	ctx, cancel := context.WithCancel(testutils.TenantContext())
+	cancel()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "CreatePaymentSetting", call: func() error {
+			return s.repo.CreatePaymentSetting(ctx, &paymentsettings.PaymentSetting{SettingKey: "rate", SettingValue: "1.0", Currency: "USD", Status: "active"})
+		}},
+		{name: "noRowsAffectedError", call: func() error {
+			return s.repo.noRowsAffectedError(ctx, "pset_any")
+		}},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			assert.Equal(s.T(), pkgerrors.ErrRequestTimeout, tt.call())
+		})
+	}
+}
+
 func (s *PaymentSettingsRepositoryTestSuite) TestGetPaymentSetting() {
 	createdSetting := &paymentsettings.PaymentSetting{
 		SettingKey:   "rate",
@@ -86,7 +112,7 @@ func (s *PaymentSettingsRepositoryTestSuite) TestGetPaymentSetting() {
 		Currency:     "USD",
 		Status:       "active",
 	}
-	err := s.repo.CreatePaymentSetting(createdSetting)
+	err := s.repo.CreatePaymentSetting(testutils.TenantContext(), createdSetting)
 	require.NoError(s.T(), err)
 
 	tests := []struct {
@@ -110,7 +136,7 @@ func (s *PaymentSettingsRepositoryTestSuite) TestGetPaymentSetting() {
 
 	for _, tt := range tests {
 		s.Run(tt.name, func() {
-			result, err := s.repo.GetPaymentSetting(tt.settingID)
+			result, err := s.repo.GetPaymentSetting(testutils.TenantContext(), tt.settingID)
 
 			if tt.expectError {
 				require.Error(s.T(), err)
@@ -137,7 +163,7 @@ func (s *PaymentSettingsRepositoryTestSuite) TestFetchPaymentSettings() {
 	}
 
 	for _, setting := range settings {
-		err := s.repo.CreatePaymentSetting(setting)
+		err := s.repo.CreatePaymentSetting(testutils.TenantContext(), setting)
 		require.NoError(s.T(), err)
 	}
 
@@ -205,7 +231,7 @@ func (s *PaymentSettingsRepositoryTestSuite) TestFetchPaymentSettings() {
 
 	for _, tt := range tests {
 		s.Run(tt.name, func() {
-			result, cursor, err := s.repo.FetchPaymentSettings(tt.params)
+			result, cursor, err := s.repo.FetchPaymentSettings(testutils.TenantContext(), tt.params)
 
 			require.NoError(s.T(), err)
 			assert.Len(s.T(), result, tt.expectedCount)
@@ -228,16 +254,16 @@ func (s *PaymentSettingsRepositoryTestSuite) TestFetchPaymentSettings_Pagination
 			Currency:     currencies[i],
 			Status:       "active",
 		}
-		err := s.repo.CreatePaymentSetting(setting)
+		err := s.repo.CreatePaymentSetting(testutils.TenantContext(), setting)
 		require.NoError(s.T(), err)
 	}
 
-	firstPage, cursor, err := s.repo.FetchPaymentSettings(paymentsettings.PaymentSettingFetchParams{Limit: 2})
+	firstPage, cursor, err := s.repo.FetchPaymentSettings(testutils.TenantContext(), paymentsettings.PaymentSettingFetchParams{Limit: 2})
 	require.NoError(s.T(), err)
 	assert.Len(s.T(), firstPage, 2)
 	assert.NotEmpty(s.T(), cursor)
 
-	secondPage, cursor2, err := s.repo.FetchPaymentSettings(paymentsettings.PaymentSettingFetchParams{
+	secondPage, cursor2, err := s.repo.FetchPaymentSettings(testutils.TenantContext(), paymentsettings.PaymentSettingFetchParams{
 		Limit:  2,
 		Cursor: cursor,
 	})
@@ -249,6 +275,111 @@ func (s *PaymentSettingsRepositoryTestSuite) TestFetchPaymentSettings_Pagination
 	assert.NotEqual(s.T(), firstPage[1].ID, secondPage[1].ID)
 }
 
+func (s *PaymentSettingsRepositoryTestSuite) TestFetchPaymentSettings_EffectiveAt() {
+	ctx := testutils.TenantContext()
+	switchAt := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+
+	current := &paymentsettings.PaymentSetting{SettingKey: "rate", SettingValue: "1.0", Currency: "USD", Status: "active", ValidTo: &switchAt}
+	require.NoError(s.T(), s.repo.CreatePaymentSetting(ctx, current))
+	scheduled := &paymentsettings.PaymentSetting{SettingKey: "rate", SettingValue: "2.0", Currency: "USD", Status: "active", ValidFrom: switchAt}
+	require.NoError(s.T(), s.repo.CreatePaymentSetting(ctx, scheduled))
+
+	now, _, err := s.repo.FetchPaymentSettings(ctx, paymentsettings.PaymentSettingFetchParams{Currency: "USD", Limit: 10})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), now, 1)
+	assert.Equal(s.T(), current.ID, now[0].ID)
+
+	later, _, err := s.repo.FetchPaymentSettings(ctx, paymentsettings.PaymentSettingFetchParams{Currency: "USD", Limit: 10, EffectiveAt: switchAt})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), later, 1)
+	assert.Equal(s.T(), scheduled.ID, later[0].ID)
+
+	upcoming, err := s.repo.FetchUpcomingPaymentSettings(ctx, paymentsettings.PaymentSettingFetchParams{Currency: "USD", Limit: 10})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), upcoming, 1)
+	assert.Equal(s.T(), scheduled.ID, upcoming[0].ID)
+	assert.True(s.T(), switchAt.Equal(upcoming[0].ValidFrom))
+}
+
+func (s *PaymentSettingsRepositoryTestSuite) TestFetchActivePaymentSettings() {
+	ctx := testutils.TenantContext()
+	for _, setting := range []*paymentsettings.PaymentSetting{
+		{SettingKey: "rate", SettingValue: "1.0", Currency: paymentsettings.GlobalCurrency, Status: "active"},
+		{SettingKey: "rate", SettingValue: "1.5", Currency: "USD", Status: "active"},
+		{SettingKey: "fee", SettingValue: "0.5", Currency: "USD", Status: "inactive"},
+		{SettingKey: "fee", SettingValue: "0.7", Currency: "EUR", Status: "active"},
+	} {
+		require.NoError(s.T(), s.repo.CreatePaymentSetting(ctx, setting))
+	}
+
+	result, err := s.repo.FetchActivePaymentSettings(ctx, []string{"USD", paymentsettings.GlobalCurrency}, time.Now())
+	require.NoError(s.T(), err)
+	require.Len(s.T(), result, 2)
+	assert.Equal(s.T(), paymentsettings.GlobalCurrency, result[0].Currency)
+	assert.Equal(s.T(), "USD", result[1].Currency)
+}
+
+func (s *PaymentSettingsRepositoryTestSuite) TestFetchActivePaymentSettings_PlatformDefaults() {
+	platform := tenant.WithID(context.Background(), tenant.Platform)
+	ctx := testutils.TenantContext()
+	other := tenant.WithID(context.Background(), "other_tenant")
+
+	// Every tenant may set the key and currency of a platform default
+	defaultRate := &paymentsettings.PaymentSetting{SettingKey: "rate", SettingValue: "1.0", Currency: "USD", Status: "active"}
+	require.NoError(s.T(), s.repo.CreatePaymentSetting(platform, defaultRate))
+	ownRate := &paymentsettings.PaymentSetting{SettingKey: "rate", SettingValue: "1.5", Currency: "USD", Status: "active"}
+	require.NoError(s.T(), s.repo.CreatePaymentSetting(ctx, ownRate))
+	otherRate := &paymentsettings.PaymentSetting{SettingKey: "rate", SettingValue: "2.0", Currency: "USD", Status: "active"}
+	require.NoError(s.T(), s.repo.CreatePaymentSetting(other, otherRate))
+
+	result, err := s.repo.FetchActivePaymentSettings(ctx, []string{"USD"}, time.Now())
+	require.NoError(s.T(), err)
+	ids := make([]string, len(result))
+	for i, setting := range result {
+		ids[i] = setting.ID
+	}
+	assert.ElementsMatch(s.T(), []string{defaultRate.ID, ownRate.ID}, ids)
+
+	// The platform defaults and the settings of other tenants are not the tenant's to read or change
+	_, err = s.repo.GetPaymentSetting(ctx, otherRate.ID)
+	assert.Equal(s.T(), pkgerrors.ErrDataNotFound, err)
+	assert.Equal(s.T(), pkgerrors.ErrDataNotFound, s.repo.DeletePaymentSetting(ctx, defaultRate.ID, 0))
+}
+
+func (s *PaymentSettingsRepositoryTestSuite) TestGetPaymentSettingByKey() {
+	ctx := testutils.TenantContext()
+	switchAt := time.Now().Add(time.Hour)
+
+	current := &paymentsettings.PaymentSetting{SettingKey: "rate", SettingValue: "1.0", Currency: "USD", Status: "active", ValidTo: &switchAt}
+	require.NoError(s.T(), s.repo.CreatePaymentSetting(ctx, current))
+	scheduled := &paymentsettings.PaymentSetting{SettingKey: "rate", SettingValue: "2.0", Currency: "USD", Status: "active", ValidFrom: switchAt}
+	require.NoError(s.T(), s.repo.CreatePaymentSetting(ctx, scheduled))
+
+	result, err := s.repo.GetPaymentSettingByKey(ctx, "rate", "USD")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), current.ID, result.ID)
+
+	_, err = s.repo.GetPaymentSettingByKey(ctx, "rate", "EUR")
+	assert.Equal(s.T(), pkgerrors.ErrDataNotFound, err)
+}
+
+func (s *PaymentSettingsRepositoryTestSuite) TestCreatePaymentSetting_RejectsOverlap() {
+	ctx := testutils.TenantContext()
+	validFrom := time.Now().Add(time.Hour)
+
+	existing := &paymentsettings.PaymentSetting{SettingKey: "rate", SettingValue: "1.0", Currency: "USD", Status: "active"}
+	require.NoError(s.T(), s.repo.CreatePaymentSetting(ctx, existing))
+
+	overlapping := paymentsettings.PaymentSetting{SettingKey: "rate", SettingValue: "2.0", Currency: "USD", Status: "active", ValidFrom: validFrom}
+	found, err := s.repo.FetchOverlappingPaymentSettings(ctx, overlapping)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), found, 1)
+	assert.Equal(s.T(), existing.ID, found[0].ID)
+
+	err = s.repo.CreatePaymentSetting(ctx, &overlapping)
+	assert.Equal(s.T(), pkgerrors.ErrConflict, err)
+}
+
 func (s *PaymentSettingsRepositoryTestSuite) TestUpdatePaymentSetting() {
 	createdSetting := &paymentsettings.PaymentSetting{
 		SettingKey:   "rate",
@@ -256,7 +387,7 @@ func (s *PaymentSettingsRepositoryTestSuite) TestUpdatePaymentSetting() {
 		Currency:     "USD",
 		Status:       "active",
 	}
-	err := s.repo.CreatePaymentSetting(createdSetting)
+	err := s.repo.CreatePaymentSetting(testutils.TenantContext(), createdSetting)
 	require.NoError(s.T(), err)
 
 	tests := []struct {
@@ -273,9 +404,23 @@ func (s *PaymentSettingsRepositoryTestSuite) TestUpdatePaymentSetting() {
 				SettingValue: "1.5",
 				Currency:     "EUR",
 				Status:       "inactive",
+				Version:      1,
 			},
 			expectError: false,
 		},
+		{
+			name: "stale version",
+			setting: &paymentsettings.PaymentSetting{
+				ID:           createdSetting.ID,
+				SettingKey:   "rate",
+				SettingValue: "1.7",
+				Currency:     "EUR",
+				Status:       "inactive",
+				Version:      1,
+			},
+			expectError: true,
+			expectedErr: pkgerrors.ErrPreconditionFailed,
+		},
 		{
 			name: "payment setting not found",
 			setting: &paymentsettings.PaymentSetting{
@@ -292,7 +437,7 @@ func (s *PaymentSettingsRepositoryTestSuite) TestUpdatePaymentSetting() {
 
 	for _, tt := range tests {
 		s.Run(tt.name, func() {
-			err := s.repo.UpdatePaymentSetting(tt.setting)
+			err := s.repo.UpdatePaymentSetting(testutils.TenantContext(), tt.setting)
 
 			if tt.expectError {
 				require.Error(s.T(), err)
@@ -302,12 +447,13 @@ func (s *PaymentSettingsRepositoryTestSuite) TestUpdatePaymentSetting() {
 			} else {
 				require.NoError(s.T(), err)
 
-				updated, err := s.repo.GetPaymentSetting(tt.setting.ID)
+				updated, err := s.repo.GetPaymentSetting(testutils.TenantContext(), tt.setting.ID)
 				require.NoError(s.T(), err)
 				assert.Equal(s.T(), tt.setting.SettingKey, updated.SettingKey)
 				assert.Equal(s.T(), tt.setting.SettingValue, updated.SettingValue)
 				assert.Equal(s.T(), tt.setting.Currency, updated.Currency)
 				assert.Equal(s.T(), tt.setting.Status, updated.Status)
+				assert.Equal(s.T(), int64(2), updated.Version)
 			}
 		})
 	}
@@ -320,18 +466,27 @@ func (s *PaymentSettingsRepositoryTestSuite) TestDeletePaymentSetting() {
 		Currency:     "USD",
 		Status:       "active",
 	}
-	err := s.repo.CreatePaymentSetting(createdSetting)
+	err := s.repo.CreatePaymentSetting(testutils.TenantContext(), createdSetting)
 	require.NoError(s.T(), err)
 
 	tests := []struct {
 		name        string
 		settingID   string
+		version     int64
 		expectError bool
 		expectedErr error
 	}{
+		{
+			name:        "stale version",
+			settingID:   createdSetting.ID,
+			version:     2,
+			expectError: true,
+			expectedErr: pkgerrors.ErrPreconditionFailed,
+		},
 		{
 			name:        "successful payment setting deletion",
 			settingID:   createdSetting.ID,
+			version:     1,
 			expectError: false,
 		},
 		{
@@ -344,7 +499,7 @@ func (s *PaymentSettingsRepositoryTestSuite) TestDeletePaymentSetting() {
 
 	for _, tt := range tests {
 		s.Run(tt.name, func() {
-			err := s.repo.DeletePaymentSetting(tt.settingID)
+			err := s.repo.DeletePaymentSetting(testutils.TenantContext(), tt.settingID, tt.version)
 
 			if tt.expectError {
 				require.Error(s.T(), err)
@@ -354,7 +509,7 @@ func (s *PaymentSettingsRepositoryTestSuite) TestDeletePaymentSetting() {
 			} else {
 				require.NoError(s.T(), err)
 
-				_, err := s.repo.GetPaymentSetting(tt.settingID)
+				_, err := s.repo.GetPaymentSetting(testutils.TenantContext(), tt.settingID)
 				assert.Equal(s.T(), pkgerrors.ErrDataNotFound, err)
 			}
 		})
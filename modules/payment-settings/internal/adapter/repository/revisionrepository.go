@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/rs/zerolog/log"
+
+	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/internal/ports"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/dbutils"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/transaction"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/uniqueid"
+)
+
+type revisionRepository struct {
+	db *sql.DB
+}
+
+// NewRevisionRepository creates the repository of setting revisions. Revisions are only ever inserted.
+func NewRevisionRepository(db *sql.DB) ports.IRevisionRepository {
+	return &revisionRepository{
+		db: db,
+	}
+}
+
+func (r *revisionRepository) qb() sq.StatementBuilderType {
+	return sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+}
+
+func (r *revisionRepository) conn(ctx context.Context) transaction.DBTX {
+	return transaction.Executor(ctx, r.db)
+}
+
+var revisionColumns = []string{"id", "tenant_id", "setting_id", "version", "action", "setting_key", "currency", "status", "valid_from", "valid_to", "old_value", "new_value", "actor", "reason", "created_at"}
+
+func (r *revisionRepository) CreateRevision(ctx context.Context, revision *paymentsettings.SettingRevision) (err error) {
+	revision.TenantID, err = tenant.Required(ctx)
+	if err != nil {
+		return err
+	}
+	revision.ID, err = uniqueid.GeneratePK("psrev")
+	if err != nil {
+		return err
+	}
+	revision.CreatedAt = time.Now()
+
+	_, err = r.qb().Insert("payment_settings_module.payment_setting_revisions").
+		Columns(revisionColumns...).
+		Values(revision.ID, revision.TenantID, revision.SettingID, revision.Version, revision.Action, revision.SettingKey, revision.Currency, revision.Status,
+			revision.ValidFrom, revision.ValidTo, revision.OldValue, revision.NewValue, revision.Actor, revision.Reason, revision.CreatedAt).
+		RunWith(r.conn(ctx)).
+		ExecContext(ctx)
+	if err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+
+	return nil
+}
+
+func (r *revisionRepository) GetRevision(ctx context.Context, id string) (result paymentsettings.SettingRevision, err error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	row := r.qb().Select(revisionColumns...).
+		From("payment_settings_module.payment_setting_revisions").
+		Where(sq.Eq{"id": id}).
+		Where(scope).
+		RunWith(r.conn(ctx)).
+		QueryRowContext(ctx)
+	if err = scanRevision(row, &result); err != nil {
+		return result, dbutils.HandlePostgresError(err)
+	}
+
+	return result, nil
+}
+
+func (r *revisionRepository) FetchRevisions(ctx context.Context, params paymentsettings.RevisionFetchParams) (result []paymentsettings.SettingRevision, nextCursor string, err error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Revision IDs are ULIDs, so ordering by ID orders by creation
+	query := r.qb().Select(revisionColumns...).
+		From("payment_settings_module.payment_setting_revisions").
+		Where(scope).
+		Where(sq.Eq{"setting_id": params.SettingID}).
+		OrderBy("id DESC")
+
+	if params.Cursor != "" {
+		cursorID, decodeErr := dbutils.DecodeCursor(params.Cursor)
+		if decodeErr != nil {
+			return nil, "", decodeErr
+		}
+		query = query.Where(sq.Lt{"id": cursorID})
+	}
+
+	// Fetch one extra to determine if there's a next page
+	query = query.Limit(uint64(params.Limit + 1))
+
+	rows, err := query.RunWith(r.conn(ctx)).QueryContext(ctx)
+	if err != nil {
+		return nil, "", dbutils.HandlePostgresError(err)
+	}
+	defer func() {
+		errClose := rows.Close()
+		if errClose != nil {
+			log.Error().Err(errClose).Msg("failed to close rows")
+		}
+	}()
+
+	result = make([]paymentsettings.SettingRevision, 0)
+	for rows.Next() {
+		var revision paymentsettings.SettingRevision
+		if err := scanRevision(rows, &revision); err != nil {
+			return nil, "", err
+		}
+		result = append(result, revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", dbutils.HandlePostgresError(err)
+	}
+
+	if len(result) > params.Limit {
+		result = result[:params.Limit]
+		nextCursor = dbutils.EncodeCursor(result[len(result)-1].ID)
+	}
+
+	return result, nextCursor, nil
+}
+
+func (r *revisionRepository) FetchPaymentSettingsAsOf(ctx context.Context, at time.Time, params paymentsettings.PaymentSettingFetchParams) (result []paymentsettings.PaymentSetting, nextCursor string, err error) {
+	scope, err := tenantScope(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The latest revision of every setting at the instant; its first revision is when the setting was created
+	latest := r.qb().Select(
+		"DISTINCT ON (setting_id) setting_id", "tenant_id", "setting_key", "new_value", "currency", "status", "valid_from", "valid_to", "version", "action",
+		"MIN(created_at) OVER (PARTITION BY setting_id) AS first_created_at", "created_at",
+	).
+		From("payment_settings_module.payment_setting_revisions").
+		Where(scope).
+		Where(sq.LtOrEq{"created_at": at.UTC()}).
+		OrderBy("setting_id", "created_at DESC", "id DESC")
+
+	query := r.qb().Select("setting_id", "tenant_id", "setting_key", "new_value", "currency", "status", "valid_from", "valid_to", "version", "first_created_at", "created_at").
+		FromSelect(latest, "latest").
+		// A setting whose latest revision deleted it did not exist at the instant
+		Where(sq.NotEq{"action": paymentsettings.RevisionActionDeleted}).
+		// and one that existed was only in force within its validity range
+		Where(sq.LtOrEq{"valid_from": at}).
+		Where(sq.Or{sq.Eq{"valid_to": nil}, sq.Gt{"valid_to": at}}).
+		OrderBy("setting_id DESC")
+
+	if params.Cursor != "" {
+		cursorID, decodeErr := dbutils.DecodeCursor(params.Cursor)
+		if decodeErr != nil {
+			return nil, "", decodeErr
+		}
+		query = query.Where(sq.Lt{"setting_id": cursorID})
+	}
+
+	if params.Currency != "" {
+		query = query.Where(sq.Eq{"currency": params.Currency})
+	}
+
+	if params.SettingKey != "" {
+		query = query.Where(sq.Eq{"setting_key": params.SettingKey})
+	}
+
+	if params.Status != "" {
+		query = query.Where(sq.Eq{"status": params.Status})
+	}
+
+	// Fetch one extra to determine if there's a next page
+	query = query.Limit(uint64(params.Limit + 1))
+
+	rows, err := query.RunWith(r.conn(ctx)).QueryContext(ctx)
+	if err != nil {
+		return nil, "", dbutils.HandlePostgresError(err)
+	}
+	defer func() {
+		errClose := rows.Close()
+		if errClose != nil {
+			log.Error().Err(errClose).Msg("failed to close rows")
+		}
+	}()
+
+	result = make([]paymentsettings.PaymentSetting, 0)
+	for rows.Next() {
+		var setting paymentsettings.PaymentSetting
+		if err := rows.Scan(&setting.ID, &setting.TenantID, &setting.SettingKey, &setting.SettingValue, &setting.Currency, &setting.Status, &setting.ValidFrom, &setting.ValidTo, &setting.Version, &setting.CreatedAt, &setting.UpdatedAt); err != nil {
+			return nil, "", err
+		}
+		result = append(result, setting)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", dbutils.HandlePostgresError(err)
+	}
+
+	if len(result) > params.Limit {
+		result = result[:params.Limit]
+		nextCursor = dbutils.EncodeCursor(result[len(result)-1].ID)
+	}
+
+	return result, nextCursor, nil
+}
+
+func scanRevision(row sq.RowScanner, revision *paymentsettings.SettingRevision) error {
+	return row.Scan(&revision.ID, &revision.TenantID, &revision.SettingID, &revision.Version, &revision.Action, &revision.SettingKey, &revision.Currency, &revision.Status,
+		&revision.ValidFrom, &revision.ValidTo, &revision.OldValue, &revision.NewValue, &revision.Actor, &revision.Reason, &revision.CreatedAt)
+}
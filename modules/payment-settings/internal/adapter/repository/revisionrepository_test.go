@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
+	pkgerrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/testutils"
+)
+
+type RevisionRepositoryTestSuite struct {
+	suite.Suite
+	pgContainer *testutils.PostgresContainer
+	repo        *revisionRepository
+}
+
+func (s *RevisionRepositoryTestSuite) SetupSuite() {
+	if testing.Short() {
+		s.T().Skip("Skipping repository integration test in short mode")
+	}
+	s.pgContainer = testutils.SetupPostgres(s.T())
+	s.pgContainer.RunMigrations(s.T(), "../../../../../migrations")
+	s.repo = &revisionRepository{db: s.pgContainer.DB}
+}
+
+func (s *RevisionRepositoryTestSuite) TearDownSuite() {
+	s.pgContainer.Teardown(s.T())
+}
+
+func (s *RevisionRepositoryTestSuite) SetupTest() {
+	s.pgContainer.TruncateTables(s.T(), "payment_settings_module.payment_setting_revisions")
+}
+
+func (s *RevisionRepositoryTestSuite) revise(settingID, action string, version int64, oldValue, newValue *string) paymentsettings.SettingRevision {
+	revision := paymentsettings.SettingRevision{
+		SettingID:  settingID,
+		Version:    version,
+		Action:     action,
+		SettingKey: paymentsettings.SettingKeyMaxTransactionAmount,
+		Currency:   "USD",
+		Status:     paymentsettings.SettingStatusActive,
+		OldValue:   oldValue,
+		NewValue:   newValue,
+		Actor:      "alice",
+	}
+	require.NoError(s.T(), s.repo.CreateRevision(testutils.TenantContext(), &revision))
+	// Keep revisions of the same setting at distinct instants
+	time.Sleep(5 * time.Millisecond)
+	return revision
+}
+
+func (s *RevisionRepositoryTestSuite) TestFetchRevisions_NewestFirst() {
+	ctx := testutils.TenantContext()
+	value := func(v string) *string { return &v }
+
+	created := s.revise("pset_1", paymentsettings.RevisionActionCreated, 1, nil, value("10000.00"))
+	updated := s.revise("pset_1", paymentsettings.RevisionActionUpdated, 2, value("10000.00"), value("5000.00"))
+	s.revise("pset_2", paymentsettings.RevisionActionCreated, 1, nil, value("10.00"))
+
+	page, nextCursor, err := s.repo.FetchRevisions(ctx, paymentsettings.RevisionFetchParams{SettingID: "pset_1", Limit: 1})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), page, 1)
+	assert.Equal(s.T(), updated.ID, page[0].ID)
+	assert.Equal(s.T(), "10000.00", *page[0].OldValue)
+	assert.NotEmpty(s.T(), nextCursor)
+
+	page, nextCursor, err = s.repo.FetchRevisions(ctx, paymentsettings.RevisionFetchParams{SettingID: "pset_1", Limit: 1, Cursor: nextCursor})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), page, 1)
+	assert.Equal(s.T(), created.ID, page[0].ID)
+	assert.Nil(s.T(), page[0].OldValue)
+	assert.Empty(s.T(), nextCursor)
+
+	_, err = s.repo.GetRevision(ctx, "psrev_missing")
+	assert.Equal(s.T(), pkgerrors.ErrDataNotFound, err)
+}
+
+func (s *RevisionRepositoryTestSuite) TestFetchPaymentSettingsAsOf() {
+	ctx := testutils.TenantContext()
+	value := func(v string) *string { return &v }
+
+	created := s.revise("pset_1", paymentsettings.RevisionActionCreated, 1, nil, value("10000.00"))
+	updated := s.revise("pset_1", paymentsettings.RevisionActionUpdated, 2, value("10000.00"), value("5000.00"))
+	deleted := s.revise("pset_1", paymentsettings.RevisionActionDeleted, 2, value("5000.00"), nil)
+
+	params := paymentsettings.PaymentSettingFetchParams{Currency: "USD", Limit: 10}
+
+	before, _, err := s.repo.FetchPaymentSettingsAsOf(ctx, created.CreatedAt.Add(-time.Millisecond), params)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), before)
+
+	atCreation, _, err := s.repo.FetchPaymentSettingsAsOf(ctx, created.CreatedAt, params)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), atCreation, 1)
+	assert.Equal(s.T(), "10000.00", atCreation[0].SettingValue)
+	assert.Equal(s.T(), int64(1), atCreation[0].Version)
+
+	// The instant is the same in any time zone
+	inOtherZone, _, err := s.repo.FetchPaymentSettingsAsOf(ctx, created.CreatedAt.In(time.FixedZone("UTC-10", -10*60*60)), params)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), inOtherZone, 1)
+	assert.Equal(s.T(), "10000.00", inOtherZone[0].SettingValue)
+
+	afterUpdate, _, err := s.repo.FetchPaymentSettingsAsOf(ctx, updated.CreatedAt, params)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), afterUpdate, 1)
+	assert.Equal(s.T(), "5000.00", afterUpdate[0].SettingValue)
+	assert.WithinDuration(s.T(), created.CreatedAt, afterUpdate[0].CreatedAt, time.Millisecond)
+
+	afterDeletion, _, err := s.repo.FetchPaymentSettingsAsOf(ctx, deleted.CreatedAt, params)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), afterDeletion)
+}
+
+func TestRevisionRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(RevisionRepositoryTestSuite))
+}
@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/eventbus"
+)
+
+// IEventPublisher is an outbound port publishing the module's events to in-process subscribers.
+// It is implemented by the shared event bus; the module does not know who subscribes.
+type IEventPublisher interface {
+	Publish(ctx context.Context, event eventbus.Event) error
+}
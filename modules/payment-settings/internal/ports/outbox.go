@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/outbox"
+)
+
+// IEventOutbox is an outbound port recording the module's domain events in its outbox.
+// Append joins the transaction in ctx, so an event is only recorded when the change it describes commits.
+type IEventOutbox interface {
+	Append(ctx context.Context, events ...outbox.Event) error
+}
@@ -10,16 +10,31 @@
 package ports
 
 import (
+	"context"
+	"time"
+
 	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
 )
 
 // IPaymentSettingsRepository is an outbound port for payment settings data persistence.
 // This interface is defined by the domain and implemented by the repository adapter.
 // The domain doesn't know or care about the underlying storage mechanism.
+// Every method only sees the settings of the tenant in ctx, which must be a single tenant; settings are
+// created for it.
 type IPaymentSettingsRepository interface {
-	FetchPaymentSettings(params paymentsettings.PaymentSettingFetchParams) (result []paymentsettings.PaymentSetting, nextCursor string, err error)
-	GetPaymentSetting(id string) (paymentsettings.PaymentSetting, error)
-	CreatePaymentSetting(settings *paymentsettings.PaymentSetting) error
-	UpdatePaymentSetting(settings *paymentsettings.PaymentSetting) error
-	DeletePaymentSetting(id string) error
+	FetchPaymentSettings(ctx context.Context, params paymentsettings.PaymentSettingFetchParams) (result []paymentsettings.PaymentSetting, nextCursor string, err error)
+	FetchUpcomingPaymentSettings(ctx context.Context, params paymentsettings.PaymentSettingFetchParams) (result []paymentsettings.PaymentSetting, err error)
+	// FetchActivePaymentSettings returns every active setting of the given currencies in force at the given instant,
+	// of the tenant in ctx and of tenant.Platform.
+	FetchActivePaymentSettings(ctx context.Context, currencies []string, at time.Time) (result []paymentsettings.PaymentSetting, err error)
+	// FetchOverlappingPaymentSettings returns the other settings of the key and currency whose validity range overlaps the one of setting.
+	FetchOverlappingPaymentSettings(ctx context.Context, setting paymentsettings.PaymentSetting) (result []paymentsettings.PaymentSetting, err error)
+	GetPaymentSetting(ctx context.Context, id string) (paymentsettings.PaymentSetting, error)
+	// GetPaymentSettingByKey returns the setting of the key and currency in force now.
+	GetPaymentSettingByKey(ctx context.Context, key, currency string) (paymentsettings.PaymentSetting, error)
+	// GetPaymentSettingForUpdate reads the setting and locks it until the transaction in ctx ends.
+	GetPaymentSettingForUpdate(ctx context.Context, id string) (paymentsettings.PaymentSetting, error)
+	CreatePaymentSetting(ctx context.Context, settings *paymentsettings.PaymentSetting) error
+	UpdatePaymentSetting(ctx context.Context, settings *paymentsettings.PaymentSetting) error
+	DeletePaymentSetting(ctx context.Context, id string, expectedVersion int64) error
 }
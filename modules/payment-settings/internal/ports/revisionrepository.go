@@ -0,0 +1,18 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
+)
+
+// IRevisionRepository is an outbound port for the append-only history of setting changes.
+// CreateRevision joins the transaction in ctx, so a revision is only recorded when its change commits.
+type IRevisionRepository interface {
+	CreateRevision(ctx context.Context, revision *paymentsettings.SettingRevision) error
+	GetRevision(ctx context.Context, id string) (paymentsettings.SettingRevision, error)
+	FetchRevisions(ctx context.Context, params paymentsettings.RevisionFetchParams) (result []paymentsettings.SettingRevision, nextCursor string, err error)
+	// FetchPaymentSettingsAsOf rebuilds the settings that existed at the given instant from their latest revision before it.
+	FetchPaymentSettingsAsOf(ctx context.Context, at time.Time, params paymentsettings.PaymentSettingFetchParams) (result []paymentsettings.PaymentSetting, nextCursor string, err error)
+}
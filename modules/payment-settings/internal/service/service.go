@@ -1,34 +1,583 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
 	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/internal/ports"
+	pkgerrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/outbox"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/transaction"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/uniqueid"
 )
 
 type PaymentSettingsService struct {
-	repo ports.IPaymentSettingsRepository
+	repo           ports.IPaymentSettingsRepository
+	revisionRepo   ports.IRevisionRepository
+	schema         *paymentsettings.Schema
+	txManager      transaction.Manager
+	eventOutbox    ports.IEventOutbox
+	eventPublisher ports.IEventPublisher
+}
+
+// NewPaymentSettingsService creates the service. Values of the keys declared in schema are validated on create and update.
+func NewPaymentSettingsService(repo ports.IPaymentSettingsRepository, revisionRepo ports.IRevisionRepository, schema *paymentsettings.Schema, txManager transaction.Manager, eventOutbox ports.IEventOutbox, eventPublisher ports.IEventPublisher) (service *PaymentSettingsService) {
+	return &PaymentSettingsService{repo: repo, revisionRepo: revisionRepo, schema: schema, txManager: txManager, eventOutbox: eventOutbox, eventPublisher: eventPublisher}
+}
+
+func (s *PaymentSettingsService) CreatePaymentSetting(ctx context.Context, settings *paymentsettings.PaymentSetting) (err error) {
+	if settings.ValidFrom.IsZero() {
+		settings.ValidFrom = time.Now()
+	}
+	settings.ValidTo = openEnded(settings.ValidTo)
+	if err = s.validate(*settings); err != nil {
+		return err
+	}
+	return s.txManager.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		if err = s.checkValidity(ctx, *settings); err != nil {
+			return err
+		}
+		if err = s.repo.CreatePaymentSetting(ctx, settings); err != nil {
+			return err
+		}
+		if err = s.revise(ctx, paymentsettings.RevisionActionCreated, nil, settings, ""); err != nil {
+			return err
+		}
+		return s.record(ctx, paymentsettings.EventTypePaymentSettingCreated, *settings, settings.CreatedAt)
+	})
+}
+
+// UpdatePaymentSetting locks the setting before updating it, so its revision records the value it replaced
+// and the validity left unset is taken from it.
+func (s *PaymentSettingsService) UpdatePaymentSetting(ctx context.Context, settings *paymentsettings.PaymentSetting) (err error) {
+	if err = s.validate(*settings); err != nil {
+		return err
+	}
+	return s.txManager.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		current, err := s.repo.GetPaymentSettingForUpdate(ctx, settings.ID)
+		if err != nil {
+			return err
+		}
+		if settings.ValidFrom.IsZero() {
+			settings.ValidFrom = current.ValidFrom
+		}
+		if settings.ValidTo == nil {
+			settings.ValidTo = current.ValidTo
+		} else {
+			settings.ValidTo = openEnded(settings.ValidTo)
+		}
+		if err = s.checkValidity(ctx, *settings); err != nil {
+			return err
+		}
+		if err = s.repo.UpdatePaymentSetting(ctx, settings); err != nil {
+			return err
+		}
+		if err = s.revise(ctx, paymentsettings.RevisionActionUpdated, &current, settings, ""); err != nil {
+			return err
+		}
+		return s.record(ctx, paymentsettings.EventTypePaymentSettingUpdated, *settings, settings.UpdatedAt)
+	})
+}
+
+// UpsertPaymentSetting finds the setting and creates or updates it in one transaction. Concurrent upserts
+// both creating the setting are caught by the exclusion constraint of the table: one fails with a conflict.
+func (s *PaymentSettingsService) UpsertPaymentSetting(ctx context.Context, settings *paymentsettings.PaymentSetting) (created bool, err error) {
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		current, err := s.repo.GetPaymentSettingByKey(ctx, settings.SettingKey, settings.Currency)
+		created = pkgerrors.IsNotFound(err)
+		if created {
+			if settings.Version != 0 {
+				return pkgerrors.ErrPreconditionFailed
+			}
+			if err = s.checkScheduled(ctx, *settings); err != nil {
+				return err
+			}
+			return s.CreatePaymentSetting(ctx, settings)
+		}
+		if err != nil {
+			return err
+		}
+		settings.ID = current.ID
+		return s.UpdatePaymentSetting(ctx, settings)
+	})
+	return created, err
+}
+
+// ApplyPaymentSettings computes the plan in the transaction that applies it, so it is computed from the
+// state it is applied to. The changes go through the single-setting operations, which join the transaction
+// and record their revisions and events as usual.
+func (s *PaymentSettingsService) ApplyPaymentSettings(ctx context.Context, desired []paymentsettings.PaymentSetting, options paymentsettings.ApplyOptions) (plan paymentsettings.SettingsPlan, err error) {
+	if err = s.validateDesired(desired); err != nil {
+		return plan, err
+	}
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		current, err := s.fetchAllPaymentSettings(ctx)
+		if err != nil {
+			return err
+		}
+		plan = planChanges(current, desired, options.Prune)
+		if options.DryRun {
+			return nil
+		}
+		for _, change := range plan.Changes {
+			switch change.Action {
+			case paymentsettings.PlanActionCreate:
+				err = s.CreatePaymentSetting(ctx, change.After)
+			case paymentsettings.PlanActionUpdate:
+				err = s.UpdatePaymentSetting(ctx, change.After)
+			case paymentsettings.PlanActionDelete:
+				err = s.DeletePaymentSetting(ctx, change.Before.ID, change.Before.Version)
+			}
+			if err != nil {
+				setting := change.After
+				if setting == nil {
+					setting = change.Before
+				}
+				return fmt.Errorf("%s %s of %s: %w", change.Action, setting.SettingKey, setting.Currency, err)
+			}
+		}
+		return nil
+	})
+	return plan, err
+}
+
+// DeletePaymentSetting reads the setting before deleting it, so the deleted event and revision carry its last state.
+func (s *PaymentSettingsService) DeletePaymentSetting(ctx context.Context, id string, expectedVersion int64) (err error) {
+	return s.txManager.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		setting, err := s.repo.GetPaymentSettingForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err = s.repo.DeletePaymentSetting(ctx, id, expectedVersion); err != nil {
+			return err
+		}
+		if err = s.revise(ctx, paymentsettings.RevisionActionDeleted, &setting, nil, ""); err != nil {
+			return err
+		}
+		return s.record(ctx, paymentsettings.EventTypePaymentSettingDeleted, setting, time.Now())
+	})
+}
+
+// RollbackPaymentSetting is an update to the state recorded by an earlier revision of the same setting.
+// Without a reason in ctx, the revision of the rollback names the revision it restored.
+func (s *PaymentSettingsService) RollbackPaymentSetting(ctx context.Context, id string, revisionID string, expectedVersion int64) (result paymentsettings.PaymentSetting, err error) {
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		current, err := s.repo.GetPaymentSettingForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		target, err := s.revisionRepo.GetRevision(ctx, revisionID)
+		if pkgerrors.IsNotFound(err) || (err == nil && target.SettingID != id) {
+			return pkgerrors.NewNotFoundError(fmt.Errorf("payment setting %s has no revision %s", id, revisionID))
+		}
+		if err != nil {
+			return err
+		}
+		if target.NewValue == nil {
+			return pkgerrors.NewValidationError(fmt.Errorf("revision %s deleted the setting and cannot be restored", revisionID))
+		}
+
+		result = current
+		result.SettingKey = target.SettingKey
+		result.SettingValue = *target.NewValue
+		result.Currency = target.Currency
+		result.Status = target.Status
+		result.ValidFrom = target.ValidFrom
+		result.ValidTo = target.ValidTo
+		result.Version = expectedVersion
+		if err = s.validate(result); err != nil {
+			return err
+		}
+		if err = s.checkValidity(ctx, result); err != nil {
+			return err
+		}
+		if err = s.repo.UpdatePaymentSetting(ctx, &result); err != nil {
+			return err
+		}
+		if err = s.revise(ctx, paymentsettings.RevisionActionRolledBack, &current, &result, fmt.Sprintf("Rolled back to revision %s", revisionID)); err != nil {
+			return err
+		}
+		return s.record(ctx, paymentsettings.EventTypePaymentSettingUpdated, result, result.UpdatedAt)
+	})
+	if err != nil {
+		return paymentsettings.PaymentSetting{}, err
+	}
+	return result, nil
+}
+
+func (s *PaymentSettingsService) FetchPaymentSettings(ctx context.Context, params paymentsettings.PaymentSettingFetchParams) (result []paymentsettings.PaymentSetting, nextCursor string, err error) {
+	return s.repo.FetchPaymentSettings(ctx, params)
+}
+
+func (s *PaymentSettingsService) FetchUpcomingPaymentSettings(ctx context.Context, params paymentsettings.PaymentSettingFetchParams) (result []paymentsettings.PaymentSetting, err error) {
+	return s.repo.FetchUpcomingPaymentSettings(ctx, params)
+}
+
+func (s *PaymentSettingsService) GetEffectiveSettings(ctx context.Context, params paymentsettings.EffectiveSettingsParams) (result paymentsettings.EffectiveSettings, err error) {
+	if params.Currency == "" {
+		return result, pkgerrors.NewValidationError(fmt.Errorf("currency is required"))
+	}
+	tenantID, err := tenant.Required(ctx)
+	if err != nil {
+		return result, err
+	}
+	if params.EffectiveAt.IsZero() {
+		params.EffectiveAt = time.Now()
+	}
+	settings, err := s.repo.FetchActivePaymentSettings(ctx, []string{params.Currency, paymentsettings.GlobalCurrency}, params.EffectiveAt)
+	if err != nil {
+		return result, err
+	}
+	return paymentsettings.EffectiveSettings{
+		TenantID:    tenantID,
+		Currency:    params.Currency,
+		EffectiveAt: params.EffectiveAt,
+		Settings:    paymentsettings.ResolveEffectiveSettings(tenantID, params.Currency, settings),
+	}, nil
+}
+
+func (s *PaymentSettingsService) FetchPaymentSettingsAsOf(ctx context.Context, at time.Time, params paymentsettings.PaymentSettingFetchParams) (result []paymentsettings.PaymentSetting, nextCursor string, err error) {
+	return s.revisionRepo.FetchPaymentSettingsAsOf(ctx, at, params)
+}
+
+func (s *PaymentSettingsService) FetchSettingRevisions(ctx context.Context, params paymentsettings.RevisionFetchParams) (result []paymentsettings.SettingRevision, nextCursor string, err error) {
+	return s.revisionRepo.FetchRevisions(ctx, params)
+}
+
+func (s *PaymentSettingsService) GetPaymentSetting(ctx context.Context, id string) (result paymentsettings.PaymentSetting, err error) {
+	return s.repo.GetPaymentSetting(ctx, id)
+}
+
+func (s *PaymentSettingsService) GetPaymentSettingByKey(ctx context.Context, key, currency string) (result paymentsettings.PaymentSetting, err error) {
+	return s.repo.GetPaymentSettingByKey(ctx, key, currency)
+}
+
+func (s *PaymentSettingsService) Schema() *paymentsettings.Schema {
+	return s.schema
 }
 
-func NewPaymentSettingsService(repo ports.IPaymentSettingsRepository) (service *PaymentSettingsService) {
-	return &PaymentSettingsService{repo: repo}
+func (s *PaymentSettingsService) GetDecimalSetting(ctx context.Context, key, currency string) (value *big.Rat, err error) {
+	raw, err := s.typedValue(ctx, key, currency, paymentsettings.ValueTypeDecimal)
+	if err != nil {
+		return nil, err
+	}
+	if value, err = paymentsettings.ParseDecimal(raw); err != nil {
+		return nil, fmt.Errorf("payment setting %s of %s: %w", key, currency, err)
+	}
+	return value, nil
 }
 
-func (s *PaymentSettingsService) CreatePaymentSetting(settings *paymentsettings.PaymentSetting) (err error) {
-	return s.repo.CreatePaymentSetting(settings)
+func (s *PaymentSettingsService) GetIntegerSetting(ctx context.Context, key, currency string) (value int64, err error) {
+	raw, err := s.typedValue(ctx, key, currency, paymentsettings.ValueTypeInteger)
+	if err != nil {
+		return 0, err
+	}
+	if value, err = strconv.ParseInt(raw, 10, 64); err != nil {
+		return 0, fmt.Errorf("payment setting %s of %s is not an integer: %w", key, currency, err)
+	}
+	return value, nil
 }
 
-func (s *PaymentSettingsService) UpdatePaymentSetting(settings *paymentsettings.PaymentSetting) (err error) {
-	return s.repo.UpdatePaymentSetting(settings)
+func (s *PaymentSettingsService) GetDurationSetting(ctx context.Context, key, currency string) (value time.Duration, err error) {
+	raw, err := s.typedValue(ctx, key, currency, paymentsettings.ValueTypeDuration)
+	if err != nil {
+		return 0, err
+	}
+	if value, err = time.ParseDuration(raw); err != nil {
+		return 0, fmt.Errorf("payment setting %s of %s is not a duration: %w", key, currency, err)
+	}
+	return value, nil
 }
 
-func (s *PaymentSettingsService) DeletePaymentSetting(id string) (err error) {
-	return s.repo.DeletePaymentSetting(id)
+func (s *PaymentSettingsService) GetBooleanSetting(ctx context.Context, key, currency string) (value bool, err error) {
+	raw, err := s.typedValue(ctx, key, currency, paymentsettings.ValueTypeBoolean)
+	if err != nil {
+		return false, err
+	}
+	if value, err = strconv.ParseBool(raw); err != nil {
+		return false, fmt.Errorf("payment setting %s of %s is not a boolean: %w", key, currency, err)
+	}
+	return value, nil
 }
 
-func (s *PaymentSettingsService) FetchPaymentSettings(params paymentsettings.PaymentSettingFetchParams) (result []paymentsettings.PaymentSetting, nextCursor string, err error) {
-	return s.repo.FetchPaymentSettings(params)
+func (s *PaymentSettingsService) GetEnumSetting(ctx context.Context, key, currency string) (value string, err error) {
+	return s.typedValue(ctx, key, currency, paymentsettings.ValueTypeEnum)
 }
 
-func (s *PaymentSettingsService) GetPaymentSetting(id string) (result paymentsettings.PaymentSetting, err error) {
-	return s.repo.GetPaymentSetting(id)
+func (s *PaymentSettingsService) DecodeJSONSetting(ctx context.Context, key, currency string, target interface{}) (err error) {
+	raw, err := s.typedValue(ctx, key, currency, paymentsettings.ValueTypeJSON)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal([]byte(raw), target); err != nil {
+		return fmt.Errorf("failed to decode payment setting %s of %s: %w", key, currency, err)
+	}
+	return nil
+}
+
+// validateDesired reports every invalid and duplicate setting of a desired state at once, so a
+// configuration file is fixed in one pass instead of one error at a time.
+func (s *PaymentSettingsService) validateDesired(desired []paymentsettings.PaymentSetting) error {
+	var errs []error
+	seen := make(map[settingIdentity]bool, len(desired))
+	for _, setting := range desired {
+		if setting.SettingKey == "" || setting.Currency == "" {
+			errs = append(errs, fmt.Errorf("setting %q of currency %q: key and currency are required", setting.SettingKey, setting.Currency))
+			continue
+		}
+		identity := identityOf(setting)
+		if seen[identity] {
+			errs = append(errs, fmt.Errorf("%s of %s is listed more than once", setting.SettingKey, setting.Currency))
+		}
+		seen[identity] = true
+		if err := s.schema.Validate(setting.SettingKey, setting.SettingValue); err != nil {
+			errs = append(errs, fmt.Errorf("%s of %s: %w", setting.SettingKey, setting.Currency, err))
+		}
+	}
+	if len(errs) > 0 {
+		return pkgerrors.NewValidationError(errors.Join(errs...))
+	}
+	return nil
+}
+
+// fetchAllPaymentSettings pages through every setting in force now.
+func (s *PaymentSettingsService) fetchAllPaymentSettings(ctx context.Context) (result []paymentsettings.PaymentSetting, err error) {
+	params := paymentsettings.PaymentSettingFetchParams{Limit: 100}
+	for {
+		page, nextCursor, err := s.repo.FetchPaymentSettings(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, page...)
+		if nextCursor == "" {
+			return result, nil
+		}
+		params.Cursor = nextCursor
+	}
+}
+
+// settingIdentity identifies a setting in force across environments, whose IDs differ.
+type settingIdentity struct {
+	key      string
+	currency string
+}
+
+func identityOf(setting paymentsettings.PaymentSetting) settingIdentity {
+	return settingIdentity{key: setting.SettingKey, currency: setting.Currency}
+}
+
+// planChanges compares the settings in force with the desired ones. Updates carry the ID, version and
+// validity of the current setting, so they fail if it changes before they are applied.
+func planChanges(current, desired []paymentsettings.PaymentSetting, prune bool) (plan paymentsettings.SettingsPlan) {
+	inForce := make(map[settingIdentity]paymentsettings.PaymentSetting, len(current))
+	for _, setting := range current {
+		inForce[identityOf(setting)] = setting
+	}
+
+	listed := make(map[settingIdentity]bool, len(desired))
+	for _, setting := range desired {
+		identity := identityOf(setting)
+		listed[identity] = true
+		before, ok := inForce[identity]
+		switch {
+		case !ok:
+			after := setting
+			after.ID, after.Version, after.ValidFrom, after.ValidTo = "", 0, time.Time{}, nil
+			plan.Changes = append(plan.Changes, paymentsettings.SettingChange{Action: paymentsettings.PlanActionCreate, After: &after})
+		case before.SettingValue != setting.SettingValue || before.Status != setting.Status:
+			after := before
+			after.SettingValue, after.Status = setting.SettingValue, setting.Status
+			plan.Changes = append(plan.Changes, paymentsettings.SettingChange{Action: paymentsettings.PlanActionUpdate, Before: &before, After: &after})
+		default:
+			plan.Unchanged++
+		}
+	}
+
+	if prune {
+		for _, setting := range current {
+			if !listed[identityOf(setting)] {
+				before := setting
+				plan.Changes = append(plan.Changes, paymentsettings.SettingChange{Action: paymentsettings.PlanActionDelete, Before: &before})
+			}
+		}
+	}
+
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		return changeIdentity(plan.Changes[i]).less(changeIdentity(plan.Changes[j]))
+	})
+	return plan
+}
+
+func changeIdentity(change paymentsettings.SettingChange) settingIdentity {
+	if change.After != nil {
+		return identityOf(*change.After)
+	}
+	return identityOf(*change.Before)
+}
+
+func (i settingIdentity) less(other settingIdentity) bool {
+	if i.key != other.key {
+		return i.key < other.key
+	}
+	return i.currency < other.currency
+}
+
+// validate checks the value of the setting against the schema.
+func (s *PaymentSettingsService) validate(setting paymentsettings.PaymentSetting) error {
+	if err := s.schema.Validate(setting.SettingKey, setting.SettingValue); err != nil {
+		return pkgerrors.NewValidationError(err)
+	}
+	return nil
+}
+
+// checkScheduled rejects a setting created by an upsert that overlaps a version of the key and currency
+// scheduled for later: the upsert found nothing in force now, and replacing the scheduled version instead
+// would not be what the caller asked for.
+func (s *PaymentSettingsService) checkScheduled(ctx context.Context, setting paymentsettings.PaymentSetting) error {
+	upcoming, err := s.repo.FetchUpcomingPaymentSettings(ctx, paymentsettings.PaymentSettingFetchParams{
+		SettingKey: setting.SettingKey,
+		Currency:   setting.Currency,
+		Limit:      1,
+	})
+	if err != nil || len(upcoming) == 0 {
+		return err
+	}
+	scheduled := upcoming[0]
+	validTo := openEnded(setting.ValidTo)
+	if validTo != nil && !validTo.After(scheduled.ValidFrom) {
+		return nil
+	}
+	if scheduled.ValidTo != nil && !setting.ValidFrom.IsZero() && !setting.ValidFrom.Before(*scheduled.ValidTo) {
+		return nil
+	}
+	return pkgerrors.NewValidationError(fmt.Errorf("%s of %s has no setting in force but payment setting %s is scheduled from %s: update it by its ID, or set a validTo ending before it",
+		setting.SettingKey, setting.Currency, scheduled.ID, scheduled.ValidFrom.Format(time.RFC3339)))
+}
+
+// openEnded returns nil for a ValidTo set to the zero time, which asks for a validity without end.
+func openEnded(validTo *time.Time) *time.Time {
+	if validTo != nil && validTo.IsZero() {
+		return nil
+	}
+	return validTo
+}
+
+// checkValidity rejects an empty validity range and one overlapping another setting of the same key and
+// currency, naming that setting. Concurrent writes are caught by the exclusion constraint of the table
+// instead, with a less helpful message.
+func (s *PaymentSettingsService) checkValidity(ctx context.Context, setting paymentsettings.PaymentSetting) error {
+	if setting.ValidTo != nil && !setting.ValidTo.After(setting.ValidFrom) {
+		return pkgerrors.NewValidationError(fmt.Errorf("validTo must be after validFrom"))
+	}
+	overlapping, err := s.repo.FetchOverlappingPaymentSettings(ctx, setting)
+	if err != nil {
+		return err
+	}
+	if len(overlapping) == 0 {
+		return nil
+	}
+	other := overlapping[0]
+	validTo := "indefinitely"
+	if other.ValidTo != nil {
+		validTo = "until " + other.ValidTo.Format(time.RFC3339)
+	}
+	return pkgerrors.NewConflictError(fmt.Errorf("%s of %s overlaps payment setting %s, valid from %s %s",
+		setting.SettingKey, setting.Currency, other.ID, other.ValidFrom.Format(time.RFC3339), validTo))
+}
+
+// typedValue returns the raw effective value of key for currency. A key declared in the schema
+// must be declared with valueType, so a caller cannot read a decimal as an integer by mistake.
+func (s *PaymentSettingsService) typedValue(ctx context.Context, key, currency string, valueType paymentsettings.ValueType) (value string, err error) {
+	if declared, ok := s.schema.Lookup(key); ok && declared.Type != valueType {
+		return "", fmt.Errorf("payment setting %s is declared as %s, not %s", key, declared.Type, valueType)
+	}
+	effective, err := s.GetEffectiveSettings(ctx, paymentsettings.EffectiveSettingsParams{Currency: currency})
+	if err != nil {
+		return "", err
+	}
+	setting, ok := effective.Lookup(key)
+	if !ok {
+		return "", pkgerrors.NewNotFoundError(fmt.Errorf("no active payment setting %s for %s", key, currency))
+	}
+	return setting.Value, nil
+}
+
+// revise records the change from before to after as a revision, in the transaction of the change. before is
+// nil for a creation and after is nil for a deletion. defaultReason is used when ctx carries no reason.
+func (s *PaymentSettingsService) revise(ctx context.Context, action string, before, after *paymentsettings.PaymentSetting, defaultReason string) (err error) {
+	info := paymentsettings.ChangeInfoFromContext(ctx)
+	if info.Reason == "" {
+		info.Reason = defaultReason
+	}
+	revision := paymentsettings.SettingRevision{
+		Action: action,
+		Actor:  info.Actor,
+		Reason: info.Reason,
+	}
+	state := after
+	if before != nil {
+		revision.OldValue = &before.SettingValue
+		state = before
+	}
+	if after != nil {
+		revision.NewValue = &after.SettingValue
+		state = after
+	}
+	revision.SettingID = state.ID
+	revision.Version = state.Version
+	revision.SettingKey = state.SettingKey
+	revision.Currency = state.Currency
+	revision.Status = state.Status
+	revision.ValidFrom = state.ValidFrom
+	revision.ValidTo = state.ValidTo
+
+	if err = s.revisionRepo.CreateRevision(ctx, &revision); err != nil {
+		return fmt.Errorf("failed to record %s revision of payment setting %s: %w", action, state.ID, err)
+	}
+	return nil
+}
+
+// record appends an event carrying the setting to the module outbox, in the transaction of the change,
+// and publishes it on the event bus once that transaction committed.
+func (s *PaymentSettingsService) record(ctx context.Context, eventType string, setting paymentsettings.PaymentSetting, occurredAt time.Time) (err error) {
+	payload, err := json.Marshal(setting)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event of payment setting %s: %w", eventType, setting.ID, err)
+	}
+	event := outbox.Event{
+		Type:        eventType,
+		AggregateID: setting.ID,
+		Payload:     payload,
+		OccurredAt:  occurredAt,
+	}
+	if event.ID, err = uniqueid.GeneratePK("evt"); err != nil {
+		return err
+	}
+	if err = s.eventOutbox.Append(ctx, event); err != nil {
+		return fmt.Errorf("failed to record %s event of payment setting %s: %w", eventType, setting.ID, err)
+	}
+
+	transaction.AfterCommit(ctx, func(ctx context.Context) {
+		// The change is committed; a failing subscriber must not fail it
+		err := s.eventPublisher.Publish(ctx, paymentsettings.PaymentSettingChanged{
+			EventID:    event.ID,
+			Type:       eventType,
+			Setting:    setting,
+			OccurredAt: occurredAt,
+		})
+		if err != nil {
+			log.Error().Err(err).Str("event_id", event.ID).Str("event_type", eventType).Msg("Failed to handle payment setting event")
+		}
+	})
+	return nil
 }
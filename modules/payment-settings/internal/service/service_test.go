@@ -1,18 +1,59 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/internal/ports/mocks"
 	pkgerrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/outbox"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/testutils"
 )
 
+// inlineTxManager runs the unit of work directly, without a database transaction.
+type inlineTxManager struct{}
+
+func (inlineTxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// newMockRepo creates a repository mock that finds no overlapping settings.
+func newMockRepo(t *testing.T) *mocks.MockIPaymentSettingsRepository {
+	repo := mocks.NewMockIPaymentSettingsRepository(t)
+	repo.On("FetchOverlappingPaymentSettings", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+	return repo
+}
+
+// anyRevisionRepo accepts every recorded revision; TestPaymentSettingsService_RecordsRevisions asserts them.
+func anyRevisionRepo(t *testing.T) *mocks.MockIRevisionRepository {
+	revisionRepo := mocks.NewMockIRevisionRepository(t)
+	revisionRepo.On("CreateRevision", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return revisionRepo
+}
+
+// anyEventOutbox accepts every appended event; TestPaymentSettingsService_RecordsOutboxEvents asserts them.
+func anyEventOutbox(t *testing.T) *mocks.MockIEventOutbox {
+	eventOutbox := mocks.NewMockIEventOutbox(t)
+	eventOutbox.On("Append", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return eventOutbox
+}
+
+// anyEventPublisher accepts every event published on the bus; TestPaymentSettingsService_PublishesBusEvents asserts them.
+func anyEventPublisher(t *testing.T) *mocks.MockIEventPublisher {
+	eventPublisher := mocks.NewMockIEventPublisher(t)
+	eventPublisher.On("Publish", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return eventPublisher
+}
+
 func TestPaymentSettingsService_CreatePaymentSetting(t *testing.T) {
 	tests := []struct {
 		name                  string
@@ -59,12 +100,12 @@ func TestPaymentSettingsService_CreatePaymentSetting(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockRepo := mocks.NewMockIPaymentSettingsRepository(t)
+			mockRepo := newMockRepo(t)
 
-			mockRepo.On("CreatePaymentSetting", tt.setting).Return(tt.mockError)
+			mockRepo.On("CreatePaymentSetting", mock.Anything, tt.setting).Return(tt.mockError)
 
-			service := NewPaymentSettingsService(mockRepo)
-			err := service.CreatePaymentSetting(tt.setting)
+			service := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+			err := service.CreatePaymentSetting(context.Background(), tt.setting)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -119,12 +160,12 @@ func TestPaymentSettingsService_GetPaymentSetting(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockRepo := mocks.NewMockIPaymentSettingsRepository(t)
+			mockRepo := newMockRepo(t)
 
-			mockRepo.On("GetPaymentSetting", tt.settingID).Return(tt.mockSetting, tt.mockError)
+			mockRepo.On("GetPaymentSetting", mock.Anything, tt.settingID).Return(tt.mockSetting, tt.mockError)
 
-			service := NewPaymentSettingsService(mockRepo)
-			result, err := service.GetPaymentSetting(tt.settingID)
+			service := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+			result, err := service.GetPaymentSetting(context.Background(), tt.settingID)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -205,9 +246,9 @@ func TestPaymentSettingsService_FetchPaymentSettings(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockRepo := mocks.NewMockIPaymentSettingsRepository(t)
+			mockRepo := newMockRepo(t)
 
-			mockRepo.On("FetchPaymentSettings", mock.MatchedBy(func(params paymentsettings.PaymentSettingFetchParams) bool {
+			mockRepo.On("FetchPaymentSettings", mock.Anything, mock.MatchedBy(func(params paymentsettings.PaymentSettingFetchParams) bool {
 				return params.Cursor == tt.params.Cursor &&
 					params.Limit == tt.params.Limit &&
 					params.Currency == tt.params.Currency &&
@@ -215,8 +256,8 @@ func TestPaymentSettingsService_FetchPaymentSettings(t *testing.T) {
 					params.Status == tt.params.Status
 			})).Return(tt.mockSettings, tt.mockCursor, tt.mockError)
 
-			service := NewPaymentSettingsService(mockRepo)
-			result, cursor, err := service.FetchPaymentSettings(tt.params)
+			service := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+			result, cursor, err := service.FetchPaymentSettings(context.Background(), tt.params)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -276,12 +317,13 @@ func TestPaymentSettingsService_UpdatePaymentSetting(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockRepo := mocks.NewMockIPaymentSettingsRepository(t)
+			mockRepo := newMockRepo(t)
 
-			mockRepo.On("UpdatePaymentSetting", tt.setting).Return(tt.mockError)
+			mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, tt.setting.ID).Return(*tt.setting, nil)
+			mockRepo.On("UpdatePaymentSetting", mock.Anything, tt.setting).Return(tt.mockError)
 
-			service := NewPaymentSettingsService(mockRepo)
-			err := service.UpdatePaymentSetting(tt.setting)
+			service := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+			err := service.UpdatePaymentSetting(context.Background(), tt.setting)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -296,19 +338,29 @@ func TestPaymentSettingsService_DeletePaymentSetting(t *testing.T) {
 	tests := []struct {
 		name        string
 		settingID   string
+		version     int64
+		getError    error
 		mockError   error
 		expectError bool
 	}{
 		{
 			name:        "successful payment setting deletion",
 			settingID:   "pset_123",
+			version:     1,
 			mockError:   nil,
 			expectError: false,
 		},
+		{
+			name:        "stale version",
+			settingID:   "pset_123",
+			version:     1,
+			mockError:   pkgerrors.ErrPreconditionFailed,
+			expectError: true,
+		},
 		{
 			name:        "payment setting not found",
 			settingID:   "pset_nonexistent",
-			mockError:   pkgerrors.ErrDataNotFound,
+			getError:    pkgerrors.ErrDataNotFound,
 			expectError: true,
 		},
 		{
@@ -321,12 +373,15 @@ func TestPaymentSettingsService_DeletePaymentSetting(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockRepo := mocks.NewMockIPaymentSettingsRepository(t)
+			mockRepo := newMockRepo(t)
 
-			mockRepo.On("DeletePaymentSetting", tt.settingID).Return(tt.mockError)
+			mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, tt.settingID).Return(paymentsettings.PaymentSetting{ID: tt.settingID, Version: tt.version}, tt.getError)
+			if tt.getError == nil {
+				mockRepo.On("DeletePaymentSetting", mock.Anything, tt.settingID, tt.version).Return(tt.mockError)
+			}
 
-			service := NewPaymentSettingsService(mockRepo)
-			err := service.DeletePaymentSetting(tt.settingID)
+			service := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+			err := service.DeletePaymentSetting(context.Background(), tt.settingID, tt.version)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -336,3 +391,629 @@ func TestPaymentSettingsService_DeletePaymentSetting(t *testing.T) {
 		})
 	}
 }
+
+func TestPaymentSettingsService_RecordsOutboxEvents(t *testing.T) {
+	setting := paymentsettings.PaymentSetting{ID: "pset_123", SettingKey: "rate", SettingValue: "1.5", Currency: "USD", Status: "active", Version: 2}
+	isEvent := func(eventType string) interface{} {
+		return mock.MatchedBy(func(event outbox.Event) bool {
+			var payload paymentsettings.PaymentSetting
+			return event.Type == eventType && event.AggregateID == setting.ID &&
+				json.Unmarshal(event.Payload, &payload) == nil && payload.SettingValue == setting.SettingValue
+		})
+	}
+
+	t.Run("create records payment_setting.created", func(t *testing.T) {
+		mockRepo := newMockRepo(t)
+		mockOutbox := mocks.NewMockIEventOutbox(t)
+		created := setting
+
+		mockRepo.On("CreatePaymentSetting", mock.Anything, &created).Return(nil)
+		mockOutbox.On("Append", mock.Anything, isEvent(paymentsettings.EventTypePaymentSettingCreated)).Return(nil)
+
+		err := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, mockOutbox, anyEventPublisher(t)).CreatePaymentSetting(context.Background(), &created)
+		assert.NoError(t, err)
+	})
+
+	t.Run("update records payment_setting.updated", func(t *testing.T) {
+		mockRepo := newMockRepo(t)
+		mockOutbox := mocks.NewMockIEventOutbox(t)
+		updated := setting
+
+		mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, updated.ID).Return(updated, nil)
+		mockRepo.On("UpdatePaymentSetting", mock.Anything, &updated).Return(nil)
+		mockOutbox.On("Append", mock.Anything, isEvent(paymentsettings.EventTypePaymentSettingUpdated)).Return(nil)
+
+		err := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, mockOutbox, anyEventPublisher(t)).UpdatePaymentSetting(context.Background(), &updated)
+		assert.NoError(t, err)
+	})
+
+	t.Run("delete records the last state of the setting", func(t *testing.T) {
+		mockRepo := newMockRepo(t)
+		mockOutbox := mocks.NewMockIEventOutbox(t)
+
+		mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, setting.ID).Return(setting, nil)
+		mockRepo.On("DeletePaymentSetting", mock.Anything, setting.ID, setting.Version).Return(nil)
+		mockOutbox.On("Append", mock.Anything, isEvent(paymentsettings.EventTypePaymentSettingDeleted)).Return(nil)
+
+		err := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, mockOutbox, anyEventPublisher(t)).DeletePaymentSetting(context.Background(), setting.ID, setting.Version)
+		assert.NoError(t, err)
+	})
+
+	t.Run("outbox failure fails the change", func(t *testing.T) {
+		mockRepo := newMockRepo(t)
+		mockOutbox := mocks.NewMockIEventOutbox(t)
+		updated := setting
+
+		mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, updated.ID).Return(updated, nil)
+		mockRepo.On("UpdatePaymentSetting", mock.Anything, &updated).Return(nil)
+		mockOutbox.On("Append", mock.Anything, mock.Anything).Return(errors.New("insert failed"))
+
+		err := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, mockOutbox, anyEventPublisher(t)).UpdatePaymentSetting(context.Background(), &updated)
+		assert.Error(t, err)
+	})
+}
+
+func TestPaymentSettingsService_PublishesBusEvents(t *testing.T) {
+	setting := paymentsettings.PaymentSetting{ID: "pset_123", SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "5000.00", Currency: "USD", Status: "active", Version: 2}
+
+	t.Run("committed change is published with the outbox event ID", func(t *testing.T) {
+		mockRepo := newMockRepo(t)
+		mockOutbox := mocks.NewMockIEventOutbox(t)
+		mockPublisher := mocks.NewMockIEventPublisher(t)
+		updated := setting
+
+		var recorded outbox.Event
+		mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, updated.ID).Return(updated, nil)
+		mockRepo.On("UpdatePaymentSetting", mock.Anything, &updated).Return(nil)
+		mockOutbox.On("Append", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			recorded = args.Get(1).(outbox.Event)
+		}).Return(nil)
+		mockPublisher.On("Publish", mock.Anything, mock.MatchedBy(func(event paymentsettings.PaymentSettingChanged) bool {
+			return event.EventID == recorded.ID && event.EventName() == paymentsettings.EventTypePaymentSettingUpdated && event.Setting == setting
+		})).Return(nil)
+
+		err := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, mockOutbox, mockPublisher).UpdatePaymentSetting(context.Background(), &updated)
+		assert.NoError(t, err)
+	})
+
+	t.Run("failing subscriber does not fail the committed change", func(t *testing.T) {
+		mockRepo := newMockRepo(t)
+		mockPublisher := mocks.NewMockIEventPublisher(t)
+		created := setting
+
+		mockRepo.On("CreatePaymentSetting", mock.Anything, &created).Return(nil)
+		mockPublisher.On("Publish", mock.Anything, mock.Anything).Return(errors.New("subscriber failed"))
+
+		err := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), mockPublisher).CreatePaymentSetting(context.Background(), &created)
+		assert.NoError(t, err)
+	})
+
+	t.Run("failed change is not published", func(t *testing.T) {
+		mockRepo := newMockRepo(t)
+		created := setting
+
+		mockRepo.On("CreatePaymentSetting", mock.Anything, &created).Return(pkgerrors.ErrDuplicatedData)
+
+		err := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, mocks.NewMockIEventOutbox(t), mocks.NewMockIEventPublisher(t)).CreatePaymentSetting(context.Background(), &created)
+		assert.Error(t, err)
+	})
+}
+
+func TestPaymentSettingsService_ValidatesAgainstSchema(t *testing.T) {
+	tests := []struct {
+		name              string
+		setting           paymentsettings.PaymentSetting
+		rejectUnknownKeys bool
+		expectError       bool
+	}{
+		{name: "valid declared value", setting: paymentsettings.PaymentSetting{SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "10000.00", Currency: "USD"}},
+		{name: "invalid declared value", setting: paymentsettings.PaymentSetting{SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "1O000", Currency: "USD"}, expectError: true},
+		{name: "value out of bounds", setting: paymentsettings.PaymentSetting{SettingKey: paymentsettings.SettingKeyPaymentTimeoutSeconds, SettingValue: "0", Currency: "USD"}, expectError: true},
+		{name: "unknown key accepted", setting: paymentsettings.PaymentSetting{SettingKey: "rate", SettingValue: "1.0", Currency: "USD"}},
+		{name: "unknown key rejected", setting: paymentsettings.PaymentSetting{SettingKey: "rate", SettingValue: "1.0", Currency: "USD"}, rejectUnknownKeys: true, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := paymentsettings.DefaultSchema()
+			schema.RejectUnknownKeys = tt.rejectUnknownKeys
+
+			mockRepo := newMockRepo(t)
+			created, updated := tt.setting, tt.setting
+			if !tt.expectError {
+				mockRepo.On("CreatePaymentSetting", mock.Anything, &created).Return(nil)
+				mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, updated.ID).Return(updated, nil)
+				mockRepo.On("UpdatePaymentSetting", mock.Anything, &updated).Return(nil)
+			}
+
+			service := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), schema, inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+			createErr := service.CreatePaymentSetting(context.Background(), &created)
+			updateErr := service.UpdatePaymentSetting(context.Background(), &updated)
+
+			if tt.expectError {
+				assert.True(t, pkgerrors.IsErrorCode(createErr, pkgerrors.ErrorCodeValidation), "create: %v", createErr)
+				assert.True(t, pkgerrors.IsErrorCode(updateErr, pkgerrors.ErrorCodeValidation), "update: %v", updateErr)
+			} else {
+				assert.NoError(t, createErr)
+				assert.NoError(t, updateErr)
+			}
+		})
+	}
+}
+
+func TestPaymentSettingsService_TypedAccessors(t *testing.T) {
+	schema, err := paymentsettings.NewSchema(
+		paymentsettings.SettingSchema{Key: "max", Type: paymentsettings.ValueTypeDecimal},
+		paymentsettings.SettingSchema{Key: "timeout", Type: paymentsettings.ValueTypeInteger},
+		paymentsettings.SettingSchema{Key: "grace", Type: paymentsettings.ValueTypeDuration},
+		paymentsettings.SettingSchema{Key: "retry", Type: paymentsettings.ValueTypeBoolean},
+		paymentsettings.SettingSchema{Key: "mode", Type: paymentsettings.ValueTypeEnum, AllowedValues: []string{"auto", "manual"}},
+		paymentsettings.SettingSchema{Key: "providers", Type: paymentsettings.ValueTypeJSON},
+	)
+	require.NoError(t, err)
+
+	values := map[string]string{
+		"max":       "10000.50",
+		"timeout":   "300",
+		"grace":     "90s",
+		"retry":     "true",
+		"mode":      "manual",
+		"providers": `["simulator"]`,
+	}
+	newService := func(t *testing.T) *PaymentSettingsService {
+		var usd []paymentsettings.PaymentSetting
+		for key, value := range values {
+			currency := "USD"
+			// The timeout is read from the global scope
+			if key == "timeout" {
+				currency = paymentsettings.GlobalCurrency
+			}
+			usd = append(usd, paymentsettings.PaymentSetting{SettingKey: key, SettingValue: value, Currency: currency})
+		}
+		mockRepo := newMockRepo(t)
+		mockRepo.On("FetchActivePaymentSettings", mock.Anything, []string{"USD", paymentsettings.GlobalCurrency}, mock.Anything).Return(usd, nil).Maybe()
+		mockRepo.On("FetchActivePaymentSettings", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+		return NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), schema, inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+	}
+	ctx := testutils.TenantContext()
+
+	t.Run("read typed values", func(t *testing.T) {
+		service := newService(t)
+
+		decimal, err := service.GetDecimalSetting(ctx, "max", "USD")
+		require.NoError(t, err)
+		assert.Equal(t, "10000.50", decimal.FloatString(2))
+
+		integer, err := service.GetIntegerSetting(ctx, "timeout", "USD")
+		require.NoError(t, err)
+		assert.Equal(t, int64(300), integer)
+
+		duration, err := service.GetDurationSetting(ctx, "grace", "USD")
+		require.NoError(t, err)
+		assert.Equal(t, 90*time.Second, duration)
+
+		boolean, err := service.GetBooleanSetting(ctx, "retry", "USD")
+		require.NoError(t, err)
+		assert.True(t, boolean)
+
+		enum, err := service.GetEnumSetting(ctx, "mode", "USD")
+		require.NoError(t, err)
+		assert.Equal(t, "manual", enum)
+
+		var providers []string
+		require.NoError(t, service.DecodeJSONSetting(ctx, "providers", "USD", &providers))
+		assert.Equal(t, []string{"simulator"}, providers)
+	})
+
+	t.Run("declared type must match", func(t *testing.T) {
+		_, err := newService(t).GetIntegerSetting(ctx, "max", "USD")
+		assert.Error(t, err)
+	})
+
+	t.Run("missing setting is not found", func(t *testing.T) {
+		_, err := newService(t).GetDecimalSetting(ctx, "max", "EUR")
+		assert.True(t, pkgerrors.IsNotFound(err), "%v", err)
+	})
+}
+
+func TestPaymentSettingsService_GetEffectiveSettings(t *testing.T) {
+	effectiveAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	settings := []paymentsettings.PaymentSetting{
+		{ID: "pset_global_max", SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "10000.00", Currency: paymentsettings.GlobalCurrency},
+		{ID: "pset_global_timeout", SettingKey: paymentsettings.SettingKeyPaymentTimeoutSeconds, SettingValue: "300", Currency: paymentsettings.GlobalCurrency},
+		{ID: "pset_jpy_max", SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "1000000", Currency: "JPY"},
+	}
+
+	t.Run("currency settings override the global ones", func(t *testing.T) {
+		mockRepo := newMockRepo(t)
+		mockRepo.On("FetchActivePaymentSettings", mock.Anything, []string{"JPY", paymentsettings.GlobalCurrency}, effectiveAt).Return(settings, nil)
+
+		service := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+		result, err := service.GetEffectiveSettings(testutils.TenantContext(), paymentsettings.EffectiveSettingsParams{Currency: "JPY", EffectiveAt: effectiveAt})
+
+		require.NoError(t, err)
+		assert.Equal(t, testutils.TestTenantID, result.TenantID)
+		assert.Equal(t, "JPY", result.Currency)
+		assert.Equal(t, map[string]paymentsettings.EffectiveSetting{
+			paymentsettings.SettingKeyMaxTransactionAmount:  {Value: "1000000", Scope: paymentsettings.ScopeCurrency, SettingID: "pset_jpy_max"},
+			paymentsettings.SettingKeyPaymentTimeoutSeconds: {Value: "300", Scope: paymentsettings.ScopeGlobal, SettingID: "pset_global_timeout"},
+		}, result.Settings)
+	})
+
+	t.Run("currency is required", func(t *testing.T) {
+		service := NewPaymentSettingsService(newMockRepo(t), anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+		_, err := service.GetEffectiveSettings(testutils.TenantContext(), paymentsettings.EffectiveSettingsParams{})
+
+		assert.True(t, pkgerrors.IsErrorCode(err, pkgerrors.ErrorCodeValidation), "%v", err)
+	})
+
+	t.Run("tenant is required", func(t *testing.T) {
+		service := NewPaymentSettingsService(newMockRepo(t), anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+		_, err := service.GetEffectiveSettings(context.Background(), paymentsettings.EffectiveSettingsParams{Currency: "JPY"})
+
+		assert.ErrorIs(t, err, tenant.ErrRequired)
+	})
+}
+
+func TestPaymentSettingsService_RecordsRevisions(t *testing.T) {
+	validFrom := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	setting := paymentsettings.PaymentSetting{ID: "pset_123", SettingKey: "rate", SettingValue: "1.5", Currency: "USD", Status: "active", ValidFrom: validFrom, Version: 2}
+	ctx := paymentsettings.WithChangeInfo(context.Background(), paymentsettings.ChangeInfo{Actor: "alice", Reason: "INC-42"})
+	value := func(v string) *string { return &v }
+
+	t.Run("create records no old value", func(t *testing.T) {
+		mockRepo := newMockRepo(t)
+		mockRevisions := mocks.NewMockIRevisionRepository(t)
+		created := setting
+
+		mockRepo.On("CreatePaymentSetting", mock.Anything, &created).Return(nil)
+		mockRevisions.On("CreateRevision", mock.Anything, &paymentsettings.SettingRevision{
+			SettingID: setting.ID, Version: 2, Action: paymentsettings.RevisionActionCreated, SettingKey: "rate", Currency: "USD", Status: "active", ValidFrom: validFrom,
+			NewValue: value("1.5"), Actor: "alice", Reason: "INC-42",
+		}).Return(nil)
+
+		err := NewPaymentSettingsService(mockRepo, mockRevisions, paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t)).CreatePaymentSetting(ctx, &created)
+		assert.NoError(t, err)
+	})
+
+	t.Run("update records the replaced value", func(t *testing.T) {
+		mockRepo := newMockRepo(t)
+		mockRevisions := mocks.NewMockIRevisionRepository(t)
+		updated := setting
+		updated.SettingValue = "2.0"
+
+		mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, setting.ID).Return(setting, nil)
+		mockRepo.On("UpdatePaymentSetting", mock.Anything, &updated).Run(func(args mock.Arguments) {
+			args.Get(1).(*paymentsettings.PaymentSetting).Version = 3
+		}).Return(nil)
+		mockRevisions.On("CreateRevision", mock.Anything, &paymentsettings.SettingRevision{
+			SettingID: setting.ID, Version: 3, Action: paymentsettings.RevisionActionUpdated, SettingKey: "rate", Currency: "USD", Status: "active", ValidFrom: validFrom,
+			OldValue: value("1.5"), NewValue: value("2.0"), Actor: "alice", Reason: "INC-42",
+		}).Return(nil)
+
+		err := NewPaymentSettingsService(mockRepo, mockRevisions, paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t)).UpdatePaymentSetting(ctx, &updated)
+		assert.NoError(t, err)
+	})
+
+	t.Run("delete records no new value and defaults to the system actor", func(t *testing.T) {
+		mockRepo := newMockRepo(t)
+		mockRevisions := mocks.NewMockIRevisionRepository(t)
+
+		mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, setting.ID).Return(setting, nil)
+		mockRepo.On("DeletePaymentSetting", mock.Anything, setting.ID, setting.Version).Return(nil)
+		mockRevisions.On("CreateRevision", mock.Anything, &paymentsettings.SettingRevision{
+			SettingID: setting.ID, Version: 2, Action: paymentsettings.RevisionActionDeleted, SettingKey: "rate", Currency: "USD", Status: "active", ValidFrom: validFrom,
+			OldValue: value("1.5"), Actor: paymentsettings.ActorSystem,
+		}).Return(nil)
+
+		err := NewPaymentSettingsService(mockRepo, mockRevisions, paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t)).DeletePaymentSetting(context.Background(), setting.ID, setting.Version)
+		assert.NoError(t, err)
+	})
+
+	t.Run("revision failure fails the change", func(t *testing.T) {
+		mockRepo := newMockRepo(t)
+		mockRevisions := mocks.NewMockIRevisionRepository(t)
+		created := setting
+
+		mockRepo.On("CreatePaymentSetting", mock.Anything, &created).Return(nil)
+		mockRevisions.On("CreateRevision", mock.Anything, mock.Anything).Return(errors.New("insert failed"))
+
+		err := NewPaymentSettingsService(mockRepo, mockRevisions, paymentsettings.DefaultSchema(), inlineTxManager{}, mocks.NewMockIEventOutbox(t), mocks.NewMockIEventPublisher(t)).CreatePaymentSetting(ctx, &created)
+		assert.Error(t, err)
+	})
+}
+
+func TestPaymentSettingsService_RollbackPaymentSetting(t *testing.T) {
+	current := paymentsettings.PaymentSetting{ID: "pset_123", SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "1.00", Currency: "USD", Status: "active", Version: 3}
+	value := func(v string) *string { return &v }
+	previous := paymentsettings.SettingRevision{
+		ID: "psrev_1", SettingID: current.ID, Version: 1, Action: paymentsettings.RevisionActionCreated,
+		SettingKey: current.SettingKey, Currency: "USD", Status: "active", NewValue: value("10000.00"),
+	}
+
+	tests := []struct {
+		name         string
+		revision     paymentsettings.SettingRevision
+		revisionErr  error
+		expectedCode string
+	}{
+		{name: "restores the revision", revision: previous},
+		{name: "unknown revision", revisionErr: pkgerrors.ErrDataNotFound, expectedCode: pkgerrors.ErrorCodeDataNotFound},
+		{name: "revision of another setting", revision: func() paymentsettings.SettingRevision {
+			other := previous
+			other.SettingID = "pset_other"
+			return other
+		}(), expectedCode: pkgerrors.ErrorCodeDataNotFound},
+		{name: "deletion revision", revision: func() paymentsettings.SettingRevision {
+			deleted := previous
+			deleted.Action, deleted.OldValue, deleted.NewValue = paymentsettings.RevisionActionDeleted, value("1.00"), nil
+			return deleted
+		}(), expectedCode: pkgerrors.ErrorCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := newMockRepo(t)
+			mockRevisions := mocks.NewMockIRevisionRepository(t)
+
+			mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, current.ID).Return(current, nil)
+			mockRevisions.On("GetRevision", mock.Anything, "psrev_1").Return(tt.revision, tt.revisionErr)
+			if tt.expectedCode == "" {
+				mockRepo.On("UpdatePaymentSetting", mock.Anything, mock.MatchedBy(func(setting *paymentsettings.PaymentSetting) bool {
+					return setting.SettingValue == "10000.00" && setting.Version == current.Version
+				})).Return(nil)
+				mockRevisions.On("CreateRevision", mock.Anything, mock.MatchedBy(func(revision *paymentsettings.SettingRevision) bool {
+					return revision.Action == paymentsettings.RevisionActionRolledBack && *revision.OldValue == "1.00" &&
+						*revision.NewValue == "10000.00" && revision.Reason == "Rolled back to revision psrev_1"
+				})).Return(nil)
+			}
+
+			service := NewPaymentSettingsService(mockRepo, mockRevisions, paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+			result, err := service.RollbackPaymentSetting(context.Background(), current.ID, "psrev_1", current.Version)
+
+			if tt.expectedCode != "" {
+				assert.True(t, pkgerrors.IsErrorCode(err, tt.expectedCode), "%v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "10000.00", result.SettingValue)
+		})
+	}
+}
+
+func TestPaymentSettingsService_ChecksValidity(t *testing.T) {
+	validFrom := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	validTo := validFrom.Add(24 * time.Hour)
+	existing := paymentsettings.PaymentSetting{ID: "pset_existing", SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "10000.00", Currency: "USD", Status: "active", ValidFrom: validFrom}
+
+	tests := []struct {
+		name         string
+		validTo      *time.Time
+		overlapping  []paymentsettings.PaymentSetting
+		expectedCode string
+	}{
+		{name: "schedules the setting", validTo: &validTo},
+		{name: "empty range", validTo: &validFrom, expectedCode: pkgerrors.ErrorCodeValidation},
+		{name: "overlapping setting", overlapping: []paymentsettings.PaymentSetting{existing}, expectedCode: pkgerrors.ErrorCodeConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting := paymentsettings.PaymentSetting{SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "5000.00", Currency: "USD", Status: "active", ValidFrom: validFrom, ValidTo: tt.validTo}
+
+			mockRepo := mocks.NewMockIPaymentSettingsRepository(t)
+			mockRepo.On("FetchOverlappingPaymentSettings", mock.Anything, setting).Return(tt.overlapping, nil).Maybe()
+			if tt.expectedCode == "" {
+				mockRepo.On("CreatePaymentSetting", mock.Anything, &setting).Return(nil)
+			}
+
+			service := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+			err := service.CreatePaymentSetting(context.Background(), &setting)
+
+			if tt.expectedCode != "" {
+				assert.True(t, pkgerrors.IsErrorCode(err, tt.expectedCode), "%v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, validFrom, setting.ValidFrom)
+		})
+	}
+
+	t.Run("conflict names the overlapping setting", func(t *testing.T) {
+		setting := paymentsettings.PaymentSetting{SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "5000.00", Currency: "USD", Status: "active"}
+		mockRepo := mocks.NewMockIPaymentSettingsRepository(t)
+		mockRepo.On("FetchOverlappingPaymentSettings", mock.Anything, mock.Anything).Return([]paymentsettings.PaymentSetting{existing}, nil)
+
+		err := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t)).CreatePaymentSetting(context.Background(), &setting)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "pset_existing")
+		assert.False(t, setting.ValidFrom.IsZero(), "validFrom defaults to now")
+	})
+}
+
+func TestPaymentSettingsService_UpdateKeepsUnsetValidity(t *testing.T) {
+	validFrom := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	storedValidTo := validFrom.Add(30 * 24 * time.Hour)
+	newValidTo := validFrom.Add(60 * 24 * time.Hour)
+	current := paymentsettings.PaymentSetting{ID: "pset_123", SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "10000.00", Currency: "USD", Status: "active", ValidFrom: validFrom, ValidTo: &storedValidTo, Version: 2}
+
+	tests := []struct {
+		name            string
+		validTo         *time.Time
+		expectedValidTo *time.Time
+	}{
+		{name: "unset validTo keeps the stored one", expectedValidTo: &storedValidTo},
+		{name: "validTo replaces the stored one", validTo: &newValidTo, expectedValidTo: &newValidTo},
+		{name: "zero validTo removes the end", validTo: &time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting := paymentsettings.PaymentSetting{ID: current.ID, SettingKey: current.SettingKey, SettingValue: "5000.00", Currency: "USD", Status: "active", ValidTo: tt.validTo}
+
+			mockRepo := newMockRepo(t)
+			mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, current.ID).Return(current, nil)
+			mockRepo.On("UpdatePaymentSetting", mock.Anything, mock.Anything).Return(nil)
+
+			service := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+			require.NoError(t, service.UpdatePaymentSetting(context.Background(), &setting))
+
+			assert.Equal(t, validFrom, setting.ValidFrom)
+			assert.Equal(t, tt.expectedValidTo, setting.ValidTo)
+		})
+	}
+}
+
+func TestPaymentSettingsService_UpsertPaymentSetting(t *testing.T) {
+	current := paymentsettings.PaymentSetting{ID: "pset_123", SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "10000.00", Currency: "USD", Status: "active", Version: 2}
+	scheduled := paymentsettings.PaymentSetting{ID: "pset_scheduled", SettingKey: current.SettingKey, SettingValue: "5000.00", Currency: "USD", Status: "active", ValidFrom: time.Now().Add(time.Hour), Version: 1}
+
+	tests := []struct {
+		name            string
+		version         int64
+		validTo         *time.Time
+		lookupErr       error
+		upcoming        []paymentsettings.PaymentSetting
+		expectedCreated bool
+		expectedCode    string
+	}{
+		{name: "updates the setting in force", version: 2},
+		{name: "creates a missing setting", lookupErr: pkgerrors.ErrDataNotFound, expectedCreated: true},
+		{name: "expected version of a missing setting", version: 2, lookupErr: pkgerrors.ErrDataNotFound, expectedCreated: true, expectedCode: pkgerrors.ErrorCodePreconditionFailed},
+		{name: "missing setting with a scheduled version", lookupErr: pkgerrors.ErrDataNotFound, upcoming: []paymentsettings.PaymentSetting{scheduled}, expectedCreated: true, expectedCode: pkgerrors.ErrorCodeValidation},
+		{name: "missing setting ending before the scheduled version", validTo: &scheduled.ValidFrom, lookupErr: pkgerrors.ErrDataNotFound, upcoming: []paymentsettings.PaymentSetting{scheduled}, expectedCreated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting := paymentsettings.PaymentSetting{SettingKey: current.SettingKey, SettingValue: "5000.00", Currency: "USD", Status: "active", ValidTo: tt.validTo, Version: tt.version}
+
+			mockRepo := newMockRepo(t)
+			mockRepo.On("GetPaymentSettingByKey", mock.Anything, current.SettingKey, "USD").Return(current, tt.lookupErr)
+			mockRepo.On("FetchUpcomingPaymentSettings", mock.Anything, mock.Anything).Return(tt.upcoming, nil).Maybe()
+			if tt.expectedCode == "" && tt.expectedCreated {
+				mockRepo.On("CreatePaymentSetting", mock.Anything, mock.MatchedBy(func(setting *paymentsettings.PaymentSetting) bool {
+					return setting.ID == "" && setting.SettingValue == "5000.00"
+				})).Return(nil)
+			}
+			if tt.expectedCode == "" && !tt.expectedCreated {
+				mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, current.ID).Return(current, nil)
+				mockRepo.On("UpdatePaymentSetting", mock.Anything, mock.MatchedBy(func(setting *paymentsettings.PaymentSetting) bool {
+					return setting.ID == current.ID && setting.SettingValue == "5000.00" && setting.Version == current.Version
+				})).Return(nil)
+			}
+
+			service := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+			created, err := service.UpsertPaymentSetting(context.Background(), &setting)
+
+			if tt.expectedCode != "" {
+				assert.True(t, pkgerrors.IsErrorCode(err, tt.expectedCode), "%v", err)
+				if tt.upcoming != nil {
+					assert.Contains(t, err.Error(), scheduled.ID)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCreated, created)
+		})
+	}
+}
+
+func TestPaymentSettingsService_ApplyPaymentSettings(t *testing.T) {
+	usdMax := paymentsettings.PaymentSetting{ID: "pset_usd_max", SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "10000.00", Currency: "USD", Status: "active", Version: 2}
+	usdTimeout := paymentsettings.PaymentSetting{ID: "pset_usd_timeout", SettingKey: paymentsettings.SettingKeyPaymentTimeoutSeconds, SettingValue: "300", Currency: "USD", Status: "active", Version: 1}
+	eurMin := paymentsettings.PaymentSetting{ID: "pset_eur_min", SettingKey: paymentsettings.SettingKeyMinTransactionAmount, SettingValue: "1.00", Currency: "EUR", Status: "active", Version: 4}
+
+	desired := []paymentsettings.PaymentSetting{
+		{SettingKey: paymentsettings.SettingKeyPaymentTimeoutSeconds, SettingValue: "600", Currency: "GBP", Status: "active"},
+		{SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "5000.00", Currency: "USD", Status: "active"},
+		{SettingKey: paymentsettings.SettingKeyPaymentTimeoutSeconds, SettingValue: "300", Currency: "USD", Status: "active"},
+	}
+
+	newRepo := func(t *testing.T) *mocks.MockIPaymentSettingsRepository {
+		mockRepo := newMockRepo(t)
+		mockRepo.On("FetchPaymentSettings", mock.Anything, paymentsettings.PaymentSettingFetchParams{Limit: 100}).
+			Return([]paymentsettings.PaymentSetting{usdMax, usdTimeout}, "next", nil).Once()
+		mockRepo.On("FetchPaymentSettings", mock.Anything, paymentsettings.PaymentSettingFetchParams{Limit: 100, Cursor: "next"}).
+			Return([]paymentsettings.PaymentSetting{eurMin}, "", nil).Once()
+		return mockRepo
+	}
+
+	t.Run("dry run only plans", func(t *testing.T) {
+		service := NewPaymentSettingsService(newRepo(t), anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+		plan, err := service.ApplyPaymentSettings(context.Background(), desired, paymentsettings.ApplyOptions{DryRun: true, Prune: true})
+
+		require.NoError(t, err)
+		require.Len(t, plan.Changes, 3)
+		assert.Equal(t, 1, plan.Unchanged)
+		// Ordered by key and currency
+		assert.Equal(t, paymentsettings.PlanActionUpdate, plan.Changes[0].Action)
+		assert.Equal(t, "10000.00", plan.Changes[0].Before.SettingValue)
+		assert.Equal(t, "5000.00", plan.Changes[0].After.SettingValue)
+		assert.Equal(t, paymentsettings.PlanActionDelete, plan.Changes[1].Action)
+		assert.Equal(t, eurMin.ID, plan.Changes[1].Before.ID)
+		assert.Equal(t, paymentsettings.PlanActionCreate, plan.Changes[2].Action)
+		assert.Equal(t, "GBP", plan.Changes[2].After.Currency)
+	})
+
+	t.Run("applies the plan", func(t *testing.T) {
+		mockRepo := newRepo(t)
+		mockRepo.On("CreatePaymentSetting", mock.Anything, mock.MatchedBy(func(setting *paymentsettings.PaymentSetting) bool {
+			return setting.Currency == "GBP" && setting.SettingValue == "600"
+		})).Return(nil)
+		mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, usdMax.ID).Return(usdMax, nil)
+		mockRepo.On("UpdatePaymentSetting", mock.Anything, mock.MatchedBy(func(setting *paymentsettings.PaymentSetting) bool {
+			return setting.ID == usdMax.ID && setting.SettingValue == "5000.00" && setting.Version == usdMax.Version
+		})).Return(nil)
+		mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, eurMin.ID).Return(eurMin, nil)
+		mockRepo.On("DeletePaymentSetting", mock.Anything, eurMin.ID, eurMin.Version).Return(nil)
+
+		service := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+		plan, err := service.ApplyPaymentSettings(context.Background(), desired, paymentsettings.ApplyOptions{Prune: true})
+
+		require.NoError(t, err)
+		assert.Len(t, plan.Changes, 3)
+	})
+
+	t.Run("keeps unlisted settings without prune", func(t *testing.T) {
+		mockRepo := newRepo(t)
+		mockRepo.On("CreatePaymentSetting", mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, usdMax.ID).Return(usdMax, nil)
+		mockRepo.On("UpdatePaymentSetting", mock.Anything, mock.Anything).Return(nil)
+
+		service := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+		plan, err := service.ApplyPaymentSettings(context.Background(), desired, paymentsettings.ApplyOptions{})
+
+		require.NoError(t, err)
+		assert.Equal(t, 0, plan.Count(paymentsettings.PlanActionDelete))
+	})
+
+	t.Run("fails on the first failing change", func(t *testing.T) {
+		mockRepo := newRepo(t)
+		mockRepo.On("GetPaymentSettingForUpdate", mock.Anything, usdMax.ID).Return(usdMax, nil)
+		mockRepo.On("UpdatePaymentSetting", mock.Anything, mock.Anything).Return(pkgerrors.ErrPreconditionFailed)
+
+		service := NewPaymentSettingsService(mockRepo, anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+		_, err := service.ApplyPaymentSettings(context.Background(), desired, paymentsettings.ApplyOptions{})
+
+		assert.True(t, pkgerrors.IsErrorCode(err, pkgerrors.ErrorCodePreconditionFailed), "%v", err)
+		assert.ErrorContains(t, err, "update max_transaction_amount of USD")
+	})
+
+	t.Run("reports every invalid entry", func(t *testing.T) {
+		invalid := []paymentsettings.PaymentSetting{
+			{SettingKey: paymentsettings.SettingKeyPaymentTimeoutSeconds, SettingValue: "300", Currency: "USD", Status: "active"},
+			{SettingKey: paymentsettings.SettingKeyPaymentTimeoutSeconds, SettingValue: "600", Currency: "USD", Status: "active"},
+			{SettingKey: paymentsettings.SettingKeyMaxTransactionAmount, SettingValue: "lots", Currency: "EUR", Status: "active"},
+			{SettingKey: paymentsettings.SettingKeyMinTransactionAmount, SettingValue: "1.00", Status: "active"},
+		}
+
+		service := NewPaymentSettingsService(newMockRepo(t), anyRevisionRepo(t), paymentsettings.DefaultSchema(), inlineTxManager{}, anyEventOutbox(t), anyEventPublisher(t))
+		_, err := service.ApplyPaymentSettings(context.Background(), invalid, paymentsettings.ApplyOptions{})
+
+		assert.True(t, pkgerrors.IsErrorCode(err, pkgerrors.ErrorCodeValidation), "%v", err)
+		assert.ErrorContains(t, err, "payment_timeout_seconds of USD is listed more than once")
+		assert.ErrorContains(t, err, "max_transaction_amount of EUR")
+		assert.ErrorContains(t, err, "key and currency are required")
+	})
+}
@@ -1,18 +1,33 @@
 package paymentsettings
 
-import "github.com/labstack/echo/v4"
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/outbox"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+// OutboxRelayJobName is the scheduler job publishing the events of the module outbox.
+const OutboxRelayJobName = "payment-settings-outbox-relay"
+
+// IdempotencyPurgeJobName is the scheduler job deleting the expired idempotency keys of the module.
+const IdempotencyPurgeJobName = "payment-settings-idempotency-purge"
 
 // Module encapsulates the Payment Settings module following hexagonal architecture.
 //
 // Structure:
 //   - Service: The hexagon core containing business logic
 //   - RegisterController: Inbound adapter for HTTP/REST API
+//   - OutboxRelay: Publishes the module's domain events, also run and replayed by the outbox-relay command
+//   - Jobs: Scheduled jobs exposed to the scheduler command
 //
 // This module is self-contained and can be composed with other modules in the monolith.
 // All dependencies are injected via the factory, maintaining loose coupling and testability.
 type Module struct {
 	Service            IPaymentSettingsService
 	RegisterController func(*echo.Group)
+	OutboxRelay        *outbox.Relay
+	Jobs               []scheduler.Job
 }
 
 // RegisterHTTPHandlers registers all HTTP endpoints for this module.
@@ -0,0 +1,40 @@
+package paymentsettings
+
+// Actions of a settings plan.
+const (
+	PlanActionCreate = "create"
+	PlanActionUpdate = "update"
+	PlanActionDelete = "delete"
+)
+
+// ApplyOptions tunes how a desired state of the settings is applied.
+type ApplyOptions struct {
+	// DryRun only computes the plan.
+	DryRun bool
+	// Prune deletes the settings in force that the desired state does not list.
+	Prune bool
+}
+
+// SettingChange is one change of a plan. Before is nil for a creation and After is nil for a deletion.
+type SettingChange struct {
+	Action string          `json:"action"`
+	Before *PaymentSetting `json:"before"`
+	After  *PaymentSetting `json:"after"`
+}
+
+// SettingsPlan is the set of changes that turns the settings in force into a desired state, ordered by key
+// and currency. Unchanged counts the listed settings that already have the desired value and status.
+type SettingsPlan struct {
+	Changes   []SettingChange `json:"changes"`
+	Unchanged int             `json:"unchanged"`
+}
+
+// Count returns the number of changes of the given action.
+func (p SettingsPlan) Count(action string) (count int) {
+	for _, change := range p.Changes {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
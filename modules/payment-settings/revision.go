@@ -0,0 +1,68 @@
+package paymentsettings
+
+import (
+	"context"
+	"time"
+)
+
+// Actions of a setting revision.
+const (
+	RevisionActionCreated    = "created"
+	RevisionActionUpdated    = "updated"
+	RevisionActionDeleted    = "deleted"
+	RevisionActionRolledBack = "rolled_back"
+)
+
+// ActorSystem is the actor of changes whose context carries no ChangeInfo, e.g. changes made by a command.
+const ActorSystem = "system"
+
+// SettingRevision is the immutable record of one change of a setting, written in the transaction of the
+// change. Version, SettingKey, Currency, Status and the validity range describe the setting after the
+// change, or before it for a deletion. OldValue is nil for a creation and NewValue is nil for a deletion.
+type SettingRevision struct {
+	ID         string     `json:"id"`
+	TenantID   string     `json:"tenantId"`
+	SettingID  string     `json:"settingId"`
+	Version    int64      `json:"version"`
+	Action     string     `json:"action"`
+	SettingKey string     `json:"settingKey"`
+	Currency   string     `json:"currency"`
+	Status     string     `json:"status"`
+	ValidFrom  time.Time  `json:"validFrom"`
+	ValidTo    *time.Time `json:"validTo"`
+	OldValue   *string    `json:"oldValue"`
+	NewValue   *string    `json:"newValue"`
+	Actor      string     `json:"actor"`
+	Reason     string     `json:"reason"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// RevisionFetchParams contains filtering and pagination parameters for querying the revisions of a setting.
+type RevisionFetchParams struct {
+	SettingID string `json:"settingId"`
+	Limit     int    `json:"limit"`
+	Cursor    string `json:"cursor"`
+}
+
+// ChangeInfo is who makes a change and why. It is carried by the context of the change and recorded
+// in its revision.
+type ChangeInfo struct {
+	Actor  string
+	Reason string
+}
+
+type changeInfoKey struct{}
+
+// WithChangeInfo returns a context whose setting changes are recorded with info.
+func WithChangeInfo(ctx context.Context, info ChangeInfo) context.Context {
+	return context.WithValue(ctx, changeInfoKey{}, info)
+}
+
+// ChangeInfoFromContext returns the ChangeInfo of ctx. The actor defaults to ActorSystem.
+func ChangeInfoFromContext(ctx context.Context) ChangeInfo {
+	info, _ := ctx.Value(changeInfoKey{}).(ChangeInfo)
+	if info.Actor == "" {
+		info.Actor = ActorSystem
+	}
+	return info
+}
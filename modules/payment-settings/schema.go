@@ -0,0 +1,258 @@
+package paymentsettings
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ValueType is the type a setting value is parsed as.
+type ValueType string
+
+const (
+	// ValueTypeDecimal is a plain decimal number such as "10000.00".
+	ValueTypeDecimal ValueType = "decimal"
+	// ValueTypeInteger is a base-10 integer such as "300".
+	ValueTypeInteger ValueType = "integer"
+	// ValueTypeDuration is a Go duration such as "90s" or "1h30m".
+	ValueTypeDuration ValueType = "duration"
+	// ValueTypeBoolean is "true" or "false".
+	ValueTypeBoolean ValueType = "boolean"
+	// ValueTypeEnum is one of the AllowedValues of the key.
+	ValueTypeEnum ValueType = "enum"
+	// ValueTypeJSON is any JSON document.
+	ValueTypeJSON ValueType = "json"
+)
+
+// decimalPattern only admits plain decimals; big.Rat alone would also accept "1/3" and "1e4".
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// SettingSchema declares a known setting key. Min and Max are inclusive bounds written in the
+// syntax of the value type and only apply to decimal, integer and duration values.
+type SettingSchema struct {
+	Key           string    `json:"key"`
+	Type          ValueType `json:"type"`
+	Description   string    `json:"description"`
+	Min           string    `json:"min,omitempty"`
+	Max           string    `json:"max,omitempty"`
+	AllowedValues []string  `json:"allowedValues,omitempty"`
+}
+
+// Validate reports why value is not a valid value of the key, or nil.
+func (s SettingSchema) Validate(value string) error {
+	switch s.Type {
+	case ValueTypeDecimal:
+		parsed, err := ParseDecimal(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a decimal: %w", s.Key, err)
+		}
+		return s.checkBounds(value, func(bound string) (int, error) {
+			limit, err := ParseDecimal(bound)
+			if err != nil {
+				return 0, err
+			}
+			return parsed.Cmp(limit), nil
+		})
+	case ValueTypeInteger:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be an integer, got %q", s.Key, value)
+		}
+		return s.checkBounds(value, func(bound string) (int, error) {
+			limit, err := strconv.ParseInt(bound, 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return compare(parsed, limit), nil
+		})
+	case ValueTypeDuration:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a duration such as 90s or 1h30m, got %q", s.Key, value)
+		}
+		return s.checkBounds(value, func(bound string) (int, error) {
+			limit, err := time.ParseDuration(bound)
+			if err != nil {
+				return 0, err
+			}
+			return compare(parsed, limit), nil
+		})
+	case ValueTypeBoolean:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%s must be true or false, got %q", s.Key, value)
+		}
+		return nil
+	case ValueTypeEnum:
+		if !slices.Contains(s.AllowedValues, value) {
+			return fmt.Errorf("%s must be one of %v, got %q", s.Key, s.AllowedValues, value)
+		}
+		return nil
+	case ValueTypeJSON:
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("%s must be a JSON document", s.Key)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s has unknown value type %q", s.Key, s.Type)
+}
+
+// checkBounds compares the value against Min and Max through cmp, which returns the sign of value-bound.
+func (s SettingSchema) checkBounds(value string, cmp func(bound string) (int, error)) error {
+	if s.Min != "" {
+		result, err := cmp(s.Min)
+		if err != nil {
+			return fmt.Errorf("%s has an invalid minimum %q: %w", s.Key, s.Min, err)
+		}
+		if result < 0 {
+			return fmt.Errorf("%s must be at least %s, got %s", s.Key, s.Min, value)
+		}
+	}
+	if s.Max != "" {
+		result, err := cmp(s.Max)
+		if err != nil {
+			return fmt.Errorf("%s has an invalid maximum %q: %w", s.Key, s.Max, err)
+		}
+		if result > 0 {
+			return fmt.Errorf("%s must be at most %s, got %s", s.Key, s.Max, value)
+		}
+	}
+	return nil
+}
+
+// Schema is the registry of known setting keys. Values of a declared key are validated against its
+// declaration; keys that are not declared are accepted unless RejectUnknownKeys is set.
+type Schema struct {
+	RejectUnknownKeys bool
+	settings          map[string]SettingSchema
+}
+
+// NewSchema creates a registry of the given declarations. It fails on duplicate keys and on bounds
+// or allowed values that do not fit the value type.
+func NewSchema(settings ...SettingSchema) (schema *Schema, err error) {
+	schema = &Schema{settings: make(map[string]SettingSchema, len(settings))}
+	for _, setting := range settings {
+		if setting.Key == "" {
+			return nil, fmt.Errorf("setting schema without a key")
+		}
+		if _, ok := schema.settings[setting.Key]; ok {
+			return nil, fmt.Errorf("setting %s is declared twice", setting.Key)
+		}
+		if err = setting.validateDeclaration(); err != nil {
+			return nil, err
+		}
+		schema.settings[setting.Key] = setting
+	}
+	return schema, nil
+}
+
+// validateDeclaration checks that the bounds and allowed values are values of the type themselves.
+func (s SettingSchema) validateDeclaration() error {
+	switch s.Type {
+	case ValueTypeDecimal, ValueTypeInteger, ValueTypeDuration:
+		unbounded := s
+		unbounded.Min, unbounded.Max = "", ""
+		for _, bound := range []string{s.Min, s.Max} {
+			if bound == "" {
+				continue
+			}
+			if err := unbounded.Validate(bound); err != nil {
+				return fmt.Errorf("invalid bound of setting %s: %w", s.Key, err)
+			}
+		}
+	case ValueTypeEnum:
+		if len(s.AllowedValues) == 0 {
+			return fmt.Errorf("enum setting %s declares no allowed values", s.Key)
+		}
+	case ValueTypeBoolean, ValueTypeJSON:
+	default:
+		return fmt.Errorf("setting %s has unknown value type %q", s.Key, s.Type)
+	}
+	if s.Type != ValueTypeEnum && len(s.AllowedValues) > 0 {
+		return fmt.Errorf("setting %s declares allowed values but is not an enum", s.Key)
+	}
+	return nil
+}
+
+// Lookup returns the declaration of key.
+func (s *Schema) Lookup(key string) (setting SettingSchema, ok bool) {
+	setting, ok = s.settings[key]
+	return setting, ok
+}
+
+// Settings returns every declaration, ordered by key.
+func (s *Schema) Settings() []SettingSchema {
+	settings := make([]SettingSchema, 0, len(s.settings))
+	for _, setting := range s.settings {
+		settings = append(settings, setting)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
+
+// Validate reports why value is not acceptable for key, or nil.
+func (s *Schema) Validate(key, value string) error {
+	setting, ok := s.settings[key]
+	if !ok {
+		if s.RejectUnknownKeys {
+			return fmt.Errorf("unknown setting key %q", key)
+		}
+		return nil
+	}
+	return setting.Validate(value)
+}
+
+// DefaultSchema declares the well-known setting keys.
+func DefaultSchema() *Schema {
+	schema, err := NewSchema(
+		SettingSchema{
+			Key:         SettingKeyMinTransactionAmount,
+			Type:        ValueTypeDecimal,
+			Description: "Smallest amount accepted for a payment in the currency of the setting.",
+			Min:         "0",
+		},
+		SettingSchema{
+			Key:         SettingKeyMaxTransactionAmount,
+			Type:        ValueTypeDecimal,
+			Description: "Largest amount accepted for a payment in the currency of the setting.",
+			Min:         "0",
+		},
+		SettingSchema{
+			Key:         SettingKeyPaymentTimeoutSeconds,
+			Type:        ValueTypeInteger,
+			Description: "Seconds a payment may stay pending before it times out.",
+			Min:         "1",
+			Max:         "86400",
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default settings schema: %v", err))
+	}
+	return schema
+}
+
+// ParseDecimal parses a plain decimal such as "-12.50".
+func ParseDecimal(value string) (*big.Rat, error) {
+	if !decimalPattern.MatchString(value) {
+		return nil, fmt.Errorf("invalid decimal %q", value)
+	}
+	parsed, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %q", value)
+	}
+	return parsed, nil
+}
+
+func compare[T int64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+package paymentsettings_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
+)
+
+func TestSettingSchema_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		schema      paymentsettings.SettingSchema
+		value       string
+		expectError bool
+	}{
+		{name: "decimal", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeDecimal}, value: "10000.00"},
+		{name: "decimal with a typo", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeDecimal}, value: "1O000", expectError: true},
+		{name: "decimal in exponent notation", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeDecimal}, value: "1e4", expectError: true},
+		{name: "decimal below min", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeDecimal, Min: "0"}, value: "-0.01", expectError: true},
+		{name: "decimal at max", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeDecimal, Max: "100.5"}, value: "100.50"},
+		{name: "integer", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeInteger, Min: "1", Max: "86400"}, value: "300"},
+		{name: "integer with a fraction", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeInteger}, value: "300.5", expectError: true},
+		{name: "integer above max", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeInteger, Max: "86400"}, value: "86401", expectError: true},
+		{name: "duration", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeDuration, Max: "1h"}, value: "90s"},
+		{name: "duration above max", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeDuration, Max: "1h"}, value: "2h", expectError: true},
+		{name: "duration without unit", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeDuration}, value: "90", expectError: true},
+		{name: "boolean", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeBoolean}, value: "false"},
+		{name: "boolean shorthand", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeBoolean}, value: "1", expectError: true},
+		{name: "enum", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeEnum, AllowedValues: []string{"auto", "manual"}}, value: "manual"},
+		{name: "enum not allowed", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeEnum, AllowedValues: []string{"auto", "manual"}}, value: "Manual", expectError: true},
+		{name: "json", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeJSON}, value: `{"providers":["simulator"]}`},
+		{name: "invalid json", schema: paymentsettings.SettingSchema{Type: paymentsettings.ValueTypeJSON}, value: `{"providers":`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schema.Validate(tt.value)
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewSchema_RejectsInvalidDeclarations(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings []paymentsettings.SettingSchema
+	}{
+		{name: "missing key", settings: []paymentsettings.SettingSchema{{Type: paymentsettings.ValueTypeBoolean}}},
+		{name: "duplicate key", settings: []paymentsettings.SettingSchema{
+			{Key: "retry", Type: paymentsettings.ValueTypeBoolean},
+			{Key: "retry", Type: paymentsettings.ValueTypeBoolean},
+		}},
+		{name: "unknown type", settings: []paymentsettings.SettingSchema{{Key: "retry", Type: "text"}}},
+		{name: "bound of another type", settings: []paymentsettings.SettingSchema{{Key: "timeout", Type: paymentsettings.ValueTypeInteger, Max: "1h"}}},
+		{name: "enum without allowed values", settings: []paymentsettings.SettingSchema{{Key: "mode", Type: paymentsettings.ValueTypeEnum}}},
+		{name: "allowed values of a non-enum", settings: []paymentsettings.SettingSchema{{Key: "retry", Type: paymentsettings.ValueTypeBoolean, AllowedValues: []string{"true"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := paymentsettings.NewSchema(tt.settings...)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema := paymentsettings.DefaultSchema()
+
+	assert.NoError(t, schema.Validate(paymentsettings.SettingKeyMaxTransactionAmount, "10000.00"))
+	assert.Error(t, schema.Validate(paymentsettings.SettingKeyMaxTransactionAmount, "1O000"))
+	assert.Error(t, schema.Validate(paymentsettings.SettingKeyPaymentTimeoutSeconds, "0"))
+	assert.NoError(t, schema.Validate("rate", "anything"), "unknown keys are accepted by default")
+
+	schema.RejectUnknownKeys = true
+	assert.Error(t, schema.Validate("rate", "anything"))
+}
+
+func TestSchema_Settings(t *testing.T) {
+	schema, err := paymentsettings.NewSchema(
+		paymentsettings.SettingSchema{Key: "retry", Type: paymentsettings.ValueTypeBoolean},
+		paymentsettings.SettingSchema{Key: "mode", Type: paymentsettings.ValueTypeEnum, AllowedValues: []string{"auto"}},
+	)
+	require.NoError(t, err)
+
+	settings := schema.Settings()
+	require.Len(t, settings, 2)
+	assert.Equal(t, "mode", settings[0].Key)
+	assert.Equal(t, "retry", settings[1].Key)
+
+	declared, ok := schema.Lookup("retry")
+	assert.True(t, ok)
+	assert.Equal(t, paymentsettings.ValueTypeBoolean, declared.Type)
+}
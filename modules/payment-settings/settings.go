@@ -9,27 +9,80 @@
 // allowing this module to evolve without impacting dependent modules.
 package paymentsettings
 
-import "time"
+import (
+	"context"
+	"math/big"
+	"time"
+)
+
+// Well-known setting keys. Other modules resolve these keys through their own ports,
+// so the names are part of this module's public contract.
+const (
+	SettingKeyMinTransactionAmount  = "min_transaction_amount"
+	SettingKeyMaxTransactionAmount  = "max_transaction_amount"
+	SettingKeyPaymentTimeoutSeconds = "payment_timeout_seconds"
+)
+
+// SettingStatusActive marks a setting that is in force.
+const SettingStatusActive = "active"
+
+// Domain event types recorded in the module outbox and, once committed, published on the in-process
+// event bus as PaymentSettingChanged. The payload of each event is the PaymentSetting after the change,
+// or before it for a deletion.
+const (
+	EventTypePaymentSettingCreated = "payment_setting.created"
+	EventTypePaymentSettingUpdated = "payment_setting.updated"
+	EventTypePaymentSettingDeleted = "payment_setting.deleted"
+)
 
 // PaymentSetting represents payment configuration in the domain model.
 // This is the core entity for managing payment-related settings and configurations.
+// Version is incremented on every update and used for optimistic concurrency control.
+//
+// A setting is in force from ValidFrom until ValidTo (exclusive), or indefinitely when ValidTo is nil.
+// A key and currency may have several settings whose validity ranges do not overlap, so a change
+// can be scheduled ahead of time.
+//
+// TenantID is the merchant the setting belongs to; the settings of tenant.Platform are the defaults every
+// tenant falls back to for the keys it does not set.
 type PaymentSetting struct {
-	ID           string    `json:"id"`
-	SettingKey   string    `json:"settingKey"`
-	SettingValue string    `json:"settingValue"`
-	Currency     string    `json:"currency"`
-	Status       string    `json:"status"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	ID           string     `json:"id"`
+	TenantID     string     `json:"tenantId"`
+	SettingKey   string     `json:"settingKey"`
+	SettingValue string     `json:"settingValue"`
+	Currency     string     `json:"currency"`
+	Status       string     `json:"status"`
+	ValidFrom    time.Time  `json:"validFrom"`
+	ValidTo      *time.Time `json:"validTo"`
+	Version      int64      `json:"version"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+// PaymentSettingChanged is published on the in-process event bus once a setting change committed,
+// so other modules can react to it without this module knowing them. Type is one of the
+// EventTypePaymentSetting* constants; EventID matches the outbox event.
+type PaymentSettingChanged struct {
+	EventID    string
+	Type       string
+	Setting    PaymentSetting
+	OccurredAt time.Time
+}
+
+// EventName implements eventbus.Event.
+func (e PaymentSettingChanged) EventName() string {
+	return e.Type
 }
 
 // PaymentSettingFetchParams contains filtering and pagination parameters for querying payment settings.
+// Only the settings in force at EffectiveAt are returned; the zero time means now.
 type PaymentSettingFetchParams struct {
-	Currency   string `json:"currency"`
-	SettingKey string `json:"settingKey"`
-	Limit      int    `json:"limit"`
-	Cursor     string `json:"cursor"`
-	Status     string `json:"status"`
+	Currency    string    `json:"currency"`
+	SettingKey  string    `json:"settingKey"`
+	Limit       int       `json:"limit"`
+	Cursor      string    `json:"cursor"`
+	Status      string    `json:"status"`
+	EffectiveAt time.Time `json:"effectiveAt"`
 }
 
 // IPaymentSettingsService defines the public API of the Payment Settings module.
@@ -37,9 +90,58 @@ type PaymentSettingFetchParams struct {
 // Instead, other modules define their own port interfaces (like IPaymentSettingsPort in the payment module)
 // specifying only the methods they need. This maintains loose coupling and follows interface segregation.
 type IPaymentSettingsService interface {
-	FetchPaymentSettings(params PaymentSettingFetchParams) (result []PaymentSetting, nextCursor string, err error)
-	CreatePaymentSetting(settings *PaymentSetting) error
-	GetPaymentSetting(id string) (PaymentSetting, error)
-	UpdatePaymentSetting(settings *PaymentSetting) error
-	DeletePaymentSetting(id string) error
+	// FetchPaymentSettings returns the settings in force at params.EffectiveAt, by default now.
+	FetchPaymentSettings(ctx context.Context, params PaymentSettingFetchParams) (result []PaymentSetting, nextCursor string, err error)
+	// FetchUpcomingPaymentSettings returns the settings scheduled to come into force after params.EffectiveAt,
+	// by default now, soonest first. The cursor is not supported.
+	FetchUpcomingPaymentSettings(ctx context.Context, params PaymentSettingFetchParams) (result []PaymentSetting, err error)
+	// GetEffectiveSettings resolves the active settings of params.Currency for the tenant in ctx, falling back
+	// to the settings of GlobalCurrency and then to the platform defaults for the keys the tenant does not set
+	// (see ResolveEffectiveSettings). It fails with a validation error without a currency.
+	GetEffectiveSettings(ctx context.Context, params EffectiveSettingsParams) (EffectiveSettings, error)
+	// CreatePaymentSetting starts the validity of the setting now unless ValidFrom is set. It fails with a
+	// conflict when the range overlaps another setting of the same key and currency.
+	CreatePaymentSetting(ctx context.Context, settings *PaymentSetting) error
+	GetPaymentSetting(ctx context.Context, id string) (PaymentSetting, error)
+	// GetPaymentSettingByKey returns the setting of key and currency in force now, without falling back to
+	// the global scope. It fails with a not found error when there is none.
+	GetPaymentSettingByKey(ctx context.Context, key, currency string) (PaymentSetting, error)
+	// UpsertPaymentSetting updates the setting of settings.SettingKey and settings.Currency in force now, or
+	// creates it when there is none, and reports whether it was created. settings.Version and the validity work
+	// as in UpdatePaymentSetting; a non-zero version fails with precondition failed when there is no setting.
+	// Creating a setting that would overlap a version scheduled for later fails with a validation error naming it.
+	UpsertPaymentSetting(ctx context.Context, settings *PaymentSetting) (created bool, err error)
+	// UpdatePaymentSetting applies the change only if settings.Version still matches the stored version (0 skips the check).
+	// A zero ValidFrom and a nil ValidTo keep the stored ones; a ValidTo set to the zero time removes the end
+	// of the validity.
+	UpdatePaymentSetting(ctx context.Context, settings *PaymentSetting) error
+	// ApplyPaymentSettings turns the settings in force into the desired ones, matched by key and currency:
+	// missing settings are created, settings with another value or status are updated and, with
+	// options.Prune, unlisted settings are deleted. The validity of updated settings is kept. Every change is
+	// applied in one transaction, so either all or none of them are; the plan is returned in both cases.
+	ApplyPaymentSettings(ctx context.Context, desired []PaymentSetting, options ApplyOptions) (SettingsPlan, error)
+	// DeletePaymentSetting deletes the setting only if expectedVersion still matches the stored version (0 skips the check).
+	DeletePaymentSetting(ctx context.Context, id string, expectedVersion int64) error
+
+	// FetchSettingRevisions returns the revisions of a setting, newest first. Revisions outlive the setting.
+	FetchSettingRevisions(ctx context.Context, params RevisionFetchParams) (result []SettingRevision, nextCursor string, err error)
+	// FetchPaymentSettingsAsOf returns the settings in force at the given instant as they were configured then,
+	// rebuilt from their revisions.
+	FetchPaymentSettingsAsOf(ctx context.Context, at time.Time, params PaymentSettingFetchParams) (result []PaymentSetting, nextCursor string, err error)
+	// RollbackPaymentSetting restores the key, value, currency, status and validity a setting had after one of its
+	// revisions, as a new revision. expectedVersion works as in UpdatePaymentSetting.
+	RollbackPaymentSetting(ctx context.Context, id string, revisionID string, expectedVersion int64) (PaymentSetting, error)
+
+	// Schema returns the registry the values are validated against on create and update.
+	Schema() *Schema
+	// The typed accessors return the effective value of key for currency, which may come from the global
+	// scope. They fail with a not found error when there is none, and when the schema declares the key with
+	// another value type.
+	GetDecimalSetting(ctx context.Context, key, currency string) (*big.Rat, error)
+	GetIntegerSetting(ctx context.Context, key, currency string) (int64, error)
+	GetDurationSetting(ctx context.Context, key, currency string) (time.Duration, error)
+	GetBooleanSetting(ctx context.Context, key, currency string) (bool, error)
+	GetEnumSetting(ctx context.Context, key, currency string) (string, error)
+	// DecodeJSONSetting unmarshals the effective JSON value of key for currency into target.
+	DecodeJSONSetting(ctx context.Context, key, currency string, target interface{}) error
 }
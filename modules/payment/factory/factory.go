@@ -11,15 +11,26 @@ package factory
 
 import (
 	"database/sql"
+	"fmt"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/adapter/controller"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/adapter/cron"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/adapter/gateway"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/adapter/repository"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/adapter/settingscache"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/adapter/subscriber"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/ports"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/service"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/eventbus"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/idempotency"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/middlewares"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/outbox"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/transaction"
 )
 
 // ModuleConfig contains all external dependencies required to initialize the Payment module.
@@ -29,11 +40,56 @@ import (
 //   - Instead of direct module import, we depend on a port interface
 //   - The calling code injects the actual implementation
 //   - This prevents circular dependencies and maintains module boundaries
+//
+// WebhookPort follows the same pattern for the Webhooks module, which sends payment events to merchants.
+//
+// EventBus is the in-process bus shared by all modules: the Payment module publishes PaymentChanged on it
+// and subscribes to PaymentSettingChanged, without the Payment Settings module knowing about it.
 type ModuleConfig struct {
 	DB                  *sql.DB
 	PaymentSettingsPort ports.IPaymentSettingsPort
+	WebhookPort         ports.IWebhookPort
+	EventBus            *eventbus.Bus
 	CronBatchSize       int
 	CronDryRun          bool
+	// CronMaxItems and CronMaxDuration bound a single cron run (0 = unlimited).
+	CronMaxItems    int
+	CronMaxDuration time.Duration
+	// CronConcurrency is the number of payments processed in parallel (default 1).
+	CronConcurrency int
+	// CronLeaseDuration is how long a claimed payment stays reserved for this run (default 5m).
+	CronLeaseDuration time.Duration
+	// CronRetryDelay is how long a payment that failed to process waits before it is claimed again (default 15m).
+	CronRetryDelay time.Duration
+	// CronSchedule is the default cron expression of the payment updater in the scheduler (default every 5 minutes).
+	CronSchedule string
+	// IdempotencyKeyTTL is how long Idempotency-Key responses are replayed (default 24h).
+	IdempotencyKeyTTL time.Duration
+	// IdempotencyPurgeSchedule is the default cron expression of the expired idempotency key purge (default hourly).
+	IdempotencyPurgeSchedule string
+	// TxManager runs units of work across modules (default: a manager on DB).
+	TxManager transaction.Manager
+	// Gateway is the payment service provider (default: the simulator configured below).
+	Gateway ports.IPaymentGateway
+	// GatewaySimulatorRules forces simulator outcomes, e.g. "decline:*:1000-;timeout:USD:13.13-13.13:capture".
+	GatewaySimulatorRules string
+	// GatewaySimulatorLatency delays every simulated gateway call.
+	GatewaySimulatorLatency time.Duration
+	// WebhookSecrets holds the signing secret of each provider sending webhooks, by provider name.
+	WebhookSecrets map[string]string
+	// WebhookTolerance bounds the age of a webhook signature (default 5m).
+	WebhookTolerance time.Duration
+	// EventPublisher receives the domain events of the module outbox (default: outbox.LogPublisher).
+	EventPublisher outbox.Publisher
+	// OutboxSchedule is the default cron expression of the outbox relay in the scheduler (default every 10 seconds).
+	OutboxSchedule string
+	// DisableSettingsCache reads PaymentSettingsPort on every payment instead of through the settings cache.
+	DisableSettingsCache bool
+	// SettingsCacheTTL bounds how long cached settings are used (default 1m). Changes published on EventBus
+	// invalidate the cache at once; changes made by other processes are seen after at most the TTL.
+	SettingsCacheTTL time.Duration
+	// SettingsCacheMaxEntries bounds the number of currencies whose settings are cached (default 1000).
+	SettingsCacheMaxEntries int
 }
 
 // NewModule assembles and wires the complete Payment module using dependency injection.
@@ -46,12 +102,56 @@ type ModuleConfig struct {
 //
 // The result is a fully independent module that can be deployed as part of a monolith
 // or potentially extracted into a microservice with minimal changes.
-func NewModule(config ModuleConfig) *payment.Module {
+//
+// An error is returned when the configuration is invalid, e.g. malformed gateway simulator rules.
+func NewModule(config ModuleConfig) (*payment.Module, error) {
 	// Wire up outbound adapters (repositories)
 	paymentRepo := repository.NewPaymentRepository(config.DB)
 
+	// Repositories of every module join the transaction this manager puts in the context
+	if config.TxManager == nil {
+		config.TxManager = transaction.NewManager(config.DB, transaction.Config{})
+	}
+
+	// The simulator stands in for a real payment service provider
+	if config.Gateway == nil {
+		rules, err := gateway.ParseSimulatorRules(config.GatewaySimulatorRules)
+		if err != nil {
+			return nil, fmt.Errorf("payment gateway simulator rules: %w", err)
+		}
+		config.Gateway = gateway.NewSimulator(gateway.SimulatorConfig{
+			Rules:   rules,
+			Latency: config.GatewaySimulatorLatency,
+		})
+	}
+
 	// Wire up the hexagon core (service)
-	paymentService := service.NewPaymentService(paymentRepo, config.PaymentSettingsPort)
+	providerEventRepo := repository.NewProviderEventRepository(config.DB)
+	// Domain events are recorded in the payment module schema
+	outboxStore := outbox.NewPostgresStore(config.DB, "payment_module.outbox")
+	// Without a shared bus the module's events only reach its own subscribers
+	if config.EventBus == nil {
+		config.EventBus = eventbus.New(eventbus.Config{})
+	}
+
+	// Settings change rarely; the cache in front of the port is invalidated by their change events
+	settingsPort := config.PaymentSettingsPort
+	var settingsCache *settingscache.Cache
+	if !config.DisableSettingsCache {
+		settingsCache = settingscache.NewCache(config.PaymentSettingsPort, settingscache.Config{
+			TTL:        config.SettingsCacheTTL,
+			MaxEntries: config.SettingsCacheMaxEntries,
+		})
+		settingsPort = settingsCache
+	}
+	paymentService := service.NewPaymentService(paymentRepo, settingsPort, config.TxManager, config.Gateway, providerEventRepo, config.WebhookPort, outboxStore, config.EventBus)
+
+	// Wire up event subscribers (inbound)
+	var cacheInvalidator subscriber.SettingsCache
+	if settingsCache != nil {
+		cacheInvalidator = settingsCache
+	}
+	subscriber.NewSettingsSubscriber(cacheInvalidator).Register(config.EventBus)
 
 	// Set default cron batch size if not provided
 	if config.CronBatchSize == 0 {
@@ -60,16 +160,50 @@ func NewModule(config ModuleConfig) *payment.Module {
 
 	// Wire up cron adapters
 	paymentUpdater := cron.NewPaymentUpdater(paymentService, cron.PaymentUpdaterConfig{
-		BatchSize: config.CronBatchSize,
-		DryRun:    config.CronDryRun,
+		BatchSize:     config.CronBatchSize,
+		DryRun:        config.CronDryRun,
+		MaxItems:      config.CronMaxItems,
+		MaxDuration:   config.CronMaxDuration,
+		Concurrency:   config.CronConcurrency,
+		LeaseDuration: config.CronLeaseDuration,
+		RetryDelay:    config.CronRetryDelay,
 	})
 
+	if config.CronSchedule == "" {
+		config.CronSchedule = "*/5 * * * *"
+	}
+
+	if config.EventPublisher == nil {
+		config.EventPublisher = outbox.LogPublisher{}
+	}
+	outboxRelay := outbox.NewRelay(outboxStore, config.EventPublisher, outbox.RelayConfig{Name: payment.OutboxRelayJobName})
+	if config.OutboxSchedule == "" {
+		config.OutboxSchedule = "@every 10s"
+	}
+
+	// Idempotency keys for POST /payments are persisted in the payment module schema
+	idempotencyStore := idempotency.NewPostgresStore(config.DB, "payment_module.idempotency_keys")
+	idempotencyPurger := idempotency.NewPurger(idempotencyStore, payment.IdempotencyPurgeJobName)
+	if config.IdempotencyPurgeSchedule == "" {
+		config.IdempotencyPurgeSchedule = "@hourly"
+	}
+
 	// Create the module with all adapters
 	return &payment.Module{
 		Service: paymentService,
 		RegisterController: func(e *echo.Group) {
-			controller.NewPaymentController(e, paymentService)
+			controller.NewPaymentController(e, paymentService, middlewares.Idempotency(idempotencyStore, config.IdempotencyKeyTTL))
+			controller.NewWebhookController(e, paymentService, config.WebhookSecrets, config.WebhookTolerance)
+			if settingsCache != nil {
+				controller.NewSettingsCacheController(e, settingsCache)
+			}
 		},
 		PaymentUpdater: paymentUpdater,
-	}
+		OutboxRelay:    outboxRelay,
+		Jobs: []scheduler.Job{
+			{Name: payment.PaymentUpdaterJobName, Schedule: config.CronSchedule, Runner: paymentUpdater},
+			{Name: payment.OutboxRelayJobName, Schedule: config.OutboxSchedule, Runner: outboxRelay},
+			{Name: payment.IdempotencyPurgeJobName, Schedule: config.IdempotencyPurgeSchedule, Runner: idempotencyPurger},
+		},
+	}, nil
 }
@@ -0,0 +1,19 @@
+package factory_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/factory"
+)
+
+func TestNewModule_InvalidSimulatorRules(t *testing.T) {
+	module, err := factory.NewModule(factory.ModuleConfig{
+		GatewaySimulatorRules: "decline:*:1000",
+	})
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "simulator rules")
+	require.Nil(t, module)
+}
@@ -1,33 +1,41 @@
 package dto
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/money"
 )
 
+// CreatePaymentRequest accepts the amount as a JSON string ("299.99") or number.
+// It is parsed exactly against the currency exponent, never through float64.
 type CreatePaymentRequest struct {
-	Amount    float64   `json:"amount"`
-	Currency  string    `json:"currency"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	Amount    json.Number `json:"amount"`
+	Currency  string      `json:"currency"`
+	Status    string      `json:"status"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
 }
 
-func (r *CreatePaymentRequest) ToPayment() payment.Payment {
+func (r *CreatePaymentRequest) ToPayment() (payment.Payment, error) {
+	amount, err := parseAmount(r.Amount, r.Currency)
+	if err != nil {
+		return payment.Payment{}, err
+	}
 	return payment.Payment{
-		Amount:    r.Amount,
-		Currency:  r.Currency,
+		Amount:    amount,
 		Status:    r.Status,
 		CreatedAt: r.CreatedAt,
 		UpdatedAt: r.UpdatedAt,
-	}
+	}, nil
 }
 
 func FromPaymentToCreateRequest(p payment.Payment) CreatePaymentRequest {
 	return CreatePaymentRequest{
-		Amount:    p.Amount,
-		Currency:  p.Currency,
+		Amount:    json.Number(p.Amount.String()),
+		Currency:  p.Currency(),
 		Status:    p.Status,
 		CreatedAt: p.CreatedAt,
 		UpdatedAt: p.UpdatedAt,
@@ -35,20 +43,49 @@ func FromPaymentToCreateRequest(p payment.Payment) CreatePaymentRequest {
 }
 
 type UpdatePaymentRequest struct {
-	Amount    float64   `json:"amount"`
-	Currency  string    `json:"currency"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	Amount    json.Number `json:"amount"`
+	Currency  string      `json:"currency"`
+	Status    string      `json:"status"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
 }
 
-func (r *UpdatePaymentRequest) ToPayment(id string) payment.Payment {
+func (r *UpdatePaymentRequest) ToPayment(id string) (payment.Payment, error) {
+	amount, err := parseAmount(r.Amount, r.Currency)
+	if err != nil {
+		return payment.Payment{}, err
+	}
 	return payment.Payment{
 		ID:        id,
-		Amount:    r.Amount,
-		Currency:  r.Currency,
+		Amount:    amount,
 		Status:    r.Status,
 		CreatedAt: r.CreatedAt,
 		UpdatedAt: r.UpdatedAt,
+	}, nil
+}
+
+func parseAmount(amount json.Number, currency string) (money.Money, error) {
+	m, err := money.Parse(amount.String(), currency)
+	if err != nil {
+		return money.Money{}, errors.NewValidationError(err)
+	}
+	return m, nil
+}
+
+// ProviderEventRequest is the payload of a PSP webhook delivery.
+type ProviderEventRequest struct {
+	EventID    string    `json:"eventId"`
+	Reference  string    `json:"reference"`
+	Status     string    `json:"status"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+func (r *ProviderEventRequest) ToProviderEvent(provider string) payment.ProviderEvent {
+	return payment.ProviderEvent{
+		Provider:   provider,
+		EventID:    r.EventID,
+		Reference:  r.Reference,
+		Status:     r.Status,
+		OccurredAt: r.OccurredAt,
 	}
 }
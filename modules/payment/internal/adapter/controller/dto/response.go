@@ -6,23 +6,32 @@ import (
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
 )
 
+// PaymentResponse serialises the amount as a decimal string, e.g. "299.99".
 type PaymentResponse struct {
-	ID        string    `json:"id"`
-	Amount    float64   `json:"amount"`
-	Currency  string    `json:"currency"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID                string    `json:"id"`
+	TenantID          string    `json:"tenantId"`
+	Amount            string    `json:"amount"`
+	Currency          string    `json:"currency"`
+	Status            string    `json:"status"`
+	Version           int64     `json:"version"`
+	Provider          string    `json:"provider"`
+	ProviderReference string    `json:"providerReference"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
 }
 
 func FromPaymentToResponse(p payment.Payment) PaymentResponse {
 	return PaymentResponse{
-		ID:        p.ID,
-		Amount:    p.Amount,
-		Currency:  p.Currency,
-		Status:    p.Status,
-		CreatedAt: p.CreatedAt,
-		UpdatedAt: p.UpdatedAt,
+		ID:                p.ID,
+		TenantID:          p.TenantID,
+		Amount:            p.Amount.String(),
+		Currency:          p.Currency(),
+		Status:            p.Status,
+		Version:           p.Version,
+		Provider:          p.Provider,
+		ProviderReference: p.ProviderReference,
+		CreatedAt:         p.CreatedAt,
+		UpdatedAt:         p.UpdatedAt,
 	}
 }
 
@@ -35,3 +44,10 @@ func FromPaymentListToResponse(p []payment.Payment) PaymentListResponse {
 	}
 	return payments
 }
+
+// WebhookAckResponse acknowledges a PSP webhook delivery. Duplicate is true for a redelivery
+// of an event that was already applied.
+type WebhookAckResponse struct {
+	EventID   string `json:"eventId"`
+	Duplicate bool   `json:"duplicate"`
+}
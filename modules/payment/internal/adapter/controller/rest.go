@@ -8,15 +8,18 @@ import (
 
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/adapter/controller/dto"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/etag"
 )
 
 type paymentController struct {
 	paymentService payment.IPaymentService
 }
 
-func NewPaymentController(e *echo.Group, paymentService payment.IPaymentService) (controller *paymentController) {
+// NewPaymentController registers the payment routes. createMiddlewares are applied to
+// POST /payments only, e.g. the Idempotency-Key middleware.
+func NewPaymentController(e *echo.Group, paymentService payment.IPaymentService, createMiddlewares ...echo.MiddlewareFunc) (controller *paymentController) {
 	controller = &paymentController{paymentService: paymentService}
-	e.POST("/payments", controller.CreatePayment)
+	e.POST("/payments", controller.CreatePayment, createMiddlewares...)
 	e.GET("/payments/:id", controller.GetPayment)
 	e.GET("/payments", controller.FetchPayments)
 	e.PUT("/payments/:id", controller.UpdatePayment)
@@ -29,20 +32,25 @@ func (c *paymentController) CreatePayment(ctx echo.Context) (err error) {
 	if err = ctx.Bind(&paymentRequest); err != nil {
 		return err
 	}
-	paymentData := paymentRequest.ToPayment()
-	err = c.paymentService.CreatePayment(&paymentData)
+	paymentData, err := paymentRequest.ToPayment()
 	if err != nil {
 		return err
 	}
+	err = c.paymentService.CreatePayment(ctx.Request().Context(), &paymentData)
+	if err != nil {
+		return err
+	}
+	ctx.Response().Header().Set(etag.HeaderETag, etag.Format(paymentData.Version))
 	return ctx.JSON(http.StatusCreated, dto.FromPaymentToResponse(paymentData))
 }
 
 func (c *paymentController) GetPayment(ctx echo.Context) (err error) {
 	id := ctx.Param("id")
-	payment, err := c.paymentService.GetPayment(id)
+	payment, err := c.paymentService.GetPayment(ctx.Request().Context(), id)
 	if err != nil {
 		return err
 	}
+	ctx.Response().Header().Set(etag.HeaderETag, etag.Format(payment.Version))
 	return ctx.JSON(http.StatusOK, dto.FromPaymentToResponse(payment))
 }
 
@@ -55,7 +63,7 @@ func (c *paymentController) FetchPayments(ctx echo.Context) (err error) {
 		}
 	}
 
-	result, nextCursor, err := c.paymentService.FetchPayments(payment.FetchPaymentsParams{
+	result, nextCursor, err := c.paymentService.FetchPayments(ctx.Request().Context(), payment.FetchPaymentsParams{
 		Cursor:   cursor,
 		Limit:    limit,
 		Currency: ctx.QueryParam("currency"),
@@ -68,23 +76,38 @@ func (c *paymentController) FetchPayments(ctx echo.Context) (err error) {
 	return ctx.JSON(http.StatusOK, dto.FromPaymentListToResponse(result))
 }
 
+// UpdatePayment requires an If-Match header carrying the ETag of the version being updated.
 func (c *paymentController) UpdatePayment(ctx echo.Context) (err error) {
 	id := ctx.Param("id")
+	version, err := etag.ParseIfMatch(ctx.Request().Header.Get(etag.HeaderIfMatch))
+	if err != nil {
+		return err
+	}
 	var paymentRequest *dto.UpdatePaymentRequest
 	if err = ctx.Bind(&paymentRequest); err != nil {
 		return err
 	}
-	paymentData := paymentRequest.ToPayment(id)
-	err = c.paymentService.UpdatePayment(&paymentData)
+	paymentData, err := paymentRequest.ToPayment(id)
 	if err != nil {
 		return err
 	}
+	paymentData.Version = version
+	err = c.paymentService.UpdatePayment(ctx.Request().Context(), &paymentData)
+	if err != nil {
+		return err
+	}
+	ctx.Response().Header().Set(etag.HeaderETag, etag.Format(paymentData.Version))
 	return ctx.JSON(http.StatusOK, dto.FromPaymentToResponse(paymentData))
 }
 
+// DeletePayment requires an If-Match header carrying the ETag of the version being deleted.
 func (c *paymentController) DeletePayment(ctx echo.Context) (err error) {
 	id := ctx.Param("id")
-	err = c.paymentService.DeletePayment(id)
+	version, err := etag.ParseIfMatch(ctx.Request().Header.Get(etag.HeaderIfMatch))
+	if err != nil {
+		return err
+	}
+	err = c.paymentService.DeletePayment(ctx.Request().Context(), id, version)
 	if err != nil {
 		return err
 	}
@@ -3,8 +3,11 @@
 package controller_test
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"testing"
 
 	"github.com/labstack/echo/v4"
@@ -12,9 +15,14 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
 	paymentsettingsfactory "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings/factory"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/factory"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/adapter/controller/dto"
+	webhooksfactory "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/factory"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/etag"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/eventbus"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/testutils"
 )
 
@@ -22,6 +30,7 @@ type PaymentControllerE2ETestSuite struct {
 	suite.Suite
 	pgContainer *testutils.PostgresContainer
 	echo        *echo.Echo
+	eventBus    *eventbus.Bus
 }
 
 func (s *PaymentControllerE2ETestSuite) SetupSuite() {
@@ -35,17 +44,32 @@ func (s *PaymentControllerE2ETestSuite) SetupSuite() {
 	s.echo = testutils.NewEchoForTest()
 	apiGroup := s.echo.Group("/api/v1")
 
+	// Setting changes reach the payment settings cache over the shared bus, as in cmd/rest.go
+	s.eventBus = eventbus.New(eventbus.Config{})
+
 	paymentSettingsModule := paymentsettingsfactory.NewModule(paymentsettingsfactory.ModuleConfig{
+		DB:       s.pgContainer.DB,
+		EventBus: s.eventBus,
+	})
+
+	webhooksModule := webhooksfactory.NewModule(webhooksfactory.ModuleConfig{
 		DB: s.pgContainer.DB,
 	})
 
-	paymentModule := factory.NewModule(factory.ModuleConfig{
+	paymentModule, err := factory.NewModule(factory.ModuleConfig{
 		DB:                  s.pgContainer.DB,
 		PaymentSettingsPort: paymentSettingsModule.Service,
+		WebhookPort:         webhooksModule.Service,
+		EventBus:            s.eventBus,
+		// Authorizations of exactly 5000.00 USD are declined by the simulated gateway
+		GatewaySimulatorRules: "decline:USD:5000.00-5000.00:authorize",
+		WebhookSecrets:        map[string]string{"simulator": webhookSecret},
 	})
+	s.Require().NoError(err)
 
 	paymentSettingsModule.RegisterHTTPHandlers(apiGroup)
 	paymentModule.RegisterHTTPHandlers(apiGroup)
+	webhooksModule.RegisterHTTPHandlers(apiGroup)
 }
 
 func (s *PaymentControllerE2ETestSuite) TearDownSuite() {
@@ -53,12 +77,26 @@ func (s *PaymentControllerE2ETestSuite) TearDownSuite() {
 }
 
 func (s *PaymentControllerE2ETestSuite) SetupTest() {
-	s.pgContainer.TruncateTables(s.T(), "payments", "payment_settings")
+	s.pgContainer.TruncateTables(s.T(), "payments", "payment_settings", "payment_module.idempotency_keys", "payment_module.provider_events", "payment_module.outbox", "webhooks_module.endpoints")
+	s.seedTransactionLimits()
+	// The seed bypasses the payment-settings module, so the settings cache is not told about it
+	require.NoError(s.T(), s.eventBus.Publish(context.Background(), paymentsettings.PaymentSettingChanged{Type: paymentsettings.EventTypePaymentSettingUpdated}))
+}
+
+// seedTransactionLimits configures the min/max amounts enforced on payment creation.
+func (s *PaymentControllerE2ETestSuite) seedTransactionLimits() {
+	_, err := s.pgContainer.DB.Exec(`
+		INSERT INTO payment_settings_module.payment_settings (id, setting_key, setting_value, currency, status) VALUES
+		('pset-e2e-usd-min', 'min_transaction_amount', '10.00', 'USD', 'active'),
+		('pset-e2e-usd-max', 'max_transaction_amount', '10000.00', 'USD', 'active'),
+		('pset-e2e-eur-min', 'min_transaction_amount', '10.00', 'EUR', 'active'),
+		('pset-e2e-eur-max', 'max_transaction_amount', '8500.00', 'EUR', 'active')`)
+	require.NoError(s.T(), err)
 }
 
 func (s *PaymentControllerE2ETestSuite) TestE2E_CreatePayment_Success() {
 	requestBody := dto.CreatePaymentRequest{
-		Amount:   100.50,
+		Amount:   "100.50",
 		Currency: "USD",
 		Status:   "pending",
 	}
@@ -71,13 +109,129 @@ func (s *PaymentControllerE2ETestSuite) TestE2E_CreatePayment_Success() {
 	testutils.ParseJSONResponse(s.T(), rec, &response)
 
 	assert.NotEmpty(s.T(), response.ID)
-	assert.Equal(s.T(), requestBody.Amount, response.Amount)
+	assert.Equal(s.T(), requestBody.Amount.String(), response.Amount)
 	assert.Equal(s.T(), requestBody.Currency, response.Currency)
 	assert.Equal(s.T(), requestBody.Status, response.Status)
+	assert.Equal(s.T(), "simulator", response.Provider)
+	assert.Equal(s.T(), "sim_"+response.ID, response.ProviderReference)
 	assert.NotZero(s.T(), response.CreatedAt)
 	assert.NotZero(s.T(), response.UpdatedAt)
 }
 
+func (s *PaymentControllerE2ETestSuite) TestE2E_CreatePayment_DeclinedByGateway() {
+	requestBody := dto.CreatePaymentRequest{
+		Amount:   "5000.00",
+		Currency: "USD",
+		Status:   "pending",
+	}
+
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payments", requestBody)
+
+	testutils.AssertStatusCode(s.T(), rec, http.StatusCreated)
+
+	var response dto.PaymentResponse
+	testutils.ParseJSONResponse(s.T(), rec, &response)
+
+	assert.Equal(s.T(), "failed", response.Status)
+	assert.Equal(s.T(), "sim_"+response.ID, response.ProviderReference)
+}
+
+func (s *PaymentControllerE2ETestSuite) TestE2E_CreatePayment_NotPending() {
+	requestBody := dto.CreatePaymentRequest{
+		Amount:   "100.00",
+		Currency: "USD",
+		Status:   "completed",
+	}
+
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payments", requestBody)
+
+	testutils.AssertStatusCode(s.T(), rec, http.StatusBadRequest)
+}
+
+func (s *PaymentControllerE2ETestSuite) TestE2E_CreatePayment_AmountOutOfRange() {
+	tests := []struct {
+		name     string
+		amount   json.Number
+		currency string
+	}{
+		{name: "above maximum", amount: "50000.00", currency: "USD"},
+		{name: "below minimum", amount: "5.00", currency: "EUR"},
+		{name: "currency without limits", amount: "100.00", currency: "JPY"},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			requestBody := dto.CreatePaymentRequest{
+				Amount:   tt.amount,
+				Currency: tt.currency,
+				Status:   "pending",
+			}
+
+			rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payments", requestBody)
+
+			testutils.AssertStatusCode(s.T(), rec, http.StatusBadRequest)
+		})
+	}
+}
+
+func (s *PaymentControllerE2ETestSuite) TestE2E_CreatePayment_ExcessPrecision() {
+	requestBody := dto.CreatePaymentRequest{
+		Amount:   "299.999",
+		Currency: "USD",
+		Status:   "pending",
+	}
+
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payments", requestBody)
+
+	testutils.AssertStatusCode(s.T(), rec, http.StatusBadRequest)
+}
+
+func (s *PaymentControllerE2ETestSuite) TestE2E_CreatePayment_AmountSerialisedAsString() {
+	requestBody := map[string]interface{}{
+		"amount":   299.99,
+		"currency": "USD",
+		"status":   "pending",
+	}
+
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payments", requestBody)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusCreated)
+
+	var response map[string]interface{}
+	testutils.ParseJSONResponse(s.T(), rec, &response)
+
+	assert.Equal(s.T(), "299.99", response["amount"])
+}
+
+func (s *PaymentControllerE2ETestSuite) TestE2E_CreatePayment_IdempotencyKey() {
+	requestBody := dto.CreatePaymentRequest{
+		Amount:   "100.50",
+		Currency: "USD",
+		Status:   "pending",
+	}
+	headers := map[string]string{"Idempotency-Key": "e2e-create-payment"}
+
+	firstRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPost, "/api/v1/payments", requestBody, headers)
+	testutils.AssertStatusCode(s.T(), firstRec, http.StatusCreated)
+
+	retryRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPost, "/api/v1/payments", requestBody, headers)
+	testutils.AssertStatusCode(s.T(), retryRec, http.StatusCreated)
+	assert.Equal(s.T(), "true", retryRec.Header().Get("Idempotent-Replayed"))
+
+	var first, retry dto.PaymentResponse
+	testutils.ParseJSONResponse(s.T(), firstRec, &first)
+	testutils.ParseJSONResponse(s.T(), retryRec, &retry)
+	assert.Equal(s.T(), first.ID, retry.ID)
+
+	listRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, "/api/v1/payments", nil)
+	var payments []dto.PaymentResponse
+	testutils.ParseJSONResponse(s.T(), listRec, &payments)
+	assert.Len(s.T(), payments, 1)
+
+	requestBody.Amount = "200.00"
+	mismatchRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPost, "/api/v1/payments", requestBody, headers)
+	testutils.AssertStatusCode(s.T(), mismatchRec, http.StatusUnprocessableEntity)
+}
+
 func (s *PaymentControllerE2ETestSuite) TestE2E_CreatePayment_InvalidJSON() {
 	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payments", "invalid json")
 
@@ -86,9 +240,9 @@ func (s *PaymentControllerE2ETestSuite) TestE2E_CreatePayment_InvalidJSON() {
 
 func (s *PaymentControllerE2ETestSuite) TestE2E_GetPayment_Success() {
 	createReq := dto.CreatePaymentRequest{
-		Amount:   150.00,
+		Amount:   "150.00",
 		Currency: "EUR",
-		Status:   "completed",
+		Status:   "pending",
 	}
 	createRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payments", createReq)
 	require.Equal(s.T(), http.StatusCreated, createRec.Code)
@@ -107,6 +261,8 @@ func (s *PaymentControllerE2ETestSuite) TestE2E_GetPayment_Success() {
 	assert.Equal(s.T(), createResponse.Amount, getResponse.Amount)
 	assert.Equal(s.T(), createResponse.Currency, getResponse.Currency)
 	assert.Equal(s.T(), createResponse.Status, getResponse.Status)
+	assert.Equal(s.T(), createResponse.ProviderReference, getResponse.ProviderReference)
+	assert.Equal(s.T(), etag.Format(createResponse.Version), getRec.Header().Get(etag.HeaderETag))
 }
 
 func (s *PaymentControllerE2ETestSuite) TestE2E_GetPayment_NotFound() {
@@ -117,9 +273,10 @@ func (s *PaymentControllerE2ETestSuite) TestE2E_GetPayment_NotFound() {
 
 func (s *PaymentControllerE2ETestSuite) TestE2E_FetchPayments_Success() {
 	payments := []dto.CreatePaymentRequest{
-		{Amount: 100.00, Currency: "USD", Status: "pending"},
-		{Amount: 200.00, Currency: "USD", Status: "completed"},
-		{Amount: 300.00, Currency: "EUR", Status: "pending"},
+		{Amount: "100.00", Currency: "USD", Status: "pending"},
+		// Declined by the simulated gateway, so the payment is failed
+		{Amount: "5000.00", Currency: "USD", Status: "pending"},
+		{Amount: "300.00", Currency: "EUR", Status: "pending"},
 	}
 
 	for _, p := range payments {
@@ -139,9 +296,10 @@ func (s *PaymentControllerE2ETestSuite) TestE2E_FetchPayments_Success() {
 
 func (s *PaymentControllerE2ETestSuite) TestE2E_FetchPayments_WithFilters() {
 	payments := []dto.CreatePaymentRequest{
-		{Amount: 100.00, Currency: "USD", Status: "pending"},
-		{Amount: 200.00, Currency: "USD", Status: "completed"},
-		{Amount: 300.00, Currency: "EUR", Status: "pending"},
+		{Amount: "100.00", Currency: "USD", Status: "pending"},
+		// Declined by the simulated gateway, so the payment is failed
+		{Amount: "5000.00", Currency: "USD", Status: "pending"},
+		{Amount: "300.00", Currency: "EUR", Status: "pending"},
 	}
 
 	for _, p := range payments {
@@ -166,7 +324,7 @@ func (s *PaymentControllerE2ETestSuite) TestE2E_FetchPayments_WithFilters() {
 		},
 		{
 			name:          "filter by currency and status",
-			queryParams:   "?currency=USD&status=completed&limit=10",
+			queryParams:   "?currency=USD&status=failed&limit=10",
 			expectedCount: 1,
 		},
 	}
@@ -188,7 +346,7 @@ func (s *PaymentControllerE2ETestSuite) TestE2E_FetchPayments_WithFilters() {
 func (s *PaymentControllerE2ETestSuite) TestE2E_FetchPayments_Pagination() {
 	for i := 0; i < 5; i++ {
 		p := dto.CreatePaymentRequest{
-			Amount:   float64(100 * (i + 1)),
+			Amount:   json.Number(strconv.Itoa(100 * (i + 1))),
 			Currency: "USD",
 			Status:   "pending",
 		}
@@ -218,7 +376,7 @@ func (s *PaymentControllerE2ETestSuite) TestE2E_FetchPayments_Pagination() {
 
 func (s *PaymentControllerE2ETestSuite) TestE2E_UpdatePayment_Success() {
 	createReq := dto.CreatePaymentRequest{
-		Amount:   100.00,
+		Amount:   "100.00",
 		Currency: "USD",
 		Status:   "pending",
 	}
@@ -229,39 +387,117 @@ func (s *PaymentControllerE2ETestSuite) TestE2E_UpdatePayment_Success() {
 	testutils.ParseJSONResponse(s.T(), createRec, &createResponse)
 
 	updateReq := dto.UpdatePaymentRequest{
-		Amount:   200.00,
+		Amount:   "200.00",
 		Currency: "EUR",
-		Status:   "completed",
+		Status:   "processing",
 	}
 
-	updateRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPut, fmt.Sprintf("/api/v1/payments/%s", createResponse.ID), updateReq)
+	ifMatch := map[string]string{etag.HeaderIfMatch: createRec.Header().Get(etag.HeaderETag)}
+	updateRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPut, fmt.Sprintf("/api/v1/payments/%s", createResponse.ID), updateReq, ifMatch)
 
 	testutils.AssertStatusCode(s.T(), updateRec, http.StatusOK)
+	assert.Equal(s.T(), etag.Format(createResponse.Version+1), updateRec.Header().Get(etag.HeaderETag))
 
 	var updateResponse dto.PaymentResponse
 	testutils.ParseJSONResponse(s.T(), updateRec, &updateResponse)
 
 	assert.Equal(s.T(), createResponse.ID, updateResponse.ID)
-	assert.Equal(s.T(), updateReq.Amount, updateResponse.Amount)
+	assert.Equal(s.T(), updateReq.Amount.String(), updateResponse.Amount)
 	assert.Equal(s.T(), updateReq.Currency, updateResponse.Currency)
 	assert.Equal(s.T(), updateReq.Status, updateResponse.Status)
+	assert.Equal(s.T(), createResponse.Version+1, updateResponse.Version)
+	assert.Equal(s.T(), createResponse.ProviderReference, updateResponse.ProviderReference)
+}
+
+func (s *PaymentControllerE2ETestSuite) TestE2E_UpdatePayment_StaleVersion() {
+	createReq := dto.CreatePaymentRequest{
+		Amount:   "100.00",
+		Currency: "USD",
+		Status:   "pending",
+	}
+	createRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payments", createReq)
+	require.Equal(s.T(), http.StatusCreated, createRec.Code)
+
+	var createResponse dto.PaymentResponse
+	testutils.ParseJSONResponse(s.T(), createRec, &createResponse)
+
+	path := fmt.Sprintf("/api/v1/payments/%s", createResponse.ID)
+	ifMatch := map[string]string{etag.HeaderIfMatch: createRec.Header().Get(etag.HeaderETag)}
+
+	firstReq := dto.UpdatePaymentRequest{Amount: "150.00", Currency: "USD", Status: "pending"}
+	firstRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPut, path, firstReq, ifMatch)
+	testutils.AssertStatusCode(s.T(), firstRec, http.StatusOK)
+
+	// A second writer still holding the original ETag must not overwrite the first update.
+	secondReq := dto.UpdatePaymentRequest{Amount: "175.00", Currency: "USD", Status: "pending"}
+	secondRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPut, path, secondReq, ifMatch)
+	testutils.AssertStatusCode(s.T(), secondRec, http.StatusPreconditionFailed)
+
+	getRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, path, nil)
+	var getResponse dto.PaymentResponse
+	testutils.ParseJSONResponse(s.T(), getRec, &getResponse)
+	assert.Equal(s.T(), "150.00", getResponse.Amount)
+}
+
+func (s *PaymentControllerE2ETestSuite) TestE2E_UpdatePayment_MissingIfMatch() {
+	createReq := dto.CreatePaymentRequest{
+		Amount:   "100.00",
+		Currency: "USD",
+		Status:   "pending",
+	}
+	createRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payments", createReq)
+	require.Equal(s.T(), http.StatusCreated, createRec.Code)
+
+	var createResponse dto.PaymentResponse
+	testutils.ParseJSONResponse(s.T(), createRec, &createResponse)
+
+	updateReq := dto.UpdatePaymentRequest{Amount: "150.00", Currency: "USD", Status: "pending"}
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPut, fmt.Sprintf("/api/v1/payments/%s", createResponse.ID), updateReq)
+
+	testutils.AssertStatusCode(s.T(), rec, http.StatusPreconditionRequired)
+}
+
+func (s *PaymentControllerE2ETestSuite) TestE2E_UpdatePayment_IllegalTransition() {
+	// Declined by the simulated gateway, so the payment is failed
+	createReq := dto.CreatePaymentRequest{
+		Amount:   "5000.00",
+		Currency: "USD",
+		Status:   "pending",
+	}
+	createRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payments", createReq)
+	require.Equal(s.T(), http.StatusCreated, createRec.Code)
+
+	var createResponse dto.PaymentResponse
+	testutils.ParseJSONResponse(s.T(), createRec, &createResponse)
+
+	updateReq := dto.UpdatePaymentRequest{
+		Amount:   "5000.00",
+		Currency: "USD",
+		Status:   "pending",
+	}
+
+	ifMatch := map[string]string{etag.HeaderIfMatch: createRec.Header().Get(etag.HeaderETag)}
+	rec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPut, fmt.Sprintf("/api/v1/payments/%s", createResponse.ID), updateReq, ifMatch)
+
+	testutils.AssertStatusCode(s.T(), rec, http.StatusConflict)
 }
 
 func (s *PaymentControllerE2ETestSuite) TestE2E_UpdatePayment_NotFound() {
 	updateReq := dto.UpdatePaymentRequest{
-		Amount:   200.00,
+		Amount:   "200.00",
 		Currency: "EUR",
 		Status:   "completed",
 	}
 
-	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPut, "/api/v1/payments/pay_nonexistent", updateReq)
+	ifMatch := map[string]string{etag.HeaderIfMatch: "*"}
+	rec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPut, "/api/v1/payments/pay_nonexistent", updateReq, ifMatch)
 
 	testutils.AssertStatusCode(s.T(), rec, http.StatusNotFound)
 }
 
 func (s *PaymentControllerE2ETestSuite) TestE2E_DeletePayment_Success() {
 	createReq := dto.CreatePaymentRequest{
-		Amount:   100.00,
+		Amount:   "100.00",
 		Currency: "USD",
 		Status:   "pending",
 	}
@@ -271,7 +507,12 @@ func (s *PaymentControllerE2ETestSuite) TestE2E_DeletePayment_Success() {
 	var createResponse dto.PaymentResponse
 	testutils.ParseJSONResponse(s.T(), createRec, &createResponse)
 
-	deleteRec := testutils.MakeRequest(s.T(), s.echo, http.MethodDelete, fmt.Sprintf("/api/v1/payments/%s", createResponse.ID), nil)
+	staleRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodDelete, fmt.Sprintf("/api/v1/payments/%s", createResponse.ID), nil,
+		map[string]string{etag.HeaderIfMatch: etag.Format(createResponse.Version + 1)})
+	testutils.AssertStatusCode(s.T(), staleRec, http.StatusPreconditionFailed)
+
+	ifMatch := map[string]string{etag.HeaderIfMatch: createRec.Header().Get(etag.HeaderETag)}
+	deleteRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodDelete, fmt.Sprintf("/api/v1/payments/%s", createResponse.ID), nil, ifMatch)
 
 	testutils.AssertStatusCode(s.T(), deleteRec, http.StatusNoContent)
 
@@ -280,7 +521,8 @@ func (s *PaymentControllerE2ETestSuite) TestE2E_DeletePayment_Success() {
 }
 
 func (s *PaymentControllerE2ETestSuite) TestE2E_DeletePayment_NotFound() {
-	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodDelete, "/api/v1/payments/pay_nonexistent", nil)
+	ifMatch := map[string]string{etag.HeaderIfMatch: "*"}
+	rec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodDelete, "/api/v1/payments/pay_nonexistent", nil, ifMatch)
 
 	testutils.AssertStatusCode(s.T(), rec, http.StatusNotFound)
 }
@@ -291,3 +533,83 @@ func TestE2E_PaymentControllerTestSuite(t *testing.T) {
 	}
 	suite.Run(t, new(PaymentControllerE2ETestSuite))
 }
+
+func (s *PaymentControllerE2ETestSuite) TestE2E_CreatePayment_PublishesWebhookEvents() {
+	endpointReq := map[string]interface{}{"url": "https://merchant.example/webhooks", "eventTypes": []string{"*"}}
+	endpointRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/webhooks/endpoints", endpointReq)
+	require.Equal(s.T(), http.StatusCreated, endpointRec.Code)
+	var endpoint struct {
+		ID string `json:"id"`
+	}
+	testutils.ParseJSONResponse(s.T(), endpointRec, &endpoint)
+
+	// 5000.00 USD is declined at authorization: the payment is created and then fails
+	createReq := dto.CreatePaymentRequest{Amount: "5000.00", Currency: "USD", Status: "pending"}
+	createRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payments", createReq)
+	require.Equal(s.T(), http.StatusCreated, createRec.Code)
+	var created dto.PaymentResponse
+	testutils.ParseJSONResponse(s.T(), createRec, &created)
+
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, fmt.Sprintf("/api/v1/webhooks/endpoints/%s/deliveries", endpoint.ID), nil)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusOK)
+	var deliveries []struct {
+		EventType string `json:"eventType"`
+		Status    string `json:"status"`
+		Payload   struct {
+			Data struct {
+				ID             string `json:"id"`
+				Status         string `json:"status"`
+				PreviousStatus string `json:"previousStatus"`
+			} `json:"data"`
+		} `json:"payload"`
+	}
+	testutils.ParseJSONResponse(s.T(), rec, &deliveries)
+
+	require.Len(s.T(), deliveries, 2)
+	byType := map[string]int{deliveries[0].EventType: 0, deliveries[1].EventType: 1}
+	require.Contains(s.T(), byType, "payment.created")
+	require.Contains(s.T(), byType, "payment.status_changed")
+
+	createdEvent := deliveries[byType["payment.created"]]
+	assert.Equal(s.T(), created.ID, createdEvent.Payload.Data.ID)
+	// Deliveries are only sent by the dispatcher job
+	assert.Equal(s.T(), "pending", createdEvent.Status)
+
+	changedEvent := deliveries[byType["payment.status_changed"]]
+	assert.Equal(s.T(), "failed", changedEvent.Payload.Data.Status)
+	assert.Equal(s.T(), "pending", changedEvent.Payload.Data.PreviousStatus)
+}
+
+func (s *PaymentControllerE2ETestSuite) TestE2E_CreatePayment_SeesSettingChanges() {
+	requestBody := dto.CreatePaymentRequest{
+		Amount:   "9000.00",
+		Currency: "USD",
+		Status:   "pending",
+	}
+
+	// The USD limits are cached by the first payment
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payments", requestBody)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusCreated)
+
+	// Lower the maximum through the payment-settings API
+	getRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, "/api/v1/payment-settings/pset-e2e-usd-max", nil)
+	testutils.AssertStatusCode(s.T(), getRec, http.StatusOK)
+	updateRec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPut, "/api/v1/payment-settings/pset-e2e-usd-max", map[string]string{
+		"settingKey":   paymentsettings.SettingKeyMaxTransactionAmount,
+		"settingValue": "8000.00",
+		"currency":     "USD",
+		"status":       paymentsettings.SettingStatusActive,
+	}, map[string]string{etag.HeaderIfMatch: getRec.Header().Get(etag.HeaderETag)})
+	testutils.AssertStatusCode(s.T(), updateRec, http.StatusOK)
+
+	// The next payment is validated against the new maximum, not the cached one
+	rec = testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payments", requestBody)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusBadRequest)
+
+	statsRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, "/api/v1/payments/settings-cache", nil)
+	testutils.AssertStatusCode(s.T(), statsRec, http.StatusOK)
+	var stats payment.SettingsCacheStats
+	testutils.ParseJSONResponse(s.T(), statsRec, &stats)
+	assert.Positive(s.T(), stats.Invalidations)
+	assert.Positive(s.T(), stats.Misses)
+}
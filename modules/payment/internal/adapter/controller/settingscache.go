@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+)
+
+// SettingsCacheStatsProvider reports the cache of payment settings.
+type SettingsCacheStatsProvider interface {
+	Stats() payment.SettingsCacheStats
+}
+
+type settingsCacheController struct {
+	settingsCache SettingsCacheStatsProvider
+}
+
+// NewSettingsCacheController registers the endpoint observing the payment settings cache.
+func NewSettingsCacheController(e *echo.Group, settingsCache SettingsCacheStatsProvider) (controller *settingsCacheController) {
+	controller = &settingsCacheController{
+		settingsCache: settingsCache,
+	}
+	e.GET("/payments/settings-cache", controller.GetStats)
+	return controller
+}
+
+// GetStats returns the hit, miss, load and eviction counters of the cache since the process started.
+func (c *settingsCacheController) GetStats(ctx echo.Context) (err error) {
+	return ctx.JSON(http.StatusOK, c.settingsCache.Stats())
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/adapter/controller/dto"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/webhook"
+)
+
+// maxWebhookBodySize bounds the payload read before the signature is verified.
+const maxWebhookBodySize = 1 << 20
+
+type webhookController struct {
+	paymentService payment.IPaymentService
+	secrets        map[string]string
+	tolerance      time.Duration
+}
+
+// NewWebhookController registers the inbound PSP webhook. secrets holds the signing secret of each
+// provider by name; deliveries from a provider without a secret are rejected. tolerance bounds the
+// age of a signature (webhook.DefaultTolerance when 0).
+func NewWebhookController(e *echo.Group, paymentService payment.IPaymentService, secrets map[string]string, tolerance time.Duration) (controller *webhookController) {
+	controller = &webhookController{
+		paymentService: paymentService,
+		secrets:        secrets,
+		tolerance:      tolerance,
+	}
+	e.POST("/webhooks/psp/:provider", controller.HandleProviderEvent)
+	return controller
+}
+
+// HandleProviderEvent verifies the Webhook-Signature header over the raw body before the payload is
+// parsed, then applies the event. Redeliveries are acknowledged without being applied again.
+func (c *webhookController) HandleProviderEvent(ctx echo.Context) (err error) {
+	provider := ctx.Param("provider")
+	body, err := io.ReadAll(io.LimitReader(ctx.Request().Body, maxWebhookBodySize))
+	if err != nil {
+		return err
+	}
+
+	err = webhook.Verify(c.secrets[provider], ctx.Request().Header.Get(webhook.HeaderSignature), body, c.tolerance, time.Now())
+	if err != nil {
+		log.Warn().Err(err).
+			Str("provider", provider).
+			Str("ip", ctx.RealIP()).
+			Msg("Rejected PSP webhook with an invalid signature")
+		return errors.ErrUnauthorized
+	}
+
+	var eventRequest dto.ProviderEventRequest
+	if err = json.Unmarshal(body, &eventRequest); err != nil {
+		return errors.NewValidationError(fmt.Errorf("invalid webhook payload: %w", err))
+	}
+
+	duplicate, err := c.paymentService.HandleProviderEvent(ctx.Request().Context(), eventRequest.ToProviderEvent(provider))
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, dto.WebhookAckResponse{EventID: eventRequest.EventID, Duplicate: duplicate})
+}
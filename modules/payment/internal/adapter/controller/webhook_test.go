@@ -0,0 +1,85 @@
+//go:build e2e
+
+package controller_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/adapter/controller/dto"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/testutils"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/webhook"
+)
+
+const webhookSecret = "whsec_e2e"
+
+// sendProviderEvent delivers a provider event signed with secret.
+func (s *PaymentControllerE2ETestSuite) sendProviderEvent(event dto.ProviderEventRequest, secret string) *httptest.ResponseRecorder {
+	body, err := json.Marshal(event)
+	require.NoError(s.T(), err)
+	headers := map[string]string{webhook.HeaderSignature: webhook.Sign(secret, time.Now(), body)}
+	return testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPost, "/api/v1/webhooks/psp/simulator", json.RawMessage(body), headers)
+}
+
+func (s *PaymentControllerE2ETestSuite) createAuthorizedPayment() dto.PaymentResponse {
+	createReq := dto.CreatePaymentRequest{Amount: "100.00", Currency: "USD", Status: "pending"}
+	createRec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/payments", createReq)
+	require.Equal(s.T(), http.StatusCreated, createRec.Code)
+
+	var created dto.PaymentResponse
+	testutils.ParseJSONResponse(s.T(), createRec, &created)
+	require.NotEmpty(s.T(), created.ProviderReference)
+	return created
+}
+
+func (s *PaymentControllerE2ETestSuite) TestE2E_ProviderWebhook_CapturedCompletesPayment() {
+	created := s.createAuthorizedPayment()
+	event := dto.ProviderEventRequest{EventID: "evt_capture_1", Reference: created.ProviderReference, Status: "captured"}
+
+	rec := s.sendProviderEvent(event, webhookSecret)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusOK)
+
+	var ack dto.WebhookAckResponse
+	testutils.ParseJSONResponse(s.T(), rec, &ack)
+	assert.Equal(s.T(), "evt_capture_1", ack.EventID)
+	assert.False(s.T(), ack.Duplicate)
+
+	getRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, fmt.Sprintf("/api/v1/payments/%s", created.ID), nil)
+	var current dto.PaymentResponse
+	testutils.ParseJSONResponse(s.T(), getRec, &current)
+	assert.Equal(s.T(), "completed", current.Status)
+
+	// A redelivery is acknowledged without being applied again
+	rec = s.sendProviderEvent(event, webhookSecret)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusOK)
+	testutils.ParseJSONResponse(s.T(), rec, &ack)
+	assert.True(s.T(), ack.Duplicate)
+}
+
+func (s *PaymentControllerE2ETestSuite) TestE2E_ProviderWebhook_InvalidSignature() {
+	created := s.createAuthorizedPayment()
+	event := dto.ProviderEventRequest{EventID: "evt_forged", Reference: created.ProviderReference, Status: "captured"}
+
+	rec := s.sendProviderEvent(event, "not-the-secret")
+	testutils.AssertStatusCode(s.T(), rec, http.StatusUnauthorized)
+
+	getRec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, fmt.Sprintf("/api/v1/payments/%s", created.ID), nil)
+	var current dto.PaymentResponse
+	testutils.ParseJSONResponse(s.T(), getRec, &current)
+	assert.Equal(s.T(), "pending", current.Status)
+}
+
+func (s *PaymentControllerE2ETestSuite) TestE2E_ProviderWebhook_UnknownProvider() {
+	body := []byte(`{"eventId":"evt_1","reference":"ref","status":"captured"}`)
+	headers := map[string]string{webhook.HeaderSignature: webhook.Sign(webhookSecret, time.Now(), body)}
+
+	rec := testutils.MakeRequestWithHeaders(s.T(), s.echo, http.MethodPost, "/api/v1/webhooks/psp/unknown", json.RawMessage(body), headers)
+
+	testutils.AssertStatusCode(s.T(), rec, http.StatusUnauthorized)
+}
@@ -1,18 +1,48 @@
 package cron
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/lock"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+// Reasons a run stopped walking pages.
+const (
+	StopReasonExhausted   = "exhausted"
+	StopReasonMaxItems    = "max_items"
+	StopReasonMaxDuration = "max_duration"
+	StopReasonInterrupted = "interrupted"
+	StopReasonFetchFailed = "fetch_failed"
 )
 
 // PaymentUpdaterConfig contains configuration for the payment updater cron job
 type PaymentUpdaterConfig struct {
+	// BatchSize is the page size used to walk pending payments.
 	BatchSize int
 	DryRun    bool
+	// MaxItems caps the number of payments processed in one run (0 = unlimited).
+	MaxItems int
+	// MaxDuration caps the wall-clock time of one run (0 = unlimited).
+	MaxDuration time.Duration
+	// Concurrency is the number of workers processing a claimed page (default 1).
+	Concurrency int
+	// LeaseDuration is how long claimed payments stay reserved for this run (default 5m).
+	// Leases of a crashed run expire after it and the payments become claimable again.
+	LeaseDuration time.Duration
+	// RetryDelay is how long a payment that failed to process is deferred before it is claimed again (default 15m),
+	// so payments that keep failing do not use up the budget of every run.
+	RetryDelay time.Duration
+	// WorkerID identifies this run's claims (default hostname-pid).
+	WorkerID string
 }
 
 // PaymentUpdater is the cron adapter for payment update operations
@@ -23,100 +53,158 @@ type PaymentUpdater struct {
 
 // NewPaymentUpdater creates a new payment updater cron adapter
 func NewPaymentUpdater(paymentService payment.IPaymentService, config PaymentUpdaterConfig) *PaymentUpdater {
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+	if config.LeaseDuration <= 0 {
+		config.LeaseDuration = 5 * time.Minute
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = 15 * time.Minute
+	}
+	if config.WorkerID == "" {
+		config.WorkerID = defaultWorkerID()
+	}
 	return &PaymentUpdater{
 		paymentService: paymentService,
 		config:         config,
 	}
 }
 
+// defaultWorkerID identifies the current process, so claims of overlapping runs never collide.
+func defaultWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
 // ExecutionResult contains the results of a cron job execution
 type ExecutionResult struct {
-	StartTime      time.Time
-	EndTime        time.Time
-	Duration       time.Duration
+	StartTime time.Time
+	EndTime   time.Time
+	Duration  time.Duration
+	DryRun    bool
+	// Lock is the single-instance lock held by this run, when the job runs under one.
+	Lock           *lock.Holder
+	StopReason     string
 	ProcessedCount int
 	SuccessCount   int
 	ErrorCount     int
+	Pages          []PageResult
 	Errors         []error
 }
 
-// Execute runs the payment update cron job
-func (u *PaymentUpdater) Execute() (resultData interface{}, err error) {
+// RunSummary reports the outcome of the run to the job run history
+func (r *ExecutionResult) RunSummary() scheduler.RunSummary {
+	errs := make([]string, len(r.Errors))
+	for i, err := range r.Errors {
+		errs[i] = err.Error()
+	}
+	return scheduler.RunSummary{
+		ProcessedCount: r.ProcessedCount,
+		SuccessCount:   r.SuccessCount,
+		ErrorCount:     r.ErrorCount,
+		Errors:         errs,
+		DryRun:         r.DryRun,
+	}
+}
+
+// PageResult contains the progress made on a single page of pending payments
+type PageResult struct {
+	Page         int
+	FetchedCount int
+	SuccessCount int
+	ErrorCount   int
+	Duration     time.Duration
+}
+
+// Execute walks pending payments oldest-first, page by page, until none are left or the
+// MaxItems/MaxDuration budget is spent. Each page is claimed with a lease before it is processed
+// by the worker pool, so overlapping runs process disjoint payments. Progress is persisted on the
+// payments themselves: processed payments leave the pending status and failed ones are deferred by
+// RetryDelay, so the next run resumes with the oldest payments not yet attempted instead of starting
+// over with the ones that keep failing. Cancelling ctx stops the job before the next payment.
+// The job serves every tenant: pages span all of them and each payment is processed for its own tenant.
+func (u *PaymentUpdater) Execute(ctx context.Context) (resultData interface{}, err error) {
+	ctx = tenant.WithAllTenants(ctx)
 	result := &ExecutionResult{
 		StartTime: time.Now(),
+		DryRun:    u.config.DryRun,
+		Pages:     make([]PageResult, 0),
 		Errors:    make([]error, 0),
 	}
 
 	log.Info().
 		Str("started_at", result.StartTime.Format(time.RFC3339)).
+		Int("batch_size", u.config.BatchSize).
+		Int("max_items", u.config.MaxItems).
+		Dur("max_duration", u.config.MaxDuration).
+		Int("concurrency", u.config.Concurrency).
+		Str("worker_id", u.config.WorkerID).
 		Msg("Payment update cron job started")
 
+	if holder, ok := lock.HolderFromContext(ctx); ok {
+		result.Lock = holder
+	}
+
 	if u.config.DryRun {
 		log.Info().Msg("Running in DRY-RUN mode. No actual updates will be performed")
 	}
 
-	log.Info().
-		Int("batch_size", u.config.BatchSize).
-		Msg("Fetching pending payments")
-
-	payments, _, err := u.paymentService.FetchPayments(payment.FetchPaymentsParams{
-		Limit:  u.config.BatchSize,
-		Status: "pending",
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch payments: %w", err)
-	}
+	// Leases not consumed by a successful update (errors, interruption) are handed back at the end
+	defer u.releaseClaims(context.WithoutCancel(ctx))
 
-	log.Info().
-		Int("count", len(payments)).
-		Msg("Found payments to process")
-
-	if len(payments) == 0 {
-		log.Info().Msg("No payments to process")
-		result.EndTime = time.Now()
-		result.Duration = result.EndTime.Sub(result.StartTime)
-		return result, nil
-	}
-
-	// Process payments
-	for i, p := range payments {
-		if i >= u.config.BatchSize {
-			log.Info().
-				Int("batch_size", u.config.BatchSize).
-				Msg("Batch size limit reached. Stopping processing")
+	cursor := ""
+	for page := 1; ; page++ {
+		if result.StopReason = u.budgetStopReason(ctx, result); result.StopReason != "" {
 			break
 		}
 
-		result.ProcessedCount++
+		limit := u.config.BatchSize
+		if u.config.MaxItems > 0 && u.config.MaxItems-result.ProcessedCount < limit {
+			limit = u.config.MaxItems - result.ProcessedCount
+		}
+
+		payments, nextCursor, fetchErr := u.nextPage(ctx, cursor, limit)
+		if fetchErr != nil {
+			result.StopReason = StopReasonFetchFailed
+			u.finish(result)
+			return result, fmt.Errorf("failed to fetch payments: %w", fetchErr)
+		}
+
+		pageResult := u.processPage(ctx, page, payments, result)
+		result.Pages = append(result.Pages, pageResult)
 
 		log.Info().
-			Int("current", i+1).
-			Int("total", len(payments)).
-			Str("payment_id", p.ID).
-			Str("status", p.Status).
-			Float64("amount", p.Amount).
-			Str("currency", p.Currency).
-			Msg("Processing payment")
-
-		// Apply business logic for payment updates
-		if err := u.processPayment(&p); err != nil {
-			log.Error().
-				Err(err).
-				Str("payment_id", p.ID).
-				Msg("Failed to process payment")
-			result.ErrorCount++
-			result.Errors = append(result.Errors, fmt.Errorf("payment %s: %w", p.ID, err))
-			continue
+			Int("page", pageResult.Page).
+			Int("fetched", pageResult.FetchedCount).
+			Int("success", pageResult.SuccessCount).
+			Int("errors", pageResult.ErrorCount).
+			Dur("duration", pageResult.Duration).
+			Int("processed_total", result.ProcessedCount).
+			Msg("Processed page of pending payments")
+
+		if ctx.Err() != nil {
+			// The page was cut short; its unprocessed payments are released and claimed first by the next run
+			result.StopReason = StopReasonInterrupted
+			break
+		}
+
+		if nextCursor == "" {
+			result.StopReason = StopReasonExhausted
+			break
 		}
 
-		result.SuccessCount++
+		cursor = nextCursor
 	}
 
-	result.EndTime = time.Now()
-	result.Duration = result.EndTime.Sub(result.StartTime)
+	u.finish(result)
 
-	// Print summary
-	u.printSummary(result)
+	if err = ctx.Err(); err != nil {
+		return result, fmt.Errorf("cron job interrupted: %w", err)
+	}
 
 	if result.ErrorCount > 0 {
 		return result, fmt.Errorf("cron job completed with %d error(s)", result.ErrorCount)
@@ -125,14 +213,146 @@ func (u *PaymentUpdater) Execute() (resultData interface{}, err error) {
 	return result, nil
 }
 
+// nextPage claims the next page of pending payments. Dry runs only read them, leaving no leases behind.
+func (u *PaymentUpdater) nextPage(ctx context.Context, cursor string, limit int) (payments []payment.Payment, nextCursor string, err error) {
+	if u.config.DryRun {
+		return u.paymentService.FetchPayments(ctx, payment.FetchPaymentsParams{
+			Cursor:    cursor,
+			Limit:     limit,
+			Status:    payment.StatusPending,
+			SortOrder: payment.SortOldestFirst,
+		})
+	}
+	return u.paymentService.ClaimPendingPayments(ctx, payment.ClaimPaymentsParams{
+		Owner:         u.config.WorkerID,
+		Limit:         limit,
+		Cursor:        cursor,
+		LeaseDuration: u.config.LeaseDuration,
+	})
+}
+
+// releaseClaims drops the leases held by this run
+func (u *PaymentUpdater) releaseClaims(ctx context.Context) {
+	if u.config.DryRun {
+		return
+	}
+	if err := u.paymentService.ReleasePaymentClaims(ctx, u.config.WorkerID); err != nil {
+		log.Error().Err(err).Str("worker_id", u.config.WorkerID).Msg("Failed to release payment claims")
+	}
+}
+
+// budgetStopReason returns why the run must stop before fetching the next page, or an empty string.
+func (u *PaymentUpdater) budgetStopReason(ctx context.Context, result *ExecutionResult) (reason string) {
+	switch {
+	case ctx.Err() != nil:
+		return StopReasonInterrupted
+	case u.config.MaxItems > 0 && result.ProcessedCount >= u.config.MaxItems:
+		return StopReasonMaxItems
+	case u.config.MaxDuration > 0 && time.Since(result.StartTime) >= u.config.MaxDuration:
+		return StopReasonMaxDuration
+	default:
+		return ""
+	}
+}
+
+// processPage processes one page of payments with the worker pool and adds its counts to the run result
+func (u *PaymentUpdater) processPage(ctx context.Context, page int, payments []payment.Payment, result *ExecutionResult) (pageResult PageResult) {
+	start := time.Now()
+	pageResult = PageResult{
+		Page:         page,
+		FetchedCount: len(payments),
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		jobs = make(chan payment.Payment)
+	)
+
+	for w := 0; w < u.config.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				// Drain the remaining jobs without processing them once the run is cancelled
+				if ctx.Err() != nil {
+					continue
+				}
+
+				log.Debug().
+					Str("payment_id", p.ID).
+					Str("status", p.Status).
+					Str("amount", p.Amount.String()).
+					Str("currency", p.Currency()).
+					Msg("Processing payment")
+
+				// Apply business logic for payment updates
+				paymentCtx := tenant.WithID(ctx, p.TenantID)
+				err := u.processPayment(paymentCtx, &p)
+				if err != nil {
+					u.deferPayment(paymentCtx, &p)
+				}
+
+				mu.Lock()
+				result.ProcessedCount++
+				if err != nil {
+					log.Error().
+						Err(err).
+						Str("payment_id", p.ID).
+						Msg("Failed to process payment")
+					pageResult.ErrorCount++
+					result.ErrorCount++
+					result.Errors = append(result.Errors, fmt.Errorf("payment %s: %w", p.ID, err))
+				} else {
+					pageResult.SuccessCount++
+					result.SuccessCount++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, p := range payments {
+		if ctx.Err() != nil {
+			log.Warn().Msg("Cron job cancelled. Stopping processing")
+			break
+		}
+		jobs <- p
+	}
+	close(jobs)
+	wg.Wait()
+
+	pageResult.Duration = time.Since(start)
+	return pageResult
+}
+
+// deferPayment postpones the next attempt of a payment that failed to process. Dry runs leave no trace,
+// and payments cut short by a cancelled run are not deferred: they did not fail.
+func (u *PaymentUpdater) deferPayment(ctx context.Context, p *payment.Payment) {
+	if u.config.DryRun || ctx.Err() != nil {
+		return
+	}
+	until := time.Now().Add(u.config.RetryDelay)
+	if err := u.paymentService.DeferPayment(ctx, p.ID, until); err != nil {
+		log.Error().Err(err).Str("payment_id", p.ID).Msg("Failed to defer payment")
+	}
+}
+
+// finish stamps the end of the run and prints the summary
+func (u *PaymentUpdater) finish(result *ExecutionResult) {
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	u.printSummary(result)
+}
+
 // processPayment handles the business logic for a single payment
-func (u *PaymentUpdater) processPayment(p *payment.Payment) (err error) {
-	// Skip payments that don't need processing
-	if p.Status != "pending" {
+func (u *PaymentUpdater) processPayment(ctx context.Context, p *payment.Payment) (err error) {
+	// Skip payments that don't need processing; processing payments have an unresolved capture
+	if p.Status != payment.StatusPending && p.Status != payment.StatusProcessing {
 		log.Info().
 			Str("payment_id", p.ID).
 			Str("status", p.Status).
-			Msg("Skipped payment. Status is not pending")
+			Msg("Skipped payment. Status is not pending or processing")
 		return nil
 	}
 
@@ -140,43 +360,38 @@ func (u *PaymentUpdater) processPayment(p *payment.Payment) (err error) {
 	if u.config.DryRun {
 		log.Info().
 			Str("payment_id", p.ID).
-			Msg("DRY-RUN mode. Would update payment to processing")
+			Msg("DRY-RUN mode. Would process payment through the payment gateway")
 		return nil
 	}
 
-	// Update payment status
-	p.Status = "processing"
-	if err = u.paymentService.UpdatePayment(p); err != nil {
-		return fmt.Errorf("failed to update payment: %w", err)
+	// Authorize and capture with the payment gateway, moving the payment through the state machine
+	if err = u.paymentService.ProcessPayment(ctx, p); err != nil {
+		return fmt.Errorf("failed to process payment: %w", err)
 	}
 
 	log.Info().
 		Str("payment_id", p.ID).
-		Str("new_status", "processing").
-		Msg("Updated payment status")
+		Str("new_status", p.Status).
+		Str("provider_reference", p.ProviderReference).
+		Msg("Processed payment")
 	return nil
 }
 
 // printSummary prints the execution summary
 func (u *PaymentUpdater) printSummary(result *ExecutionResult) {
+	event := log.Info()
 	if result.ErrorCount > 0 {
-		log.Warn().
-			Str("completed_at", result.EndTime.Format(time.RFC3339)).
-			Dur("duration", result.Duration).
-			Int("processed", result.ProcessedCount).
-			Int("success", result.SuccessCount).
-			Int("errors", result.ErrorCount).
-			Str("status", u.getJobStatus(result.ErrorCount)).
-			Msg("Payment update cron job completed")
-		return
+		event = log.Warn()
 	}
 
-	log.Info().
+	event.
 		Str("completed_at", result.EndTime.Format(time.RFC3339)).
 		Dur("duration", result.Duration).
+		Int("pages", len(result.Pages)).
 		Int("processed", result.ProcessedCount).
 		Int("success", result.SuccessCount).
 		Int("errors", result.ErrorCount).
+		Str("stop_reason", result.StopReason).
 		Str("status", u.getJobStatus(result.ErrorCount)).
 		Msg("Payment update cron job completed")
 }
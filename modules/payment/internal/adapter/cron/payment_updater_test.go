@@ -0,0 +1,226 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+	paymentmocks "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/mocks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/money"
+)
+
+func pendingPayments(ids ...string) []payment.Payment {
+	payments := make([]payment.Payment, len(ids))
+	for i, id := range ids {
+		payments[i] = payment.Payment{ID: id, Amount: money.MustParse("10.00", "USD"), Status: payment.StatusPending, Version: 1}
+	}
+	return payments
+}
+
+func fetchParams(cursor string, limit int) payment.FetchPaymentsParams {
+	return payment.FetchPaymentsParams{
+		Cursor:    cursor,
+		Limit:     limit,
+		Status:    payment.StatusPending,
+		SortOrder: payment.SortOldestFirst,
+	}
+}
+
+func claimParams(cursor string, limit int) payment.ClaimPaymentsParams {
+	return payment.ClaimPaymentsParams{
+		Owner:         "worker-1",
+		Limit:         limit,
+		Cursor:        cursor,
+		LeaseDuration: 5 * time.Minute,
+	}
+}
+
+func TestPaymentUpdater_Execute_WalksAllPages(t *testing.T) {
+	mockService := paymentmocks.NewMockIPaymentService(t)
+
+	mockService.On("ClaimPendingPayments", mock.Anything, claimParams("", 2)).Return(pendingPayments("pay_1", "pay_2"), "cursor_2", nil)
+	mockService.On("ClaimPendingPayments", mock.Anything, claimParams("cursor_2", 2)).Return(pendingPayments("pay_3"), "", nil)
+	mockService.On("ProcessPayment", mock.Anything, mock.MatchedBy(func(p *payment.Payment) bool {
+		return p.Status == payment.StatusPending
+	})).Return(nil).Times(3)
+
+	mockService.On("ReleasePaymentClaims", mock.Anything, "worker-1").Return(nil)
+
+	updater := NewPaymentUpdater(mockService, PaymentUpdaterConfig{WorkerID: "worker-1", BatchSize: 2})
+	resultData, err := updater.Execute(context.Background())
+
+	require.NoError(t, err)
+	result := resultData.(*ExecutionResult)
+	assert.Equal(t, StopReasonExhausted, result.StopReason)
+	assert.Equal(t, 3, result.ProcessedCount)
+	assert.Equal(t, 3, result.SuccessCount)
+	require.Len(t, result.Pages, 2)
+	assert.Equal(t, PageResult{Page: 1, FetchedCount: 2, SuccessCount: 2}, withoutDuration(result.Pages[0]))
+	assert.Equal(t, PageResult{Page: 2, FetchedCount: 1, SuccessCount: 1}, withoutDuration(result.Pages[1]))
+}
+
+func TestPaymentUpdater_Execute_StartsFromOldestEveryRun(t *testing.T) {
+	mockService := paymentmocks.NewMockIPaymentService(t)
+
+	// Both runs are stopped by their budget after one page and neither resumes from the other's cursor
+	mockService.On("ClaimPendingPayments", mock.Anything, claimParams("", 1)).Return(pendingPayments("pay_1"), "cursor_1", nil).Once()
+	mockService.On("ClaimPendingPayments", mock.Anything, claimParams("", 1)).Return(pendingPayments("pay_2"), "cursor_2", nil).Once()
+	mockService.On("ProcessPayment", mock.Anything, mock.Anything).Return(nil).Times(2)
+
+	mockService.On("ReleasePaymentClaims", mock.Anything, "worker-1").Return(nil).Times(2)
+
+	updater := NewPaymentUpdater(mockService, PaymentUpdaterConfig{WorkerID: "worker-1", BatchSize: 1, MaxItems: 1})
+	for i := 0; i < 2; i++ {
+		resultData, err := updater.Execute(context.Background())
+
+		require.NoError(t, err)
+		result := resultData.(*ExecutionResult)
+		assert.Equal(t, StopReasonMaxItems, result.StopReason)
+		assert.Equal(t, 1, result.ProcessedCount)
+	}
+}
+
+func TestPaymentUpdater_Execute_StopsAtMaxItems(t *testing.T) {
+	mockService := paymentmocks.NewMockIPaymentService(t)
+
+	mockService.On("ClaimPendingPayments", mock.Anything, claimParams("", 2)).Return(pendingPayments("pay_1", "pay_2"), "cursor_2", nil)
+	// The last page is shrunk to the remaining budget
+	mockService.On("ClaimPendingPayments", mock.Anything, claimParams("cursor_2", 1)).Return(pendingPayments("pay_3"), "cursor_3", nil)
+	mockService.On("ProcessPayment", mock.Anything, mock.Anything).Return(nil).Times(3)
+
+	mockService.On("ReleasePaymentClaims", mock.Anything, "worker-1").Return(nil)
+
+	updater := NewPaymentUpdater(mockService, PaymentUpdaterConfig{WorkerID: "worker-1", BatchSize: 2, MaxItems: 3})
+	resultData, err := updater.Execute(context.Background())
+
+	require.NoError(t, err)
+	result := resultData.(*ExecutionResult)
+	assert.Equal(t, StopReasonMaxItems, result.StopReason)
+	assert.Equal(t, 3, result.ProcessedCount)
+}
+
+func TestPaymentUpdater_Execute_DryRunLeavesNoClaims(t *testing.T) {
+	mockService := paymentmocks.NewMockIPaymentService(t)
+
+	mockService.On("FetchPayments", mock.Anything, fetchParams("", 1)).Return(pendingPayments("pay_1"), "cursor_1", nil)
+	mockService.On("FetchPayments", mock.Anything, fetchParams("cursor_1", 1)).Return(pendingPayments("pay_2"), "", nil)
+
+	updater := NewPaymentUpdater(mockService, PaymentUpdaterConfig{WorkerID: "worker-1", BatchSize: 1, DryRun: true})
+	resultData, err := updater.Execute(context.Background())
+
+	require.NoError(t, err)
+	result := resultData.(*ExecutionResult)
+	assert.Equal(t, StopReasonExhausted, result.StopReason)
+	assert.Equal(t, 2, result.SuccessCount)
+}
+
+func TestPaymentUpdater_Execute_Interrupted(t *testing.T) {
+	mockService := paymentmocks.NewMockIPaymentService(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mockService.On("ClaimPendingPayments", mock.Anything, claimParams("", 10)).Return(pendingPayments("pay_1", "pay_2"), "cursor_2", nil)
+	// The signal arrives while the first payment is being processed
+	mockService.On("ProcessPayment", mock.Anything, mock.Anything).Run(func(mock.Arguments) { cancel() }).Return(nil).Once()
+
+	mockService.On("ReleasePaymentClaims", mock.Anything, "worker-1").Return(nil)
+
+	updater := NewPaymentUpdater(mockService, PaymentUpdaterConfig{WorkerID: "worker-1", BatchSize: 10})
+	resultData, err := updater.Execute(ctx)
+
+	require.ErrorIs(t, err, context.Canceled)
+	result := resultData.(*ExecutionResult)
+	assert.Equal(t, StopReasonInterrupted, result.StopReason)
+	assert.Equal(t, 1, result.ProcessedCount)
+}
+
+func TestPaymentUpdater_Execute_DefersFailedPayments(t *testing.T) {
+	mockService := paymentmocks.NewMockIPaymentService(t)
+
+	mockService.On("ClaimPendingPayments", mock.Anything, claimParams("", 10)).Return(pendingPayments("pay_1", "pay_2"), "", nil)
+	mockService.On("ProcessPayment", mock.Anything, mock.MatchedBy(func(p *payment.Payment) bool {
+		return p.ID == "pay_1"
+	})).Return(errors.New("gateway unavailable"))
+	mockService.On("ProcessPayment", mock.Anything, mock.MatchedBy(func(p *payment.Payment) bool {
+		return p.ID == "pay_2"
+	})).Return(nil)
+
+	// Only the failed payment is kept out of the next runs, for the configured delay
+	before := time.Now()
+	mockService.On("DeferPayment", mock.Anything, "pay_1", mock.MatchedBy(func(until time.Time) bool {
+		return !until.Before(before.Add(time.Hour)) && until.Before(time.Now().Add(time.Hour+time.Second))
+	})).Return(nil).Once()
+
+	mockService.On("ReleasePaymentClaims", mock.Anything, "worker-1").Return(nil)
+
+	updater := NewPaymentUpdater(mockService, PaymentUpdaterConfig{WorkerID: "worker-1", BatchSize: 10, RetryDelay: time.Hour})
+	resultData, err := updater.Execute(context.Background())
+
+	require.Error(t, err)
+	result := resultData.(*ExecutionResult)
+	assert.Equal(t, 1, result.SuccessCount)
+	assert.Equal(t, 1, result.ErrorCount)
+}
+
+func TestPaymentUpdater_Execute_FetchError(t *testing.T) {
+	mockService := paymentmocks.NewMockIPaymentService(t)
+
+	mockService.On("ClaimPendingPayments", mock.Anything, claimParams("", 10)).Return(nil, "", errors.New("connection refused"))
+
+	mockService.On("ReleasePaymentClaims", mock.Anything, "worker-1").Return(nil)
+
+	updater := NewPaymentUpdater(mockService, PaymentUpdaterConfig{WorkerID: "worker-1", BatchSize: 10})
+	resultData, err := updater.Execute(context.Background())
+
+	require.Error(t, err)
+	assert.Equal(t, StopReasonFetchFailed, resultData.(*ExecutionResult).StopReason)
+}
+
+func TestPaymentUpdater_Execute_WorkerPool(t *testing.T) {
+	mockService := paymentmocks.NewMockIPaymentService(t)
+
+	mockService.On("ClaimPendingPayments", mock.Anything, claimParams("", 6)).Return(pendingPayments("pay_1", "pay_2", "pay_3", "pay_4", "pay_5"), "", nil)
+	mockService.On("ReleasePaymentClaims", mock.Anything, "worker-1").Return(nil)
+	mockService.On("DeferPayment", mock.Anything, "pay_3", mock.AnythingOfType("time.Time")).Return(nil)
+
+	var inFlight, maxInFlight int32
+	mockService.On("ProcessPayment", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		current := atomic.AddInt32(&inFlight, 1)
+		for {
+			seen := atomic.LoadInt32(&maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+	}).Return(func(_ context.Context, p *payment.Payment) error {
+		if p.ID == "pay_3" {
+			return errors.New("gateway unavailable")
+		}
+		return nil
+	})
+
+	updater := NewPaymentUpdater(mockService, PaymentUpdaterConfig{WorkerID: "worker-1", BatchSize: 6, Concurrency: 3})
+	resultData, err := updater.Execute(context.Background())
+
+	require.Error(t, err)
+	result := resultData.(*ExecutionResult)
+	assert.Equal(t, 5, result.ProcessedCount)
+	assert.Equal(t, 4, result.SuccessCount)
+	assert.Equal(t, 1, result.ErrorCount)
+	assert.Greater(t, atomic.LoadInt32(&maxInFlight), int32(1))
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(3))
+}
+
+func withoutDuration(page PageResult) PageResult {
+	page.Duration = 0
+	return page
+}
@@ -0,0 +1,256 @@
+// Package gateway contains the payment gateway (PSP) adapters.
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/ports"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/money"
+)
+
+// Outcomes a simulator rule can force.
+const (
+	OutcomeSucceed = "succeed"
+	OutcomeDecline = "decline"
+	OutcomeTimeout = "timeout"
+)
+
+// Operations a simulator rule can be limited to.
+const (
+	OperationAuthorize = "authorize"
+	OperationCapture   = "capture"
+	OperationRefund    = "refund"
+)
+
+// SimulatorRule forces an outcome for operations matching a currency and an amount range.
+// The first matching rule wins; operations matching no rule succeed.
+type SimulatorRule struct {
+	Outcome string
+	// Currency limits the rule to one currency (empty = any).
+	Currency string
+	// MinAmount and MaxAmount are inclusive decimal bounds in the payment currency (empty = unbounded).
+	MinAmount string
+	MaxAmount string
+	// Operation limits the rule to one operation (empty = all).
+	Operation string
+}
+
+// SimulatorConfig configures the simulated provider.
+type SimulatorConfig struct {
+	Rules []SimulatorRule
+	// Latency delays every operation, to make timeouts and concurrency observable (default none).
+	Latency time.Duration
+}
+
+type simulatedPayment struct {
+	status string
+	amount money.Money
+}
+
+// Simulator is a deterministic in-memory payment provider for development and tests.
+// Outcomes depend only on the rules, the amount and the currency, never on chance.
+// State is kept per process, so a capture does not require an authorization made by this
+// process, e.g. by the REST server for a payment the cron updater captures.
+// Authorizations are deduplicated by their idempotency key like a real provider does.
+type Simulator struct {
+	config         SimulatorConfig
+	mu             sync.Mutex
+	payments       map[string]simulatedPayment
+	authorizations map[string]ports.GatewayResult
+}
+
+// NewSimulator creates a simulated provider.
+func NewSimulator(config SimulatorConfig) *Simulator {
+	return &Simulator{
+		config:         config,
+		payments:       map[string]simulatedPayment{},
+		authorizations: map[string]ports.GatewayResult{},
+	}
+}
+
+func (s *Simulator) Name() string {
+	return "simulator"
+}
+
+func (s *Simulator) Authorize(ctx context.Context, req ports.AuthorizeRequest) (result ports.GatewayResult, err error) {
+	if req.IdempotencyKey != "" {
+		s.mu.Lock()
+		previous, ok := s.authorizations[req.IdempotencyKey]
+		s.mu.Unlock()
+		if ok {
+			return previous, nil
+		}
+	}
+
+	reference := "sim_" + req.PaymentID
+	result, err = s.apply(ctx, OperationAuthorize, reference, req.Amount, ports.GatewayStatusAuthorized)
+	if err == nil && req.IdempotencyKey != "" {
+		s.mu.Lock()
+		s.authorizations[req.IdempotencyKey] = result
+		s.mu.Unlock()
+	}
+	return result, err
+}
+
+func (s *Simulator) Capture(ctx context.Context, reference string, amount money.Money) (result ports.GatewayResult, err error) {
+	return s.apply(ctx, OperationCapture, reference, amount, ports.GatewayStatusCaptured)
+}
+
+func (s *Simulator) Refund(ctx context.Context, reference string, amount money.Money) (result ports.GatewayResult, err error) {
+	s.mu.Lock()
+	current, ok := s.payments[reference]
+	s.mu.Unlock()
+	if !ok || current.status != ports.GatewayStatusCaptured {
+		return ports.GatewayResult{Reference: reference, Status: ports.GatewayStatusDeclined, DeclineReason: "payment is not captured"}, nil
+	}
+	return s.apply(ctx, OperationRefund, reference, amount, ports.GatewayStatusRefunded)
+}
+
+func (s *Simulator) GetStatus(ctx context.Context, reference string) (result ports.GatewayResult, err error) {
+	if err = s.wait(ctx); err != nil {
+		return ports.GatewayResult{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.payments[reference]
+	if !ok {
+		return ports.GatewayResult{}, errors.ErrDataNotFound
+	}
+	return ports.GatewayResult{Reference: reference, Status: current.status}, nil
+}
+
+// apply runs one operation: it waits for the configured latency, evaluates the rules and
+// records the new status when the operation succeeds.
+func (s *Simulator) apply(ctx context.Context, operation, reference string, amount money.Money, successStatus string) (result ports.GatewayResult, err error) {
+	if err = s.wait(ctx); err != nil {
+		return ports.GatewayResult{}, err
+	}
+
+	outcome, err := s.outcome(operation, amount)
+	if err != nil {
+		return ports.GatewayResult{}, err
+	}
+
+	switch outcome {
+	case OutcomeTimeout:
+		return ports.GatewayResult{}, ports.ErrGatewayTimeout
+	case OutcomeDecline:
+		s.record(reference, ports.GatewayStatusDeclined, amount)
+		return ports.GatewayResult{Reference: reference, Status: ports.GatewayStatusDeclined, DeclineReason: "declined by simulator rule"}, nil
+	}
+
+	s.record(reference, successStatus, amount)
+	return ports.GatewayResult{Reference: reference, Status: successStatus}, nil
+}
+
+func (s *Simulator) record(reference, status string, amount money.Money) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.payments[reference] = simulatedPayment{status: status, amount: amount}
+}
+
+func (s *Simulator) wait(ctx context.Context) error {
+	if s.config.Latency <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-ctx.Done():
+		return ports.ErrGatewayTimeout
+	case <-time.After(s.config.Latency):
+		return nil
+	}
+}
+
+func (s *Simulator) outcome(operation string, amount money.Money) (outcome string, err error) {
+	for _, rule := range s.config.Rules {
+		matches, err := rule.matches(operation, amount)
+		if err != nil {
+			return "", err
+		}
+		if matches {
+			return rule.Outcome, nil
+		}
+	}
+	return OutcomeSucceed, nil
+}
+
+func (r SimulatorRule) matches(operation string, amount money.Money) (bool, error) {
+	if r.Operation != "" && r.Operation != operation {
+		return false, nil
+	}
+	if r.Currency != "" && r.Currency != amount.Currency() {
+		return false, nil
+	}
+	for _, bound := range []struct {
+		value string
+		// sign is the comparison result that puts the amount outside the bound
+		sign int
+	}{{r.MinAmount, -1}, {r.MaxAmount, 1}} {
+		if bound.value == "" {
+			continue
+		}
+		limit, err := money.Parse(bound.value, amount.Currency())
+		if err != nil {
+			return false, fmt.Errorf("invalid simulator rule amount %q: %w", bound.value, err)
+		}
+		cmp, err := amount.Cmp(limit)
+		if err != nil {
+			return false, err
+		}
+		if cmp == bound.sign {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ParseSimulatorRules parses rules written as "outcome:currency:min-max[:operation]" separated by
+// semicolons, e.g. "decline:*:1000-;timeout:USD:13.13-13.13:capture". "*" or an empty currency
+// matches any currency and either amount bound may be left empty.
+func ParseSimulatorRules(spec string) (rules []SimulatorRule, err error) {
+	for _, raw := range strings.Split(spec, ";") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		parts := strings.Split(raw, ":")
+		if len(parts) < 3 || len(parts) > 4 {
+			return nil, fmt.Errorf("invalid simulator rule %q: expected outcome:currency:min-max[:operation]", raw)
+		}
+
+		rule := SimulatorRule{Outcome: parts[0], Currency: parts[1]}
+		switch rule.Outcome {
+		case OutcomeSucceed, OutcomeDecline, OutcomeTimeout:
+		default:
+			return nil, fmt.Errorf("invalid simulator rule %q: unknown outcome %q", raw, rule.Outcome)
+		}
+		if rule.Currency == "*" {
+			rule.Currency = ""
+		}
+
+		minAmount, maxAmount, found := strings.Cut(parts[2], "-")
+		if !found {
+			return nil, fmt.Errorf("invalid simulator rule %q: amount range must be min-max", raw)
+		}
+		rule.MinAmount, rule.MaxAmount = minAmount, maxAmount
+
+		if len(parts) == 4 {
+			rule.Operation = parts[3]
+			switch rule.Operation {
+			case OperationAuthorize, OperationCapture, OperationRefund:
+			default:
+				return nil, fmt.Errorf("invalid simulator rule %q: unknown operation %q", raw, rule.Operation)
+			}
+		}
+
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/ports"
+	pkgerrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/money"
+)
+
+func TestSimulator_Rules(t *testing.T) {
+	rules, err := ParseSimulatorRules("decline:*:1000-;timeout:USD:13.13-13.13:capture")
+	require.NoError(t, err)
+	sim := NewSimulator(SimulatorConfig{Rules: rules})
+
+	tests := []struct {
+		name           string
+		amount         money.Money
+		expectedStatus string
+		expectedError  error
+	}{
+		{name: "no matching rule succeeds", amount: money.MustParse("100.00", "USD"), expectedStatus: ports.GatewayStatusCaptured},
+		{name: "amount at the lower bound is declined", amount: money.MustParse("1000.00", "EUR"), expectedStatus: ports.GatewayStatusDeclined},
+		{name: "capture rule times out", amount: money.MustParse("13.13", "USD"), expectedError: ports.ErrGatewayTimeout},
+		{name: "capture rule is limited to its currency", amount: money.MustParse("13.13", "EUR"), expectedStatus: ports.GatewayStatusCaptured},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			auth, err := sim.Authorize(ctx, ports.AuthorizeRequest{PaymentID: "pay_1", Amount: tt.amount})
+			require.NoError(t, err)
+			assert.Equal(t, "sim_pay_1", auth.Reference)
+			if auth.Status == ports.GatewayStatusDeclined {
+				assert.Equal(t, tt.expectedStatus, auth.Status)
+				return
+			}
+
+			capture, err := sim.Capture(ctx, auth.Reference, tt.amount)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, capture.Status)
+		})
+	}
+}
+
+func TestSimulator_RefundAndStatus(t *testing.T) {
+	sim := NewSimulator(SimulatorConfig{})
+	ctx := context.Background()
+	amount := money.MustParse("50.00", "USD")
+
+	_, err := sim.GetStatus(ctx, "sim_unknown")
+	assert.ErrorIs(t, err, pkgerrors.ErrDataNotFound)
+
+	auth, err := sim.Authorize(ctx, ports.AuthorizeRequest{PaymentID: "pay_1", Amount: amount})
+	require.NoError(t, err)
+
+	refund, err := sim.Refund(ctx, auth.Reference, amount)
+	require.NoError(t, err)
+	assert.Equal(t, ports.GatewayStatusDeclined, refund.Status, "an authorized payment cannot be refunded")
+
+	_, err = sim.Capture(ctx, auth.Reference, amount)
+	require.NoError(t, err)
+	refund, err = sim.Refund(ctx, auth.Reference, amount)
+	require.NoError(t, err)
+	assert.Equal(t, ports.GatewayStatusRefunded, refund.Status)
+
+	status, err := sim.GetStatus(ctx, auth.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, ports.GatewayStatusRefunded, status.Status)
+}
+
+func TestSimulator_AuthorizeIsIdempotent(t *testing.T) {
+	sim := NewSimulator(SimulatorConfig{})
+	ctx := context.Background()
+	req := ports.AuthorizeRequest{PaymentID: "pay_1", Amount: money.MustParse("50.00", "USD"), IdempotencyKey: "auth_1"}
+
+	auth, err := sim.Authorize(ctx, req)
+	require.NoError(t, err)
+	_, err = sim.Capture(ctx, auth.Reference, req.Amount)
+	require.NoError(t, err)
+
+	// A retry with the same key answers with the first authorization and leaves the payment captured
+	retry, err := sim.Authorize(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, auth, retry)
+	status, err := sim.GetStatus(ctx, auth.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, ports.GatewayStatusCaptured, status.Status)
+}
+
+func TestSimulator_LatencyHonoursContext(t *testing.T) {
+	sim := NewSimulator(SimulatorConfig{Latency: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := sim.Authorize(ctx, ports.AuthorizeRequest{PaymentID: "pay_1", Amount: money.MustParse("1.00", "USD")})
+	assert.ErrorIs(t, err, ports.ErrGatewayTimeout)
+}
+
+func TestParseSimulatorRules(t *testing.T) {
+	rules, err := ParseSimulatorRules(" succeed:JPY:-500 ; timeout:*:-:authorize ")
+	require.NoError(t, err)
+	assert.Equal(t, []SimulatorRule{
+		{Outcome: OutcomeSucceed, Currency: "JPY", MaxAmount: "500"},
+		{Outcome: OutcomeTimeout, Operation: OperationAuthorize},
+	}, rules)
+
+	for _, spec := range []string{"explode:*:-", "decline:*", "decline:*:100", "decline:*:-:void"} {
+		_, err = ParseSimulatorRules(spec)
+		assert.Error(t, err, spec)
+	}
+}
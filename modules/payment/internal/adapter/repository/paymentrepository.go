@@ -1,7 +1,12 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -10,10 +15,15 @@ import (
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/ports"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/dbutils"
-	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	pkgerrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/money"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/transaction"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/uniqueid"
 )
 
+var paymentColumns = []string{"id", "tenant_id", "amount", "currency", "status", "version", "provider", "provider_reference", "authorization_key", "created_at", "updated_at"}
+
 type paymentRepository struct {
 	db *sql.DB
 }
@@ -28,7 +38,32 @@ func (r *paymentRepository) qb() sq.StatementBuilderType {
 	return sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 }
 
-func (r *paymentRepository) CreatePayment(p *payment.Payment) (err error) {
+// conn returns the transaction carried by ctx, so the repository joins a unit of work when there is one.
+func (r *paymentRepository) conn(ctx context.Context) transaction.DBTX {
+	return transaction.Executor(ctx, r.db)
+}
+
+// scanPayment reads a payment row. Amounts are scanned as decimal strings so the
+// DECIMAL column never goes through floating point.
+func (r *paymentRepository) scanPayment(row sq.RowScanner) (p payment.Payment, err error) {
+	var amount, currency string
+	if err = row.Scan(&p.ID, &p.TenantID, &amount, &currency, &p.Status, &p.Version, &p.Provider, &p.ProviderReference, &p.AuthorizationKey, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return payment.Payment{}, err
+	}
+
+	p.Amount, err = money.Parse(amount, currency)
+	if err != nil {
+		return payment.Payment{}, fmt.Errorf("invalid amount stored for payment %s: %w", p.ID, err)
+	}
+
+	return p, nil
+}
+
+func (r *paymentRepository) CreatePayment(ctx context.Context, p *payment.Payment) (err error) {
+	p.TenantID, err = tenant.Required(ctx)
+	if err != nil {
+		return err
+	}
 	p.ID, err = uniqueid.GeneratePK("pay")
 	if err != nil {
 		return err
@@ -37,12 +72,13 @@ func (r *paymentRepository) CreatePayment(p *payment.Payment) (err error) {
 	now := time.Now()
 	p.CreatedAt = now
 	p.UpdatedAt = now
+	p.Version = 1
 
 	_, err = r.qb().Insert("payment_module.payments").
-		Columns("id", "amount", "currency", "status", "created_at", "updated_at").
-		Values(p.ID, p.Amount, p.Currency, p.Status, p.CreatedAt, p.UpdatedAt).
-		RunWith(r.db).
-		Exec()
+		Columns(paymentColumns...).
+		Values(p.ID, p.TenantID, p.Amount.String(), p.Currency(), p.Status, p.Version, p.Provider, p.ProviderReference, p.AuthorizationKey, p.CreatedAt, p.UpdatedAt).
+		RunWith(r.conn(ctx)).
+		ExecContext(ctx)
 	if err != nil {
 		return dbutils.HandlePostgresError(err)
 	}
@@ -50,13 +86,19 @@ func (r *paymentRepository) CreatePayment(p *payment.Payment) (err error) {
 	return nil
 }
 
-func (r *paymentRepository) GetPayment(id string) (p payment.Payment, err error) {
-	err = r.qb().Select("id", "amount", "currency", "status", "created_at", "updated_at").
+func (r *paymentRepository) GetPayment(ctx context.Context, id string) (p payment.Payment, err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return payment.Payment{}, err
+	}
+
+	// A payment of another tenant is not found, even by its ID
+	p, err = r.scanPayment(r.qb().Select(paymentColumns...).
 		From("payment_module.payments").
 		Where(sq.Eq{"id": id}).
-		RunWith(r.db).
-		QueryRow().
-		Scan(&p.ID, &p.Amount, &p.Currency, &p.Status, &p.CreatedAt, &p.UpdatedAt)
+		Where(tenantFilter).
+		RunWith(r.conn(ctx)).
+		QueryRowContext(ctx))
 	if err != nil {
 		return payment.Payment{}, dbutils.HandlePostgresError(err)
 	}
@@ -64,10 +106,41 @@ func (r *paymentRepository) GetPayment(id string) (p payment.Payment, err error)
 	return p, nil
 }
 
-func (r *paymentRepository) FetchPayments(params payment.FetchPaymentsParams) (result []payment.Payment, nextCursor string, err error) {
-	query := r.qb().Select("id", "amount", "currency", "status", "created_at", "updated_at").
+func (r *paymentRepository) GetPaymentByProviderReference(ctx context.Context, provider, reference string) (p payment.Payment, err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return payment.Payment{}, err
+	}
+
+	p, err = r.scanPayment(r.qb().Select(paymentColumns...).
 		From("payment_module.payments").
-		OrderBy("id DESC")
+		Where(sq.Eq{"provider": provider, "provider_reference": reference}).
+		Where(tenantFilter).
+		RunWith(r.conn(ctx)).
+		QueryRowContext(ctx))
+	if err != nil {
+		return payment.Payment{}, dbutils.HandlePostgresError(err)
+	}
+
+	return p, nil
+}
+
+func (r *paymentRepository) FetchPayments(ctx context.Context, params payment.FetchPaymentsParams) (result []payment.Payment, nextCursor string, err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	oldestFirst := params.SortOrder == payment.SortOldestFirst
+
+	// ULIDs are time-ordered, so sorting by id sorts by creation time
+	query := r.qb().Select(paymentColumns...).
+		From("payment_module.payments").
+		Where(tenantFilter)
+	if oldestFirst {
+		query = query.OrderBy("id ASC")
+	} else {
+		query = query.OrderBy("id DESC")
+	}
 
 	// Apply cursor-based pagination using ULID
 	if params.Cursor != "" {
@@ -75,7 +148,11 @@ func (r *paymentRepository) FetchPayments(params payment.FetchPaymentsParams) (r
 		if decodeErr != nil {
 			return nil, "", decodeErr
 		}
-		query = query.Where(sq.Lt{"id": cursorID})
+		if oldestFirst {
+			query = query.Where(sq.Gt{"id": cursorID})
+		} else {
+			query = query.Where(sq.Lt{"id": cursorID})
+		}
 	}
 
 	if params.Currency != "" {
@@ -89,9 +166,9 @@ func (r *paymentRepository) FetchPayments(params payment.FetchPaymentsParams) (r
 	// Fetch one extra to determine if there's a next page
 	query = query.Limit(uint64(params.Limit + 1))
 
-	rows, err := query.RunWith(r.db).Query()
+	rows, err := query.RunWith(r.conn(ctx)).QueryContext(ctx)
 	if err != nil {
-		return nil, "", err
+		return nil, "", dbutils.HandlePostgresError(err)
 	}
 	defer func() {
 		errClose := rows.Close()
@@ -102,15 +179,15 @@ func (r *paymentRepository) FetchPayments(params payment.FetchPaymentsParams) (r
 
 	result = make([]payment.Payment, 0)
 	for rows.Next() {
-		var p payment.Payment
-		if err := rows.Scan(&p.ID, &p.Amount, &p.Currency, &p.Status, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		p, err := r.scanPayment(rows)
+		if err != nil {
 			return nil, "", err
 		}
 		result = append(result, p)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, "", err
+		return nil, "", dbutils.HandlePostgresError(err)
 	}
 
 	// Check if there are more results
@@ -124,17 +201,56 @@ func (r *paymentRepository) FetchPayments(params payment.FetchPaymentsParams) (r
 	return result, nextCursor, nil
 }
 
-func (r *paymentRepository) UpdatePayment(p *payment.Payment) (err error) {
+// UpdatePayment compare-and-swaps on the version: the row is only updated when p.Version
+// still matches (unless it is 0) and p.Version is set to the new version.
+func (r *paymentRepository) UpdatePayment(ctx context.Context, p *payment.Payment) (err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return err
+	}
 	p.UpdatedAt = time.Now()
 
-	result, err := r.qb().Update("payment_module.payments").
-		Set("amount", p.Amount).
-		Set("currency", p.Currency).
+	query := r.qb().Update("payment_module.payments").
+		Set("amount", p.Amount.String()).
+		Set("currency", p.Currency()).
 		Set("status", p.Status).
+		Set("provider", p.Provider).
+		Set("provider_reference", p.ProviderReference).
+		Set("authorization_key", p.AuthorizationKey).
+		Set("version", sq.Expr("version + 1")).
 		Set("updated_at", p.UpdatedAt).
 		Where(sq.Eq{"id": p.ID}).
-		RunWith(r.db).
-		Exec()
+		Where(tenantFilter).
+		Suffix("RETURNING version")
+	if p.Version != 0 {
+		query = query.Where(sq.Eq{"version": p.Version})
+	}
+
+	err = query.RunWith(r.conn(ctx)).QueryRowContext(ctx).Scan(&p.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r.noRowsAffectedError(ctx, p.ID)
+	}
+	if err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+
+	return nil
+}
+
+func (r *paymentRepository) DeletePayment(ctx context.Context, id string, expectedVersion int64) (err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := r.qb().Delete("payment_module.payments").
+		Where(sq.Eq{"id": id}).
+		Where(tenantFilter)
+	if expectedVersion != 0 {
+		query = query.Where(sq.Eq{"version": expectedVersion})
+	}
+
+	result, err := query.RunWith(r.conn(ctx)).ExecContext(ctx)
 	if err != nil {
 		return dbutils.HandlePostgresError(err)
 	}
@@ -145,17 +261,115 @@ func (r *paymentRepository) UpdatePayment(p *payment.Payment) (err error) {
 	}
 
 	if rowsAffected == 0 {
-		return errors.ErrDataNotFound
+		return r.noRowsAffectedError(ctx, id)
 	}
 
 	return nil
 }
 
-func (r *paymentRepository) DeletePayment(id string) (err error) {
-	result, err := r.qb().Delete("payment_module.payments").
+// ClaimPendingPayments leases the oldest claimable pending payments to params.Owner in a single statement.
+// Processing payments are claimable too: one that is not leased was left with an unresolved capture.
+// FOR UPDATE SKIP LOCKED lets concurrent claimers pass over each other's rows instead of blocking,
+// and payments whose lease has expired are claimable again. Deferred payments wait for their next attempt.
+func (r *paymentRepository) ClaimPendingPayments(ctx context.Context, params payment.ClaimPaymentsParams) (result []payment.Payment, nextCursor string, err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+
+	// The subquery keeps the default "?" placeholders; the outer statement renumbers them to $n
+	claimable := sq.Select("id").
+		From("payment_module.payments").
+		Where(tenantFilter).
+		Where(sq.Eq{"status": []string{payment.StatusPending, payment.StatusProcessing}}).
+		Where(sq.Or{sq.Eq{"lease_expires_at": nil}, sq.LtOrEq{"lease_expires_at": now}}).
+		Where(sq.Or{sq.Eq{"next_attempt_at": nil}, sq.LtOrEq{"next_attempt_at": now}}).
+		OrderBy("id ASC").
+		Limit(uint64(params.Limit)).
+		Suffix("FOR UPDATE SKIP LOCKED")
+
+	if params.Cursor != "" {
+		cursorID, decodeErr := dbutils.DecodeCursor(params.Cursor)
+		if decodeErr != nil {
+			return nil, "", decodeErr
+		}
+		claimable = claimable.Where(sq.Gt{"id": cursorID})
+	}
+
+	rows, err := r.qb().Update("payment_module.payments").
+		Set("claimed_by", params.Owner).
+		Set("lease_expires_at", now.Add(params.LeaseDuration)).
+		Where(sq.Expr("id IN (?)", claimable)).
+		Suffix("RETURNING " + strings.Join(paymentColumns, ", ")).
+		RunWith(r.conn(ctx)).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, "", dbutils.HandlePostgresError(err)
+	}
+	defer func() {
+		errClose := rows.Close()
+		if errClose != nil {
+			log.Error().Err(errClose).Msg("failed to close rows")
+		}
+	}()
+
+	result = make([]payment.Payment, 0, params.Limit)
+	for rows.Next() {
+		p, err := r.scanPayment(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		result = append(result, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", dbutils.HandlePostgresError(err)
+	}
+
+	// RETURNING does not preserve the subquery order
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	// A full batch means there may be more to claim after the last one
+	if len(result) > 0 && len(result) == params.Limit {
+		nextCursor = dbutils.EncodeCursor(result[len(result)-1].ID)
+	}
+
+	return result, nextCursor, nil
+}
+
+func (r *paymentRepository) ReleasePaymentClaims(ctx context.Context, owner string) (err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.qb().Update("payment_module.payments").
+		Set("claimed_by", nil).
+		Set("lease_expires_at", nil).
+		Where(sq.Eq{"claimed_by": owner}).
+		Where(tenantFilter).
+		RunWith(r.conn(ctx)).
+		ExecContext(ctx)
+	if err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+
+	return nil
+}
+
+func (r *paymentRepository) DeferPayment(ctx context.Context, id string, until time.Time) (err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.qb().Update("payment_module.payments").
+		Set("next_attempt_at", until).
 		Where(sq.Eq{"id": id}).
-		RunWith(r.db).
-		Exec()
+		Where(tenantFilter).
+		RunWith(r.conn(ctx)).
+		ExecContext(ctx)
 	if err != nil {
 		return dbutils.HandlePostgresError(err)
 	}
@@ -166,8 +380,33 @@ func (r *paymentRepository) DeletePayment(id string) (err error) {
 	}
 
 	if rowsAffected == 0 {
-		return errors.ErrDataNotFound
+		return pkgerrors.ErrDataNotFound
 	}
 
 	return nil
 }
+
+// noRowsAffectedError tells a missing payment apart from a stale version after a versioned write matched nothing.
+func (r *paymentRepository) noRowsAffectedError(ctx context.Context, id string) (err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return err
+	}
+
+	var count int
+	err = r.qb().Select("COUNT(1)").
+		From("payment_module.payments").
+		Where(sq.Eq{"id": id}).
+		Where(tenantFilter).
+		RunWith(r.conn(ctx)).
+		QueryRowContext(ctx).
+		Scan(&count)
+	if err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+
+	if count == 0 {
+		return pkgerrors.ErrDataNotFound
+	}
+	return pkgerrors.ErrPreconditionFailed
+}
@@ -1,7 +1,12 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -9,7 +14,10 @@ import (
 
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
 	pkgerrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/money"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/testutils"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/transaction"
 )
 
 type PaymentRepositoryTestSuite struct {
@@ -44,18 +52,16 @@ func (s *PaymentRepositoryTestSuite) TestCreatePayment() {
 		{
 			name: "successful payment creation",
 			payment: &payment.Payment{
-				Amount:   100.50,
-				Currency: "USD",
-				Status:   "pending",
+				Amount: money.MustParse("100.50", "USD"),
+				Status: "pending",
 			},
 			expectError: false,
 		},
 		{
 			name: "create payment with different currency",
 			payment: &payment.Payment{
-				Amount:   250.00,
-				Currency: "EUR",
-				Status:   "completed",
+				Amount: money.MustParse("250.00", "EUR"),
+				Status: "completed",
 			},
 			expectError: false,
 		},
@@ -63,7 +69,7 @@ func (s *PaymentRepositoryTestSuite) TestCreatePayment() {
 
 	for _, tt := range tests {
 		s.Run(tt.name, func() {
-			err := s.repo.CreatePayment(tt.payment)
+			err := s.repo.CreatePayment(testutils.TenantContext(), tt.payment)
 
 			if tt.expectError {
 				assert.Error(s.T(), err)
@@ -79,11 +85,10 @@ func (s *PaymentRepositoryTestSuite) TestCreatePayment() {
 
 func (s *PaymentRepositoryTestSuite) TestGetPayment() {
 	createdPayment := &payment.Payment{
-		Amount:   100.50,
-		Currency: "USD",
-		Status:   "pending",
+		Amount: money.MustParse("100.50", "USD"),
+		Status: "pending",
 	}
-	err := s.repo.CreatePayment(createdPayment)
+	err := s.repo.CreatePayment(testutils.TenantContext(), createdPayment)
 	require.NoError(s.T(), err)
 
 	tests := []struct {
@@ -107,7 +112,7 @@ func (s *PaymentRepositoryTestSuite) TestGetPayment() {
 
 	for _, tt := range tests {
 		s.Run(tt.name, func() {
-			result, err := s.repo.GetPayment(tt.paymentID)
+			result, err := s.repo.GetPayment(testutils.TenantContext(), tt.paymentID)
 
 			if tt.expectError {
 				require.Error(s.T(), err)
@@ -118,22 +123,67 @@ func (s *PaymentRepositoryTestSuite) TestGetPayment() {
 				require.NoError(s.T(), err)
 				assert.Equal(s.T(), createdPayment.ID, result.ID)
 				assert.Equal(s.T(), createdPayment.Amount, result.Amount)
-				assert.Equal(s.T(), createdPayment.Currency, result.Currency)
+				assert.Equal(s.T(), createdPayment.Currency(), result.Currency())
 				assert.Equal(s.T(), createdPayment.Status, result.Status)
 			}
 		})
 	}
 }
 
+func (s *PaymentRepositoryTestSuite) TestGetPaymentByProviderReference() {
+	ctx := testutils.TenantContext()
+	p := &payment.Payment{
+		Amount:            money.MustParse("100.50", "USD"),
+		Status:            "pending",
+		Provider:          "simulator",
+		ProviderReference: "sim_ref_1",
+	}
+	require.NoError(s.T(), s.repo.CreatePayment(ctx, p))
+
+	found, err := s.repo.GetPaymentByProviderReference(ctx, "simulator", "sim_ref_1")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), p.ID, found.ID)
+
+	_, err = s.repo.GetPaymentByProviderReference(ctx, "other", "sim_ref_1")
+	assert.Equal(s.T(), pkgerrors.ErrDataNotFound, err)
+}
+
+func (s *PaymentRepositoryTestSuite) TestContextCancelled() {
+	ctx, cancel := context.WithCancel(testutils.TenantContext())
+	cancel()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "CreatePayment", call: func() error {
+			return s.repo.CreatePayment(ctx, &payment.Payment{Amount: money.MustParse("100.00", "USD"), Status: "pending"})
+		}},
+		{name: "GetPayment", call: func() error {
+			_, err := s.repo.GetPayment(ctx, "pay_any")
+			return err
+		}},
+		{name: "noRowsAffectedError", call: func() error {
+			return s.repo.noRowsAffectedError(ctx, "pay_any")
+		}},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			assert.Equal(s.T(), pkgerrors.ErrRequestTimeout, tt.call())
+		})
+	}
+}
+
 func (s *PaymentRepositoryTestSuite) TestFetchPayments() {
 	payments := []*payment.Payment{
-		{Amount: 100.00, Currency: "USD", Status: "pending"},
-		{Amount: 200.00, Currency: "USD", Status: "completed"},
-		{Amount: 300.00, Currency: "EUR", Status: "pending"},
+		{Amount: money.MustParse("100.00", "USD"), Status: "pending"},
+		{Amount: money.MustParse("200.00", "USD"), Status: "completed"},
+		{Amount: money.MustParse("300.00", "EUR"), Status: "pending"},
 	}
 
 	for _, p := range payments {
-		err := s.repo.CreatePayment(p)
+		err := s.repo.CreatePayment(testutils.TenantContext(), p)
 		require.NoError(s.T(), err)
 	}
 
@@ -191,7 +241,7 @@ func (s *PaymentRepositoryTestSuite) TestFetchPayments() {
 
 	for _, tt := range tests {
 		s.Run(tt.name, func() {
-			result, cursor, err := s.repo.FetchPayments(tt.params)
+			result, cursor, err := s.repo.FetchPayments(testutils.TenantContext(), tt.params)
 
 			require.NoError(s.T(), err)
 			assert.Len(s.T(), result, tt.expectedCount)
@@ -208,20 +258,19 @@ func (s *PaymentRepositoryTestSuite) TestFetchPayments() {
 func (s *PaymentRepositoryTestSuite) TestFetchPayments_Pagination() {
 	for i := 0; i < 5; i++ {
 		p := &payment.Payment{
-			Amount:   float64(100 * (i + 1)),
-			Currency: "USD",
-			Status:   "pending",
+			Amount: money.MustParse(strconv.Itoa(100*(i+1)), "USD"),
+			Status: "pending",
 		}
-		err := s.repo.CreatePayment(p)
+		err := s.repo.CreatePayment(testutils.TenantContext(), p)
 		require.NoError(s.T(), err)
 	}
 
-	firstPage, cursor, err := s.repo.FetchPayments(payment.FetchPaymentsParams{Limit: 2})
+	firstPage, cursor, err := s.repo.FetchPayments(testutils.TenantContext(), payment.FetchPaymentsParams{Limit: 2})
 	require.NoError(s.T(), err)
 	assert.Len(s.T(), firstPage, 2)
 	assert.NotEmpty(s.T(), cursor)
 
-	secondPage, cursor2, err := s.repo.FetchPayments(payment.FetchPaymentsParams{
+	secondPage, cursor2, err := s.repo.FetchPayments(testutils.TenantContext(), payment.FetchPaymentsParams{
 		Limit:  2,
 		Cursor: cursor,
 	})
@@ -233,13 +282,152 @@ func (s *PaymentRepositoryTestSuite) TestFetchPayments_Pagination() {
 	assert.NotEqual(s.T(), firstPage[1].ID, secondPage[1].ID)
 }
 
+func (s *PaymentRepositoryTestSuite) TestFetchPayments_OldestFirst() {
+	created := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		p := &payment.Payment{
+			Amount: money.MustParse(strconv.Itoa(100*(i+1)), "USD"),
+			Status: "pending",
+		}
+		err := s.repo.CreatePayment(testutils.TenantContext(), p)
+		require.NoError(s.T(), err)
+		created = append(created, p.ID)
+	}
+
+	walked := make([]string, 0, 5)
+	cursor := ""
+	for {
+		page, next, err := s.repo.FetchPayments(testutils.TenantContext(), payment.FetchPaymentsParams{
+			Limit:     2,
+			Cursor:    cursor,
+			SortOrder: payment.SortOldestFirst,
+		})
+		require.NoError(s.T(), err)
+		for _, p := range page {
+			walked = append(walked, p.ID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	// IDs created within the same millisecond are not ordered, so compare against the sorted IDs
+	sort.Strings(created)
+	assert.Equal(s.T(), created, walked)
+}
+
+func (s *PaymentRepositoryTestSuite) TestClaimPendingPayments() {
+	for i := 0; i < 4; i++ {
+		err := s.repo.CreatePayment(testutils.TenantContext(), &payment.Payment{
+			Amount: money.MustParse("10.00", "USD"),
+			Status: "pending",
+		})
+		require.NoError(s.T(), err)
+	}
+	claim := func(owner string, limit int, lease time.Duration) []string {
+		claimed, _, err := s.repo.ClaimPendingPayments(testutils.TenantContext(), payment.ClaimPaymentsParams{
+			Owner:         owner,
+			Limit:         limit,
+			LeaseDuration: lease,
+		})
+		require.NoError(s.T(), err)
+		ids := make([]string, len(claimed))
+		for i, p := range claimed {
+			ids[i] = p.ID
+		}
+		return ids
+	}
+
+	s.Run("workers claim disjoint sets", func() {
+		first := claim("worker-a", 2, time.Minute)
+		second := claim("worker-b", 10, time.Minute)
+
+		assert.Len(s.T(), first, 2)
+		assert.Len(s.T(), second, 2)
+		for _, id := range second {
+			assert.NotContains(s.T(), first, id)
+		}
+		assert.Empty(s.T(), claim("worker-c", 10, time.Minute))
+	})
+
+	s.Run("released claims can be claimed again", func() {
+		require.NoError(s.T(), s.repo.ReleasePaymentClaims(testutils.TenantContext(), "worker-a"))
+		assert.Len(s.T(), claim("worker-c", 10, time.Millisecond), 2)
+	})
+
+	s.Run("expired leases can be claimed again", func() {
+		time.Sleep(5 * time.Millisecond)
+		assert.Len(s.T(), claim("worker-d", 10, time.Minute), 2)
+	})
+
+	s.Run("concurrent claims never overlap", func() {
+		require.NoError(s.T(), s.repo.ReleasePaymentClaims(testutils.TenantContext(), "worker-b"))
+		require.NoError(s.T(), s.repo.ReleasePaymentClaims(testutils.TenantContext(), "worker-d"))
+
+		results := make(chan []string, 4)
+		for i := 0; i < 4; i++ {
+			go func(owner string) {
+				results <- claim(owner, 1, time.Minute)
+			}("worker-" + strconv.Itoa(i))
+		}
+		seen := map[string]bool{}
+		for i := 0; i < 4; i++ {
+			for _, id := range <-results {
+				assert.False(s.T(), seen[id], "payment %s claimed twice", id)
+				seen[id] = true
+			}
+		}
+	})
+}
+
+func (s *PaymentRepositoryTestSuite) TestClaimPendingPayments_UnresolvedProcessing() {
+	ctx := testutils.TenantContext()
+	ids := map[string]string{}
+	for _, status := range []string{payment.StatusPending, payment.StatusProcessing, payment.StatusCompleted} {
+		p := &payment.Payment{Amount: money.MustParse("10.00", "USD"), Status: status}
+		require.NoError(s.T(), s.repo.CreatePayment(ctx, p))
+		ids[status] = p.ID
+	}
+
+	claimed, _, err := s.repo.ClaimPendingPayments(ctx, payment.ClaimPaymentsParams{Owner: "worker-a", Limit: 10, LeaseDuration: time.Minute})
+	require.NoError(s.T(), err)
+
+	claimedIDs := make([]string, len(claimed))
+	for i, p := range claimed {
+		claimedIDs[i] = p.ID
+	}
+	assert.ElementsMatch(s.T(), []string{ids[payment.StatusPending], ids[payment.StatusProcessing]}, claimedIDs)
+}
+
+func (s *PaymentRepositoryTestSuite) TestClaimPendingPayments_SkipsDeferred() {
+	ctx := testutils.TenantContext()
+	ids := make([]string, 3)
+	for i := range ids {
+		p := &payment.Payment{Amount: money.MustParse("10.00", "USD"), Status: payment.StatusPending}
+		require.NoError(s.T(), s.repo.CreatePayment(ctx, p))
+		ids[i] = p.ID
+	}
+
+	// The oldest payment failed and waits for its next attempt, the second one is due again
+	require.NoError(s.T(), s.repo.DeferPayment(ctx, ids[0], time.Now().Add(time.Hour)))
+	require.NoError(s.T(), s.repo.DeferPayment(ctx, ids[1], time.Now().Add(-time.Minute)))
+
+	claimed, _, err := s.repo.ClaimPendingPayments(ctx, payment.ClaimPaymentsParams{Owner: "worker-a", Limit: 1, LeaseDuration: time.Minute})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), claimed, 1)
+	assert.Equal(s.T(), ids[1], claimed[0].ID)
+
+	err = s.repo.DeferPayment(ctx, "unknown", time.Now())
+	assert.ErrorIs(s.T(), err, pkgerrors.ErrDataNotFound)
+}
+
 func (s *PaymentRepositoryTestSuite) TestUpdatePayment() {
 	createdPayment := &payment.Payment{
-		Amount:   100.50,
-		Currency: "USD",
-		Status:   "pending",
+		Amount: money.MustParse("100.50", "USD"),
+		Status: "pending",
 	}
-	err := s.repo.CreatePayment(createdPayment)
+	err := s.repo.CreatePayment(testutils.TenantContext(), createdPayment)
 	require.NoError(s.T(), err)
 
 	tests := []struct {
@@ -251,20 +439,32 @@ func (s *PaymentRepositoryTestSuite) TestUpdatePayment() {
 		{
 			name: "successful payment update",
 			payment: &payment.Payment{
-				ID:       createdPayment.ID,
-				Amount:   150.00,
-				Currency: "EUR",
-				Status:   "completed",
+				ID:                createdPayment.ID,
+				Amount:            money.MustParse("150.00", "EUR"),
+				Status:            "completed",
+				Version:           1,
+				Provider:          "simulator",
+				ProviderReference: "sim_ref",
 			},
 			expectError: false,
 		},
+		{
+			name: "stale version",
+			payment: &payment.Payment{
+				ID:      createdPayment.ID,
+				Amount:  money.MustParse("175.00", "EUR"),
+				Status:  "completed",
+				Version: 1,
+			},
+			expectError: true,
+			expectedErr: pkgerrors.ErrPreconditionFailed,
+		},
 		{
 			name: "payment not found",
 			payment: &payment.Payment{
-				ID:       "pay_nonexistent",
-				Amount:   150.00,
-				Currency: "EUR",
-				Status:   "completed",
+				ID:     "pay_nonexistent",
+				Amount: money.MustParse("150.00", "EUR"),
+				Status: "completed",
 			},
 			expectError: true,
 			expectedErr: pkgerrors.ErrDataNotFound,
@@ -273,7 +473,7 @@ func (s *PaymentRepositoryTestSuite) TestUpdatePayment() {
 
 	for _, tt := range tests {
 		s.Run(tt.name, func() {
-			err := s.repo.UpdatePayment(tt.payment)
+			err := s.repo.UpdatePayment(testutils.TenantContext(), tt.payment)
 
 			if tt.expectError {
 				require.Error(s.T(), err)
@@ -283,11 +483,15 @@ func (s *PaymentRepositoryTestSuite) TestUpdatePayment() {
 			} else {
 				require.NoError(s.T(), err)
 
-				updated, err := s.repo.GetPayment(tt.payment.ID)
+				updated, err := s.repo.GetPayment(testutils.TenantContext(), tt.payment.ID)
 				require.NoError(s.T(), err)
 				assert.Equal(s.T(), tt.payment.Amount, updated.Amount)
-				assert.Equal(s.T(), tt.payment.Currency, updated.Currency)
+				assert.Equal(s.T(), tt.payment.Currency(), updated.Currency())
 				assert.Equal(s.T(), tt.payment.Status, updated.Status)
+				assert.Equal(s.T(), tt.payment.Provider, updated.Provider)
+				assert.Equal(s.T(), tt.payment.ProviderReference, updated.ProviderReference)
+				assert.Equal(s.T(), int64(2), updated.Version)
+				assert.Equal(s.T(), updated.Version, tt.payment.Version)
 			}
 		})
 	}
@@ -295,22 +499,30 @@ func (s *PaymentRepositoryTestSuite) TestUpdatePayment() {
 
 func (s *PaymentRepositoryTestSuite) TestDeletePayment() {
 	createdPayment := &payment.Payment{
-		Amount:   100.50,
-		Currency: "USD",
-		Status:   "pending",
+		Amount: money.MustParse("100.50", "USD"),
+		Status: "pending",
 	}
-	err := s.repo.CreatePayment(createdPayment)
+	err := s.repo.CreatePayment(testutils.TenantContext(), createdPayment)
 	require.NoError(s.T(), err)
 
 	tests := []struct {
 		name        string
 		paymentID   string
+		version     int64
 		expectError bool
 		expectedErr error
 	}{
+		{
+			name:        "stale version",
+			paymentID:   createdPayment.ID,
+			version:     2,
+			expectError: true,
+			expectedErr: pkgerrors.ErrPreconditionFailed,
+		},
 		{
 			name:        "successful payment deletion",
 			paymentID:   createdPayment.ID,
+			version:     1,
 			expectError: false,
 		},
 		{
@@ -323,7 +535,7 @@ func (s *PaymentRepositoryTestSuite) TestDeletePayment() {
 
 	for _, tt := range tests {
 		s.Run(tt.name, func() {
-			err := s.repo.DeletePayment(tt.paymentID)
+			err := s.repo.DeletePayment(testutils.TenantContext(), tt.paymentID, tt.version)
 
 			if tt.expectError {
 				require.Error(s.T(), err)
@@ -333,13 +545,88 @@ func (s *PaymentRepositoryTestSuite) TestDeletePayment() {
 			} else {
 				require.NoError(s.T(), err)
 
-				_, err := s.repo.GetPayment(tt.paymentID)
+				_, err := s.repo.GetPayment(testutils.TenantContext(), tt.paymentID)
 				assert.Equal(s.T(), pkgerrors.ErrDataNotFound, err)
 			}
 		})
 	}
 }
 
+func (s *PaymentRepositoryTestSuite) TestTenantIsolation() {
+	ctx := testutils.TenantContext()
+	own := &payment.Payment{Amount: money.MustParse("100.50", "USD"), Status: "pending"}
+	require.NoError(s.T(), s.repo.CreatePayment(ctx, own))
+	assert.Equal(s.T(), testutils.TestTenantID, own.TenantID)
+
+	other := tenant.WithID(context.Background(), "other_tenant")
+	foreign := &payment.Payment{Amount: money.MustParse("20.00", "USD"), Status: "pending"}
+	require.NoError(s.T(), s.repo.CreatePayment(other, foreign))
+
+	// A guessed ID of another tenant's payment is not found, for reads and writes alike
+	_, err := s.repo.GetPayment(ctx, foreign.ID)
+	assert.Equal(s.T(), pkgerrors.ErrDataNotFound, err)
+	update := *foreign
+	update.Status = payment.StatusProcessing
+	assert.Equal(s.T(), pkgerrors.ErrDataNotFound, s.repo.UpdatePayment(ctx, &update))
+	assert.Equal(s.T(), pkgerrors.ErrDataNotFound, s.repo.DeletePayment(ctx, foreign.ID, 0))
+
+	result, _, err := s.repo.FetchPayments(ctx, payment.FetchPaymentsParams{Limit: 10})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), result, 1)
+	assert.Equal(s.T(), own.ID, result[0].ID)
+
+	_, err = s.repo.GetPayment(context.Background(), own.ID)
+	assert.ErrorIs(s.T(), err, tenant.ErrRequired)
+
+	// Jobs acting for every tenant see every payment
+	result, _, err = s.repo.FetchPayments(tenant.WithAllTenants(context.Background()), payment.FetchPaymentsParams{Limit: 10})
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), result, 2)
+}
+
+func (s *PaymentRepositoryTestSuite) TestWithinTransaction() {
+	txManager := transaction.NewManager(s.pgContainer.DB, transaction.Config{})
+	errAbort := errors.New("abort")
+
+	s.Run("commit makes writes visible", func() {
+		created := &payment.Payment{Amount: money.MustParse("10.00", "USD"), Status: "pending"}
+		err := txManager.WithinTransaction(testutils.TenantContext(), func(ctx context.Context) error {
+			return s.repo.CreatePayment(ctx, created)
+		})
+		require.NoError(s.T(), err)
+
+		_, err = s.repo.GetPayment(testutils.TenantContext(), created.ID)
+		assert.NoError(s.T(), err)
+	})
+
+	s.Run("error rolls back", func() {
+		created := &payment.Payment{Amount: money.MustParse("10.00", "USD"), Status: "pending"}
+		err := txManager.WithinTransaction(testutils.TenantContext(), func(ctx context.Context) error {
+			require.NoError(s.T(), s.repo.CreatePayment(ctx, created))
+			return errAbort
+		})
+		require.ErrorIs(s.T(), err, errAbort)
+
+		_, err = s.repo.GetPayment(testutils.TenantContext(), created.ID)
+		assert.Equal(s.T(), pkgerrors.ErrDataNotFound, err)
+	})
+
+	s.Run("nested call joins the outer transaction", func() {
+		created := &payment.Payment{Amount: money.MustParse("10.00", "USD"), Status: "pending"}
+		err := txManager.WithinTransaction(testutils.TenantContext(), func(ctx context.Context) error {
+			err := txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+				return s.repo.CreatePayment(ctx, created)
+			})
+			require.NoError(s.T(), err)
+			return errAbort
+		})
+		require.ErrorIs(s.T(), err, errAbort)
+
+		_, err = s.repo.GetPayment(testutils.TenantContext(), created.ID)
+		assert.Equal(s.T(), pkgerrors.ErrDataNotFound, err)
+	})
+}
+
 func TestPaymentRepositoryTestSuite(t *testing.T) {
 	suite.Run(t, new(PaymentRepositoryTestSuite))
 }
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/ports"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/dbutils"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/transaction"
+)
+
+type providerEventRepository struct {
+	db *sql.DB
+}
+
+func NewProviderEventRepository(db *sql.DB) ports.IProviderEventRepository {
+	return &providerEventRepository{
+		db: db,
+	}
+}
+
+func (r *providerEventRepository) qb() sq.StatementBuilderType {
+	return sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+}
+
+func (r *providerEventRepository) conn(ctx context.Context) transaction.DBTX {
+	return transaction.Executor(ctx, r.db)
+}
+
+func (r *providerEventRepository) SaveProviderEvent(ctx context.Context, event payment.ProviderEvent, paymentID string) (created bool, err error) {
+	var occurredAt *time.Time
+	if !event.OccurredAt.IsZero() {
+		occurredAt = &event.OccurredAt
+	}
+
+	// A concurrent delivery of the same event waits on the primary key and then does nothing
+	result, err := r.qb().Insert("payment_module.provider_events").
+		Columns("provider", "event_id", "payment_id", "status", "occurred_at", "received_at").
+		Values(event.Provider, event.EventID, paymentID, event.Status, occurredAt, time.Now()).
+		Suffix("ON CONFLICT (provider, event_id) DO NOTHING").
+		RunWith(r.conn(ctx)).
+		ExecContext(ctx)
+	if err != nil {
+		return false, dbutils.HandlePostgresError(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
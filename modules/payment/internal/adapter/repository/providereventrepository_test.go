@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/testutils"
+)
+
+type ProviderEventRepositoryTestSuite struct {
+	suite.Suite
+	pgContainer *testutils.PostgresContainer
+	repo        *providerEventRepository
+}
+
+func (s *ProviderEventRepositoryTestSuite) SetupSuite() {
+	if testing.Short() {
+		s.T().Skip("Skipping repository integration test in short mode")
+	}
+	s.pgContainer = testutils.SetupPostgres(s.T())
+	s.pgContainer.RunMigrations(s.T(), "../../../../../migrations")
+	s.repo = &providerEventRepository{db: s.pgContainer.DB}
+}
+
+func (s *ProviderEventRepositoryTestSuite) TearDownSuite() {
+	s.pgContainer.Teardown(s.T())
+}
+
+func (s *ProviderEventRepositoryTestSuite) SetupTest() {
+	s.pgContainer.TruncateTables(s.T(), "payment_module.provider_events")
+}
+
+func (s *ProviderEventRepositoryTestSuite) TestSaveProviderEvent_Deduplicates() {
+	ctx := context.Background()
+	event := payment.ProviderEvent{
+		Provider:   "simulator",
+		EventID:    "evt_1",
+		Reference:  "sim_pay_1",
+		Status:     "captured",
+		OccurredAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	created, err := s.repo.SaveProviderEvent(ctx, event, "pay_1")
+	require.NoError(s.T(), err)
+	assert.True(s.T(), created)
+
+	created, err = s.repo.SaveProviderEvent(ctx, event, "pay_1")
+	require.NoError(s.T(), err)
+	assert.False(s.T(), created, "a redelivered event must not be recorded twice")
+
+	// Event IDs are only unique per provider
+	event.Provider = "other"
+	created, err = s.repo.SaveProviderEvent(ctx, event, "pay_1")
+	require.NoError(s.T(), err)
+	assert.True(s.T(), created)
+}
+
+func TestProviderEventRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(ProviderEventRepositoryTestSuite))
+}
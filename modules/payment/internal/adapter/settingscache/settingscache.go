@@ -0,0 +1,197 @@
+package settingscache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/ports"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+// Config tunes the settings cache.
+type Config struct {
+	// TTL bounds how long a result is served; it is the staleness limit for changes made by
+	// other processes, whose events never reach this process (default 1m).
+	TTL time.Duration
+	// MaxEntries bounds the number of cached results; the least recently used one is evicted (default 1000).
+	MaxEntries int
+}
+
+// cacheKey identifies a result: tenants get their own effective settings for the same params.
+type cacheKey struct {
+	tenantID string
+	params   paymentsettings.EffectiveSettingsParams
+}
+
+type entry struct {
+	key       cacheKey
+	settings  paymentsettings.EffectiveSettings
+	expiresAt time.Time
+}
+
+// call is a load in flight that concurrent misses of the same key wait for.
+type call struct {
+	done     chan struct{}
+	settings paymentsettings.EffectiveSettings
+	err      error
+}
+
+// Cache is an outbound adapter decorating IPaymentSettingsPort with an in-memory cache of the effective
+// settings, keyed by the tenant in ctx and the params. Concurrent misses of the same key share one load,
+// so an expired entry never turns into a burst of identical queries. Errors are never cached.
+//
+// A result expires at the TTL, or earlier when one of its settings goes out of force, so a setting whose
+// validity ends is not served past its end, not even when a global default takes over from it. A setting
+// scheduled to come into force is picked up within the TTL.
+//
+// Invalidate drops every entry. It is called when a setting changes, and loads that were already running
+// at that moment are not stored, so a result read before the change is never served after it.
+type Cache struct {
+	next   ports.IPaymentSettingsPort
+	config Config
+	now    func() time.Time
+
+	mu         sync.Mutex
+	entries    map[cacheKey]*list.Element
+	lru        *list.List
+	calls      map[cacheKey]*call
+	generation uint64
+	stats      payment.SettingsCacheStats
+}
+
+// NewCache creates a cache in front of next.
+func NewCache(next ports.IPaymentSettingsPort, config Config) *Cache {
+	if config.TTL <= 0 {
+		config.TTL = time.Minute
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = 1000
+	}
+	return &Cache{
+		next:    next,
+		config:  config,
+		now:     time.Now,
+		entries: make(map[cacheKey]*list.Element),
+		lru:     list.New(),
+		calls:   make(map[cacheKey]*call),
+	}
+}
+
+func (c *Cache) GetEffectiveSettings(ctx context.Context, params paymentsettings.EffectiveSettingsParams) (res paymentsettings.EffectiveSettings, err error) {
+	key := cacheKey{params: params}
+	key.tenantID, _ = tenant.FromContext(ctx)
+
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		cached := elem.Value.(*entry)
+		if c.now().Before(cached.expiresAt) {
+			c.lru.MoveToFront(elem)
+			c.stats.Hits++
+			c.mu.Unlock()
+			return clone(cached.settings), nil
+		}
+		c.remove(elem)
+	}
+	c.stats.Misses++
+
+	// Another request is loading the same key: wait for its result, or its error
+	if inflight, ok := c.calls[key]; ok {
+		c.stats.SharedLoads++
+		c.mu.Unlock()
+		select {
+		case <-inflight.done:
+			return clone(inflight.settings), inflight.err
+		case <-ctx.Done():
+			return res, ctx.Err()
+		}
+	}
+
+	loading := &call{done: make(chan struct{})}
+	c.calls[key] = loading
+	generation := c.generation
+	c.mu.Unlock()
+
+	loading.settings, loading.err = c.next.GetEffectiveSettings(ctx, params)
+
+	c.mu.Lock()
+	// After an invalidation the key may already belong to a newer load
+	if c.calls[key] == loading {
+		delete(c.calls, key)
+	}
+	c.stats.Loads++
+	if loading.err == nil && generation == c.generation {
+		c.store(key, loading.settings)
+	}
+	c.mu.Unlock()
+	close(loading.done)
+
+	return clone(loading.settings), loading.err
+}
+
+// Invalidate drops every cached result and discards the loads in flight.
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries = make(map[cacheKey]*list.Element)
+	c.lru.Init()
+	// Later misses must not join loads that started before the change
+	c.calls = make(map[cacheKey]*call)
+	c.stats.Invalidations++
+}
+
+// Stats returns the counters of the cache since it was created.
+func (c *Cache) Stats() payment.SettingsCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	return stats
+}
+
+// store adds a result, evicting the least recently used one when the cache is full. Callers hold mu.
+func (c *Cache) store(key cacheKey, settings paymentsettings.EffectiveSettings) {
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	for c.lru.Len() >= c.config.MaxEntries {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+	expiresAt := c.now().Add(c.config.TTL)
+	for _, setting := range settings.Settings {
+		if setting.ValidTo != nil && setting.ValidTo.Before(expiresAt) {
+			expiresAt = *setting.ValidTo
+		}
+	}
+	c.entries[key] = c.lru.PushFront(&entry{
+		key:       key,
+		settings:  clone(settings),
+		expiresAt: expiresAt,
+	})
+}
+
+// remove drops an entry. Callers hold mu.
+func (c *Cache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*entry).key)
+}
+
+// clone copies settings so callers cannot modify the cached result.
+func clone(settings paymentsettings.EffectiveSettings) paymentsettings.EffectiveSettings {
+	if settings.Settings == nil {
+		return settings
+	}
+	keys := make(map[string]paymentsettings.EffectiveSetting, len(settings.Settings))
+	for key, setting := range settings.Settings {
+		keys[key] = setting
+	}
+	settings.Settings = keys
+	return settings
+}
@@ -0,0 +1,228 @@
+package settingscache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/ports/mocks"
+)
+
+var (
+	usd = paymentsettings.EffectiveSettingsParams{Currency: "USD"}
+	eur = paymentsettings.EffectiveSettingsParams{Currency: "EUR"}
+)
+
+func settingsOf(value string) paymentsettings.EffectiveSettings {
+	return paymentsettings.EffectiveSettings{Currency: "USD", Settings: map[string]paymentsettings.EffectiveSetting{
+		paymentsettings.SettingKeyMaxTransactionAmount: {Value: value, Scope: paymentsettings.ScopeCurrency, SettingID: "pset_max"},
+	}}
+}
+
+func maxOf(settings paymentsettings.EffectiveSettings) string {
+	return settings.Settings[paymentsettings.SettingKeyMaxTransactionAmount].Value
+}
+
+func TestCache_ServesHitsUntilExpiry(t *testing.T) {
+	mockPort := mocks.NewMockIPaymentSettingsPort(t)
+	mockPort.On("GetEffectiveSettings", mock.Anything, usd).Return(settingsOf("10000.00"), nil).Twice()
+
+	cache := NewCache(mockPort, Config{TTL: time.Minute})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		res, err := cache.GetEffectiveSettings(context.Background(), usd)
+		require.NoError(t, err)
+		assert.Equal(t, "10000.00", maxOf(res))
+	}
+
+	now = now.Add(time.Minute)
+	_, err := cache.GetEffectiveSettings(context.Background(), usd)
+	require.NoError(t, err)
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(2), stats.Misses)
+	assert.Equal(t, int64(2), stats.Loads)
+	assert.Equal(t, 1, stats.Entries)
+}
+
+func TestCache_ExpiresWhenASettingGoesOutOfForce(t *testing.T) {
+	now := time.Now()
+	validTo := now.Add(10 * time.Second)
+	ending := settingsOf("10000.00")
+	ending.Settings[paymentsettings.SettingKeyMaxTransactionAmount] = paymentsettings.EffectiveSetting{Value: "10000.00", Scope: paymentsettings.ScopeCurrency, ValidTo: &validTo}
+
+	mockPort := mocks.NewMockIPaymentSettingsPort(t)
+	mockPort.On("GetEffectiveSettings", mock.Anything, usd).Return(ending, nil).Once()
+	mockPort.On("GetEffectiveSettings", mock.Anything, usd).Return(settingsOf("5000.00"), nil).Once()
+
+	cache := NewCache(mockPort, Config{TTL: time.Minute})
+	cache.now = func() time.Time { return now }
+
+	res, err := cache.GetEffectiveSettings(context.Background(), usd)
+	require.NoError(t, err)
+	assert.Equal(t, "10000.00", maxOf(res))
+
+	now = validTo
+	res, err = cache.GetEffectiveSettings(context.Background(), usd)
+	require.NoError(t, err)
+	assert.Equal(t, "5000.00", maxOf(res))
+}
+
+func TestCache_KeysByParams(t *testing.T) {
+	mockPort := mocks.NewMockIPaymentSettingsPort(t)
+	mockPort.On("GetEffectiveSettings", mock.Anything, usd).Return(settingsOf("10000.00"), nil).Once()
+	mockPort.On("GetEffectiveSettings", mock.Anything, eur).Return(settingsOf("9000.00"), nil).Once()
+
+	cache := NewCache(mockPort, Config{})
+
+	for i := 0; i < 2; i++ {
+		usdSettings, err := cache.GetEffectiveSettings(context.Background(), usd)
+		require.NoError(t, err)
+		eurSettings, err := cache.GetEffectiveSettings(context.Background(), eur)
+		require.NoError(t, err)
+		assert.Equal(t, "10000.00", maxOf(usdSettings))
+		assert.Equal(t, "9000.00", maxOf(eurSettings))
+	}
+}
+
+func TestCache_DoesNotCacheErrors(t *testing.T) {
+	mockPort := mocks.NewMockIPaymentSettingsPort(t)
+	mockPort.On("GetEffectiveSettings", mock.Anything, usd).Return(paymentsettings.EffectiveSettings{}, errors.New("connection refused")).Once()
+	mockPort.On("GetEffectiveSettings", mock.Anything, usd).Return(settingsOf("10000.00"), nil).Once()
+
+	cache := NewCache(mockPort, Config{})
+
+	_, err := cache.GetEffectiveSettings(context.Background(), usd)
+	assert.Error(t, err)
+	res, err := cache.GetEffectiveSettings(context.Background(), usd)
+	require.NoError(t, err)
+	assert.Equal(t, "10000.00", maxOf(res))
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	mockPort := mocks.NewMockIPaymentSettingsPort(t)
+	mockPort.On("GetEffectiveSettings", mock.Anything, usd).Return(settingsOf("10000.00"), nil).Once()
+	mockPort.On("GetEffectiveSettings", mock.Anything, eur).Return(settingsOf("9000.00"), nil).Twice()
+	gbp := paymentsettings.EffectiveSettingsParams{Currency: "GBP"}
+	mockPort.On("GetEffectiveSettings", mock.Anything, gbp).Return(settingsOf("8000.00"), nil).Once()
+
+	cache := NewCache(mockPort, Config{MaxEntries: 2})
+	ctx := context.Background()
+
+	_, _ = cache.GetEffectiveSettings(ctx, usd)
+	_, _ = cache.GetEffectiveSettings(ctx, eur)
+	_, _ = cache.GetEffectiveSettings(ctx, usd) // USD is now the most recently used
+	_, _ = cache.GetEffectiveSettings(ctx, gbp) // evicts EUR
+	_, _ = cache.GetEffectiveSettings(ctx, usd)
+	_, _ = cache.GetEffectiveSettings(ctx, eur)
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(2), stats.Evictions)
+	assert.Equal(t, 2, stats.Entries)
+}
+
+func TestCache_CallersCannotModifyCachedResult(t *testing.T) {
+	mockPort := mocks.NewMockIPaymentSettingsPort(t)
+	mockPort.On("GetEffectiveSettings", mock.Anything, usd).Return(settingsOf("10000.00"), nil).Once()
+
+	cache := NewCache(mockPort, Config{})
+
+	res, err := cache.GetEffectiveSettings(context.Background(), usd)
+	require.NoError(t, err)
+	res.Settings[paymentsettings.SettingKeyMaxTransactionAmount] = paymentsettings.EffectiveSetting{Value: "1"}
+
+	res, err = cache.GetEffectiveSettings(context.Background(), usd)
+	require.NoError(t, err)
+	assert.Equal(t, "10000.00", maxOf(res))
+}
+
+func TestCache_SharesConcurrentLoads(t *testing.T) {
+	mockPort := mocks.NewMockIPaymentSettingsPort(t)
+	release := make(chan struct{})
+	mockPort.On("GetEffectiveSettings", mock.Anything, usd).Run(func(mock.Arguments) {
+		<-release
+	}).Return(settingsOf("10000.00"), nil).Once()
+
+	cache := NewCache(mockPort, Config{})
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make(chan string, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := cache.GetEffectiveSettings(context.Background(), usd)
+			if assert.NoError(t, err) {
+				results <- maxOf(res)
+			}
+		}()
+	}
+
+	// Every caller but the loader waits for the load in flight
+	assert.Eventually(t, func() bool { return cache.Stats().SharedLoads == callers-1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for value := range results {
+		assert.Equal(t, "10000.00", value)
+	}
+	assert.Equal(t, int64(1), cache.Stats().Loads)
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	t.Run("drops cached results", func(t *testing.T) {
+		mockPort := mocks.NewMockIPaymentSettingsPort(t)
+		mockPort.On("GetEffectiveSettings", mock.Anything, usd).Return(settingsOf("10000.00"), nil).Once()
+		mockPort.On("GetEffectiveSettings", mock.Anything, usd).Return(settingsOf("20000.00"), nil).Once()
+
+		cache := NewCache(mockPort, Config{})
+
+		_, err := cache.GetEffectiveSettings(context.Background(), usd)
+		require.NoError(t, err)
+		cache.Invalidate()
+		res, err := cache.GetEffectiveSettings(context.Background(), usd)
+		require.NoError(t, err)
+
+		assert.Equal(t, "20000.00", maxOf(res))
+		assert.Equal(t, int64(1), cache.Stats().Invalidations)
+	})
+
+	t.Run("does not store a load that started before", func(t *testing.T) {
+		mockPort := mocks.NewMockIPaymentSettingsPort(t)
+		started := make(chan struct{})
+		release := make(chan struct{})
+		mockPort.On("GetEffectiveSettings", mock.Anything, usd).Run(func(mock.Arguments) {
+			close(started)
+			<-release
+		}).Return(settingsOf("10000.00"), nil).Once()
+		mockPort.On("GetEffectiveSettings", mock.Anything, usd).Return(settingsOf("20000.00"), nil).Once()
+
+		cache := NewCache(mockPort, Config{})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = cache.GetEffectiveSettings(context.Background(), usd)
+		}()
+		<-started
+		cache.Invalidate()
+		close(release)
+		<-done
+
+		res, err := cache.GetEffectiveSettings(context.Background(), usd)
+		require.NoError(t, err)
+		assert.Equal(t, "20000.00", maxOf(res))
+	})
+}
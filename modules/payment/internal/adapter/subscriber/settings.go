@@ -0,0 +1,61 @@
+package subscriber
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/eventbus"
+)
+
+// SettingsSubscriberName identifies the subscriber in the event bus logs.
+const SettingsSubscriberName = "payment.settings-subscriber"
+
+// SettingsCache is the cache of payment settings the subscriber invalidates.
+type SettingsCache interface {
+	Invalidate()
+}
+
+// SettingsSubscriber is an inbound adapter through which the payment module reacts to changes in the
+// payment-settings module. The events arrive over the event bus, so payment-settings never knows about it.
+type SettingsSubscriber struct {
+	settingsCache SettingsCache
+}
+
+// NewSettingsSubscriber creates the subscriber; settingsCache is nil when settings are not cached.
+func NewSettingsSubscriber(settingsCache SettingsCache) *SettingsSubscriber {
+	return &SettingsSubscriber{settingsCache: settingsCache}
+}
+
+// Register subscribes the handlers of the payment module on bus.
+func (s *SettingsSubscriber) Register(bus *eventbus.Bus) {
+	eventbus.Subscribe(bus, SettingsSubscriberName, eventbus.ModeSync, s.HandlePaymentSettingChanged)
+}
+
+// HandlePaymentSettingChanged drops the cached settings, so the next payment is validated against the
+// change. It runs synchronously, before the request that changed the setting returns. Changes of the
+// settings payments are validated against are logged, so the moment new limits took effect shows up
+// next to the payments they applied to.
+func (s *SettingsSubscriber) HandlePaymentSettingChanged(_ context.Context, event paymentsettings.PaymentSettingChanged) error {
+	if s.settingsCache != nil {
+		s.settingsCache.Invalidate()
+	}
+
+	switch event.Setting.SettingKey {
+	case paymentsettings.SettingKeyMinTransactionAmount,
+		paymentsettings.SettingKeyMaxTransactionAmount,
+		paymentsettings.SettingKeyPaymentTimeoutSeconds:
+	default:
+		return nil
+	}
+
+	log.Info().
+		Str("event_type", event.Type).
+		Str("setting_key", event.Setting.SettingKey).
+		Str("currency", event.Setting.Currency).
+		Str("setting_value", event.Setting.SettingValue).
+		Str("status", event.Setting.Status).
+		Msg("Payment setting in use changed")
+	return nil
+}
@@ -0,0 +1,52 @@
+package ports
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/money"
+)
+
+// Outcomes of a gateway operation.
+const (
+	GatewayStatusAuthorized = "authorized"
+	GatewayStatusCaptured   = "captured"
+	GatewayStatusRefunded   = "refunded"
+	GatewayStatusDeclined   = "declined"
+	// GatewayStatusPending means the provider has not decided yet; ask again later.
+	GatewayStatusPending = "pending"
+)
+
+// ErrGatewayTimeout is returned when the provider did not answer in time. The outcome of the
+// operation is unknown and must be resolved through GetStatus or a retry.
+var ErrGatewayTimeout = errors.New("payment gateway timed out")
+
+// AuthorizeRequest reserves an amount with the provider for a payment.
+// IdempotencyKey is stored with the payment before the call: the provider answers a repeated request
+// with the same key with the result of the first one instead of authorizing the payment again.
+type AuthorizeRequest struct {
+	PaymentID      string
+	Amount         money.Money
+	IdempotencyKey string
+}
+
+// GatewayResult is the provider's answer to an operation.
+type GatewayResult struct {
+	// Reference identifies the payment at the provider and is used by every later operation.
+	Reference string
+	Status    string
+	// DeclineReason explains a declined operation.
+	DeclineReason string
+}
+
+// IPaymentGateway is an outbound port to the payment service provider (PSP).
+// Operations are idempotent per payment reference, so a call whose outcome is unknown
+// (ErrGatewayTimeout) can safely be repeated.
+type IPaymentGateway interface {
+	// Name identifies the provider recorded on payments.
+	Name() string
+	Authorize(ctx context.Context, req AuthorizeRequest) (result GatewayResult, err error)
+	Capture(ctx context.Context, reference string, amount money.Money) (result GatewayResult, err error)
+	Refund(ctx context.Context, reference string, amount money.Money) (result GatewayResult, err error)
+	GetStatus(ctx context.Context, reference string) (result GatewayResult, err error)
+}
@@ -9,15 +9,26 @@
 // on low-level modules (adapters), both depend on abstractions (ports).
 package ports
 
-import "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+import (
+	"context"
+	"time"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+)
 
 // IPaymentRepository is an outbound port for payment data persistence.
 // This interface is defined by the domain and implemented by the repository adapter.
 // The domain doesn't know or care if this uses PostgreSQL, MongoDB, or in-memory storage.
+// Every method only sees the payments of the tenant in ctx, or of every tenant under tenant.WithAllTenants;
+// payments are created for the tenant in ctx.
 type IPaymentRepository interface {
-	CreatePayment(p *payment.Payment) error
-	GetPayment(id string) (payment.Payment, error)
-	FetchPayments(params payment.FetchPaymentsParams) (payments []payment.Payment, nextCursor string, err error)
-	UpdatePayment(p *payment.Payment) error
-	DeletePayment(id string) error
+	CreatePayment(ctx context.Context, p *payment.Payment) error
+	GetPayment(ctx context.Context, id string) (payment.Payment, error)
+	GetPaymentByProviderReference(ctx context.Context, provider, reference string) (payment.Payment, error)
+	FetchPayments(ctx context.Context, params payment.FetchPaymentsParams) (payments []payment.Payment, nextCursor string, err error)
+	UpdatePayment(ctx context.Context, p *payment.Payment) error
+	DeletePayment(ctx context.Context, id string, expectedVersion int64) error
+	ClaimPendingPayments(ctx context.Context, params payment.ClaimPaymentsParams) (payments []payment.Payment, nextCursor string, err error)
+	ReleasePaymentClaims(ctx context.Context, owner string) error
+	DeferPayment(ctx context.Context, id string, until time.Time) error
 }
@@ -1,6 +1,8 @@
 package ports
 
 import (
+	"context"
+
 	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
 )
 
@@ -16,7 +18,7 @@ import (
 // not direct dependencies. Each module defines its own view of what it needs from others.
 //
 // IMPORTANT - Circular Dependency Prevention:
-// This port imports structs from the payment-settings module (PaymentSetting, PaymentSettingFetchParams).
+// This port imports structs from the payment-settings module (EffectiveSettings, EffectiveSettingsParams).
 // To prevent circular dependencies, establish a clear dependency direction:
 //   - Payment module CAN import from Payment Settings module (current direction)
 //   - Payment Settings module MUST NOT import from Payment module
@@ -24,6 +26,9 @@ import (
 // If bidirectional communication is needed in the future, consider:
 //   - Creating a shared types package for common structs
 //   - Using DTOs (Data Transfer Objects) instead of direct struct dependencies
+//
+// The Payment module reads the resolved view of a currency, so it does not know how global defaults and
+// currency overrides are stored or merged.
 type IPaymentSettingsPort interface {
-	FetchPaymentSettings(params paymentsettings.PaymentSettingFetchParams) (res []paymentsettings.PaymentSetting, nextCursor string, err error)
+	GetEffectiveSettings(ctx context.Context, params paymentsettings.EffectiveSettingsParams) (paymentsettings.EffectiveSettings, error)
 }
@@ -0,0 +1,15 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+)
+
+// IProviderEventRepository is an outbound port recording the provider events that were applied,
+// so an event the provider delivers more than once is applied only once.
+type IProviderEventRepository interface {
+	// SaveProviderEvent records the event for the payment. created is false when the provider
+	// event ID was already recorded.
+	SaveProviderEvent(ctx context.Context, event payment.ProviderEvent, paymentID string) (created bool, err error)
+}
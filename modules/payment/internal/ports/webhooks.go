@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+)
+
+// IWebhookPort is an outbound port for inter-module communication with the Webhooks module.
+// Publish joins the transaction in ctx and only records the deliveries; the Webhooks module sends
+// them in the background, so a slow or failing merchant endpoint never affects a payment request.
+//
+// As with IPaymentSettingsPort, the dependency only goes one way:
+// the Webhooks module MUST NOT import from the Payment module.
+type IWebhookPort interface {
+	Publish(ctx context.Context, event webhooks.Event) error
+}
@@ -1,52 +1,454 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
 	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/ports"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/money"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/outbox"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/transaction"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/uniqueid"
 )
 
 type PaymentService struct {
 	paymentRepo         ports.IPaymentRepository
 	paymentSettingsRepo ports.IPaymentSettingsPort
+	txManager           transaction.Manager
+	gateway             ports.IPaymentGateway
+	providerEventRepo   ports.IProviderEventRepository
+	webhookPort         ports.IWebhookPort
+	eventOutbox         ports.IEventOutbox
+	eventPublisher      ports.IEventPublisher
 }
 
-func NewPaymentService(paymentRepo ports.IPaymentRepository, paymentSettingsRepo ports.IPaymentSettingsPort) (service *PaymentService) {
+func NewPaymentService(paymentRepo ports.IPaymentRepository, paymentSettingsRepo ports.IPaymentSettingsPort, txManager transaction.Manager, gateway ports.IPaymentGateway, providerEventRepo ports.IProviderEventRepository, webhookPort ports.IWebhookPort, eventOutbox ports.IEventOutbox, eventPublisher ports.IEventPublisher) (service *PaymentService) {
 	return &PaymentService{
 		paymentRepo:         paymentRepo,
 		paymentSettingsRepo: paymentSettingsRepo,
+		txManager:           txManager,
+		gateway:             gateway,
+		providerEventRepo:   providerEventRepo,
+		webhookPort:         webhookPort,
+		eventOutbox:         eventOutbox,
+		eventPublisher:      eventPublisher,
 	}
 }
 
-func (s *PaymentService) CreatePayment(p *payment.Payment) (err error) {
-	paymentSettings, _, err := s.paymentSettingsRepo.FetchPaymentSettings(paymentsettings.PaymentSettingFetchParams{
-		Currency: p.Currency,
-		Limit:    1,
-		Cursor:   "",
+// CreatePayment validates the amount against the settings and stores the payment in one transaction,
+// so the limits that were checked are the ones in force when the payment is written. When the settings
+// port is cached, "in force" means as of the last change this process was told about (see factory).
+// Payments are created pending and then authorized with the payment gateway. The gateway is called
+// after the commit so a slow provider never holds the transaction open; when the authorization fails
+// the payment stays pending and the cron updater authorizes it later. The authorization key is stored
+// with the payment, so when the authorization succeeds but its result cannot be saved, the provider
+// answers the cron updater's retry with the same authorization instead of authorizing twice.
+// Domain events are recorded in the same transactions as the changes they report.
+func (s *PaymentService) CreatePayment(ctx context.Context, p *payment.Payment) (err error) {
+	if p.Status == "" {
+		p.Status = payment.StatusPending
+	}
+	if !payment.IsValidStatus(p.Status) {
+		return errors.NewValidationError(fmt.Errorf("unknown payment status %q", p.Status))
+	}
+	// Every other status is reached through the gateway and the state machine, never set by the client
+	if p.Status != payment.StatusPending {
+		return errors.NewValidationError(fmt.Errorf("payments are created %s, not %s", payment.StatusPending, p.Status))
+	}
+	if p.AuthorizationKey, err = uniqueid.GeneratePK("auth"); err != nil {
+		return err
+	}
+
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		if err = s.validateTransactionAmount(ctx, p); err != nil {
+			return err
+		}
+		if err = s.paymentRepo.CreatePayment(ctx, p); err != nil {
+			return err
+		}
+		return s.publish(ctx, payment.EventTypePaymentCreated, *p, "")
 	})
 	if err != nil {
 		return err
 	}
-	_ = paymentSettings
-	return s.paymentRepo.CreatePayment(p)
+
+	authorized := *p
+	if err = s.authorize(ctx, &authorized); err == nil {
+		err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+			if err = s.paymentRepo.UpdatePayment(ctx, &authorized); err != nil {
+				return err
+			}
+			return s.publishStatusChange(ctx, authorized, p.Status)
+		})
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("payment_id", p.ID).Msg("Payment authorization deferred to the cron updater")
+		return nil
+	}
+	*p = authorized
+	return nil
+}
+
+// ProcessPayment drives a pending payment through the payment gateway: it is authorized unless that
+// already happened at creation, moved to processing, captured and then completed or failed.
+// When the capture times out the provider is asked for the outcome; if it is still unknown the payment
+// stays processing and an error is returned. The cron updater claims such a payment again and the
+// capture is repeated, which the gateway answers with the outcome of the first one.
+func (s *PaymentService) ProcessPayment(ctx context.Context, p *payment.Payment) (err error) {
+	if p.Status != payment.StatusPending && p.Status != payment.StatusProcessing {
+		return errors.NewConflictError(fmt.Errorf("payment %s is %s, only pending and processing payments can be processed", p.ID, p.Status))
+	}
+
+	if p.ProviderReference == "" {
+		// Payments created before authorization keys existed get one before the gateway is called
+		if p.AuthorizationKey == "" {
+			if p.AuthorizationKey, err = uniqueid.GeneratePK("auth"); err != nil {
+				return err
+			}
+			if err = s.UpdatePayment(ctx, p); err != nil {
+				return err
+			}
+		}
+		if err = s.authorize(ctx, p); err != nil {
+			return err
+		}
+		if p.Status == payment.StatusFailed {
+			return s.UpdatePayment(ctx, p)
+		}
+	}
+
+	if p.Status == payment.StatusPending {
+		if err = p.TransitionTo(payment.StatusProcessing); err != nil {
+			return err
+		}
+		if err = s.UpdatePayment(ctx, p); err != nil {
+			return err
+		}
+	}
+
+	result, err := s.gateway.Capture(ctx, p.ProviderReference, p.Amount)
+	if stderrors.Is(err, ports.ErrGatewayTimeout) {
+		// The capture may or may not have happened; the provider knows
+		result, err = s.gateway.GetStatus(ctx, p.ProviderReference)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to capture payment %s: %w", p.ID, err)
+	}
+
+	switch result.Status {
+	case ports.GatewayStatusCaptured:
+		err = p.TransitionTo(payment.StatusCompleted)
+	case ports.GatewayStatusDeclined:
+		err = p.TransitionTo(payment.StatusFailed)
+	default:
+		return fmt.Errorf("capture of payment %s is %s at the provider", p.ID, result.Status)
+	}
+	if err != nil {
+		return err
+	}
+	return s.UpdatePayment(ctx, p)
+}
+
+// authorize reserves the payment amount with the gateway and records the provider reference on p.
+// A declined authorization fails the payment; p is not saved.
+func (s *PaymentService) authorize(ctx context.Context, p *payment.Payment) (err error) {
+	result, err := s.gateway.Authorize(ctx, ports.AuthorizeRequest{PaymentID: p.ID, Amount: p.Amount, IdempotencyKey: p.AuthorizationKey})
+	if err != nil {
+		return fmt.Errorf("failed to authorize payment %s: %w", p.ID, err)
+	}
+
+	switch result.Status {
+	case ports.GatewayStatusAuthorized:
+	case ports.GatewayStatusDeclined:
+		log.Info().Str("payment_id", p.ID).Str("reason", result.DeclineReason).Msg("Payment authorization declined")
+		if err = p.TransitionTo(payment.StatusFailed); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("authorization of payment %s is %s at the provider", p.ID, result.Status)
+	}
+
+	p.Provider = s.gateway.Name()
+	p.ProviderReference = result.Reference
+	return nil
+}
+
+// validateTransactionAmount enforces the min/max transaction amount in effect for the payment currency.
+func (s *PaymentService) validateTransactionAmount(ctx context.Context, p *payment.Payment) (err error) {
+	settings, err := s.paymentSettingsRepo.GetEffectiveSettings(ctx, paymentsettings.EffectiveSettingsParams{Currency: p.Currency()})
+	if err != nil {
+		return err
+	}
+
+	minAmount, err := amountSetting(settings, paymentsettings.SettingKeyMinTransactionAmount)
+	if err != nil {
+		return err
+	}
+
+	maxAmount, err := amountSetting(settings, paymentsettings.SettingKeyMaxTransactionAmount)
+	if err != nil {
+		return err
+	}
+
+	belowMin, err := p.Amount.Cmp(minAmount)
+	if err != nil {
+		return err
+	}
+	if belowMin < 0 {
+		return errors.NewValidationError(fmt.Errorf("amount %s is below the minimum transaction amount of %s",
+			p.Amount.Format(), minAmount.Format()))
+	}
+
+	aboveMax, err := p.Amount.Cmp(maxAmount)
+	if err != nil {
+		return err
+	}
+	if aboveMax > 0 {
+		return errors.NewValidationError(fmt.Errorf("amount %s exceeds the maximum transaction amount of %s",
+			p.Amount.Format(), maxAmount.Format()))
+	}
+
+	return nil
+}
+
+// amountSetting parses the amount setting of key in the currency of settings, whether it is set for the
+// currency or globally.
+func amountSetting(settings paymentsettings.EffectiveSettings, key string) (amount money.Money, err error) {
+	setting, ok := settings.Lookup(key)
+	if !ok {
+		return money.Money{}, errors.NewValidationError(fmt.Errorf("no active %s configured for currency %s", key, settings.Currency))
+	}
+
+	amount, err = money.Parse(setting.Value, settings.Currency)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("invalid %s setting for currency %s: %w", key, settings.Currency, err)
+	}
+
+	return amount, nil
 }
 
-func (s *PaymentService) GetPayment(id string) (result payment.Payment, err error) {
-	p, err := s.paymentRepo.GetPayment(id)
+func (s *PaymentService) GetPayment(ctx context.Context, id string) (result payment.Payment, err error) {
+	p, err := s.paymentRepo.GetPayment(ctx, id)
 	if err != nil {
 		return payment.Payment{}, err
 	}
 	return p, nil
 }
 
-func (s *PaymentService) FetchPayments(params payment.FetchPaymentsParams) (result []payment.Payment, nextCursor string, err error) {
-	return s.paymentRepo.FetchPayments(params)
+func (s *PaymentService) FetchPayments(ctx context.Context, params payment.FetchPaymentsParams) (result []payment.Payment, nextCursor string, err error) {
+	return s.paymentRepo.FetchPayments(ctx, params)
+}
+
+// UpdatePayment persists changes to a payment. Status changes must follow the payment state machine
+// and a non-zero p.Version must match the stored version. The read and the write run in one transaction.
+func (s *PaymentService) UpdatePayment(ctx context.Context, p *payment.Payment) (err error) {
+	return s.txManager.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		current, err := s.paymentRepo.GetPayment(ctx, p.ID)
+		if err != nil {
+			return err
+		}
+
+		if p.Version != 0 && p.Version != current.Version {
+			return errors.ErrPreconditionFailed
+		}
+
+		if p.Status == "" {
+			p.Status = current.Status
+		}
+		// Provider references are only ever set by the gateway flow, never cleared by a client update
+		if p.ProviderReference == "" {
+			p.Provider, p.ProviderReference = current.Provider, current.ProviderReference
+		}
+		if p.AuthorizationKey == "" {
+			p.AuthorizationKey = current.AuthorizationKey
+		}
+		if err = payment.ValidateStatusTransition(current.Status, p.Status); err != nil {
+			return err
+		}
+
+		if err = s.paymentRepo.UpdatePayment(ctx, p); err != nil {
+			return err
+		}
+		return s.publishStatusChange(ctx, *p, current.Status)
+	})
+}
+
+// publish records a payment event in the module outbox and for the merchant webhooks, in the transaction in ctx,
+// and publishes it on the event bus once that transaction committed.
+func (s *PaymentService) publish(ctx context.Context, eventType string, p payment.Payment, previousStatus string) (err error) {
+	eventData := payment.EventData{
+		ID:                p.ID,
+		TenantID:          p.TenantID,
+		Amount:            p.Amount.String(),
+		Currency:          p.Currency(),
+		Status:            p.Status,
+		PreviousStatus:    previousStatus,
+		Version:           p.Version,
+		Provider:          p.Provider,
+		ProviderReference: p.ProviderReference,
+		UpdatedAt:         p.UpdatedAt,
+	}
+	data, err := json.Marshal(eventData)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event of payment %s: %w", eventType, p.ID, err)
+	}
+
+	event := outbox.Event{
+		Type:        eventType,
+		AggregateID: p.ID,
+		Payload:     data,
+		OccurredAt:  p.UpdatedAt,
+	}
+	if event.ID, err = uniqueid.GeneratePK("evt"); err != nil {
+		return err
+	}
+	if err = s.eventOutbox.Append(ctx, event); err != nil {
+		return fmt.Errorf("failed to record %s event of payment %s: %w", eventType, p.ID, err)
+	}
+
+	// Merchants see the same event ID as outbox consumers
+	err = s.webhookPort.Publish(ctx, webhooks.Event{
+		ID:         event.ID,
+		Type:       eventType,
+		OccurredAt: p.UpdatedAt,
+		Data:       data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish %s event of payment %s: %w", eventType, p.ID, err)
+	}
+
+	transaction.AfterCommit(ctx, func(ctx context.Context) {
+		// The change is committed; a failing subscriber must not fail it
+		err := s.eventPublisher.Publish(ctx, payment.PaymentChanged{
+			EventID:    event.ID,
+			Type:       eventType,
+			Payment:    eventData,
+			OccurredAt: p.UpdatedAt,
+		})
+		if err != nil {
+			log.Error().Err(err).Str("event_id", event.ID).Str("event_type", eventType).Msg("Failed to handle payment event")
+		}
+	})
+	return nil
+}
+
+// publishStatusChange publishes a payment.status_changed event when the status of p differs from previousStatus.
+func (s *PaymentService) publishStatusChange(ctx context.Context, p payment.Payment, previousStatus string) (err error) {
+	if p.Status == previousStatus {
+		return nil
+	}
+	return s.publish(ctx, payment.EventTypePaymentStatusChanged, p, previousStatus)
+}
+
+// providerStatuses maps provider statuses onto payment statuses. An empty payment status records
+// the event without changing the payment.
+var providerStatuses = map[string]string{
+	ports.GatewayStatusPending:    "",
+	ports.GatewayStatusAuthorized: "",
+	ports.GatewayStatusCaptured:   payment.StatusCompleted,
+	ports.GatewayStatusDeclined:   payment.StatusFailed,
+	ports.GatewayStatusRefunded:   "",
+}
+
+// HandleProviderEvent records the event and applies its status to the payment in one transaction,
+// so an event is either recorded and applied or neither, and a redelivery is recognised by its ID.
+// A status the payment can no longer move to, e.g. a late decline of a completed payment, is recorded
+// and logged but not applied; failing the event would only make the provider redeliver it.
+func (s *PaymentService) HandleProviderEvent(ctx context.Context, event payment.ProviderEvent) (duplicate bool, err error) {
+	if event.Provider == "" || event.EventID == "" || event.Reference == "" {
+		return false, errors.NewValidationError(fmt.Errorf("provider event requires a provider, an event ID and a payment reference"))
+	}
+	target, ok := providerStatuses[event.Status]
+	if !ok {
+		return false, errors.NewValidationError(fmt.Errorf("unknown provider status %q", event.Status))
+	}
+
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		// Providers do not know tenants: the payment is looked up across all of them and the event is
+		// then applied for the tenant of the payment
+		p, err := s.paymentRepo.GetPaymentByProviderReference(tenant.WithAllTenants(ctx), event.Provider, event.Reference)
+		if err != nil {
+			return err
+		}
+		ctx = tenant.WithID(ctx, p.TenantID)
+
+		created, err := s.providerEventRepo.SaveProviderEvent(ctx, event, p.ID)
+		if err != nil {
+			return err
+		}
+		if !created {
+			duplicate = true
+			return nil
+		}
+
+		if target == "" || p.Status == target {
+			return nil
+		}
+		previousStatus := p.Status
+		// A capture notification can arrive before the cron updater moved the payment to processing
+		if p.Status == payment.StatusPending && target == payment.StatusCompleted {
+			if err = p.TransitionTo(payment.StatusProcessing); err != nil {
+				return err
+			}
+		}
+		if err = p.TransitionTo(target); err != nil {
+			log.Warn().Err(err).
+				Str("provider", event.Provider).
+				Str("event_id", event.EventID).
+				Str("payment_id", p.ID).
+				Msg("Provider event not applied")
+			return nil
+		}
+		if err = s.paymentRepo.UpdatePayment(ctx, &p); err != nil {
+			return err
+		}
+		return s.publishStatusChange(ctx, p, previousStatus)
+	})
+	if err != nil {
+		return false, err
+	}
+	return duplicate, nil
+}
+
+// DeletePayment deletes the payment and records a payment.deleted event carrying its last state.
+func (s *PaymentService) DeletePayment(ctx context.Context, id string, expectedVersion int64) (err error) {
+	return s.txManager.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		p, err := s.paymentRepo.GetPayment(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err = s.paymentRepo.DeletePayment(ctx, id, expectedVersion); err != nil {
+			return err
+		}
+		p.UpdatedAt = time.Now()
+		return s.publish(ctx, payment.EventTypePaymentDeleted, p, "")
+	})
+}
+
+func (s *PaymentService) ClaimPendingPayments(ctx context.Context, params payment.ClaimPaymentsParams) (result []payment.Payment, nextCursor string, err error) {
+	if params.Owner == "" {
+		return nil, "", errors.NewValidationError(fmt.Errorf("claim owner is required"))
+	}
+	if params.LeaseDuration <= 0 {
+		return nil, "", errors.NewValidationError(fmt.Errorf("claim lease duration must be positive"))
+	}
+	return s.paymentRepo.ClaimPendingPayments(ctx, params)
 }
 
-func (s *PaymentService) UpdatePayment(p *payment.Payment) (err error) {
-	return s.paymentRepo.UpdatePayment(p)
+func (s *PaymentService) ReleasePaymentClaims(ctx context.Context, owner string) (err error) {
+	return s.paymentRepo.ReleasePaymentClaims(ctx, owner)
 }
 
-func (s *PaymentService) DeletePayment(id string) (err error) {
-	return s.paymentRepo.DeletePayment(id)
+func (s *PaymentService) DeferPayment(ctx context.Context, id string, until time.Time) (err error) {
+	return s.paymentRepo.DeferPayment(ctx, id, until)
 }
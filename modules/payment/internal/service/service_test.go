@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"testing"
 	"time"
@@ -10,17 +12,63 @@ import (
 
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
 	paymentsettings "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment-settings"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/ports"
 	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment/internal/ports/mocks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
 	pkgerrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/money"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/outbox"
 )
 
+// inlineTxManager runs the unit of work directly, without a database transaction.
+type inlineTxManager struct{}
+
+func (inlineTxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// anyWebhookPort accepts every published event; TestPaymentService_PublishesWebhookEvents asserts them.
+func anyWebhookPort(t *testing.T) *mocks.MockIWebhookPort {
+	webhookPort := mocks.NewMockIWebhookPort(t)
+	webhookPort.On("Publish", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return webhookPort
+}
+
+// anyEventOutbox accepts every appended event; TestPaymentService_RecordsOutboxEvents asserts them.
+func anyEventOutbox(t *testing.T) *mocks.MockIEventOutbox {
+	eventOutbox := mocks.NewMockIEventOutbox(t)
+	eventOutbox.On("Append", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return eventOutbox
+}
+
+// anyEventPublisher accepts every event published on the bus; TestPaymentService_PublishesBusEvents asserts them.
+func anyEventPublisher(t *testing.T) *mocks.MockIEventPublisher {
+	eventPublisher := mocks.NewMockIEventPublisher(t)
+	eventPublisher.On("Publish", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return eventPublisher
+}
+
+// effectiveSettingsOf returns the effective settings of currency with the given values by key.
+func effectiveSettingsOf(currency string, values map[string]string) paymentsettings.EffectiveSettings {
+	settings := make(map[string]paymentsettings.EffectiveSetting, len(values))
+	for key, value := range values {
+		settings[key] = paymentsettings.EffectiveSetting{Value: value, Scope: paymentsettings.ScopeCurrency, SettingID: "pset_" + key}
+	}
+	return paymentsettings.EffectiveSettings{Currency: currency, Settings: settings}
+}
+
 func TestPaymentService_CreatePayment(t *testing.T) {
+	usdLimits := map[string]string{
+		paymentsettings.SettingKeyMinTransactionAmount: "10.00",
+		paymentsettings.SettingKeyMaxTransactionAmount: "10000.00",
+	}
+
 	tests := []struct {
 		name                  string
 		payment               *payment.Payment
-		mockSettingsResponse  []paymentsettings.PaymentSetting
-		mockSettingsCursor    string
+		mockSettings          map[string]string
 		mockSettingsError     error
+		expectCreate          bool
 		mockCreateError       error
 		expectError           bool
 		expectedErrorContains string
@@ -28,53 +76,110 @@ func TestPaymentService_CreatePayment(t *testing.T) {
 		{
 			name: "successful payment creation",
 			payment: &payment.Payment{
-				Amount:   100.50,
-				Currency: "USD",
-				Status:   "pending",
+				Amount: money.MustParse("100.50", "USD"),
+				Status: "pending",
 			},
-			mockSettingsResponse: []paymentsettings.PaymentSetting{
-				{
-					ID:           "pset_123",
-					SettingKey:   "rate",
-					SettingValue: "1.0",
-					Currency:     "USD",
-					Status:       "active",
-				},
+			mockSettings: usdLimits,
+			expectCreate: true,
+			expectError:  false,
+		},
+		{
+			name: "amount equal to the maximum is accepted",
+			payment: &payment.Payment{
+				Amount: money.MustParse("10000.00", "USD"),
+				Status: "pending",
+			},
+			mockSettings: usdLimits,
+			expectCreate: true,
+			expectError:  false,
+		},
+		{
+			name: "amount above the maximum is rejected",
+			payment: &payment.Payment{
+				Amount: money.MustParse("50000.00", "USD"),
+				Status: "pending",
+			},
+			mockSettings:          usdLimits,
+			expectError:           true,
+			expectedErrorContains: "exceeds the maximum transaction amount",
+		},
+		{
+			name: "amount below the minimum is rejected",
+			payment: &payment.Payment{
+				Amount: money.MustParse("9.99", "USD"),
+				Status: "pending",
+			},
+			mockSettings:          usdLimits,
+			expectError:           true,
+			expectedErrorContains: "below the minimum transaction amount",
+		},
+		{
+			name: "currency without active limits is rejected",
+			payment: &payment.Payment{
+				Amount: money.MustParse("100.00", "JPY"),
+				Status: "pending",
+			},
+			mockSettings:          map[string]string{},
+			expectError:           true,
+			expectedErrorContains: "no active min_transaction_amount configured for currency JPY",
+		},
+		{
+			name: "empty status defaults to pending",
+			payment: &payment.Payment{
+				Amount: money.MustParse("100.00", "USD"),
+			},
+			mockSettings: usdLimits,
+			expectCreate: true,
+			expectError:  false,
+		},
+		{
+			name: "unknown status is rejected",
+			payment: &payment.Payment{
+				Amount: money.MustParse("100.00", "USD"),
+				Status: "settled",
+			},
+			expectError:           true,
+			expectedErrorContains: "unknown payment status",
+		},
+		{
+			name: "status other than pending is rejected",
+			payment: &payment.Payment{
+				Amount: money.MustParse("100.00", "USD"),
+				Status: "completed",
+			},
+			expectError:           true,
+			expectedErrorContains: "payments are created pending, not completed",
+		},
+		{
+			name: "invalid limit value",
+			payment: &payment.Payment{
+				Amount: money.MustParse("100.00", "USD"),
+				Status: "pending",
+			},
+			mockSettings: map[string]string{
+				paymentsettings.SettingKeyMinTransactionAmount: "1O.00",
 			},
-			mockSettingsCursor: "",
-			mockSettingsError:  nil,
-			mockCreateError:    nil,
-			expectError:        false,
+			expectError:           true,
+			expectedErrorContains: "invalid min_transaction_amount setting for currency USD",
 		},
 		{
 			name: "payment settings fetch error",
 			payment: &payment.Payment{
-				Amount:   100.50,
-				Currency: "EUR",
-				Status:   "pending",
+				Amount: money.MustParse("100.50", "EUR"),
+				Status: "pending",
 			},
-			mockSettingsResponse:  nil,
-			mockSettingsCursor:    "",
 			mockSettingsError:     errors.New("database connection failed"),
-			mockCreateError:       nil,
 			expectError:           true,
 			expectedErrorContains: "database connection failed",
 		},
 		{
 			name: "payment creation error",
 			payment: &payment.Payment{
-				Amount:   100.50,
-				Currency: "USD",
-				Status:   "pending",
-			},
-			mockSettingsResponse: []paymentsettings.PaymentSetting{
-				{
-					ID:       "pset_123",
-					Currency: "USD",
-				},
+				Amount: money.MustParse("100.50", "USD"),
+				Status: "pending",
 			},
-			mockSettingsCursor:    "",
-			mockSettingsError:     nil,
+			mockSettings:          usdLimits,
+			expectCreate:          true,
 			mockCreateError:       pkgerrors.ErrDuplicatedData,
 			expectError:           true,
 			expectedErrorContains: "DATA_DUPLICATE",
@@ -86,16 +191,22 @@ func TestPaymentService_CreatePayment(t *testing.T) {
 			mockRepo := mocks.NewMockIPaymentRepository(t)
 			mockSettingsPort := mocks.NewMockIPaymentSettingsPort(t)
 
-			mockSettingsPort.On("FetchPaymentSettings", mock.MatchedBy(func(params paymentsettings.PaymentSettingFetchParams) bool {
-				return params.Currency == tt.payment.Currency && params.Limit == 1
-			})).Return(tt.mockSettingsResponse, tt.mockSettingsCursor, tt.mockSettingsError)
+			mockSettingsPort.On("GetEffectiveSettings", mock.Anything, paymentsettings.EffectiveSettingsParams{Currency: tt.payment.Currency()}).
+				Return(effectiveSettingsOf(tt.payment.Currency(), tt.mockSettings), tt.mockSettingsError).Maybe()
 
-			if tt.mockSettingsError == nil {
-				mockRepo.On("CreatePayment", tt.payment).Return(tt.mockCreateError)
+			mockGateway := mocks.NewMockIPaymentGateway(t)
+
+			if tt.expectCreate {
+				mockRepo.On("CreatePayment", mock.Anything, tt.payment).Return(tt.mockCreateError)
+			}
+			if tt.expectCreate && tt.mockCreateError == nil {
+				mockGateway.On("Authorize", mock.Anything, mock.Anything).Return(ports.GatewayResult{Reference: "sim_ref", Status: ports.GatewayStatusAuthorized}, nil)
+				mockGateway.On("Name").Return("simulator")
+				mockRepo.On("UpdatePayment", mock.Anything, mock.Anything).Return(nil)
 			}
 
-			service := NewPaymentService(mockRepo, mockSettingsPort)
-			err := service.CreatePayment(tt.payment)
+			service := NewPaymentService(mockRepo, mockSettingsPort, inlineTxManager{}, mockGateway, mocks.NewMockIProviderEventRepository(t), anyWebhookPort(t), anyEventOutbox(t), anyEventPublisher(t))
+			err := service.CreatePayment(context.Background(), tt.payment)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -109,6 +220,76 @@ func TestPaymentService_CreatePayment(t *testing.T) {
 	}
 }
 
+func TestPaymentService_CreatePayment_Authorization(t *testing.T) {
+	limits := effectiveSettingsOf("USD", map[string]string{
+		paymentsettings.SettingKeyMinTransactionAmount: "1.00",
+		paymentsettings.SettingKeyMaxTransactionAmount: "10000.00",
+	})
+
+	tests := []struct {
+		name              string
+		authorizeResult   ports.GatewayResult
+		authorizeError    error
+		expectUpdate      bool
+		expectedStatus    string
+		expectedReference string
+	}{
+		{
+			name:              "authorized payment records the provider reference",
+			authorizeResult:   ports.GatewayResult{Reference: "sim_pay_1", Status: ports.GatewayStatusAuthorized},
+			expectUpdate:      true,
+			expectedStatus:    payment.StatusPending,
+			expectedReference: "sim_pay_1",
+		},
+		{
+			name:              "declined payment fails",
+			authorizeResult:   ports.GatewayResult{Reference: "sim_pay_1", Status: ports.GatewayStatusDeclined, DeclineReason: "insufficient funds"},
+			expectUpdate:      true,
+			expectedStatus:    payment.StatusFailed,
+			expectedReference: "sim_pay_1",
+		},
+		{
+			name:           "gateway timeout leaves the payment pending",
+			authorizeError: ports.ErrGatewayTimeout,
+			expectedStatus: payment.StatusPending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := mocks.NewMockIPaymentRepository(t)
+			mockSettingsPort := mocks.NewMockIPaymentSettingsPort(t)
+			mockGateway := mocks.NewMockIPaymentGateway(t)
+
+			mockSettingsPort.On("GetEffectiveSettings", mock.Anything, mock.Anything).Return(limits, nil)
+			// The authorization key is stored with the payment before the gateway is called
+			var storedKey string
+			mockRepo.On("CreatePayment", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				created := args.Get(1).(*payment.Payment)
+				created.ID = "pay_1"
+				storedKey = created.AuthorizationKey
+			}).Return(nil)
+			mockGateway.On("Authorize", mock.Anything, mock.MatchedBy(func(req ports.AuthorizeRequest) bool {
+				return req.PaymentID == "pay_1" && req.IdempotencyKey != "" && req.IdempotencyKey == storedKey
+			})).Return(tt.authorizeResult, tt.authorizeError)
+			if tt.expectUpdate {
+				mockGateway.On("Name").Return("simulator")
+				mockRepo.On("UpdatePayment", mock.Anything, mock.MatchedBy(func(p *payment.Payment) bool {
+					return p.Status == tt.expectedStatus && p.Provider == "simulator" && p.ProviderReference == tt.expectedReference
+				})).Return(nil)
+			}
+
+			p := &payment.Payment{Amount: money.MustParse("100.00", "USD")}
+			service := NewPaymentService(mockRepo, mockSettingsPort, inlineTxManager{}, mockGateway, mocks.NewMockIProviderEventRepository(t), anyWebhookPort(t), anyEventOutbox(t), anyEventPublisher(t))
+			err := service.CreatePayment(context.Background(), p)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, p.Status)
+			assert.Equal(t, tt.expectedReference, p.ProviderReference)
+		})
+	}
+}
+
 func TestPaymentService_GetPayment(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -122,10 +303,9 @@ func TestPaymentService_GetPayment(t *testing.T) {
 			name:      "successful payment retrieval",
 			paymentID: "pay_123",
 			mockPayment: payment.Payment{
-				ID:       "pay_123",
-				Amount:   100.50,
-				Currency: "USD",
-				Status:   "completed",
+				ID:     "pay_123",
+				Amount: money.MustParse("100.50", "USD"),
+				Status: "completed",
 			},
 			mockError:   nil,
 			expectError: false,
@@ -152,10 +332,10 @@ func TestPaymentService_GetPayment(t *testing.T) {
 			mockRepo := mocks.NewMockIPaymentRepository(t)
 			mockSettingsPort := mocks.NewMockIPaymentSettingsPort(t)
 
-			mockRepo.On("GetPayment", tt.paymentID).Return(tt.mockPayment, tt.mockError)
+			mockRepo.On("GetPayment", mock.Anything, tt.paymentID).Return(tt.mockPayment, tt.mockError)
 
-			service := NewPaymentService(mockRepo, mockSettingsPort)
-			result, err := service.GetPayment(tt.paymentID)
+			service := NewPaymentService(mockRepo, mockSettingsPort, inlineTxManager{}, mocks.NewMockIPaymentGateway(t), mocks.NewMockIProviderEventRepository(t), anyWebhookPort(t), anyEventOutbox(t), anyEventPublisher(t))
+			result, err := service.GetPayment(context.Background(), tt.paymentID)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -190,15 +370,13 @@ func TestPaymentService_FetchPayments(t *testing.T) {
 			mockPayments: []payment.Payment{
 				{
 					ID:        "pay_123",
-					Amount:    100.50,
-					Currency:  "USD",
+					Amount:    money.MustParse("100.50", "USD"),
 					Status:    "completed",
 					CreatedAt: time.Now(),
 				},
 				{
 					ID:        "pay_124",
-					Amount:    200.00,
-					Currency:  "USD",
+					Amount:    money.MustParse("200.00", "USD"),
 					Status:    "completed",
 					CreatedAt: time.Now(),
 				},
@@ -236,10 +414,10 @@ func TestPaymentService_FetchPayments(t *testing.T) {
 			mockRepo := mocks.NewMockIPaymentRepository(t)
 			mockSettingsPort := mocks.NewMockIPaymentSettingsPort(t)
 
-			mockRepo.On("FetchPayments", tt.params).Return(tt.mockPayments, tt.mockCursor, tt.mockError)
+			mockRepo.On("FetchPayments", mock.Anything, tt.params).Return(tt.mockPayments, tt.mockCursor, tt.mockError)
 
-			service := NewPaymentService(mockRepo, mockSettingsPort)
-			result, cursor, err := service.FetchPayments(tt.params)
+			service := NewPaymentService(mockRepo, mockSettingsPort, inlineTxManager{}, mocks.NewMockIPaymentGateway(t), mocks.NewMockIProviderEventRepository(t), anyWebhookPort(t), anyEventOutbox(t), anyEventPublisher(t))
+			result, cursor, err := service.FetchPayments(context.Background(), tt.params)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -254,43 +432,111 @@ func TestPaymentService_FetchPayments(t *testing.T) {
 
 func TestPaymentService_UpdatePayment(t *testing.T) {
 	tests := []struct {
-		name        string
-		payment     *payment.Payment
-		mockError   error
-		expectError bool
+		name           string
+		payment        *payment.Payment
+		currentStatus  string
+		currentVersion int64
+		mockGetError   error
+		expectUpdate   bool
+		mockError      error
+		expectError    bool
+		expectedStatus string
+		expectedCode   string
 	}{
 		{
 			name: "successful payment update",
 			payment: &payment.Payment{
-				ID:       "pay_123",
-				Amount:   150.00,
-				Currency: "USD",
-				Status:   "completed",
+				ID:     "pay_123",
+				Amount: money.MustParse("150.00", "USD"),
+				Status: "completed",
 			},
-			mockError:   nil,
-			expectError: false,
+			currentStatus:  "processing",
+			expectUpdate:   true,
+			expectError:    false,
+			expectedStatus: "completed",
+		},
+		{
+			name: "empty status keeps the current status",
+			payment: &payment.Payment{
+				ID:     "pay_123",
+				Amount: money.MustParse("150.00", "USD"),
+			},
+			currentStatus:  "pending",
+			expectUpdate:   true,
+			expectError:    false,
+			expectedStatus: "pending",
+		},
+		{
+			name: "illegal status transition",
+			payment: &payment.Payment{
+				ID:     "pay_123",
+				Amount: money.MustParse("150.00", "USD"),
+				Status: "pending",
+			},
+			currentStatus: "completed",
+			expectError:   true,
+			expectedCode:  pkgerrors.ErrorCodeConflict,
+		},
+		{
+			name: "unknown status",
+			payment: &payment.Payment{
+				ID:     "pay_123",
+				Amount: money.MustParse("150.00", "USD"),
+				Status: "refunded",
+			},
+			currentStatus: "pending",
+			expectError:   true,
+			expectedCode:  pkgerrors.ErrorCodeValidation,
+		},
+		{
+			name: "matching version",
+			payment: &payment.Payment{
+				ID:      "pay_123",
+				Amount:  money.MustParse("150.00", "USD"),
+				Status:  "completed",
+				Version: 2,
+			},
+			currentStatus:  "processing",
+			currentVersion: 2,
+			expectUpdate:   true,
+			expectError:    false,
+			expectedStatus: "completed",
+		},
+		{
+			name: "stale version",
+			payment: &payment.Payment{
+				ID:      "pay_123",
+				Amount:  money.MustParse("150.00", "USD"),
+				Status:  "completed",
+				Version: 1,
+			},
+			currentStatus:  "processing",
+			currentVersion: 2,
+			expectError:    true,
+			expectedCode:   pkgerrors.ErrorCodePreconditionFailed,
 		},
 		{
 			name: "payment not found",
 			payment: &payment.Payment{
-				ID:       "pay_nonexistent",
-				Amount:   150.00,
-				Currency: "USD",
-				Status:   "completed",
+				ID:     "pay_nonexistent",
+				Amount: money.MustParse("150.00", "USD"),
+				Status: "completed",
 			},
-			mockError:   pkgerrors.ErrDataNotFound,
-			expectError: true,
+			mockGetError: pkgerrors.ErrDataNotFound,
+			expectError:  true,
+			expectedCode: pkgerrors.ErrorCodeDataNotFound,
 		},
 		{
 			name: "database error",
 			payment: &payment.Payment{
-				ID:       "pay_123",
-				Amount:   150.00,
-				Currency: "USD",
-				Status:   "completed",
+				ID:     "pay_123",
+				Amount: money.MustParse("150.00", "USD"),
+				Status: "completed",
 			},
-			mockError:   errors.New("update failed"),
-			expectError: true,
+			currentStatus: "processing",
+			expectUpdate:  true,
+			mockError:     errors.New("update failed"),
+			expectError:   true,
 		},
 	}
 
@@ -299,15 +545,22 @@ func TestPaymentService_UpdatePayment(t *testing.T) {
 			mockRepo := mocks.NewMockIPaymentRepository(t)
 			mockSettingsPort := mocks.NewMockIPaymentSettingsPort(t)
 
-			mockRepo.On("UpdatePayment", tt.payment).Return(tt.mockError)
+			mockRepo.On("GetPayment", mock.Anything, tt.payment.ID).Return(payment.Payment{ID: tt.payment.ID, Status: tt.currentStatus, Version: tt.currentVersion}, tt.mockGetError)
+			if tt.expectUpdate {
+				mockRepo.On("UpdatePayment", mock.Anything, tt.payment).Return(tt.mockError)
+			}
 
-			service := NewPaymentService(mockRepo, mockSettingsPort)
-			err := service.UpdatePayment(tt.payment)
+			service := NewPaymentService(mockRepo, mockSettingsPort, inlineTxManager{}, mocks.NewMockIPaymentGateway(t), mocks.NewMockIProviderEventRepository(t), anyWebhookPort(t), anyEventOutbox(t), anyEventPublisher(t))
+			err := service.UpdatePayment(context.Background(), tt.payment)
 
 			if tt.expectError {
 				assert.Error(t, err)
+				if tt.expectedCode != "" {
+					assert.True(t, pkgerrors.IsErrorCode(err, tt.expectedCode), "unexpected error: %v", err)
+				}
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedStatus, tt.payment.Status)
 			}
 		})
 	}
@@ -317,19 +570,29 @@ func TestPaymentService_DeletePayment(t *testing.T) {
 	tests := []struct {
 		name        string
 		paymentID   string
+		version     int64
+		getError    error
 		mockError   error
 		expectError bool
 	}{
 		{
 			name:        "successful payment deletion",
 			paymentID:   "pay_123",
+			version:     1,
 			mockError:   nil,
 			expectError: false,
 		},
+		{
+			name:        "stale version",
+			paymentID:   "pay_123",
+			version:     1,
+			mockError:   pkgerrors.ErrPreconditionFailed,
+			expectError: true,
+		},
 		{
 			name:        "payment not found",
 			paymentID:   "pay_nonexistent",
-			mockError:   pkgerrors.ErrDataNotFound,
+			getError:    pkgerrors.ErrDataNotFound,
 			expectError: true,
 		},
 		{
@@ -345,10 +608,395 @@ func TestPaymentService_DeletePayment(t *testing.T) {
 			mockRepo := mocks.NewMockIPaymentRepository(t)
 			mockSettingsPort := mocks.NewMockIPaymentSettingsPort(t)
 
-			mockRepo.On("DeletePayment", tt.paymentID).Return(tt.mockError)
+			mockRepo.On("GetPayment", mock.Anything, tt.paymentID).Return(payment.Payment{ID: tt.paymentID, Amount: money.MustParse("100.00", "USD"), Version: tt.version}, tt.getError)
+			if tt.getError == nil {
+				mockRepo.On("DeletePayment", mock.Anything, tt.paymentID, tt.version).Return(tt.mockError)
+			}
+
+			service := NewPaymentService(mockRepo, mockSettingsPort, inlineTxManager{}, mocks.NewMockIPaymentGateway(t), mocks.NewMockIProviderEventRepository(t), anyWebhookPort(t), anyEventOutbox(t), anyEventPublisher(t))
+			err := service.DeletePayment(context.Background(), tt.paymentID, tt.version)
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPaymentService_ClaimPendingPayments(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      payment.ClaimPaymentsParams
+		callsRepo   bool
+		expectError bool
+	}{
+		{
+			name:      "valid claim",
+			params:    payment.ClaimPaymentsParams{Owner: "worker-1", Limit: 10, LeaseDuration: time.Minute},
+			callsRepo: true,
+		},
+		{
+			name:        "missing owner",
+			params:      payment.ClaimPaymentsParams{Limit: 10, LeaseDuration: time.Minute},
+			expectError: true,
+		},
+		{
+			name:        "missing lease duration",
+			params:      payment.ClaimPaymentsParams{Owner: "worker-1", Limit: 10},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := mocks.NewMockIPaymentRepository(t)
+			mockSettingsPort := mocks.NewMockIPaymentSettingsPort(t)
+
+			if tt.callsRepo {
+				mockRepo.On("ClaimPendingPayments", mock.Anything, tt.params).Return([]payment.Payment{{ID: "pay_1"}}, "", nil)
+			}
+
+			service := NewPaymentService(mockRepo, mockSettingsPort, inlineTxManager{}, mocks.NewMockIPaymentGateway(t), mocks.NewMockIProviderEventRepository(t), anyWebhookPort(t), anyEventOutbox(t), anyEventPublisher(t))
+			result, _, err := service.ClaimPendingPayments(context.Background(), tt.params)
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, result, 1)
+			}
+		})
+	}
+}
+
+func TestPaymentService_ProcessPayment(t *testing.T) {
+	tests := []struct {
+		name            string
+		payment         payment.Payment
+		authorizeResult *ports.GatewayResult
+		captureResult   ports.GatewayResult
+		captureError    error
+		statusResult    *ports.GatewayResult
+		expectCapture   bool
+		expectError     bool
+		expectedCode    string
+		expectedStatus  string
+	}{
+		{
+			name:           "authorized payment is captured and completed",
+			payment:        payment.Payment{ID: "pay_1", Status: payment.StatusPending, Provider: "simulator", ProviderReference: "sim_pay_1"},
+			expectCapture:  true,
+			captureResult:  ports.GatewayResult{Reference: "sim_pay_1", Status: ports.GatewayStatusCaptured},
+			expectedStatus: payment.StatusCompleted,
+		},
+		{
+			name:            "payment without reference is authorized first",
+			payment:         payment.Payment{ID: "pay_1", Status: payment.StatusPending},
+			authorizeResult: &ports.GatewayResult{Reference: "sim_pay_1", Status: ports.GatewayStatusAuthorized},
+			expectCapture:   true,
+			captureResult:   ports.GatewayResult{Reference: "sim_pay_1", Status: ports.GatewayStatusCaptured},
+			expectedStatus:  payment.StatusCompleted,
+		},
+		{
+			name:            "retried authorization reuses the stored key",
+			payment:         payment.Payment{ID: "pay_1", Status: payment.StatusPending, AuthorizationKey: "auth_1"},
+			authorizeResult: &ports.GatewayResult{Reference: "sim_pay_1", Status: ports.GatewayStatusAuthorized},
+			expectCapture:   true,
+			captureResult:   ports.GatewayResult{Reference: "sim_pay_1", Status: ports.GatewayStatusCaptured},
+			expectedStatus:  payment.StatusCompleted,
+		},
+		{
+			name:            "declined authorization fails the payment",
+			payment:         payment.Payment{ID: "pay_1", Status: payment.StatusPending},
+			authorizeResult: &ports.GatewayResult{Reference: "sim_pay_1", Status: ports.GatewayStatusDeclined},
+			expectedStatus:  payment.StatusFailed,
+		},
+		{
+			name:           "declined capture fails the payment",
+			payment:        payment.Payment{ID: "pay_1", Status: payment.StatusPending, Provider: "simulator", ProviderReference: "sim_pay_1"},
+			expectCapture:  true,
+			captureResult:  ports.GatewayResult{Reference: "sim_pay_1", Status: ports.GatewayStatusDeclined},
+			expectedStatus: payment.StatusFailed,
+		},
+		{
+			name:           "capture timeout is resolved through the status query",
+			payment:        payment.Payment{ID: "pay_1", Status: payment.StatusPending, Provider: "simulator", ProviderReference: "sim_pay_1"},
+			expectCapture:  true,
+			captureError:   ports.ErrGatewayTimeout,
+			statusResult:   &ports.GatewayResult{Reference: "sim_pay_1", Status: ports.GatewayStatusCaptured},
+			expectedStatus: payment.StatusCompleted,
+		},
+		{
+			name:           "unresolved capture leaves the payment processing",
+			payment:        payment.Payment{ID: "pay_1", Status: payment.StatusPending, Provider: "simulator", ProviderReference: "sim_pay_1"},
+			expectCapture:  true,
+			captureError:   ports.ErrGatewayTimeout,
+			statusResult:   &ports.GatewayResult{Reference: "sim_pay_1", Status: ports.GatewayStatusPending},
+			expectError:    true,
+			expectedStatus: payment.StatusProcessing,
+		},
+		{
+			name:           "unresolved processing payment is captured again",
+			payment:        payment.Payment{ID: "pay_1", Status: payment.StatusProcessing, Provider: "simulator", ProviderReference: "sim_pay_1"},
+			expectCapture:  true,
+			captureResult:  ports.GatewayResult{Reference: "sim_pay_1", Status: ports.GatewayStatusCaptured},
+			expectedStatus: payment.StatusCompleted,
+		},
+		{
+			name:           "payment that is not pending",
+			payment:        payment.Payment{ID: "pay_1", Status: payment.StatusCompleted},
+			expectError:    true,
+			expectedCode:   pkgerrors.ErrorCodeConflict,
+			expectedStatus: payment.StatusCompleted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := mocks.NewMockIPaymentRepository(t)
+			mockSettingsPort := mocks.NewMockIPaymentSettingsPort(t)
+			mockGateway := mocks.NewMockIPaymentGateway(t)
+
+			// The repository mirrors the status saved last, as the service reads it back before every update
+			stored := tt.payment
+			mockRepo.On("GetPayment", mock.Anything, "pay_1").Return(func(context.Context, string) (payment.Payment, error) {
+				return stored, nil
+			}).Maybe()
+			mockRepo.On("UpdatePayment", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				stored = *args.Get(1).(*payment.Payment)
+			}).Return(nil).Maybe()
+
+			if tt.authorizeResult != nil {
+				// The authorization key is saved before the gateway is called
+				mockGateway.On("Authorize", mock.Anything, mock.MatchedBy(func(req ports.AuthorizeRequest) bool {
+					return req.IdempotencyKey != "" && req.IdempotencyKey == stored.AuthorizationKey
+				})).Return(*tt.authorizeResult, nil)
+				mockGateway.On("Name").Return("simulator")
+			}
+			if tt.expectCapture {
+				mockGateway.On("Capture", mock.Anything, "sim_pay_1", mock.Anything).Return(tt.captureResult, tt.captureError)
+			}
+			if tt.statusResult != nil {
+				mockGateway.On("GetStatus", mock.Anything, "sim_pay_1").Return(*tt.statusResult, nil)
+			}
+
+			p := tt.payment
+			service := NewPaymentService(mockRepo, mockSettingsPort, inlineTxManager{}, mockGateway, mocks.NewMockIProviderEventRepository(t), anyWebhookPort(t), anyEventOutbox(t), anyEventPublisher(t))
+			err := service.ProcessPayment(context.Background(), &p)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				if tt.expectedCode != "" {
+					assert.True(t, pkgerrors.IsErrorCode(err, tt.expectedCode), "unexpected error: %v", err)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "sim_pay_1", p.ProviderReference)
+			}
+			assert.Equal(t, tt.expectedStatus, p.Status)
+			assert.Equal(t, tt.expectedStatus, stored.Status)
+		})
+	}
+}
+
+func TestPaymentService_HandleProviderEvent(t *testing.T) {
+	event := func(status string) payment.ProviderEvent {
+		return payment.ProviderEvent{Provider: "simulator", EventID: "evt_1", Reference: "sim_pay_1", Status: status}
+	}
+
+	tests := []struct {
+		name              string
+		event             payment.ProviderEvent
+		currentStatus     string
+		mockGetError      error
+		expectSave        bool
+		mockCreated       bool
+		expectUpdate      bool
+		expectedStatus    string
+		expectedDuplicate bool
+		expectedCode      string
+	}{
+		{
+			name:           "capture completes a processing payment",
+			event:          event(ports.GatewayStatusCaptured),
+			currentStatus:  payment.StatusProcessing,
+			expectSave:     true,
+			mockCreated:    true,
+			expectUpdate:   true,
+			expectedStatus: payment.StatusCompleted,
+		},
+		{
+			name:           "capture completes a pending payment through processing",
+			event:          event(ports.GatewayStatusCaptured),
+			currentStatus:  payment.StatusPending,
+			expectSave:     true,
+			mockCreated:    true,
+			expectUpdate:   true,
+			expectedStatus: payment.StatusCompleted,
+		},
+		{
+			name:           "decline fails the payment",
+			event:          event(ports.GatewayStatusDeclined),
+			currentStatus:  payment.StatusProcessing,
+			expectSave:     true,
+			mockCreated:    true,
+			expectUpdate:   true,
+			expectedStatus: payment.StatusFailed,
+		},
+		{
+			name:           "authorization is recorded without a status change",
+			event:          event(ports.GatewayStatusAuthorized),
+			currentStatus:  payment.StatusPending,
+			expectSave:     true,
+			mockCreated:    true,
+			expectedStatus: payment.StatusPending,
+		},
+		{
+			name:              "redelivered event is not applied again",
+			event:             event(ports.GatewayStatusCaptured),
+			currentStatus:     payment.StatusProcessing,
+			expectSave:        true,
+			expectedStatus:    payment.StatusProcessing,
+			expectedDuplicate: true,
+		},
+		{
+			name:           "late decline of a completed payment is ignored",
+			event:          event(ports.GatewayStatusDeclined),
+			currentStatus:  payment.StatusCompleted,
+			expectSave:     true,
+			mockCreated:    true,
+			expectedStatus: payment.StatusCompleted,
+		},
+		{
+			name:         "unknown provider status",
+			event:        event("chargeback"),
+			expectedCode: pkgerrors.ErrorCodeValidation,
+		},
+		{
+			name:         "missing event ID",
+			event:        payment.ProviderEvent{Provider: "simulator", Reference: "sim_pay_1", Status: ports.GatewayStatusCaptured},
+			expectedCode: pkgerrors.ErrorCodeValidation,
+		},
+		{
+			name:          "unknown payment reference",
+			event:         event(ports.GatewayStatusCaptured),
+			currentStatus: payment.StatusProcessing,
+			mockGetError:  pkgerrors.ErrDataNotFound,
+			expectedCode:  pkgerrors.ErrorCodeDataNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := mocks.NewMockIPaymentRepository(t)
+			mockEventRepo := mocks.NewMockIProviderEventRepository(t)
+
+			current := payment.Payment{ID: "pay_1", Status: tt.currentStatus, Version: 3, Provider: "simulator", ProviderReference: "sim_pay_1"}
+			if tt.currentStatus != "" {
+				mockRepo.On("GetPaymentByProviderReference", mock.Anything, "simulator", "sim_pay_1").Return(current, tt.mockGetError)
+			}
+			if tt.expectSave {
+				mockEventRepo.On("SaveProviderEvent", mock.Anything, tt.event, "pay_1").Return(tt.mockCreated, nil)
+			}
+			if tt.expectUpdate {
+				mockRepo.On("UpdatePayment", mock.Anything, mock.MatchedBy(func(p *payment.Payment) bool {
+					return p.ID == "pay_1" && p.Version == 3 && p.Status == tt.expectedStatus
+				})).Return(nil)
+			}
+
+			service := NewPaymentService(mockRepo, mocks.NewMockIPaymentSettingsPort(t), inlineTxManager{}, mocks.NewMockIPaymentGateway(t), mockEventRepo, anyWebhookPort(t), anyEventOutbox(t), anyEventPublisher(t))
+			duplicate, err := service.HandleProviderEvent(context.Background(), tt.event)
+
+			if tt.expectedCode != "" {
+				assert.True(t, pkgerrors.IsErrorCode(err, tt.expectedCode), "unexpected error: %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedDuplicate, duplicate)
+		})
+	}
+}
+
+func TestPaymentService_PublishesWebhookEvents(t *testing.T) {
+	decodeData := func(t *testing.T, event webhooks.Event) (data payment.EventData) {
+		assert.NoError(t, json.Unmarshal(event.Data, &data))
+		return data
+	}
+
+	t.Run("declined payment publishes created and status changed", func(t *testing.T) {
+		mockRepo := mocks.NewMockIPaymentRepository(t)
+		mockSettingsPort := mocks.NewMockIPaymentSettingsPort(t)
+		mockGateway := mocks.NewMockIPaymentGateway(t)
+		mockWebhookPort := mocks.NewMockIWebhookPort(t)
+
+		mockSettingsPort.On("GetEffectiveSettings", mock.Anything, mock.Anything).Return(effectiveSettingsOf("USD", map[string]string{
+			paymentsettings.SettingKeyMinTransactionAmount: "10.00",
+			paymentsettings.SettingKeyMaxTransactionAmount: "10000.00",
+		}), nil)
+		mockRepo.On("CreatePayment", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(*payment.Payment).ID = "pay_123"
+		}).Return(nil)
+		mockGateway.On("Authorize", mock.Anything, mock.Anything).Return(ports.GatewayResult{Reference: "sim_pay_123", Status: ports.GatewayStatusDeclined}, nil)
+		mockGateway.On("Name").Return("simulator")
+		mockRepo.On("UpdatePayment", mock.Anything, mock.Anything).Return(nil)
+
+		var events []webhooks.Event
+		mockWebhookPort.On("Publish", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			events = append(events, args.Get(1).(webhooks.Event))
+		}).Return(nil)
+
+		service := NewPaymentService(mockRepo, mockSettingsPort, inlineTxManager{}, mockGateway, mocks.NewMockIProviderEventRepository(t), mockWebhookPort, anyEventOutbox(t), anyEventPublisher(t))
+		err := service.CreatePayment(context.Background(), &payment.Payment{Amount: money.MustParse("100.00", "USD")})
+
+		assert.NoError(t, err)
+		if assert.Len(t, events, 2) {
+			assert.Equal(t, payment.EventTypePaymentCreated, events[0].Type)
+			created := decodeData(t, events[0])
+			assert.Equal(t, "pay_123", created.ID)
+			assert.Equal(t, "100.00", created.Amount)
+			assert.Equal(t, payment.StatusPending, created.Status)
+
+			assert.Equal(t, payment.EventTypePaymentStatusChanged, events[1].Type)
+			changed := decodeData(t, events[1])
+			assert.Equal(t, payment.StatusFailed, changed.Status)
+			assert.Equal(t, payment.StatusPending, changed.PreviousStatus)
+			assert.Equal(t, "sim_pay_123", changed.ProviderReference)
+		}
+	})
+
+	updateTests := []struct {
+		name          string
+		currentStatus string
+		newStatus     string
+		publishError  error
+		expectPublish bool
+		expectError   bool
+	}{
+		{name: "status change is published", currentStatus: payment.StatusPending, newStatus: payment.StatusProcessing, expectPublish: true},
+		{name: "unchanged status is not published", currentStatus: payment.StatusPending, newStatus: payment.StatusPending},
+		{name: "publish failure fails the update", currentStatus: payment.StatusPending, newStatus: payment.StatusProcessing, publishError: errors.New("insert failed"), expectPublish: true, expectError: true},
+	}
+
+	for _, tt := range updateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := mocks.NewMockIPaymentRepository(t)
+			mockWebhookPort := mocks.NewMockIWebhookPort(t)
+
+			mockRepo.On("GetPayment", mock.Anything, "pay_123").Return(payment.Payment{ID: "pay_123", Amount: money.MustParse("100.00", "USD"), Status: tt.currentStatus, Version: 1}, nil)
+			mockRepo.On("UpdatePayment", mock.Anything, mock.Anything).Return(nil)
+			if tt.expectPublish {
+				mockWebhookPort.On("Publish", mock.Anything, mock.MatchedBy(func(event webhooks.Event) bool {
+					var data payment.EventData
+					return event.Type == payment.EventTypePaymentStatusChanged &&
+						json.Unmarshal(event.Data, &data) == nil &&
+						data.Status == tt.newStatus && data.PreviousStatus == tt.currentStatus
+				})).Return(tt.publishError)
+			}
 
-			service := NewPaymentService(mockRepo, mockSettingsPort)
-			err := service.DeletePayment(tt.paymentID)
+			service := NewPaymentService(mockRepo, mocks.NewMockIPaymentSettingsPort(t), inlineTxManager{}, mocks.NewMockIPaymentGateway(t), mocks.NewMockIProviderEventRepository(t), mockWebhookPort, anyEventOutbox(t), anyEventPublisher(t))
+			err := service.UpdatePayment(context.Background(), &payment.Payment{ID: "pay_123", Amount: money.MustParse("100.00", "USD"), Status: tt.newStatus})
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -358,3 +1006,107 @@ func TestPaymentService_DeletePayment(t *testing.T) {
 		})
 	}
 }
+
+func TestPaymentService_RecordsOutboxEvents(t *testing.T) {
+	t.Run("outbox and webhook share the event ID", func(t *testing.T) {
+		mockRepo := mocks.NewMockIPaymentRepository(t)
+		mockWebhookPort := mocks.NewMockIWebhookPort(t)
+		mockOutbox := mocks.NewMockIEventOutbox(t)
+
+		mockRepo.On("GetPayment", mock.Anything, "pay_123").Return(payment.Payment{ID: "pay_123", Amount: money.MustParse("100.00", "USD"), Status: payment.StatusPending, Version: 1}, nil)
+		mockRepo.On("UpdatePayment", mock.Anything, mock.Anything).Return(nil)
+
+		var recorded []outbox.Event
+		mockOutbox.On("Append", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			for _, event := range args[1:] {
+				recorded = append(recorded, event.(outbox.Event))
+			}
+		}).Return(nil)
+		var published []webhooks.Event
+		mockWebhookPort.On("Publish", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			published = append(published, args.Get(1).(webhooks.Event))
+		}).Return(nil)
+
+		service := NewPaymentService(mockRepo, mocks.NewMockIPaymentSettingsPort(t), inlineTxManager{}, mocks.NewMockIPaymentGateway(t), mocks.NewMockIProviderEventRepository(t), mockWebhookPort, mockOutbox, anyEventPublisher(t))
+		err := service.UpdatePayment(context.Background(), &payment.Payment{ID: "pay_123", Amount: money.MustParse("100.00", "USD"), Status: payment.StatusProcessing})
+
+		assert.NoError(t, err)
+		if assert.Len(t, recorded, 1) && assert.Len(t, published, 1) {
+			assert.Equal(t, payment.EventTypePaymentStatusChanged, recorded[0].Type)
+			assert.Equal(t, "pay_123", recorded[0].AggregateID)
+			assert.NotEmpty(t, recorded[0].ID)
+			assert.Equal(t, recorded[0].ID, published[0].ID)
+			assert.JSONEq(t, string(recorded[0].Payload), string(published[0].Data))
+		}
+	})
+
+	t.Run("outbox failure fails the update before publishing", func(t *testing.T) {
+		mockRepo := mocks.NewMockIPaymentRepository(t)
+		mockOutbox := mocks.NewMockIEventOutbox(t)
+
+		mockRepo.On("GetPayment", mock.Anything, "pay_123").Return(payment.Payment{ID: "pay_123", Amount: money.MustParse("100.00", "USD"), Status: payment.StatusPending, Version: 1}, nil)
+		mockRepo.On("UpdatePayment", mock.Anything, mock.Anything).Return(nil)
+		mockOutbox.On("Append", mock.Anything, mock.Anything).Return(errors.New("insert failed"))
+
+		service := NewPaymentService(mockRepo, mocks.NewMockIPaymentSettingsPort(t), inlineTxManager{}, mocks.NewMockIPaymentGateway(t), mocks.NewMockIProviderEventRepository(t), mocks.NewMockIWebhookPort(t), mockOutbox, anyEventPublisher(t))
+		err := service.UpdatePayment(context.Background(), &payment.Payment{ID: "pay_123", Amount: money.MustParse("100.00", "USD"), Status: payment.StatusProcessing})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("delete records payment.deleted", func(t *testing.T) {
+		mockRepo := mocks.NewMockIPaymentRepository(t)
+		mockOutbox := mocks.NewMockIEventOutbox(t)
+
+		mockRepo.On("GetPayment", mock.Anything, "pay_123").Return(payment.Payment{ID: "pay_123", Amount: money.MustParse("100.00", "USD"), Status: payment.StatusPending, Version: 1}, nil)
+		mockRepo.On("DeletePayment", mock.Anything, "pay_123", int64(1)).Return(nil)
+		mockOutbox.On("Append", mock.Anything, mock.MatchedBy(func(event outbox.Event) bool {
+			return event.Type == payment.EventTypePaymentDeleted && event.AggregateID == "pay_123"
+		})).Return(nil)
+
+		service := NewPaymentService(mockRepo, mocks.NewMockIPaymentSettingsPort(t), inlineTxManager{}, mocks.NewMockIPaymentGateway(t), mocks.NewMockIProviderEventRepository(t), anyWebhookPort(t), mockOutbox, anyEventPublisher(t))
+		err := service.DeletePayment(context.Background(), "pay_123", 1)
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestPaymentService_PublishesBusEvents(t *testing.T) {
+	tests := []struct {
+		name         string
+		publishError error
+	}{
+		{name: "committed change is published with the outbox event ID"},
+		{name: "failing subscriber does not fail the committed change", publishError: errors.New("subscriber failed")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := mocks.NewMockIPaymentRepository(t)
+			mockOutbox := mocks.NewMockIEventOutbox(t)
+			mockPublisher := mocks.NewMockIEventPublisher(t)
+
+			mockRepo.On("GetPayment", mock.Anything, "pay_123").Return(payment.Payment{ID: "pay_123", Amount: money.MustParse("100.00", "USD"), Status: payment.StatusPending, Version: 1}, nil)
+			mockRepo.On("UpdatePayment", mock.Anything, mock.Anything).Return(nil)
+
+			var recorded outbox.Event
+			mockOutbox.On("Append", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				recorded = args.Get(1).(outbox.Event)
+			}).Return(nil)
+			var published payment.PaymentChanged
+			mockPublisher.On("Publish", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				published = args.Get(1).(payment.PaymentChanged)
+			}).Return(tt.publishError)
+
+			service := NewPaymentService(mockRepo, mocks.NewMockIPaymentSettingsPort(t), inlineTxManager{}, mocks.NewMockIPaymentGateway(t), mocks.NewMockIProviderEventRepository(t), anyWebhookPort(t), mockOutbox, mockPublisher)
+			err := service.UpdatePayment(context.Background(), &payment.Payment{ID: "pay_123", Amount: money.MustParse("100.00", "USD"), Status: payment.StatusProcessing})
+
+			assert.NoError(t, err)
+			assert.Equal(t, payment.EventTypePaymentStatusChanged, published.EventName())
+			assert.Equal(t, recorded.ID, published.EventID)
+			assert.Equal(t, "pay_123", published.Payment.ID)
+			assert.Equal(t, payment.StatusProcessing, published.Payment.Status)
+			assert.Equal(t, payment.StatusPending, published.Payment.PreviousStatus)
+		})
+	}
+}
@@ -1,11 +1,28 @@
 package payment
 
-import "github.com/labstack/echo/v4"
+import (
+	"context"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/outbox"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+// PaymentUpdaterJobName is the name the payment updater runs under in the scheduler and the job run history.
+const PaymentUpdaterJobName = "payment-updater"
+
+// OutboxRelayJobName is the name the relay publishing the module outbox runs under.
+const OutboxRelayJobName = "payment-outbox-relay"
+
+// IdempotencyPurgeJobName is the name the job deleting expired Idempotency-Key responses runs under.
+const IdempotencyPurgeJobName = "payment-idempotency-purge"
 
 // CronAdapter defines the contract for scheduled job operations within the module.
 // This is an inbound adapter allowing external cron schedulers to trigger module logic.
+// Implementations must stop promptly once ctx is cancelled.
 type CronAdapter interface {
-	Execute() (interface{}, error)
+	Execute(ctx context.Context) (interface{}, error)
 }
 
 // Module encapsulates the Payment module following hexagonal architecture.
@@ -14,6 +31,8 @@ type CronAdapter interface {
 //   - Service: The hexagon core (business logic)
 //   - RegisterController: Inbound adapter (REST API)
 //   - PaymentUpdater: Inbound adapter (cron job)
+//   - OutboxRelay: Publishes the module's domain events from its outbox, also used for replays
+//   - Jobs: Cron adapters exposed to the scheduler command under a name and default schedule
 //
 // The Module is the deployable unit in our modular monolith. It contains everything needed
 // for payment operations: domain logic, HTTP handlers, scheduled jobs, and database access.
@@ -23,6 +42,8 @@ type Module struct {
 	RegisterController func(*echo.Group)
 	// Cron adapters for scheduled jobs
 	PaymentUpdater CronAdapter
+	OutboxRelay    *outbox.Relay
+	Jobs           []scheduler.Job
 }
 
 // RegisterHTTPHandlers registers all HTTP endpoints for this module.
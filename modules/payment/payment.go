@@ -10,34 +10,150 @@
 // to operate independently while communicating with other modules via ports.
 package payment
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/money"
+)
 
 // Payment represents a payment transaction in the domain model.
 // This is the core entity in the payment bounded context.
+// Amount is an exact money value and carries the payment currency.
+// Version is incremented on every update and used for optimistic concurrency control.
+// Provider and ProviderReference identify the payment at the payment gateway once it was authorized.
+// AuthorizationKey is stored before the payment is authorized and sent along, so the provider
+// deduplicates a retried authorization.
+// TenantID is the merchant the payment belongs to, taken from the request that created it.
 type Payment struct {
-	ID        string    `json:"id"`
-	Amount    float64   `json:"amount"`
-	Currency  string    `json:"currency"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID                string      `json:"id"`
+	TenantID          string      `json:"tenantId"`
+	Amount            money.Money `json:"amount"`
+	Status            string      `json:"status"`
+	Version           int64       `json:"version"`
+	Provider          string      `json:"provider"`
+	ProviderReference string      `json:"providerReference"`
+	AuthorizationKey  string      `json:"authorizationKey"`
+	CreatedAt         time.Time   `json:"createdAt"`
+	UpdatedAt         time.Time   `json:"updatedAt"`
+}
+
+// Currency returns the ISO 4217 currency code of the payment amount.
+func (p Payment) Currency() string {
+	return p.Amount.Currency()
+}
+
+// ProviderEvent is an asynchronous notification from a payment service provider about a payment,
+// e.g. delivered by its webhook. Status is the provider's status of the payment; EventID is unique
+// per provider and used to drop redeliveries.
+type ProviderEvent struct {
+	Provider   string    `json:"provider"`
+	EventID    string    `json:"eventId"`
+	Reference  string    `json:"reference"`
+	Status     string    `json:"status"`
+	OccurredAt time.Time `json:"occurredAt"`
 }
 
+// Types of the payment domain events, recorded in the module outbox, published to merchant webhooks
+// and, once committed, published on the in-process event bus as PaymentChanged.
+// A payment.created event is recorded for every new payment, a payment.status_changed event for every
+// status change and a payment.deleted event for every deletion, all carrying EventData.
+const (
+	EventTypePaymentCreated       = "payment.created"
+	EventTypePaymentStatusChanged = "payment.status_changed"
+	EventTypePaymentDeleted       = "payment.deleted"
+)
+
+// EventData is the payload of a payment event. PreviousStatus is only set for payment.status_changed.
+type EventData struct {
+	ID                string    `json:"id"`
+	TenantID          string    `json:"tenantId"`
+	Amount            string    `json:"amount"`
+	Currency          string    `json:"currency"`
+	Status            string    `json:"status"`
+	PreviousStatus    string    `json:"previousStatus,omitempty"`
+	Version           int64     `json:"version"`
+	Provider          string    `json:"provider,omitempty"`
+	ProviderReference string    `json:"providerReference,omitempty"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+// PaymentChanged is published on the in-process event bus once a payment event committed.
+// Type is one of the EventTypePayment* constants; EventID matches the outbox and webhook event.
+type PaymentChanged struct {
+	EventID    string
+	Type       string
+	Payment    EventData
+	OccurredAt time.Time
+}
+
+// EventName implements eventbus.Event.
+func (e PaymentChanged) EventName() string {
+	return e.Type
+}
+
+// SettingsCacheStats reports the cache in front of the payment settings the module reads.
+// Misses include expired entries; SharedLoads are misses that waited for a load already in flight
+// instead of querying the settings themselves.
+type SettingsCacheStats struct {
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Loads         int64 `json:"loads"`
+	SharedLoads   int64 `json:"sharedLoads"`
+	Evictions     int64 `json:"evictions"`
+	Invalidations int64 `json:"invalidations"`
+	Entries       int   `json:"entries"`
+}
+
+// Sort orders for FetchPaymentsParams. Payments are listed newest first by default.
+const (
+	SortNewestFirst = "desc"
+	SortOldestFirst = "asc"
+)
+
 // FetchPaymentsParams contains filtering and pagination parameters for querying payments.
 type FetchPaymentsParams struct {
-	Cursor   string `json:"cursor"`
-	Limit    int    `json:"limit"`
-	Currency string `json:"currency"`
-	Status   string `json:"status"`
+	Cursor    string `json:"cursor"`
+	Limit     int    `json:"limit"`
+	Currency  string `json:"currency"`
+	Status    string `json:"status"`
+	SortOrder string `json:"sortOrder"`
+}
+
+// ClaimPaymentsParams describes a batch of payments to lease to one worker: pending payments, and
+// processing payments whose capture is unresolved. Payments are claimed oldest-first after Cursor;
+// rows locked or leased by other workers and payments deferred to a later attempt are skipped.
+type ClaimPaymentsParams struct {
+	Owner         string        `json:"owner"`
+	Limit         int           `json:"limit"`
+	Cursor        string        `json:"cursor"`
+	LeaseDuration time.Duration `json:"leaseDuration"`
 }
 
 // IPaymentService defines the public API of the Payment module.
 // This is the primary interface exposed to other modules in the monolith,
 // forming the module boundary. Other modules depend on this interface, not the implementation.
 type IPaymentService interface {
-	CreatePayment(payment *Payment) (err error)
-	GetPayment(id string) (payment Payment, err error)
-	FetchPayments(params FetchPaymentsParams) (result []Payment, nextCursor string, err error)
-	UpdatePayment(payment *Payment) (err error)
-	DeletePayment(id string) (err error)
+	CreatePayment(ctx context.Context, payment *Payment) (err error)
+	GetPayment(ctx context.Context, id string) (payment Payment, err error)
+	FetchPayments(ctx context.Context, params FetchPaymentsParams) (result []Payment, nextCursor string, err error)
+	// UpdatePayment applies the change only if payment.Version still matches the stored version (0 skips the check).
+	UpdatePayment(ctx context.Context, payment *Payment) (err error)
+	// ProcessPayment authorizes (if needed) and captures a pending payment with the payment gateway,
+	// moving it through processing to completed or failed. A processing payment is captured again to
+	// resolve an earlier capture whose outcome was unknown.
+	ProcessPayment(ctx context.Context, payment *Payment) (err error)
+	// HandleProviderEvent applies a provider event to the payment with the event's provider reference.
+	// duplicate is true when the event was already handled; it is then not applied again.
+	HandleProviderEvent(ctx context.Context, event ProviderEvent) (duplicate bool, err error)
+	// DeletePayment deletes the payment only if expectedVersion still matches the stored version (0 skips the check).
+	DeletePayment(ctx context.Context, id string, expectedVersion int64) (err error)
+	// ClaimPendingPayments leases up to params.Limit pending or unresolved processing payments to params.Owner
+	// until the lease expires, so concurrent workers process disjoint batches. nextCursor is empty when no more payments could be claimed.
+	ClaimPendingPayments(ctx context.Context, params ClaimPaymentsParams) (result []Payment, nextCursor string, err error)
+	// ReleasePaymentClaims drops every lease held by owner so other workers can pick those payments up.
+	ReleasePaymentClaims(ctx context.Context, owner string) (err error)
+	// DeferPayment keeps the payment from being claimed again before until, e.g. after a failed attempt,
+	// so payments that keep failing do not hold back the ones after them.
+	DeferPayment(ctx context.Context, id string, until time.Time) (err error)
 }
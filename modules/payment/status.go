@@ -0,0 +1,75 @@
+package payment
+
+import (
+	"fmt"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+)
+
+// Payment statuses. A payment starts as pending, is picked up for processing and
+// ends as completed or failed. Pending payments can also be cancelled, or fail directly
+// when the payment gateway declines the authorization.
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+	StatusCancelled  = "cancelled"
+)
+
+// statusTransitions lists the statuses reachable from each status.
+// Terminal statuses (completed, failed, cancelled) have no outgoing transitions.
+var statusTransitions = map[string][]string{
+	StatusPending:    {StatusProcessing, StatusCancelled, StatusFailed},
+	StatusProcessing: {StatusCompleted, StatusFailed},
+	StatusCompleted:  {},
+	StatusFailed:     {},
+	StatusCancelled:  {},
+}
+
+// IsValidStatus reports whether status is a known payment status.
+func IsValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+// IsTerminalStatus reports whether no further transitions are allowed from status.
+func IsTerminalStatus(status string) bool {
+	next, ok := statusTransitions[status]
+	return ok && len(next) == 0
+}
+
+// CanTransition reports whether a payment may move from one status to another.
+// Staying in the same status is always allowed.
+func CanTransition(from, to string) bool {
+	if from == to {
+		return IsValidStatus(from)
+	}
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateStatusTransition returns a conflict error when the transition is not allowed
+// and a validation error when the target status is unknown.
+func ValidateStatusTransition(from, to string) (err error) {
+	if !IsValidStatus(to) {
+		return errors.NewValidationError(fmt.Errorf("unknown payment status %q", to))
+	}
+	if !CanTransition(from, to) {
+		return errors.NewConflictError(fmt.Errorf("payment cannot transition from %q to %q", from, to))
+	}
+	return nil
+}
+
+// TransitionTo moves the payment to the given status if the state machine allows it.
+func (p *Payment) TransitionTo(status string) (err error) {
+	if err = ValidateStatusTransition(p.Status, status); err != nil {
+		return err
+	}
+	p.Status = status
+	return nil
+}
@@ -0,0 +1,46 @@
+package payment_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/payment"
+	pkgerrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+)
+
+func TestPayment_TransitionTo(t *testing.T) {
+	tests := []struct {
+		name         string
+		from         string
+		to           string
+		expectedCode string
+	}{
+		{name: "pending to processing", from: payment.StatusPending, to: payment.StatusProcessing},
+		{name: "pending to cancelled", from: payment.StatusPending, to: payment.StatusCancelled},
+		{name: "pending to failed", from: payment.StatusPending, to: payment.StatusFailed},
+		{name: "processing to completed", from: payment.StatusProcessing, to: payment.StatusCompleted},
+		{name: "processing to failed", from: payment.StatusProcessing, to: payment.StatusFailed},
+		{name: "same status", from: payment.StatusProcessing, to: payment.StatusProcessing},
+		{name: "pending to completed", from: payment.StatusPending, to: payment.StatusCompleted, expectedCode: pkgerrors.ErrorCodeConflict},
+		{name: "completed to pending", from: payment.StatusCompleted, to: payment.StatusPending, expectedCode: pkgerrors.ErrorCodeConflict},
+		{name: "failed to processing", from: payment.StatusFailed, to: payment.StatusProcessing, expectedCode: pkgerrors.ErrorCodeConflict},
+		{name: "cancelled to processing", from: payment.StatusCancelled, to: payment.StatusProcessing, expectedCode: pkgerrors.ErrorCodeConflict},
+		{name: "unknown target status", from: payment.StatusPending, to: "refunded", expectedCode: pkgerrors.ErrorCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &payment.Payment{ID: "pay_123", Status: tt.from}
+			err := p.TransitionTo(tt.to)
+
+			if tt.expectedCode != "" {
+				assert.True(t, pkgerrors.IsErrorCode(err, tt.expectedCode), "unexpected error: %v", err)
+				assert.Equal(t, tt.from, p.Status)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.to, p.Status)
+		})
+	}
+}
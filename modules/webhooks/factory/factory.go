@@ -0,0 +1,90 @@
+// Package factory provides module initialization and dependency wiring.
+//
+// The factory pattern is used to assemble modules in a modular monolith:
+//   - Instantiates all adapters (repositories, sender, controllers, cron jobs)
+//   - Wires dependencies through constructor injection
+//   - Returns a complete, ready-to-use Module
+//
+// This approach keeps the wiring logic separate from the domain and allows
+// different configurations for different environments (dev, test, prod).
+package factory
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/internal/adapter/controller"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/internal/adapter/cron"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/internal/adapter/repository"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/internal/adapter/sender"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/internal/service"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+// ModuleConfig contains all external dependencies required to initialize the Webhooks module.
+type ModuleConfig struct {
+	DB *sql.DB
+	// MaxAttempts is the number of attempts before a delivery fails for good (default 10).
+	MaxAttempts int
+	// InitialBackoff and MaxBackoff bound the exponential wait between attempts (default 30s and 6h).
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Timeout bounds a single delivery attempt (default 10s).
+	Timeout time.Duration
+	// DispatcherBatchSize is the number of due deliveries claimed at a time (default 50).
+	DispatcherBatchSize int
+	// DispatcherSchedule is the default cron expression of the dispatcher in the scheduler (default every 15 seconds).
+	DispatcherSchedule string
+}
+
+// NewModule assembles and wires the complete Webhooks module using dependency injection.
+//
+// This is where hexagonal architecture comes together:
+//  1. Create outbound adapters (repositories, HTTP sender)
+//  2. Inject adapters into the core service (hexagon)
+//  3. Create inbound adapters (HTTP controller, dispatcher job)
+//  4. Return the module with all components connected
+func NewModule(config ModuleConfig) *webhooks.Module {
+	// Wire up outbound adapters
+	endpointRepo := repository.NewEndpointRepository(config.DB)
+	deliveryRepo := repository.NewDeliveryRepository(config.DB)
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	httpSender := sender.NewHTTPSender(config.Timeout)
+
+	// Wire up the hexagon core (service)
+	webhookService := service.NewWebhookService(endpointRepo, deliveryRepo, httpSender, service.RetryPolicy{
+		MaxAttempts:    config.MaxAttempts,
+		InitialBackoff: config.InitialBackoff,
+		MaxBackoff:     config.MaxBackoff,
+	})
+
+	if config.DispatcherBatchSize <= 0 {
+		config.DispatcherBatchSize = 50
+	}
+
+	// Wire up cron adapters. The lease outlives a batch whose every attempt runs into the timeout,
+	// so a slow batch is never claimed twice.
+	dispatcher := cron.NewDispatcher(webhookService, cron.DispatcherConfig{
+		BatchSize:     config.DispatcherBatchSize,
+		LeaseDuration: time.Duration(config.DispatcherBatchSize)*config.Timeout + time.Minute,
+	})
+
+	if config.DispatcherSchedule == "" {
+		config.DispatcherSchedule = "@every 15s"
+	}
+
+	return &webhooks.Module{
+		Service: webhookService,
+		RegisterController: func(e *echo.Group) {
+			controller.NewWebhookController(e, webhookService)
+		},
+		Jobs: []scheduler.Job{
+			{Name: webhooks.DispatcherJobName, Schedule: config.DispatcherSchedule, Runner: dispatcher},
+		},
+	}
+}
@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+)
+
+// CreateEndpointRequest registers an endpoint. Secret is optional; one is generated when empty.
+type CreateEndpointRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"eventTypes"`
+	Status     string   `json:"status"`
+}
+
+func (r *CreateEndpointRequest) ToEndpoint() webhooks.Endpoint {
+	return webhooks.Endpoint{
+		URL:        r.URL,
+		Secret:     r.Secret,
+		EventTypes: r.EventTypes,
+		Status:     r.Status,
+	}
+}
@@ -0,0 +1,101 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+)
+
+// EndpointResponse never carries the signing secret; it is only returned when the endpoint is created.
+type EndpointResponse struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"eventTypes"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+func FromEndpointToResponse(e webhooks.Endpoint) EndpointResponse {
+	return EndpointResponse{
+		ID:         e.ID,
+		URL:        e.URL,
+		EventTypes: e.EventTypes,
+		Status:     e.Status,
+		CreatedAt:  e.CreatedAt,
+		UpdatedAt:  e.UpdatedAt,
+	}
+}
+
+// CreatedEndpointResponse is the only response carrying the secret, so the merchant can verify deliveries.
+type CreatedEndpointResponse struct {
+	EndpointResponse
+	Secret string `json:"secret"`
+}
+
+func FromEndpointToCreatedResponse(e webhooks.Endpoint) CreatedEndpointResponse {
+	return CreatedEndpointResponse{
+		EndpointResponse: FromEndpointToResponse(e),
+		Secret:           e.Secret,
+	}
+}
+
+type EndpointListResponse []EndpointResponse
+
+func FromEndpointListToResponse(e []webhooks.Endpoint) EndpointListResponse {
+	endpoints := make(EndpointListResponse, len(e))
+	for i, e := range e {
+		endpoints[i] = FromEndpointToResponse(e)
+	}
+	return endpoints
+}
+
+type DeliveryResponse struct {
+	ID             string          `json:"id"`
+	EndpointID     string          `json:"endpointId"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt"`
+	LastAttemptAt  *time.Time      `json:"lastAttemptAt"`
+	LastStatusCode int             `json:"lastStatusCode"`
+	LastError      string          `json:"lastError"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+}
+
+// FromDeliveryToResponse reports a next attempt only for pending deliveries.
+func FromDeliveryToResponse(d webhooks.Delivery) DeliveryResponse {
+	resp := DeliveryResponse{
+		ID:             d.ID,
+		EndpointID:     d.EndpointID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastAttemptAt:  d.LastAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+	}
+	if d.Status == webhooks.DeliveryStatusPending {
+		nextAttemptAt := d.NextAttemptAt
+		resp.NextAttemptAt = &nextAttemptAt
+	}
+	return resp
+}
+
+type DeliveryListResponse []DeliveryResponse
+
+func FromDeliveryListToResponse(d []webhooks.Delivery) DeliveryListResponse {
+	deliveries := make(DeliveryListResponse, len(d))
+	for i, d := range d {
+		deliveries[i] = FromDeliveryToResponse(d)
+	}
+	return deliveries
+}
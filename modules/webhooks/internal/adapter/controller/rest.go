@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/internal/adapter/controller/dto"
+)
+
+type webhookController struct {
+	webhookService webhooks.IWebhookService
+}
+
+// NewWebhookController registers the endpoint management and delivery log routes.
+func NewWebhookController(e *echo.Group, webhookService webhooks.IWebhookService) (controller *webhookController) {
+	controller = &webhookController{webhookService: webhookService}
+	e.POST("/webhooks/endpoints", controller.CreateEndpoint)
+	e.GET("/webhooks/endpoints", controller.FetchEndpoints)
+	e.GET("/webhooks/endpoints/:id", controller.GetEndpoint)
+	e.DELETE("/webhooks/endpoints/:id", controller.DeleteEndpoint)
+	e.GET("/webhooks/endpoints/:id/deliveries", controller.FetchDeliveries)
+	e.POST("/webhooks/deliveries/:id/redeliver", controller.Redeliver)
+	return controller
+}
+
+func (c *webhookController) CreateEndpoint(ctx echo.Context) (err error) {
+	var endpointRequest *dto.CreateEndpointRequest
+	if err = ctx.Bind(&endpointRequest); err != nil {
+		return err
+	}
+	endpoint := endpointRequest.ToEndpoint()
+	err = c.webhookService.CreateEndpoint(ctx.Request().Context(), &endpoint)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusCreated, dto.FromEndpointToCreatedResponse(endpoint))
+}
+
+func (c *webhookController) GetEndpoint(ctx echo.Context) (err error) {
+	id := ctx.Param("id")
+	endpoint, err := c.webhookService.GetEndpoint(ctx.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, dto.FromEndpointToResponse(endpoint))
+}
+
+func (c *webhookController) FetchEndpoints(ctx echo.Context) (err error) {
+	result, nextCursor, err := c.webhookService.FetchEndpoints(ctx.Request().Context(), webhooks.EndpointFetchParams{
+		Cursor: ctx.QueryParam("cursor"),
+		Limit:  limitParam(ctx),
+	})
+	if err != nil {
+		return err
+	}
+	ctx.Response().Header().Set("X-Next-Cursor", nextCursor)
+	return ctx.JSON(http.StatusOK, dto.FromEndpointListToResponse(result))
+}
+
+func (c *webhookController) DeleteEndpoint(ctx echo.Context) (err error) {
+	id := ctx.Param("id")
+	err = c.webhookService.DeleteEndpoint(ctx.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// FetchDeliveries returns the delivery log of an endpoint, newest first, optionally filtered by status.
+func (c *webhookController) FetchDeliveries(ctx echo.Context) (err error) {
+	result, nextCursor, err := c.webhookService.FetchDeliveries(ctx.Request().Context(), webhooks.DeliveryFetchParams{
+		EndpointID: ctx.Param("id"),
+		Status:     ctx.QueryParam("status"),
+		Cursor:     ctx.QueryParam("cursor"),
+		Limit:      limitParam(ctx),
+	})
+	if err != nil {
+		return err
+	}
+	ctx.Response().Header().Set("X-Next-Cursor", nextCursor)
+	return ctx.JSON(http.StatusOK, dto.FromDeliveryListToResponse(result))
+}
+
+// Redeliver queues the delivery again; the dispatcher sends it on its next run.
+func (c *webhookController) Redeliver(ctx echo.Context) (err error) {
+	id := ctx.Param("id")
+	delivery, err := c.webhookService.Redeliver(ctx.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusAccepted, dto.FromDeliveryToResponse(delivery))
+}
+
+func limitParam(ctx echo.Context) (limit int) {
+	limit = 10
+	if param := ctx.QueryParam("limit"); param != "" {
+		if val, convErr := strconv.Atoi(param); convErr == nil && val > 0 {
+			limit = val
+		}
+	}
+	return limit
+}
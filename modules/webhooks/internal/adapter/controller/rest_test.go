@@ -0,0 +1,195 @@
+//go:build e2e
+
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/factory"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/internal/adapter/controller/dto"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/testutils"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/webhook"
+)
+
+// merchantServer records the deliveries it receives and answers with a configurable status.
+type merchantServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newMerchantServer() *merchantServer {
+	m := &merchantServer{status: http.StatusOK}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.requests = append(m.requests, r)
+		m.bodies = append(m.bodies, body)
+		w.WriteHeader(m.status)
+	}))
+	return m
+}
+
+func (m *merchantServer) respondWith(status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status = status
+}
+
+type WebhooksControllerE2ETestSuite struct {
+	suite.Suite
+	pgContainer *testutils.PostgresContainer
+	echo        *echo.Echo
+	module      *webhooks.Module
+	merchant    *merchantServer
+}
+
+func (s *WebhooksControllerE2ETestSuite) SetupSuite() {
+	if testing.Short() {
+		s.T().Skip("Skipping E2E test in short mode")
+	}
+
+	s.pgContainer = testutils.SetupPostgres(s.T())
+	s.pgContainer.RunMigrations(s.T(), "../../../../../migrations")
+
+	s.echo = testutils.NewEchoForTest()
+	apiGroup := s.echo.Group("/api/v1")
+
+	s.module = factory.NewModule(factory.ModuleConfig{
+		DB: s.pgContainer.DB,
+		// A failed delivery is not due again within the test
+		InitialBackoff: time.Hour,
+		Timeout:        5 * time.Second,
+	})
+	s.module.RegisterHTTPHandlers(apiGroup)
+}
+
+func (s *WebhooksControllerE2ETestSuite) TearDownSuite() {
+	s.pgContainer.Teardown(s.T())
+}
+
+func (s *WebhooksControllerE2ETestSuite) SetupTest() {
+	s.pgContainer.TruncateTables(s.T(), "webhooks_module.deliveries", "webhooks_module.endpoints")
+	s.merchant = newMerchantServer()
+}
+
+func (s *WebhooksControllerE2ETestSuite) TearDownTest() {
+	s.merchant.Close()
+}
+
+func (s *WebhooksControllerE2ETestSuite) createEndpoint(eventTypes ...string) dto.CreatedEndpointResponse {
+	req := dto.CreateEndpointRequest{URL: s.merchant.URL, EventTypes: eventTypes}
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/webhooks/endpoints", req)
+	require.Equal(s.T(), http.StatusCreated, rec.Code)
+
+	var created dto.CreatedEndpointResponse
+	testutils.ParseJSONResponse(s.T(), rec, &created)
+	return created
+}
+
+func (s *WebhooksControllerE2ETestSuite) fetchDeliveries(endpointID string) []dto.DeliveryResponse {
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, fmt.Sprintf("/api/v1/webhooks/endpoints/%s/deliveries", endpointID), nil)
+	require.Equal(s.T(), http.StatusOK, rec.Code)
+
+	var deliveries []dto.DeliveryResponse
+	testutils.ParseJSONResponse(s.T(), rec, &deliveries)
+	return deliveries
+}
+
+func (s *WebhooksControllerE2ETestSuite) runDispatcher() {
+	require.Len(s.T(), s.module.Jobs, 1)
+	_, err := s.module.Jobs[0].Runner.Execute(context.Background())
+	require.NoError(s.T(), err)
+}
+
+func (s *WebhooksControllerE2ETestSuite) TestE2E_CreateEndpoint_SecretOnlyReturnedOnCreate() {
+	created := s.createEndpoint("payment.created")
+	assert.NotEmpty(s.T(), created.Secret)
+	assert.Equal(s.T(), webhooks.EndpointStatusActive, created.Status)
+
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, fmt.Sprintf("/api/v1/webhooks/endpoints/%s", created.ID), nil)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusOK)
+	assert.NotContains(s.T(), rec.Body.String(), created.Secret)
+}
+
+func (s *WebhooksControllerE2ETestSuite) TestE2E_CreateEndpoint_InvalidURL() {
+	req := dto.CreateEndpointRequest{URL: "not a url", EventTypes: []string{"*"}}
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/webhooks/endpoints", req)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusBadRequest)
+}
+
+func (s *WebhooksControllerE2ETestSuite) TestE2E_Delivery_RetriedAndRedelivered() {
+	endpoint := s.createEndpoint("payment.created")
+	s.createEndpoint("payment.status_changed")
+
+	err := s.module.Service.Publish(context.Background(), webhooks.Event{
+		Type: "payment.created",
+		Data: json.RawMessage(`{"id":"pay_1"}`),
+	})
+	require.NoError(s.T(), err)
+
+	// The first attempt fails and is rescheduled with backoff
+	s.merchant.respondWith(http.StatusInternalServerError)
+	s.runDispatcher()
+
+	deliveries := s.fetchDeliveries(endpoint.ID)
+	require.Len(s.T(), deliveries, 1)
+	assert.Equal(s.T(), webhooks.DeliveryStatusPending, deliveries[0].Status)
+	assert.Equal(s.T(), 1, deliveries[0].Attempts)
+	assert.Equal(s.T(), http.StatusInternalServerError, deliveries[0].LastStatusCode)
+	require.NotNil(s.T(), deliveries[0].NextAttemptAt)
+	assert.True(s.T(), deliveries[0].NextAttemptAt.After(time.Now().Add(30*time.Minute)))
+
+	// A manual redelivery makes it due right away
+	s.merchant.respondWith(http.StatusNoContent)
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, fmt.Sprintf("/api/v1/webhooks/deliveries/%s/redeliver", deliveries[0].ID), nil)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusAccepted)
+	s.runDispatcher()
+
+	deliveries = s.fetchDeliveries(endpoint.ID)
+	require.Len(s.T(), deliveries, 1)
+	assert.Equal(s.T(), webhooks.DeliveryStatusSucceeded, deliveries[0].Status)
+	assert.Equal(s.T(), http.StatusNoContent, deliveries[0].LastStatusCode)
+	assert.Nil(s.T(), deliveries[0].NextAttemptAt)
+
+	// Only the subscribed endpoint was called, with signed requests
+	s.merchant.mu.Lock()
+	defer s.merchant.mu.Unlock()
+	require.Len(s.T(), s.merchant.requests, 2)
+	for i, req := range s.merchant.requests {
+		assert.Equal(s.T(), "payment.created", req.Header.Get(webhook.HeaderEventType))
+		assert.Equal(s.T(), deliveries[0].EventID, req.Header.Get(webhook.HeaderEventID))
+		assert.NoError(s.T(), webhook.Verify(endpoint.Secret, req.Header.Get(webhook.HeaderSignature), s.merchant.bodies[i], 0, time.Now()))
+	}
+}
+
+func (s *WebhooksControllerE2ETestSuite) TestE2E_FetchDeliveries_UnknownEndpoint() {
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodGet, "/api/v1/webhooks/endpoints/whep-missing/deliveries", nil)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusNotFound)
+}
+
+func (s *WebhooksControllerE2ETestSuite) TestE2E_Redeliver_NotFound() {
+	rec := testutils.MakeRequest(s.T(), s.echo, http.MethodPost, "/api/v1/webhooks/deliveries/whdl-missing/redeliver", nil)
+	testutils.AssertStatusCode(s.T(), rec, http.StatusNotFound)
+}
+
+func TestWebhooksControllerE2ETestSuite(t *testing.T) {
+	suite.Run(t, new(WebhooksControllerE2ETestSuite))
+}
@@ -0,0 +1,138 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+// DispatcherConfig contains configuration for the delivery dispatcher
+type DispatcherConfig struct {
+	// BatchSize is the number of due deliveries claimed at a time (default 50).
+	BatchSize int
+	// LeaseDuration is how long claimed deliveries are hidden from other runs (default 1m).
+	// It must exceed the time needed to send a batch, or deliveries may be sent twice.
+	LeaseDuration time.Duration
+}
+
+// Dispatcher is the cron adapter sending due webhook deliveries
+type Dispatcher struct {
+	webhookService webhooks.IWebhookService
+	config         DispatcherConfig
+}
+
+// NewDispatcher creates a new delivery dispatcher cron adapter
+func NewDispatcher(webhookService webhooks.IWebhookService, config DispatcherConfig) *Dispatcher {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 50
+	}
+	if config.LeaseDuration <= 0 {
+		config.LeaseDuration = time.Minute
+	}
+	return &Dispatcher{
+		webhookService: webhookService,
+		config:         config,
+	}
+}
+
+// DispatchResult contains the results of a dispatcher run
+type DispatchResult struct {
+	StartTime time.Time
+	EndTime   time.Time
+	Duration  time.Duration
+	// SentCount is the number of deliveries attempted in this run.
+	SentCount    int
+	SuccessCount int
+	ErrorCount   int
+	Errors       []error
+}
+
+// RunSummary reports the outcome of the run to the job run history
+func (r *DispatchResult) RunSummary() scheduler.RunSummary {
+	errs := make([]string, len(r.Errors))
+	for i, err := range r.Errors {
+		errs[i] = err.Error()
+	}
+	return scheduler.RunSummary{
+		ProcessedCount: r.SentCount,
+		SuccessCount:   r.SuccessCount,
+		ErrorCount:     r.ErrorCount,
+		Errors:         errs,
+	}
+}
+
+// Execute claims and sends due deliveries batch by batch until none are due. A failed attempt
+// is rescheduled by the service and does not fail the run; the run fails only when claiming
+// fails or ctx is cancelled. Unsent deliveries of a cancelled run become due when their lease ends.
+// Deliveries of every tenant are claimed together and each is sent for its own tenant.
+func (d *Dispatcher) Execute(ctx context.Context) (resultData interface{}, err error) {
+	ctx = tenant.WithAllTenants(ctx)
+	result := &DispatchResult{
+		StartTime: time.Now(),
+		Errors:    make([]error, 0),
+	}
+
+	for ctx.Err() == nil {
+		deliveries, claimErr := d.webhookService.ClaimDueDeliveries(ctx, webhooks.ClaimDeliveriesParams{
+			Limit:         d.config.BatchSize,
+			LeaseDuration: d.config.LeaseDuration,
+		})
+		if claimErr != nil {
+			d.finish(result)
+			return result, fmt.Errorf("failed to claim due deliveries: %w", claimErr)
+		}
+		if len(deliveries) == 0 {
+			break
+		}
+
+		for i := range deliveries {
+			if ctx.Err() != nil {
+				break
+			}
+			delivery := &deliveries[i]
+			result.SentCount++
+			if sendErr := d.webhookService.SendDelivery(tenant.WithID(ctx, delivery.TenantID), delivery); sendErr != nil {
+				log.Warn().
+					Err(sendErr).
+					Str("delivery_id", delivery.ID).
+					Str("endpoint_id", delivery.EndpointID).
+					Str("status", delivery.Status).
+					Time("next_attempt_at", delivery.NextAttemptAt).
+					Msg("Webhook delivery attempt failed")
+				result.ErrorCount++
+				result.Errors = append(result.Errors, fmt.Errorf("delivery %s: %w", delivery.ID, sendErr))
+				continue
+			}
+			result.SuccessCount++
+		}
+	}
+
+	d.finish(result)
+
+	if err = ctx.Err(); err != nil {
+		return result, fmt.Errorf("webhook dispatcher interrupted: %w", err)
+	}
+	return result, nil
+}
+
+// finish stamps the end of the run and logs the summary
+func (d *Dispatcher) finish(result *DispatchResult) {
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+
+	if result.SentCount == 0 {
+		return
+	}
+	log.Info().
+		Dur("duration", result.Duration).
+		Int("sent", result.SentCount).
+		Int("success", result.SuccessCount).
+		Int("errors", result.ErrorCount).
+		Msg("Webhook dispatcher run completed")
+}
@@ -0,0 +1,69 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+	webhooksmocks "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/mocks"
+)
+
+func dueDeliveries(ids ...string) []webhooks.Delivery {
+	deliveries := make([]webhooks.Delivery, len(ids))
+	for i, id := range ids {
+		deliveries[i] = webhooks.Delivery{ID: id, EndpointID: "whep_1", Status: webhooks.DeliveryStatusPending}
+	}
+	return deliveries
+}
+
+func TestDispatcher_Execute_SendsUntilNothingIsDue(t *testing.T) {
+	mockService := webhooksmocks.NewMockIWebhookService(t)
+	params := webhooks.ClaimDeliveriesParams{Limit: 2, LeaseDuration: time.Minute}
+
+	mockService.On("ClaimDueDeliveries", mock.Anything, params).Return(dueDeliveries("whdl_1", "whdl_2"), nil).Once()
+	mockService.On("ClaimDueDeliveries", mock.Anything, params).Return(dueDeliveries("whdl_3"), nil).Once()
+	mockService.On("ClaimDueDeliveries", mock.Anything, params).Return([]webhooks.Delivery{}, nil).Once()
+	mockService.On("SendDelivery", mock.Anything, mock.MatchedBy(func(d *webhooks.Delivery) bool { return d.ID != "whdl_2" })).Return(nil)
+	// A failed attempt is rescheduled by the service and does not fail the run
+	mockService.On("SendDelivery", mock.Anything, mock.MatchedBy(func(d *webhooks.Delivery) bool { return d.ID == "whdl_2" })).Return(errors.New("endpoint responded with status 500"))
+
+	dispatcher := NewDispatcher(mockService, DispatcherConfig{BatchSize: 2})
+	resultData, err := dispatcher.Execute(context.Background())
+
+	require.NoError(t, err)
+	result := resultData.(*DispatchResult)
+	assert.Equal(t, 3, result.SentCount)
+	assert.Equal(t, 2, result.SuccessCount)
+	assert.Equal(t, 1, result.ErrorCount)
+	assert.Equal(t, 3, result.RunSummary().ProcessedCount)
+}
+
+func TestDispatcher_Execute_ClaimFailure(t *testing.T) {
+	mockService := webhooksmocks.NewMockIWebhookService(t)
+	mockService.On("ClaimDueDeliveries", mock.Anything, mock.Anything).Return(nil, errors.New("connection reset"))
+
+	dispatcher := NewDispatcher(mockService, DispatcherConfig{})
+	_, err := dispatcher.Execute(context.Background())
+
+	assert.ErrorContains(t, err, "failed to claim due deliveries")
+}
+
+func TestDispatcher_Execute_StopsWhenCancelled(t *testing.T) {
+	mockService := webhooksmocks.NewMockIWebhookService(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mockService.On("ClaimDueDeliveries", mock.Anything, mock.Anything).Return(dueDeliveries("whdl_1", "whdl_2"), nil).Once()
+	mockService.On("SendDelivery", mock.Anything, mock.Anything).Run(func(mock.Arguments) { cancel() }).Return(nil).Once()
+
+	dispatcher := NewDispatcher(mockService, DispatcherConfig{})
+	resultData, err := dispatcher.Execute(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, resultData.(*DispatchResult).SentCount)
+}
@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/rs/zerolog/log"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/internal/ports"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/dbutils"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/transaction"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/uniqueid"
+)
+
+type deliveryRepository struct {
+	db *sql.DB
+}
+
+func NewDeliveryRepository(db *sql.DB) ports.IDeliveryRepository {
+	return &deliveryRepository{
+		db: db,
+	}
+}
+
+func (r *deliveryRepository) qb() sq.StatementBuilderType {
+	return sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+}
+
+func (r *deliveryRepository) conn(ctx context.Context) transaction.DBTX {
+	return transaction.Executor(ctx, r.db)
+}
+
+var deliveryColumns = []string{
+	"id", "tenant_id", "endpoint_id", "event_id", "event_type", "payload", "status", "attempts",
+	"next_attempt_at", "last_attempt_at", "last_status_code", "last_error", "created_at", "updated_at",
+}
+
+func (r *deliveryRepository) scanDelivery(row sq.RowScanner) (d webhooks.Delivery, err error) {
+	var (
+		payload       []byte
+		lastAttemptAt sql.NullTime
+	)
+	err = row.Scan(&d.ID, &d.TenantID, &d.EndpointID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &lastAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return webhooks.Delivery{}, err
+	}
+	d.Payload = payload
+	if lastAttemptAt.Valid {
+		d.LastAttemptAt = &lastAttemptAt.Time
+	}
+	return d, nil
+}
+
+func (r *deliveryRepository) CreateDeliveries(ctx context.Context, deliveries []webhooks.Delivery) (err error) {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	tenantID, err := tenant.Required(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := r.qb().Insert("webhooks_module.deliveries").Columns(deliveryColumns...)
+	for i := range deliveries {
+		d := &deliveries[i]
+		if d.ID, err = uniqueid.GeneratePK("whdl"); err != nil {
+			return err
+		}
+		d.TenantID = tenantID
+		d.CreatedAt = now
+		d.UpdatedAt = now
+		// JSONB takes the payload as text; lib/pq would send []byte as bytea
+		query = query.Values(d.ID, d.TenantID, d.EndpointID, d.EventID, d.EventType, string(d.Payload), d.Status, d.Attempts,
+			d.NextAttemptAt, d.LastAttemptAt, d.LastStatusCode, d.LastError, d.CreatedAt, d.UpdatedAt)
+	}
+
+	if _, err = query.RunWith(r.conn(ctx)).ExecContext(ctx); err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+
+	return nil
+}
+
+func (r *deliveryRepository) GetDelivery(ctx context.Context, id string) (d webhooks.Delivery, err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return webhooks.Delivery{}, err
+	}
+
+	d, err = r.scanDelivery(r.qb().Select(deliveryColumns...).
+		From("webhooks_module.deliveries").
+		Where(sq.Eq{"id": id}).
+		Where(tenantFilter).
+		RunWith(r.conn(ctx)).
+		QueryRowContext(ctx))
+	if err != nil {
+		return webhooks.Delivery{}, dbutils.HandlePostgresError(err)
+	}
+
+	return d, nil
+}
+
+func (r *deliveryRepository) FetchDeliveries(ctx context.Context, params webhooks.DeliveryFetchParams) (result []webhooks.Delivery, nextCursor string, err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := r.qb().Select(deliveryColumns...).
+		From("webhooks_module.deliveries").
+		Where(tenantFilter).
+		OrderBy("id DESC")
+
+	if params.Cursor != "" {
+		cursorID, decodeErr := dbutils.DecodeCursor(params.Cursor)
+		if decodeErr != nil {
+			return nil, "", decodeErr
+		}
+		query = query.Where(sq.Lt{"id": cursorID})
+	}
+
+	if params.EndpointID != "" {
+		query = query.Where(sq.Eq{"endpoint_id": params.EndpointID})
+	}
+
+	if params.Status != "" {
+		query = query.Where(sq.Eq{"status": params.Status})
+	}
+
+	// Fetch one extra to determine if there's a next page
+	rows, err := query.Limit(uint64(params.Limit + 1)).RunWith(r.conn(ctx)).QueryContext(ctx)
+	if err != nil {
+		return nil, "", dbutils.HandlePostgresError(err)
+	}
+
+	result, err = r.scanDeliveries(rows)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(result) > params.Limit {
+		result = result[:params.Limit]
+		nextCursor = dbutils.EncodeCursor(result[len(result)-1].ID)
+	}
+
+	return result, nextCursor, nil
+}
+
+// ClaimDueDeliveries hides the claimed deliveries from other dispatchers by moving their next attempt
+// past the lease, in a single statement. FOR UPDATE SKIP LOCKED lets concurrent dispatchers pass over
+// each other's rows, and a delivery whose dispatcher crashed becomes due again when the lease ends.
+func (r *deliveryRepository) ClaimDueDeliveries(ctx context.Context, params webhooks.ClaimDeliveriesParams) (result []webhooks.Delivery, err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	// The subquery keeps the default "?" placeholders; the outer statement renumbers them to $n
+	due := sq.Select("id").
+		From("webhooks_module.deliveries").
+		Where(tenantFilter).
+		Where(sq.Eq{"status": webhooks.DeliveryStatusPending}).
+		Where(sq.LtOrEq{"next_attempt_at": now}).
+		OrderBy("next_attempt_at ASC", "id ASC").
+		Limit(uint64(params.Limit)).
+		Suffix("FOR UPDATE SKIP LOCKED")
+
+	rows, err := r.qb().Update("webhooks_module.deliveries").
+		Set("next_attempt_at", now.Add(params.LeaseDuration)).
+		Set("updated_at", now).
+		Where(sq.Expr("id IN (?)", due)).
+		Suffix("RETURNING " + strings.Join(deliveryColumns, ", ")).
+		RunWith(r.conn(ctx)).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, dbutils.HandlePostgresError(err)
+	}
+
+	result, err = r.scanDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not preserve the subquery order; ids are time-ordered
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	return result, nil
+}
+
+func (r *deliveryRepository) UpdateDelivery(ctx context.Context, d *webhooks.Delivery) (err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return err
+	}
+	d.UpdatedAt = time.Now()
+
+	result, err := r.qb().Update("webhooks_module.deliveries").
+		Set("status", d.Status).
+		Set("attempts", d.Attempts).
+		Set("next_attempt_at", d.NextAttemptAt).
+		Set("last_attempt_at", d.LastAttemptAt).
+		Set("last_status_code", d.LastStatusCode).
+		Set("last_error", d.LastError).
+		Set("updated_at", d.UpdatedAt).
+		Where(sq.Eq{"id": d.ID}).
+		Where(tenantFilter).
+		RunWith(r.conn(ctx)).
+		ExecContext(ctx)
+	if err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+	if rowsAffected == 0 {
+		return errors.ErrDataNotFound
+	}
+
+	return nil
+}
+
+func (r *deliveryRepository) scanDeliveries(rows *sql.Rows) (result []webhooks.Delivery, err error) {
+	defer func() {
+		errClose := rows.Close()
+		if errClose != nil {
+			log.Error().Err(errClose).Msg("failed to close rows")
+		}
+	}()
+
+	result = make([]webhooks.Delivery, 0)
+	for rows.Next() {
+		d, err := r.scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, dbutils.HandlePostgresError(err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/testutils"
+)
+
+type DeliveryRepositoryTestSuite struct {
+	suite.Suite
+	pgContainer  *testutils.PostgresContainer
+	repo         *deliveryRepository
+	endpointRepo *endpointRepository
+	endpoint     webhooks.Endpoint
+}
+
+func (s *DeliveryRepositoryTestSuite) SetupSuite() {
+	if testing.Short() {
+		s.T().Skip("Skipping repository integration test in short mode")
+	}
+	s.pgContainer = testutils.SetupPostgres(s.T())
+	s.pgContainer.RunMigrations(s.T(), "../../../../../migrations")
+	s.repo = &deliveryRepository{db: s.pgContainer.DB}
+	s.endpointRepo = &endpointRepository{db: s.pgContainer.DB}
+}
+
+func (s *DeliveryRepositoryTestSuite) TearDownSuite() {
+	s.pgContainer.Teardown(s.T())
+}
+
+func (s *DeliveryRepositoryTestSuite) SetupTest() {
+	s.pgContainer.TruncateTables(s.T(), "webhooks_module.deliveries", "webhooks_module.endpoints")
+	s.endpoint = webhooks.Endpoint{
+		URL:        "https://merchant.example/webhooks",
+		Secret:     "whsec_test",
+		EventTypes: []string{webhooks.EventTypeAll},
+		Status:     webhooks.EndpointStatusActive,
+	}
+	require.NoError(s.T(), s.endpointRepo.CreateEndpoint(testutils.TenantContext(), &s.endpoint))
+}
+
+// createDeliveries creates one delivery per next attempt time, in order. IDs only sort by creation
+// time across milliseconds, so the deliveries are created apart.
+func (s *DeliveryRepositoryTestSuite) createDeliveries(nextAttemptAt ...time.Time) []webhooks.Delivery {
+	deliveries := make([]webhooks.Delivery, len(nextAttemptAt))
+	for i, at := range nextAttemptAt {
+		time.Sleep(2 * time.Millisecond)
+		deliveries[i] = webhooks.Delivery{
+			EndpointID:    s.endpoint.ID,
+			EventID:       "evt_1",
+			EventType:     "payment.created",
+			Payload:       json.RawMessage(`{"id":"evt_1","type":"payment.created"}`),
+			Status:        webhooks.DeliveryStatusPending,
+			NextAttemptAt: at,
+		}
+		require.NoError(s.T(), s.repo.CreateDeliveries(testutils.TenantContext(), deliveries[i:i+1]))
+	}
+	return deliveries
+}
+
+func (s *DeliveryRepositoryTestSuite) TestCreateAndGetDelivery() {
+	created := s.createDeliveries(time.Now())[0]
+	assert.Contains(s.T(), created.ID, "whdl-")
+
+	got, err := s.repo.GetDelivery(testutils.TenantContext(), created.ID)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), s.endpoint.ID, got.EndpointID)
+	assert.JSONEq(s.T(), string(created.Payload), string(got.Payload))
+	assert.Equal(s.T(), webhooks.DeliveryStatusPending, got.Status)
+	assert.Nil(s.T(), got.LastAttemptAt)
+}
+
+func (s *DeliveryRepositoryTestSuite) TestClaimDueDeliveries() {
+	now := time.Now()
+	deliveries := s.createDeliveries(now.Add(-time.Minute), now.Add(-time.Second), now.Add(time.Hour))
+	params := webhooks.ClaimDeliveriesParams{Limit: 10, LeaseDuration: time.Minute}
+
+	claimed, err := s.repo.ClaimDueDeliveries(testutils.TenantContext(), params)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), claimed, 2)
+	assert.Equal(s.T(), deliveries[0].ID, claimed[0].ID)
+	assert.Equal(s.T(), deliveries[1].ID, claimed[1].ID)
+	assert.True(s.T(), claimed[0].NextAttemptAt.After(now), "a claimed delivery must be leased")
+
+	// Leased deliveries are not claimed again
+	claimed, err = s.repo.ClaimDueDeliveries(testutils.TenantContext(), params)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), claimed)
+}
+
+func (s *DeliveryRepositoryTestSuite) TestUpdateDelivery() {
+	delivery := s.createDeliveries(time.Now())[0]
+	attemptedAt := time.Now().UTC().Truncate(time.Microsecond)
+	delivery.Status = webhooks.DeliveryStatusSucceeded
+	delivery.Attempts = 1
+	delivery.LastAttemptAt = &attemptedAt
+	delivery.LastStatusCode = 204
+
+	require.NoError(s.T(), s.repo.UpdateDelivery(testutils.TenantContext(), &delivery))
+
+	got, err := s.repo.GetDelivery(testutils.TenantContext(), delivery.ID)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), webhooks.DeliveryStatusSucceeded, got.Status)
+	assert.Equal(s.T(), 1, got.Attempts)
+	assert.Equal(s.T(), 204, got.LastStatusCode)
+	require.NotNil(s.T(), got.LastAttemptAt)
+	assert.True(s.T(), attemptedAt.Equal(*got.LastAttemptAt))
+
+	missing := webhooks.Delivery{ID: "whdl_missing"}
+	assert.ErrorIs(s.T(), s.repo.UpdateDelivery(testutils.TenantContext(), &missing), errors.ErrDataNotFound)
+}
+
+func (s *DeliveryRepositoryTestSuite) TestFetchDeliveries_FiltersByStatus() {
+	deliveries := s.createDeliveries(time.Now(), time.Now(), time.Now())
+	deliveries[0].Status = webhooks.DeliveryStatusFailed
+	require.NoError(s.T(), s.repo.UpdateDelivery(testutils.TenantContext(), &deliveries[0]))
+
+	result, cursor, err := s.repo.FetchDeliveries(testutils.TenantContext(), webhooks.DeliveryFetchParams{
+		EndpointID: s.endpoint.ID,
+		Status:     webhooks.DeliveryStatusPending,
+		Limit:      1,
+	})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), result, 1)
+	assert.Equal(s.T(), deliveries[2].ID, result[0].ID, "the newest delivery comes first")
+	require.NotEmpty(s.T(), cursor)
+
+	result, cursor, err = s.repo.FetchDeliveries(testutils.TenantContext(), webhooks.DeliveryFetchParams{
+		EndpointID: s.endpoint.ID,
+		Status:     webhooks.DeliveryStatusPending,
+		Limit:      1,
+		Cursor:     cursor,
+	})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), result, 1)
+	assert.Equal(s.T(), deliveries[1].ID, result[0].ID)
+	assert.Empty(s.T(), cursor)
+}
+
+func (s *DeliveryRepositoryTestSuite) TestDeleteEndpoint_RemovesDeliveries() {
+	delivery := s.createDeliveries(time.Now())[0]
+	require.NoError(s.T(), s.endpointRepo.DeleteEndpoint(testutils.TenantContext(), s.endpoint.ID))
+
+	_, err := s.repo.GetDelivery(testutils.TenantContext(), delivery.ID)
+	assert.ErrorIs(s.T(), err, errors.ErrDataNotFound)
+}
+
+func TestDeliveryRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(DeliveryRepositoryTestSuite))
+}
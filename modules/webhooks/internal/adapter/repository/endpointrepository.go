@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/internal/ports"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/dbutils"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/transaction"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/uniqueid"
+)
+
+type endpointRepository struct {
+	db *sql.DB
+}
+
+func NewEndpointRepository(db *sql.DB) ports.IEndpointRepository {
+	return &endpointRepository{
+		db: db,
+	}
+}
+
+func (r *endpointRepository) qb() sq.StatementBuilderType {
+	return sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+}
+
+func (r *endpointRepository) conn(ctx context.Context) transaction.DBTX {
+	return transaction.Executor(ctx, r.db)
+}
+
+var endpointColumns = []string{"id", "tenant_id", "url", "secret", "event_types", "status", "created_at", "updated_at"}
+
+func (r *endpointRepository) scanEndpoint(row sq.RowScanner) (e webhooks.Endpoint, err error) {
+	err = row.Scan(&e.ID, &e.TenantID, &e.URL, &e.Secret, pq.Array(&e.EventTypes), &e.Status, &e.CreatedAt, &e.UpdatedAt)
+	return e, err
+}
+
+func (r *endpointRepository) CreateEndpoint(ctx context.Context, e *webhooks.Endpoint) (err error) {
+	e.TenantID, err = tenant.Required(ctx)
+	if err != nil {
+		return err
+	}
+	e.ID, err = uniqueid.GeneratePK("whep")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	e.CreatedAt = now
+	e.UpdatedAt = now
+
+	_, err = r.qb().Insert("webhooks_module.endpoints").
+		Columns(endpointColumns...).
+		Values(e.ID, e.TenantID, e.URL, e.Secret, pq.Array(e.EventTypes), e.Status, e.CreatedAt, e.UpdatedAt).
+		RunWith(r.conn(ctx)).
+		ExecContext(ctx)
+	if err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+
+	return nil
+}
+
+func (r *endpointRepository) GetEndpoint(ctx context.Context, id string) (e webhooks.Endpoint, err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return webhooks.Endpoint{}, err
+	}
+
+	e, err = r.scanEndpoint(r.qb().Select(endpointColumns...).
+		From("webhooks_module.endpoints").
+		Where(sq.Eq{"id": id}).
+		Where(tenantFilter).
+		RunWith(r.conn(ctx)).
+		QueryRowContext(ctx))
+	if err != nil {
+		return webhooks.Endpoint{}, dbutils.HandlePostgresError(err)
+	}
+
+	return e, nil
+}
+
+func (r *endpointRepository) FetchEndpoints(ctx context.Context, params webhooks.EndpointFetchParams) (result []webhooks.Endpoint, nextCursor string, err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := r.qb().Select(endpointColumns...).
+		From("webhooks_module.endpoints").
+		Where(tenantFilter).
+		OrderBy("id DESC")
+
+	if params.Cursor != "" {
+		cursorID, decodeErr := dbutils.DecodeCursor(params.Cursor)
+		if decodeErr != nil {
+			return nil, "", decodeErr
+		}
+		query = query.Where(sq.Lt{"id": cursorID})
+	}
+
+	// Fetch one extra to determine if there's a next page
+	result, err = r.query(ctx, query.Limit(uint64(params.Limit+1)))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(result) > params.Limit {
+		result = result[:params.Limit]
+		nextCursor = dbutils.EncodeCursor(result[len(result)-1].ID)
+	}
+
+	return result, nextCursor, nil
+}
+
+func (r *endpointRepository) FetchSubscribedEndpoints(ctx context.Context, eventType string) (result []webhooks.Endpoint, err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.query(ctx, r.qb().Select(endpointColumns...).
+		From("webhooks_module.endpoints").
+		Where(tenantFilter).
+		Where(sq.Eq{"status": webhooks.EndpointStatusActive}).
+		Where("event_types && ?", pq.Array([]string{eventType, webhooks.EventTypeAll})).
+		OrderBy("id ASC"))
+}
+
+func (r *endpointRepository) DeleteEndpoint(ctx context.Context, id string) (err error) {
+	tenantFilter, err := dbutils.TenantFilter(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.qb().Delete("webhooks_module.endpoints").
+		Where(sq.Eq{"id": id}).
+		Where(tenantFilter).
+		RunWith(r.conn(ctx)).
+		ExecContext(ctx)
+	if err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+	if rowsAffected == 0 {
+		return errors.ErrDataNotFound
+	}
+
+	return nil
+}
+
+func (r *endpointRepository) query(ctx context.Context, query sq.SelectBuilder) (result []webhooks.Endpoint, err error) {
+	rows, err := query.RunWith(r.conn(ctx)).QueryContext(ctx)
+	if err != nil {
+		return nil, dbutils.HandlePostgresError(err)
+	}
+	defer func() {
+		errClose := rows.Close()
+		if errClose != nil {
+			log.Error().Err(errClose).Msg("failed to close rows")
+		}
+	}()
+
+	result = make([]webhooks.Endpoint, 0)
+	for rows.Next() {
+		e, err := r.scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, dbutils.HandlePostgresError(err)
+	}
+
+	return result, nil
+}
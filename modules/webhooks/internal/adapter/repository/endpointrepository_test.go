@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/testutils"
+)
+
+type EndpointRepositoryTestSuite struct {
+	suite.Suite
+	pgContainer *testutils.PostgresContainer
+	repo        *endpointRepository
+}
+
+func (s *EndpointRepositoryTestSuite) SetupSuite() {
+	if testing.Short() {
+		s.T().Skip("Skipping repository integration test in short mode")
+	}
+	s.pgContainer = testutils.SetupPostgres(s.T())
+	s.pgContainer.RunMigrations(s.T(), "../../../../../migrations")
+	s.repo = &endpointRepository{db: s.pgContainer.DB}
+}
+
+func (s *EndpointRepositoryTestSuite) TearDownSuite() {
+	s.pgContainer.Teardown(s.T())
+}
+
+func (s *EndpointRepositoryTestSuite) SetupTest() {
+	s.pgContainer.TruncateTables(s.T(), "webhooks_module.deliveries", "webhooks_module.endpoints")
+}
+
+func (s *EndpointRepositoryTestSuite) createEndpoint(eventTypes []string, status string) webhooks.Endpoint {
+	endpoint := webhooks.Endpoint{
+		URL:        "https://merchant.example/webhooks",
+		Secret:     "whsec_test",
+		EventTypes: eventTypes,
+		Status:     status,
+	}
+	require.NoError(s.T(), s.repo.CreateEndpoint(testutils.TenantContext(), &endpoint))
+	return endpoint
+}
+
+func (s *EndpointRepositoryTestSuite) TestCreateAndGetEndpoint() {
+	created := s.createEndpoint([]string{"payment.created", "payment.status_changed"}, webhooks.EndpointStatusActive)
+	assert.Contains(s.T(), created.ID, "whep-")
+
+	got, err := s.repo.GetEndpoint(testutils.TenantContext(), created.ID)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), created.URL, got.URL)
+	assert.Equal(s.T(), created.Secret, got.Secret)
+	assert.Equal(s.T(), []string{"payment.created", "payment.status_changed"}, got.EventTypes)
+	assert.Equal(s.T(), webhooks.EndpointStatusActive, got.Status)
+}
+
+func (s *EndpointRepositoryTestSuite) TestGetEndpoint_NotFound() {
+	_, err := s.repo.GetEndpoint(testutils.TenantContext(), "whep_missing")
+	assert.ErrorIs(s.T(), err, errors.ErrDataNotFound)
+}
+
+func (s *EndpointRepositoryTestSuite) TestFetchEndpoints_Paginates() {
+	for i := 0; i < 3; i++ {
+		s.createEndpoint([]string{webhooks.EventTypeAll}, webhooks.EndpointStatusActive)
+	}
+
+	page, cursor, err := s.repo.FetchEndpoints(testutils.TenantContext(), webhooks.EndpointFetchParams{Limit: 2})
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), page, 2)
+	require.NotEmpty(s.T(), cursor)
+
+	page, cursor, err = s.repo.FetchEndpoints(testutils.TenantContext(), webhooks.EndpointFetchParams{Limit: 2, Cursor: cursor})
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), page, 1)
+	assert.Empty(s.T(), cursor)
+}
+
+func (s *EndpointRepositoryTestSuite) TestFetchSubscribedEndpoints() {
+	created := s.createEndpoint([]string{"payment.created"}, webhooks.EndpointStatusActive)
+	all := s.createEndpoint([]string{webhooks.EventTypeAll}, webhooks.EndpointStatusActive)
+	s.createEndpoint([]string{"payment.status_changed"}, webhooks.EndpointStatusActive)
+	s.createEndpoint([]string{"payment.created"}, webhooks.EndpointStatusDisabled)
+
+	result, err := s.repo.FetchSubscribedEndpoints(testutils.TenantContext(), "payment.created")
+	require.NoError(s.T(), err)
+
+	ids := make([]string, len(result))
+	for i, endpoint := range result {
+		ids[i] = endpoint.ID
+	}
+	assert.ElementsMatch(s.T(), []string{created.ID, all.ID}, ids)
+}
+
+func (s *EndpointRepositoryTestSuite) TestDeleteEndpoint() {
+	created := s.createEndpoint([]string{webhooks.EventTypeAll}, webhooks.EndpointStatusActive)
+
+	require.NoError(s.T(), s.repo.DeleteEndpoint(testutils.TenantContext(), created.ID))
+	assert.ErrorIs(s.T(), s.repo.DeleteEndpoint(testutils.TenantContext(), created.ID), errors.ErrDataNotFound)
+}
+
+func TestEndpointRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(EndpointRepositoryTestSuite))
+}
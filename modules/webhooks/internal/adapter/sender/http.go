@@ -0,0 +1,60 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/internal/ports"
+)
+
+// maxResponseBody is how much of a response is read before the connection is reused; the body is discarded.
+const maxResponseBody = 64 << 10
+
+type httpSender struct {
+	client *http.Client
+}
+
+// NewHTTPSender returns a sender POSTing deliveries over HTTP. timeout bounds a whole attempt
+// (default 10s); redirects are not followed, so a 3xx response counts as a failed attempt.
+func NewHTTPSender(timeout time.Duration) ports.ISender {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &httpSender{
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (s *httpSender) Send(ctx context.Context, req ports.SendRequest) (statusCode int, err error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	for key, value := range req.Headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Warn().Err(errClose).Str("url", req.URL).Msg("failed to close webhook response body")
+		}
+	}()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	return resp.StatusCode, nil
+}
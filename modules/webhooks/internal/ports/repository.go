@@ -0,0 +1,36 @@
+// Package ports defines the interfaces (ports) for external dependencies in hexagonal architecture.
+//
+// Ports are contracts that adapters must implement. By defining ports in the module:
+//   - The core domain remains independent of external systems (databases, HTTP clients, etc.)
+//   - Adapters (implementations) can be swapped without changing business logic
+//   - Testing becomes easier through mock implementations
+package ports
+
+import (
+	"context"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+)
+
+// IEndpointRepository is an outbound port for endpoint persistence. Like IDeliveryRepository it only sees
+// the rows of the tenant in ctx, or of every tenant under tenant.WithAllTenants, and creates rows for the
+// tenant in ctx.
+type IEndpointRepository interface {
+	CreateEndpoint(ctx context.Context, endpoint *webhooks.Endpoint) error
+	GetEndpoint(ctx context.Context, id string) (webhooks.Endpoint, error)
+	FetchEndpoints(ctx context.Context, params webhooks.EndpointFetchParams) (result []webhooks.Endpoint, nextCursor string, err error)
+	// FetchSubscribedEndpoints returns the active endpoints subscribed to eventType.
+	FetchSubscribedEndpoints(ctx context.Context, eventType string) ([]webhooks.Endpoint, error)
+	DeleteEndpoint(ctx context.Context, id string) error
+}
+
+// IDeliveryRepository is an outbound port for the delivery log.
+type IDeliveryRepository interface {
+	CreateDeliveries(ctx context.Context, deliveries []webhooks.Delivery) error
+	GetDelivery(ctx context.Context, id string) (webhooks.Delivery, error)
+	FetchDeliveries(ctx context.Context, params webhooks.DeliveryFetchParams) (result []webhooks.Delivery, nextCursor string, err error)
+	// ClaimDueDeliveries pushes the next attempt of due pending deliveries past the lease and returns them.
+	ClaimDueDeliveries(ctx context.Context, params webhooks.ClaimDeliveriesParams) ([]webhooks.Delivery, error)
+	// UpdateDelivery stores the status and attempt fields of the delivery.
+	UpdateDelivery(ctx context.Context, delivery *webhooks.Delivery) error
+}
@@ -0,0 +1,17 @@
+package ports
+
+import "context"
+
+// SendRequest is an HTTP POST to a webhook endpoint.
+type SendRequest struct {
+	URL     string
+	Headers map[string]string
+	Body    []byte
+}
+
+// ISender is an outbound port delivering webhook requests.
+type ISender interface {
+	// Send posts the request and returns the response status code. err is set when no response was
+	// received, e.g. on a connection error or timeout; any status code is returned without error.
+	Send(ctx context.Context, req SendRequest) (statusCode int, err error)
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/internal/ports"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/uniqueid"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/webhook"
+)
+
+// RetryPolicy decides when a failed delivery is attempted again.
+// The n-th retry waits InitialBackoff * 2^(n-1), capped at MaxBackoff.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts before a delivery fails for good (default 10).
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry (default 30s).
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between attempts (default 6h).
+	MaxBackoff time.Duration
+}
+
+// Backoff returns the wait after the given number of failed attempts.
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempts && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+// maxLastErrorLength bounds the error stored on a delivery, e.g. a long response-level message.
+const maxLastErrorLength = 500
+
+type WebhookService struct {
+	endpointRepo ports.IEndpointRepository
+	deliveryRepo ports.IDeliveryRepository
+	sender       ports.ISender
+	retryPolicy  RetryPolicy
+}
+
+func NewWebhookService(endpointRepo ports.IEndpointRepository, deliveryRepo ports.IDeliveryRepository, sender ports.ISender, retryPolicy RetryPolicy) (service *WebhookService) {
+	if retryPolicy.MaxAttempts <= 0 {
+		retryPolicy.MaxAttempts = 10
+	}
+	if retryPolicy.InitialBackoff <= 0 {
+		retryPolicy.InitialBackoff = 30 * time.Second
+	}
+	if retryPolicy.MaxBackoff <= 0 {
+		retryPolicy.MaxBackoff = 6 * time.Hour
+	}
+	return &WebhookService{
+		endpointRepo: endpointRepo,
+		deliveryRepo: deliveryRepo,
+		sender:       sender,
+		retryPolicy:  retryPolicy,
+	}
+}
+
+func (s *WebhookService) CreateEndpoint(ctx context.Context, endpoint *webhooks.Endpoint) (err error) {
+	target, err := url.Parse(endpoint.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return errors.NewValidationError(fmt.Errorf("endpoint url must be an absolute http(s) URL"))
+	}
+	if len(endpoint.EventTypes) == 0 {
+		return errors.NewValidationError(fmt.Errorf("endpoint must subscribe to at least one event type"))
+	}
+	for _, eventType := range endpoint.EventTypes {
+		if eventType == "" {
+			return errors.NewValidationError(fmt.Errorf("event types must not be empty"))
+		}
+	}
+
+	switch endpoint.Status {
+	case "":
+		endpoint.Status = webhooks.EndpointStatusActive
+	case webhooks.EndpointStatusActive, webhooks.EndpointStatusDisabled:
+	default:
+		return errors.NewValidationError(fmt.Errorf("unknown endpoint status %q", endpoint.Status))
+	}
+
+	if endpoint.Secret == "" {
+		if endpoint.Secret, err = generateSecret(); err != nil {
+			return err
+		}
+	}
+
+	return s.endpointRepo.CreateEndpoint(ctx, endpoint)
+}
+
+func (s *WebhookService) GetEndpoint(ctx context.Context, id string) (endpoint webhooks.Endpoint, err error) {
+	return s.endpointRepo.GetEndpoint(ctx, id)
+}
+
+func (s *WebhookService) FetchEndpoints(ctx context.Context, params webhooks.EndpointFetchParams) (result []webhooks.Endpoint, nextCursor string, err error) {
+	return s.endpointRepo.FetchEndpoints(ctx, params)
+}
+
+func (s *WebhookService) DeleteEndpoint(ctx context.Context, id string) (err error) {
+	return s.endpointRepo.DeleteEndpoint(ctx, id)
+}
+
+// Publish writes one pending delivery per subscribed endpoint. Both repositories join the
+// transaction in ctx, so deliveries are only recorded when the publishing change commits.
+func (s *WebhookService) Publish(ctx context.Context, event webhooks.Event) (err error) {
+	if event.Type == "" {
+		return errors.NewValidationError(fmt.Errorf("event type is required"))
+	}
+	if event.ID == "" {
+		if event.ID, err = uniqueid.GeneratePK("evt"); err != nil {
+			return err
+		}
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	endpoints, err := s.endpointRepo.FetchSubscribedEndpoints(ctx, event.Type)
+	if err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event %s: %w", event.ID, err)
+	}
+
+	deliveries := make([]webhooks.Delivery, len(endpoints))
+	for i, endpoint := range endpoints {
+		deliveries[i] = webhooks.Delivery{
+			EndpointID:    endpoint.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       payload,
+			Status:        webhooks.DeliveryStatusPending,
+			NextAttemptAt: event.OccurredAt,
+		}
+	}
+	return s.deliveryRepo.CreateDeliveries(ctx, deliveries)
+}
+
+func (s *WebhookService) FetchDeliveries(ctx context.Context, params webhooks.DeliveryFetchParams) (result []webhooks.Delivery, nextCursor string, err error) {
+	switch params.Status {
+	case "", webhooks.DeliveryStatusPending, webhooks.DeliveryStatusSucceeded, webhooks.DeliveryStatusFailed:
+	default:
+		return nil, "", errors.NewValidationError(fmt.Errorf("unknown delivery status %q", params.Status))
+	}
+	if params.EndpointID != "" {
+		// An unknown endpoint is a 404, not an empty log
+		if _, err = s.endpointRepo.GetEndpoint(ctx, params.EndpointID); err != nil {
+			return nil, "", err
+		}
+	}
+	return s.deliveryRepo.FetchDeliveries(ctx, params)
+}
+
+func (s *WebhookService) Redeliver(ctx context.Context, id string) (delivery webhooks.Delivery, err error) {
+	delivery, err = s.deliveryRepo.GetDelivery(ctx, id)
+	if err != nil {
+		return webhooks.Delivery{}, err
+	}
+
+	delivery.Status = webhooks.DeliveryStatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err = s.deliveryRepo.UpdateDelivery(ctx, &delivery); err != nil {
+		return webhooks.Delivery{}, err
+	}
+	return delivery, nil
+}
+
+func (s *WebhookService) ClaimDueDeliveries(ctx context.Context, params webhooks.ClaimDeliveriesParams) (result []webhooks.Delivery, err error) {
+	if params.Limit <= 0 {
+		return nil, errors.NewValidationError(fmt.Errorf("claim limit must be positive"))
+	}
+	if params.LeaseDuration <= 0 {
+		return nil, errors.NewValidationError(fmt.Errorf("claim lease duration must be positive"))
+	}
+	return s.deliveryRepo.ClaimDueDeliveries(ctx, params)
+}
+
+// SendDelivery signs the payload with the endpoint secret at send time, so the signature timestamp
+// is fresh on every attempt. Any 2xx response counts as delivered.
+func (s *WebhookService) SendDelivery(ctx context.Context, delivery *webhooks.Delivery) (err error) {
+	endpoint, err := s.endpointRepo.GetEndpoint(ctx, delivery.EndpointID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var attemptErr error
+	statusCode := 0
+	if endpoint.Status != webhooks.EndpointStatusActive {
+		attemptErr = fmt.Errorf("endpoint %s is %s", endpoint.ID, endpoint.Status)
+	} else {
+		statusCode, attemptErr = s.sender.Send(ctx, ports.SendRequest{
+			URL: endpoint.URL,
+			Headers: map[string]string{
+				"Content-Type":          "application/json",
+				webhook.HeaderSignature: webhook.Sign(endpoint.Secret, now, delivery.Payload),
+				webhook.HeaderEventID:   delivery.EventID,
+				webhook.HeaderEventType: delivery.EventType,
+			},
+			Body: delivery.Payload,
+		})
+		if attemptErr == nil && (statusCode < 200 || statusCode > 299) {
+			attemptErr = fmt.Errorf("endpoint responded with status %d", statusCode)
+		}
+	}
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+	switch {
+	case attemptErr == nil:
+		delivery.Status = webhooks.DeliveryStatusSucceeded
+	case delivery.Attempts >= s.retryPolicy.MaxAttempts:
+		delivery.Status = webhooks.DeliveryStatusFailed
+		delivery.LastError = truncate(attemptErr.Error(), maxLastErrorLength)
+	default:
+		delivery.Status = webhooks.DeliveryStatusPending
+		delivery.NextAttemptAt = now.Add(s.retryPolicy.Backoff(delivery.Attempts))
+		delivery.LastError = truncate(attemptErr.Error(), maxLastErrorLength)
+	}
+
+	// The outcome is recorded even when the run is being cancelled
+	if err = s.deliveryRepo.UpdateDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		return fmt.Errorf("failed to record attempt of delivery %s: %w", delivery.ID, err)
+	}
+	if attemptErr != nil {
+		return fmt.Errorf("delivery %s attempt %d failed: %w", delivery.ID, delivery.Attempts, attemptErr)
+	}
+	return nil
+}
+
+// generateSecret returns a random signing secret.
+func generateSecret() (secret string, err error) {
+	buf := make([]byte, 24)
+	if _, err = rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate endpoint secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
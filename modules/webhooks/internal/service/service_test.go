@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/internal/ports"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/modules/webhooks/internal/ports/mocks"
+	pkgerrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/webhook"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 30 * time.Second, MaxBackoff: 5 * time.Minute}
+
+	assert.Equal(t, 30*time.Second, policy.Backoff(1))
+	assert.Equal(t, time.Minute, policy.Backoff(2))
+	assert.Equal(t, 4*time.Minute, policy.Backoff(4))
+	assert.Equal(t, 5*time.Minute, policy.Backoff(5))
+	assert.Equal(t, 5*time.Minute, policy.Backoff(50))
+}
+
+func TestWebhookService_CreateEndpoint(t *testing.T) {
+	tests := []struct {
+		name         string
+		endpoint     webhooks.Endpoint
+		expectCreate bool
+		expectedCode string
+	}{
+		{
+			name:         "valid endpoint gets a secret and is active",
+			endpoint:     webhooks.Endpoint{URL: "https://merchant.example/hooks", EventTypes: []string{"payment.created"}},
+			expectCreate: true,
+		},
+		{
+			name:         "relative url",
+			endpoint:     webhooks.Endpoint{URL: "/hooks", EventTypes: []string{"payment.created"}},
+			expectedCode: pkgerrors.ErrorCodeValidation,
+		},
+		{
+			name:         "unsupported scheme",
+			endpoint:     webhooks.Endpoint{URL: "ftp://merchant.example/hooks", EventTypes: []string{"payment.created"}},
+			expectedCode: pkgerrors.ErrorCodeValidation,
+		},
+		{
+			name:         "no event types",
+			endpoint:     webhooks.Endpoint{URL: "https://merchant.example/hooks"},
+			expectedCode: pkgerrors.ErrorCodeValidation,
+		},
+		{
+			name:         "unknown status",
+			endpoint:     webhooks.Endpoint{URL: "https://merchant.example/hooks", EventTypes: []string{"*"}, Status: "paused"},
+			expectedCode: pkgerrors.ErrorCodeValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpointRepo := mocks.NewMockIEndpointRepository(t)
+			if tt.expectCreate {
+				endpointRepo.On("CreateEndpoint", mock.Anything, mock.Anything).Return(nil)
+			}
+
+			service := NewWebhookService(endpointRepo, mocks.NewMockIDeliveryRepository(t), mocks.NewMockISender(t), RetryPolicy{})
+			endpoint := tt.endpoint
+			err := service.CreateEndpoint(context.Background(), &endpoint)
+
+			if tt.expectedCode != "" {
+				assert.True(t, pkgerrors.IsErrorCode(err, tt.expectedCode), "unexpected error %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(endpoint.Secret, "whsec_"))
+			assert.Equal(t, webhooks.EndpointStatusActive, endpoint.Status)
+		})
+	}
+}
+
+func TestWebhookService_Publish(t *testing.T) {
+	endpointRepo := mocks.NewMockIEndpointRepository(t)
+	deliveryRepo := mocks.NewMockIDeliveryRepository(t)
+
+	endpointRepo.On("FetchSubscribedEndpoints", mock.Anything, "payment.created").
+		Return([]webhooks.Endpoint{{ID: "whep_1"}, {ID: "whep_2"}}, nil)
+
+	var deliveries []webhooks.Delivery
+	deliveryRepo.On("CreateDeliveries", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		deliveries = args.Get(1).([]webhooks.Delivery)
+	}).Return(nil)
+
+	service := NewWebhookService(endpointRepo, deliveryRepo, mocks.NewMockISender(t), RetryPolicy{})
+	err := service.Publish(context.Background(), webhooks.Event{
+		Type: "payment.created",
+		Data: json.RawMessage(`{"id":"pay_1"}`),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, "whep_1", deliveries[0].EndpointID)
+	assert.Equal(t, "whep_2", deliveries[1].EndpointID)
+	assert.Equal(t, deliveries[0].EventID, deliveries[1].EventID)
+	assert.Equal(t, webhooks.DeliveryStatusPending, deliveries[0].Status)
+
+	var event webhooks.Event
+	require.NoError(t, json.Unmarshal(deliveries[0].Payload, &event))
+	assert.Equal(t, deliveries[0].EventID, event.ID)
+	assert.Equal(t, "payment.created", event.Type)
+	assert.JSONEq(t, `{"id":"pay_1"}`, string(event.Data))
+	assert.False(t, event.OccurredAt.IsZero())
+}
+
+func TestWebhookService_Publish_NoSubscribers(t *testing.T) {
+	endpointRepo := mocks.NewMockIEndpointRepository(t)
+	endpointRepo.On("FetchSubscribedEndpoints", mock.Anything, "payment.created").Return([]webhooks.Endpoint{}, nil)
+
+	// No delivery is recorded when nobody listens
+	service := NewWebhookService(endpointRepo, mocks.NewMockIDeliveryRepository(t), mocks.NewMockISender(t), RetryPolicy{})
+	err := service.Publish(context.Background(), webhooks.Event{Type: "payment.created"})
+
+	assert.NoError(t, err)
+}
+
+func TestWebhookService_SendDelivery(t *testing.T) {
+	payload := json.RawMessage(`{"id":"evt_1"}`)
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Minute, MaxBackoff: time.Hour}
+
+	tests := []struct {
+		name             string
+		endpointStatus   string
+		attempts         int
+		sendStatusCode   int
+		sendError        error
+		expectSend       bool
+		expectError      bool
+		expectedStatus   string
+		expectedAttempts int
+		expectedBackoff  time.Duration
+	}{
+		{
+			name:             "2xx response succeeds",
+			endpointStatus:   webhooks.EndpointStatusActive,
+			sendStatusCode:   204,
+			expectSend:       true,
+			expectedStatus:   webhooks.DeliveryStatusSucceeded,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "5xx response is retried with backoff",
+			endpointStatus:   webhooks.EndpointStatusActive,
+			attempts:         1,
+			sendStatusCode:   503,
+			expectSend:       true,
+			expectError:      true,
+			expectedStatus:   webhooks.DeliveryStatusPending,
+			expectedAttempts: 2,
+			expectedBackoff:  2 * time.Minute,
+		},
+		{
+			name:             "connection error is retried",
+			endpointStatus:   webhooks.EndpointStatusActive,
+			sendError:        errors.New("connection refused"),
+			expectSend:       true,
+			expectError:      true,
+			expectedStatus:   webhooks.DeliveryStatusPending,
+			expectedAttempts: 1,
+			expectedBackoff:  time.Minute,
+		},
+		{
+			name:             "last attempt fails the delivery",
+			endpointStatus:   webhooks.EndpointStatusActive,
+			attempts:         2,
+			sendStatusCode:   500,
+			expectSend:       true,
+			expectError:      true,
+			expectedStatus:   webhooks.DeliveryStatusFailed,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "disabled endpoint is not called",
+			endpointStatus:   webhooks.EndpointStatusDisabled,
+			expectError:      true,
+			expectedStatus:   webhooks.DeliveryStatusPending,
+			expectedAttempts: 1,
+			expectedBackoff:  time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpointRepo := mocks.NewMockIEndpointRepository(t)
+			deliveryRepo := mocks.NewMockIDeliveryRepository(t)
+			sender := mocks.NewMockISender(t)
+
+			endpointRepo.On("GetEndpoint", mock.Anything, "whep_1").Return(webhooks.Endpoint{
+				ID:     "whep_1",
+				URL:    "https://merchant.example/hooks",
+				Secret: "whsec_test",
+				Status: tt.endpointStatus,
+			}, nil)
+			if tt.expectSend {
+				sender.On("Send", mock.Anything, mock.MatchedBy(func(req ports.SendRequest) bool {
+					return req.URL == "https://merchant.example/hooks" &&
+						req.Headers[webhook.HeaderEventID] == "evt_1" &&
+						webhook.Verify("whsec_test", req.Headers[webhook.HeaderSignature], req.Body, 0, time.Now()) == nil
+				})).Return(tt.sendStatusCode, tt.sendError)
+			}
+			deliveryRepo.On("UpdateDelivery", mock.Anything, mock.Anything).Return(nil)
+
+			service := NewWebhookService(endpointRepo, deliveryRepo, sender, policy)
+			delivery := &webhooks.Delivery{
+				ID:         "whdl_1",
+				EndpointID: "whep_1",
+				EventID:    "evt_1",
+				EventType:  "payment.created",
+				Payload:    payload,
+				Status:     webhooks.DeliveryStatusPending,
+				Attempts:   tt.attempts,
+			}
+			before := time.Now()
+			err := service.SendDelivery(context.Background(), delivery)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.NotEmpty(t, delivery.LastError)
+			} else {
+				assert.NoError(t, err)
+				assert.Empty(t, delivery.LastError)
+			}
+			assert.Equal(t, tt.expectedStatus, delivery.Status)
+			assert.Equal(t, tt.expectedAttempts, delivery.Attempts)
+			assert.Equal(t, tt.sendStatusCode, delivery.LastStatusCode)
+			require.NotNil(t, delivery.LastAttemptAt)
+			if tt.expectedBackoff > 0 {
+				assert.WithinDuration(t, before.Add(tt.expectedBackoff), delivery.NextAttemptAt, time.Second)
+			}
+		})
+	}
+}
+
+func TestWebhookService_Redeliver(t *testing.T) {
+	deliveryRepo := mocks.NewMockIDeliveryRepository(t)
+	deliveryRepo.On("GetDelivery", mock.Anything, "whdl_1").Return(webhooks.Delivery{
+		ID:       "whdl_1",
+		Status:   webhooks.DeliveryStatusFailed,
+		Attempts: 10,
+	}, nil)
+	deliveryRepo.On("UpdateDelivery", mock.Anything, mock.MatchedBy(func(d *webhooks.Delivery) bool {
+		return d.Status == webhooks.DeliveryStatusPending && d.Attempts == 0
+	})).Return(nil)
+
+	service := NewWebhookService(mocks.NewMockIEndpointRepository(t), deliveryRepo, mocks.NewMockISender(t), RetryPolicy{})
+	delivery, err := service.Redeliver(context.Background(), "whdl_1")
+
+	require.NoError(t, err)
+	assert.Equal(t, webhooks.DeliveryStatusPending, delivery.Status)
+	assert.WithinDuration(t, time.Now(), delivery.NextAttemptAt, time.Second)
+}
+
+func TestWebhookService_FetchDeliveries(t *testing.T) {
+	t.Run("unknown status", func(t *testing.T) {
+		service := NewWebhookService(mocks.NewMockIEndpointRepository(t), mocks.NewMockIDeliveryRepository(t), mocks.NewMockISender(t), RetryPolicy{})
+		_, _, err := service.FetchDeliveries(context.Background(), webhooks.DeliveryFetchParams{EndpointID: "whep_1", Status: "lost"})
+		assert.True(t, pkgerrors.IsErrorCode(err, pkgerrors.ErrorCodeValidation))
+	})
+
+	t.Run("unknown endpoint", func(t *testing.T) {
+		endpointRepo := mocks.NewMockIEndpointRepository(t)
+		endpointRepo.On("GetEndpoint", mock.Anything, "whep_missing").Return(webhooks.Endpoint{}, pkgerrors.ErrDataNotFound)
+
+		service := NewWebhookService(endpointRepo, mocks.NewMockIDeliveryRepository(t), mocks.NewMockISender(t), RetryPolicy{})
+		_, _, err := service.FetchDeliveries(context.Background(), webhooks.DeliveryFetchParams{EndpointID: "whep_missing"})
+		assert.ErrorIs(t, err, pkgerrors.ErrDataNotFound)
+	})
+}
@@ -0,0 +1,31 @@
+package webhooks
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+// Module encapsulates the Webhooks module following hexagonal architecture.
+//
+// Structure:
+//   - Service: The hexagon core (endpoints, deliveries and retries)
+//   - RegisterController: Inbound adapter (REST API)
+//   - Jobs: The delivery dispatcher, exposed to the scheduler command
+//
+// This module never depends on the modules publishing events. They depend on a port
+// satisfied by IWebhookService, which the composition root injects.
+type Module struct {
+	Service            IWebhookService
+	RegisterController func(*echo.Group)
+	Jobs               []scheduler.Job
+}
+
+// RegisterHTTPHandlers registers all HTTP endpoints for this module.
+// This allows the monolith to compose multiple modules by registering their routes
+// into the main HTTP router, maintaining module encapsulation.
+func (m *Module) RegisterHTTPHandlers(e *echo.Group) {
+	if m.RegisterController != nil {
+		m.RegisterController(e)
+	}
+}
@@ -0,0 +1,119 @@
+// Package webhooks implements the Webhooks module in a modular monolith architecture.
+//
+// This module notifies merchant systems about domain events over HTTP:
+//   - Domain model (Endpoint, Event, Delivery)
+//   - Service interface (IWebhookService) exposes the module's public API
+//   - Other modules publish events through Publish, which only records a delivery per subscribed
+//     endpoint in the caller's transaction; the dispatcher job sends deliveries in the background
+//     and retries failed ones with exponential backoff
+//
+// Merchants register endpoints through POST /api/v1/webhooks/endpoints and inspect the delivery
+// log of an endpoint through GET /api/v1/webhooks/endpoints/:id/deliveries.
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// DispatcherJobName is the name the delivery dispatcher runs under in the scheduler and the job run history.
+const DispatcherJobName = "webhook-dispatcher"
+
+// Statuses of an endpoint. Only active endpoints receive new deliveries.
+const (
+	EndpointStatusActive   = "active"
+	EndpointStatusDisabled = "disabled"
+)
+
+// Statuses of a delivery. A pending delivery is sent when it is due; it fails once every attempt failed.
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSucceeded = "succeeded"
+	DeliveryStatusFailed    = "failed"
+)
+
+// EventTypeAll subscribes an endpoint to every event type.
+const EventTypeAll = "*"
+
+// Endpoint is a merchant URL receiving the events it subscribed to.
+// Secret signs every delivery to the endpoint.
+type Endpoint struct {
+	ID         string    `json:"id"`
+	TenantID   string    `json:"tenantId"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret"`
+	EventTypes []string  `json:"eventTypes"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Event is a domain event published by a module. Data is the event payload as JSON.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Delivery is one event sent to one endpoint, with the outcome of its latest attempt.
+// Payload is the JSON body sent to the endpoint.
+type Delivery struct {
+	ID             string          `json:"id"`
+	TenantID       string          `json:"tenantId"`
+	EndpointID     string          `json:"endpointId"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	LastAttemptAt  *time.Time      `json:"lastAttemptAt"`
+	LastStatusCode int             `json:"lastStatusCode"`
+	LastError      string          `json:"lastError"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+}
+
+// EndpointFetchParams contains pagination parameters for listing endpoints, newest first.
+type EndpointFetchParams struct {
+	Limit  int    `json:"limit"`
+	Cursor string `json:"cursor"`
+}
+
+// DeliveryFetchParams contains filtering and pagination parameters for the delivery log, newest first.
+type DeliveryFetchParams struct {
+	EndpointID string `json:"endpointId"`
+	Status     string `json:"status"`
+	Limit      int    `json:"limit"`
+	Cursor     string `json:"cursor"`
+}
+
+// ClaimDeliveriesParams describes a batch of due deliveries to reserve for one dispatcher run.
+type ClaimDeliveriesParams struct {
+	Limit int `json:"limit"`
+	// LeaseDuration is how long claimed deliveries are hidden from other dispatchers.
+	LeaseDuration time.Duration `json:"leaseDuration"`
+}
+
+// IWebhookService defines the public API of the Webhooks module.
+type IWebhookService interface {
+	// CreateEndpoint registers an endpoint. A secret is generated when none is given.
+	CreateEndpoint(ctx context.Context, endpoint *Endpoint) error
+	GetEndpoint(ctx context.Context, id string) (endpoint Endpoint, err error)
+	FetchEndpoints(ctx context.Context, params EndpointFetchParams) (result []Endpoint, nextCursor string, err error)
+	// DeleteEndpoint removes the endpoint together with its delivery log.
+	DeleteEndpoint(ctx context.Context, id string) error
+	// Publish records a pending delivery of the event for every active endpoint subscribed to its type.
+	// It joins the caller's transaction and never calls an endpoint itself.
+	Publish(ctx context.Context, event Event) error
+	FetchDeliveries(ctx context.Context, params DeliveryFetchParams) (result []Delivery, nextCursor string, err error)
+	// Redeliver makes a delivery due right away with a fresh set of attempts, whatever its status.
+	Redeliver(ctx context.Context, id string) (delivery Delivery, err error)
+	// ClaimDueDeliveries reserves up to params.Limit deliveries that are due, oldest due first.
+	ClaimDueDeliveries(ctx context.Context, params ClaimDeliveriesParams) (result []Delivery, err error)
+	// SendDelivery makes one attempt to send the delivery and records the outcome. A failed attempt is
+	// retried later with exponential backoff until the attempts are exhausted; the error describes it.
+	SendDelivery(ctx context.Context, delivery *Delivery) error
+}
@@ -4,16 +4,24 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Server   ServerConfig
-	App      AppConfig
-	Cron     CronConfig
+	Database      DatabaseConfig
+	Server        ServerConfig
+	App           AppConfig
+	Cron          CronConfig
+	Scheduler     SchedulerConfig
+	Idempotency   IdempotencyConfig
+	Gateway       GatewayConfig
+	Webhooks      WebhooksConfig
+	SettingsCache SettingsCacheConfig
+	Settings      SettingsConfig
+	Tenancy       TenancyConfig
 }
 
 type DatabaseConfig struct {
@@ -43,8 +51,71 @@ type AppConfig struct {
 }
 
 type CronConfig struct {
-	BatchSize int
-	DryRun    bool
+	BatchSize   int
+	DryRun      bool
+	MaxItems    int
+	MaxDuration time.Duration
+	// Concurrency and LeaseDuration control how pending payments are claimed and processed.
+	Concurrency   int
+	LeaseDuration time.Duration
+	// RetryDelay is how long a payment that failed to process waits before it is claimed again.
+	RetryDelay time.Duration
+	// LockMode is what a run does when the job is already running on another instance: "skip" or "wait".
+	LockMode        string
+	LockWaitTimeout time.Duration
+}
+
+type SchedulerConfig struct {
+	// Schedules overrides job schedules, e.g. SCHEDULER_JOBS="payment-updater=*/10 * * * *;webhook-dispatcher=@every 30s".
+	Schedules       map[string]string
+	ShutdownTimeout time.Duration
+}
+
+type IdempotencyConfig struct {
+	KeyTTL                 time.Duration
+	PaymentSettingsEnabled bool
+}
+
+type GatewayConfig struct {
+	// SimulatorRules forces outcomes of the simulated payment gateway, e.g. "decline:*:1000-;timeout:USD:13.13-13.13:capture".
+	SimulatorRules   string
+	SimulatorLatency time.Duration
+	// WebhookSecrets holds the webhook signing secret of each provider, e.g. PSP_WEBHOOK_SECRETS="simulator=whsec_123".
+	WebhookSecrets   map[string]string
+	WebhookTolerance time.Duration
+}
+
+// WebhooksConfig controls the outbound merchant webhook deliveries.
+type WebhooksConfig struct {
+	// MaxAttempts, InitialBackoff and MaxBackoff define the exponential retry of failed deliveries.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Timeout bounds a single delivery attempt.
+	Timeout time.Duration
+}
+
+// SettingsCacheConfig controls the cache of payment settings read by the payment module.
+type SettingsCacheConfig struct {
+	Enabled bool
+	// TTL bounds how long cached settings are used before they are read again.
+	TTL        time.Duration
+	MaxEntries int
+}
+
+// SettingsConfig controls the payment settings module.
+type SettingsConfig struct {
+	// RejectUnknownKeys refuses settings whose key the settings schema does not declare.
+	RejectUnknownKeys bool
+}
+
+// TenancyConfig controls how the tenant (merchant) of a request is resolved.
+type TenancyConfig struct {
+	// APIKeys maps each API key to its tenant, e.g. TENANT_API_KEYS="sk_live_a=merchant_a;sk_live_b=merchant_b".
+	// Without keys the X-Tenant-ID request header is trusted.
+	APIKeys map[string]string
+	// AdminAPIKey acts for the platform, e.g. to manage the default payment settings. Empty disables it.
+	AdminAPIKey string
 }
 
 func Load(envFiles ...string) (cfg *Config, err error) {
@@ -81,8 +152,47 @@ func Load(envFiles ...string) (cfg *Config, err error) {
 			Debug:       getEnvAsBool("DEBUG", false),
 		},
 		Cron: CronConfig{
-			BatchSize: getEnvAsInt("CRON_BATCH_SIZE", 50),
-			DryRun:    getEnvAsBool("CRON_DRY_RUN", false),
+			BatchSize:       getEnvAsInt("CRON_BATCH_SIZE", 50),
+			DryRun:          getEnvAsBool("CRON_DRY_RUN", false),
+			MaxItems:        getEnvAsInt("CRON_MAX_ITEMS", 0),
+			MaxDuration:     getEnvAsDuration("CRON_MAX_DURATION", 0),
+			Concurrency:     getEnvAsInt("CRON_CONCURRENCY", 1),
+			LeaseDuration:   getEnvAsDuration("CRON_LEASE_DURATION", 5*time.Minute),
+			RetryDelay:      getEnvAsDuration("CRON_RETRY_DELAY", 15*time.Minute),
+			LockMode:        getEnv("CRON_LOCK_MODE", "skip"),
+			LockWaitTimeout: getEnvAsDuration("CRON_LOCK_WAIT_TIMEOUT", 0),
+		},
+		Scheduler: SchedulerConfig{
+			Schedules:       getEnvAsMap("SCHEDULER_JOBS"),
+			ShutdownTimeout: getEnvAsDuration("SCHEDULER_SHUTDOWN_TIMEOUT", 30*time.Second),
+		},
+		Idempotency: IdempotencyConfig{
+			KeyTTL:                 getEnvAsDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+			PaymentSettingsEnabled: getEnvAsBool("IDEMPOTENCY_PAYMENT_SETTINGS_ENABLED", false),
+		},
+		Gateway: GatewayConfig{
+			SimulatorRules:   getEnv("PSP_SIMULATOR_RULES", ""),
+			SimulatorLatency: getEnvAsDuration("PSP_SIMULATOR_LATENCY", 0),
+			WebhookSecrets:   getEnvAsMap("PSP_WEBHOOK_SECRETS"),
+			WebhookTolerance: getEnvAsDuration("PSP_WEBHOOK_TOLERANCE", 5*time.Minute),
+		},
+		Webhooks: WebhooksConfig{
+			MaxAttempts:    getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 10),
+			InitialBackoff: getEnvAsDuration("WEBHOOK_INITIAL_BACKOFF", 30*time.Second),
+			MaxBackoff:     getEnvAsDuration("WEBHOOK_MAX_BACKOFF", 6*time.Hour),
+			Timeout:        getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		},
+		SettingsCache: SettingsCacheConfig{
+			Enabled:    getEnvAsBool("SETTINGS_CACHE_ENABLED", true),
+			TTL:        getEnvAsDuration("SETTINGS_CACHE_TTL", time.Minute),
+			MaxEntries: getEnvAsInt("SETTINGS_CACHE_MAX_ENTRIES", 1000),
+		},
+		Settings: SettingsConfig{
+			RejectUnknownKeys: getEnvAsBool("SETTINGS_REJECT_UNKNOWN_KEYS", false),
+		},
+		Tenancy: TenancyConfig{
+			APIKeys:     getEnvAsMap("TENANT_API_KEYS"),
+			AdminAPIKey: getEnv("TENANT_ADMIN_API_KEY", ""),
 		},
 	}
 
@@ -138,6 +248,20 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	return value
 }
 
+// getEnvAsMap parses "key=value;key=value". Semicolons separate entries because cron
+// expressions contain spaces and commas.
+func getEnvAsMap(key string) map[string]string {
+	values := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv(key), ";") {
+		name, value, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			continue
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return values
+}
+
 func (c *Config) DatabaseDSN() string {
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		c.Database.Host,
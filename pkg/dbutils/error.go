@@ -1,6 +1,7 @@
 package dbutils
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 
@@ -19,12 +20,19 @@ func HandlePostgresError(err error) error {
 		return apperrors.ErrDataNotFound
 	}
 
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return apperrors.ErrRequestTimeout
+	}
+
 	var pqErr *pq.Error
 	if !errors.As(err, &pqErr) {
 		return err
 	}
 
 	switch pqErr.Code {
+	case "57014":
+		// query_canceled: the statement was cancelled because its context expired
+		return apperrors.ErrRequestTimeout
 	case "23505":
 		log.Error().
 			Str("code", string(pqErr.Code)).
@@ -32,6 +40,14 @@ func HandlePostgresError(err error) error {
 			Str("detail", pqErr.Detail).
 			Msg("Duplicate key violation")
 		return apperrors.ErrDuplicatedData
+	case "23P01":
+		// exclusion_violation: the row overlaps another one, e.g. a validity range
+		log.Error().
+			Str("code", string(pqErr.Code)).
+			Str("constraint", pqErr.Constraint).
+			Str("detail", pqErr.Detail).
+			Msg("Exclusion constraint violation")
+		return apperrors.ErrConflict
 	default:
 		log.Error().
 			Str("code", string(pqErr.Code)).
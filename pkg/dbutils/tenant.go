@@ -0,0 +1,26 @@
+package dbutils
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+// TenantColumn is the column holding the tenant of every row of tenant data.
+const TenantColumn = "tenant_id"
+
+// TenantFilter restricts a query to the rows of the tenant ctx acts for, and lets it see every row when
+// ctx acts for every tenant. It fails with tenant.ErrRequired when ctx has no tenant, so a repository
+// never runs an unscoped query by accident.
+func TenantFilter(ctx context.Context) (sq.Sqlizer, error) {
+	if tenant.IsAllTenants(ctx) {
+		return sq.Expr("TRUE"), nil
+	}
+	id, err := tenant.Required(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sq.Eq{TenantColumn: id}, nil
+}
@@ -6,15 +6,20 @@ import (
 )
 
 const (
-	ErrorCodeValidation          = "VALIDATION_ERROR"
-	ErrorCodeUnauthorized        = "UNAUTHORIZED"
-	ErrorCodeRequestTimeout      = "REQUEST_TIMEOUT"
-	ErrorCodeDataNotFound        = "DATA_NOT_FOUND"
-	ErrorCodeInternalServerError = "INTERNAL_SERVER_ERROR"
-	ErrorCodeServiceUnavailable  = "SERVICE_UNAVAILABLE"
-	ErrorCodeNotImplemented      = "NOT_IMPLEMENTED"
-	ErrorCodeDataDuplicate       = "DATA_DUPLICATE"
-	ErrorCodeConflict            = "CONFLICT"
+	ErrorCodeValidation           = "VALIDATION_ERROR"
+	ErrorCodeUnauthorized         = "UNAUTHORIZED"
+	ErrorCodeForbidden            = "FORBIDDEN"
+	ErrorCodeRequestTimeout       = "REQUEST_TIMEOUT"
+	ErrorCodeDataNotFound         = "DATA_NOT_FOUND"
+	ErrorCodeInternalServerError  = "INTERNAL_SERVER_ERROR"
+	ErrorCodeServiceUnavailable   = "SERVICE_UNAVAILABLE"
+	ErrorCodeNotImplemented       = "NOT_IMPLEMENTED"
+	ErrorCodeDataDuplicate        = "DATA_DUPLICATE"
+	ErrorCodeConflict             = "CONFLICT"
+	ErrorCodeIdempotencyMismatch  = "IDEMPOTENCY_KEY_MISMATCH"
+	ErrorCodeRequestInProgress    = "REQUEST_IN_PROGRESS"
+	ErrorCodePreconditionFailed   = "PRECONDITION_FAILED"
+	ErrorCodePreconditionRequired = "PRECONDITION_REQUIRED"
 )
 
 var (
@@ -65,6 +70,30 @@ var (
 		Message:    "Resource conflict",
 		StatusCode: http.StatusConflict,
 	}
+
+	ErrIdempotencyKeyMismatch = &Error{
+		Code:       ErrorCodeIdempotencyMismatch,
+		Message:    "Idempotency key was already used with a different request",
+		StatusCode: http.StatusUnprocessableEntity,
+	}
+
+	ErrRequestInProgress = &Error{
+		Code:       ErrorCodeRequestInProgress,
+		Message:    "A request with the same idempotency key is still in progress",
+		StatusCode: http.StatusConflict,
+	}
+
+	ErrPreconditionFailed = &Error{
+		Code:       ErrorCodePreconditionFailed,
+		Message:    "Resource was modified by another request",
+		StatusCode: http.StatusPreconditionFailed,
+	}
+
+	ErrPreconditionRequired = &Error{
+		Code:       ErrorCodePreconditionRequired,
+		Message:    "If-Match header is required",
+		StatusCode: http.StatusPreconditionRequired,
+	}
 )
 
 func NewValidationError(err error) *Error {
@@ -107,6 +136,14 @@ func NewUnauthorizedError(err error) *Error {
 	}
 }
 
+func NewForbiddenError(err error) *Error {
+	return &Error{
+		Code:       ErrorCodeForbidden,
+		Message:    err.Error(),
+		StatusCode: http.StatusForbidden,
+	}
+}
+
 func IsNotFound(err error) bool {
 	return IsErrorCode(err, ErrorCodeDataNotFound)
 }
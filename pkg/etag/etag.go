@@ -0,0 +1,49 @@
+// Package etag maps entity versions to HTTP entity tags for optimistic concurrency control.
+//
+// GET responses carry the entity version as an ETag. Clients send it back in If-Match on
+// PUT/DELETE; a stale version is rejected instead of silently overwriting a newer change.
+package etag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	apperrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+)
+
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+// AnyVersion is returned by ParseIfMatch for "If-Match: *" and means no version check.
+const AnyVersion int64 = 0
+
+// Format returns the entity tag for a version, e.g. "3".
+func Format(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ParseIfMatch extracts the expected version from an If-Match header.
+// A missing header is rejected with 428 Precondition Required so updates are never unconditional by accident.
+func ParseIfMatch(header string) (version int64, err error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, apperrors.ErrPreconditionRequired
+	}
+	if header == "*" {
+		return AnyVersion, nil
+	}
+
+	unquoted, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, apperrors.NewValidationError(fmt.Errorf("invalid If-Match header %q", header))
+	}
+
+	version, err = strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, apperrors.NewValidationError(fmt.Errorf("invalid If-Match header %q", header))
+	}
+	return version, nil
+}
@@ -0,0 +1,46 @@
+package etag_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/etag"
+)
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, `"3"`, etag.Format(3))
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name            string
+		header          string
+		expectedVersion int64
+		expectedCode    string
+	}{
+		{name: "quoted version", header: `"3"`, expectedVersion: 3},
+		{name: "round trip", header: etag.Format(42), expectedVersion: 42},
+		{name: "wildcard", header: "*", expectedVersion: etag.AnyVersion},
+		{name: "missing header", header: "", expectedCode: errors.ErrorCodePreconditionRequired},
+		{name: "unquoted version", header: "3", expectedCode: errors.ErrorCodeValidation},
+		{name: "weak tag", header: `W/"3"`, expectedCode: errors.ErrorCodeValidation},
+		{name: "zero version", header: `"0"`, expectedCode: errors.ErrorCodeValidation},
+		{name: "not a number", header: `"abc"`, expectedCode: errors.ErrorCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := etag.ParseIfMatch(tt.header)
+			if tt.expectedCode != "" {
+				require.Error(t, err)
+				assert.True(t, errors.IsErrorCode(err, tt.expectedCode), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedVersion, version)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Package eventbus implements the in-process publish/subscribe bus the modules communicate through.
+//
+// A module publishes typed events from its public package, e.g. paymentsettings.PaymentSettingChanged,
+// and other modules subscribe to them in their factory. The publisher only depends on the bus, never
+// on its subscribers, so a module can react to another one without the other one importing it.
+//
+// Events are routed by their Go type. A synchronous subscriber runs in the publisher's goroutine before
+// Publish returns, an asynchronous one in its own goroutine. A failing or panicking handler never
+// affects the other handlers of the event.
+//
+// The bus is in-process and best-effort: events are lost when the process stops. Consumers that need
+// every event read the module outboxes instead (see pkg/outbox).
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Event is a domain event published on the bus. EventName identifies the event in logs.
+type Event interface {
+	EventName() string
+}
+
+// Handler handles events of type E.
+type Handler[E Event] func(ctx context.Context, event E) error
+
+// Mode decides how a subscriber runs relative to the publisher.
+type Mode string
+
+const (
+	// ModeSync runs the handler before Publish returns; its error is returned by Publish.
+	ModeSync Mode = "sync"
+	// ModeAsync runs the handler in its own goroutine; its error is only logged.
+	ModeAsync Mode = "async"
+)
+
+// Config tunes a Bus.
+type Config struct {
+	// AsyncTimeout bounds a single asynchronous handler (default 30s).
+	AsyncTimeout time.Duration
+}
+
+type subscriber struct {
+	name   string
+	mode   Mode
+	handle func(ctx context.Context, event Event) error
+}
+
+// Bus dispatches published events to the subscribers of their type. It is safe for concurrent use.
+type Bus struct {
+	config Config
+
+	mu          sync.RWMutex
+	subscribers map[reflect.Type][]subscriber
+	closed      bool
+	inflight    sync.WaitGroup
+}
+
+// New creates an empty bus.
+func New(config Config) *Bus {
+	if config.AsyncTimeout <= 0 {
+		config.AsyncTimeout = 30 * time.Second
+	}
+	return &Bus{
+		config:      config,
+		subscribers: make(map[reflect.Type][]subscriber),
+	}
+}
+
+// Subscribe registers handler for the events of type E under name, which identifies the subscriber in logs.
+// Handlers of an event run in registration order.
+func Subscribe[E Event](b *Bus, name string, mode Mode, handler Handler[E]) {
+	eventType := reflect.TypeOf((*E)(nil)).Elem()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[eventType] = append(b.subscribers[eventType], subscriber{
+		name: name,
+		mode: mode,
+		handle: func(ctx context.Context, event Event) error {
+			return handler(ctx, event.(E))
+		},
+	})
+}
+
+// Publish dispatches event to the subscribers of its type. It returns the errors of the synchronous
+// handlers, joined; asynchronous handlers are started before the synchronous ones run and are not
+// waited for. After Close, asynchronous handlers are skipped.
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	subscribers := b.subscribers[reflect.TypeOf(event)]
+	closed := b.closed
+	if !closed {
+		// Registered under the read lock, so Close cannot start waiting in between
+		for _, sub := range subscribers {
+			if sub.mode == ModeAsync {
+				b.inflight.Add(1)
+			}
+		}
+	}
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, sub := range subscribers {
+		if sub.mode != ModeAsync {
+			continue
+		}
+		if closed {
+			log.Warn().Str("event", event.EventName()).Str("subscriber", sub.name).Msg("Event bus closed, skipping asynchronous handler")
+			continue
+		}
+		go b.runAsync(ctx, sub, event)
+	}
+	for _, sub := range subscribers {
+		if sub.mode == ModeAsync {
+			continue
+		}
+		if err := b.run(ctx, sub, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops starting asynchronous handlers and waits for the running ones until ctx is done.
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("event bus closed with handlers still running: %w", ctx.Err())
+	}
+}
+
+// runAsync runs an asynchronous handler detached from the publisher's cancellation.
+func (b *Bus) runAsync(ctx context.Context, sub subscriber, event Event) {
+	defer b.inflight.Done()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.config.AsyncTimeout)
+	defer cancel()
+
+	if err := b.run(ctx, sub, event); err != nil {
+		log.Error().Err(err).Str("event", event.EventName()).Str("subscriber", sub.name).Msg("Asynchronous event handler failed")
+	}
+}
+
+// run calls the handler, turning a panic into an error so it cannot reach the publisher or other handlers.
+func (b *Bus) run(ctx context.Context, sub subscriber, event Event) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error().Str("event", event.EventName()).Str("subscriber", sub.name).Bytes("stack", debug.Stack()).Msgf("Event handler panicked: %v", p)
+			err = fmt.Errorf("subscriber %s panicked handling %s: %v", sub.name, event.EventName(), p)
+		}
+	}()
+
+	if err = sub.handle(ctx, event); err != nil {
+		return fmt.Errorf("subscriber %s failed handling %s: %w", sub.name, event.EventName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package eventbus_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/eventbus"
+)
+
+type settingChanged struct {
+	Key string
+}
+
+func (settingChanged) EventName() string { return "setting.changed" }
+
+type paymentCreated struct {
+	ID string
+}
+
+func (paymentCreated) EventName() string { return "payment.created" }
+
+func TestBus_RoutesByEventType(t *testing.T) {
+	bus := eventbus.New(eventbus.Config{})
+
+	var settings []string
+	var payments []string
+	eventbus.Subscribe(bus, "settings", eventbus.ModeSync, func(_ context.Context, event settingChanged) error {
+		settings = append(settings, event.Key)
+		return nil
+	})
+	eventbus.Subscribe(bus, "payments", eventbus.ModeSync, func(_ context.Context, event paymentCreated) error {
+		payments = append(payments, event.ID)
+		return nil
+	})
+
+	require.NoError(t, bus.Publish(context.Background(), settingChanged{Key: "max_transaction_amount"}))
+	require.NoError(t, bus.Publish(context.Background(), paymentCreated{ID: "pay_123"}))
+
+	assert.Equal(t, []string{"max_transaction_amount"}, settings)
+	assert.Equal(t, []string{"pay_123"}, payments)
+}
+
+func TestBus_PublishWithoutSubscribers(t *testing.T) {
+	bus := eventbus.New(eventbus.Config{})
+
+	assert.NoError(t, bus.Publish(context.Background(), settingChanged{Key: "rate"}))
+}
+
+func TestBus_IsolatesFailingHandlers(t *testing.T) {
+	bus := eventbus.New(eventbus.Config{})
+
+	var calls []string
+	eventbus.Subscribe(bus, "panics", eventbus.ModeSync, func(context.Context, settingChanged) error {
+		calls = append(calls, "panics")
+		panic("boom")
+	})
+	eventbus.Subscribe(bus, "fails", eventbus.ModeSync, func(context.Context, settingChanged) error {
+		calls = append(calls, "fails")
+		return errors.New("handler failed")
+	})
+	eventbus.Subscribe(bus, "succeeds", eventbus.ModeSync, func(context.Context, settingChanged) error {
+		calls = append(calls, "succeeds")
+		return nil
+	})
+
+	err := bus.Publish(context.Background(), settingChanged{Key: "rate"})
+
+	assert.Equal(t, []string{"panics", "fails", "succeeds"}, calls)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "subscriber panics panicked")
+	assert.Contains(t, err.Error(), "handler failed")
+}
+
+func TestBus_AsyncHandlers(t *testing.T) {
+	t.Run("run detached from the publisher", func(t *testing.T) {
+		bus := eventbus.New(eventbus.Config{})
+
+		release := make(chan struct{})
+		handled := make(chan error, 1)
+		eventbus.Subscribe(bus, "async", eventbus.ModeAsync, func(ctx context.Context, _ settingChanged) error {
+			<-release
+			handled <- ctx.Err()
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		require.NoError(t, bus.Publish(ctx, settingChanged{Key: "rate"}))
+		cancel()
+		close(release)
+
+		assert.NoError(t, <-handled, "cancelling the publisher must not cancel the handler")
+	})
+
+	t.Run("errors and panics do not reach the publisher", func(t *testing.T) {
+		bus := eventbus.New(eventbus.Config{})
+
+		eventbus.Subscribe(bus, "fails", eventbus.ModeAsync, func(context.Context, settingChanged) error {
+			return errors.New("handler failed")
+		})
+		eventbus.Subscribe(bus, "panics", eventbus.ModeAsync, func(context.Context, settingChanged) error {
+			panic("boom")
+		})
+
+		assert.NoError(t, bus.Publish(context.Background(), settingChanged{Key: "rate"}))
+		assert.NoError(t, bus.Close(context.Background()))
+	})
+
+	t.Run("close waits for running handlers", func(t *testing.T) {
+		bus := eventbus.New(eventbus.Config{})
+
+		var mu sync.Mutex
+		var handled int
+		eventbus.Subscribe(bus, "slow", eventbus.ModeAsync, func(context.Context, settingChanged) error {
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			handled++
+			mu.Unlock()
+			return nil
+		})
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, bus.Publish(context.Background(), settingChanged{Key: "rate"}))
+		}
+		require.NoError(t, bus.Close(context.Background()))
+
+		mu.Lock()
+		assert.Equal(t, 3, handled)
+		mu.Unlock()
+
+		// Closed: asynchronous handlers are no longer started
+		require.NoError(t, bus.Publish(context.Background(), settingChanged{Key: "rate"}))
+		mu.Lock()
+		assert.Equal(t, 3, handled)
+		mu.Unlock()
+	})
+
+	t.Run("close gives up when ctx is done", func(t *testing.T) {
+		bus := eventbus.New(eventbus.Config{})
+
+		release := make(chan struct{})
+		defer close(release)
+		eventbus.Subscribe(bus, "stuck", eventbus.ModeAsync, func(context.Context, settingChanged) error {
+			<-release
+			return nil
+		})
+		require.NoError(t, bus.Publish(context.Background(), settingChanged{Key: "rate"}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.Error(t, bus.Close(ctx))
+	})
+}
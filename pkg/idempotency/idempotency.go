@@ -0,0 +1,57 @@
+// Package idempotency defines the port used to make unsafe HTTP requests retry-safe.
+//
+// A client sends an Idempotency-Key header with a request. The first request reserves the key,
+// runs, and stores its response. Repeats with the same key and the same request replay the stored
+// response instead of running the handler again. Keys expire after a configurable window.
+//
+// Each module persists its keys in its own schema by giving the store its own table.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Record is the stored outcome of a request made with an idempotency key.
+// StatusCode is zero while the first request is still in flight.
+type Record struct {
+	Key          string
+	RequestHash  string
+	StatusCode   int
+	ContentType  string
+	ResponseBody []byte
+	CreatedAt    time.Time
+	CompletedAt  *time.Time
+	ExpiresAt    time.Time
+}
+
+// IsCompleted reports whether the response of the original request has been stored.
+func (r Record) IsCompleted() bool {
+	return r.StatusCode != 0
+}
+
+// Store is the outbound port for idempotency key persistence.
+type Store interface {
+	// Reserve claims the key for a new request. When a live record already exists for the key,
+	// it is returned with reserved set to false. Expired records are replaced.
+	Reserve(ctx context.Context, record Record) (existing Record, reserved bool, err error)
+	// Complete stores the response of a reserved request.
+	Complete(ctx context.Context, record Record) error
+	// Release removes a reservation whose request did not produce a replayable response.
+	Release(ctx context.Context, key string) error
+	// PurgeExpired removes records that expired before the given time.
+	PurgeExpired(ctx context.Context, before time.Time) (deleted int64, err error)
+}
+
+// HashRequest fingerprints a request so a reused key with a different request can be detected.
+func HashRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,121 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/dbutils"
+)
+
+// PostgresStore persists idempotency records in a module-owned table, e.g. payment_module.idempotency_keys.
+type PostgresStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewPostgresStore creates a store backed by the given schema-qualified table.
+func NewPostgresStore(db *sql.DB, table string) *PostgresStore {
+	return &PostgresStore{
+		db:    db,
+		table: table,
+	}
+}
+
+func (s *PostgresStore) qb() sq.StatementBuilderType {
+	return sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+}
+
+func (s *PostgresStore) Reserve(ctx context.Context, record Record) (existing Record, reserved bool, err error) {
+	// Insert the reservation, replacing the row only when the previous key has expired.
+	var key string
+	err = s.qb().Insert(s.table+" AS k").
+		Columns("idempotency_key", "request_hash", "created_at", "expires_at").
+		Values(record.Key, record.RequestHash, record.CreatedAt, record.ExpiresAt).
+		Suffix(`ON CONFLICT (idempotency_key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			content_type = NULL,
+			response_body = NULL,
+			created_at = EXCLUDED.created_at,
+			completed_at = NULL,
+			expires_at = EXCLUDED.expires_at
+			WHERE k.expires_at <= EXCLUDED.created_at
+			RETURNING idempotency_key`).
+		RunWith(s.db).
+		QueryRowContext(ctx).
+		Scan(&key)
+	if err == nil {
+		return Record{}, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Record{}, false, dbutils.HandlePostgresError(err)
+	}
+
+	existing, err = s.get(ctx, record.Key)
+	if err != nil {
+		return Record{}, false, err
+	}
+	return existing, false, nil
+}
+
+func (s *PostgresStore) get(ctx context.Context, key string) (record Record, err error) {
+	var (
+		statusCode  sql.NullInt64
+		contentType sql.NullString
+		completedAt sql.NullTime
+	)
+	err = s.qb().Select("idempotency_key", "request_hash", "status_code", "content_type", "response_body",
+		"created_at", "completed_at", "expires_at").
+		From(s.table).
+		Where(sq.Eq{"idempotency_key": key}).
+		RunWith(s.db).
+		QueryRowContext(ctx).
+		Scan(&record.Key, &record.RequestHash, &statusCode, &contentType, &record.ResponseBody,
+			&record.CreatedAt, &completedAt, &record.ExpiresAt)
+	if err != nil {
+		return Record{}, dbutils.HandlePostgresError(err)
+	}
+
+	record.StatusCode = int(statusCode.Int64)
+	record.ContentType = contentType.String
+	if completedAt.Valid {
+		record.CompletedAt = &completedAt.Time
+	}
+	return record, nil
+}
+
+func (s *PostgresStore) Complete(ctx context.Context, record Record) (err error) {
+	_, err = s.qb().Update(s.table).
+		Set("status_code", record.StatusCode).
+		Set("content_type", record.ContentType).
+		Set("response_body", record.ResponseBody).
+		Set("completed_at", record.CompletedAt).
+		Where(sq.Eq{"idempotency_key": record.Key}).
+		RunWith(s.db).
+		ExecContext(ctx)
+	return dbutils.HandlePostgresError(err)
+}
+
+func (s *PostgresStore) Release(ctx context.Context, key string) (err error) {
+	_, err = s.qb().Delete(s.table).
+		Where(sq.Eq{"idempotency_key": key}).
+		Where(sq.Eq{"status_code": nil}).
+		RunWith(s.db).
+		ExecContext(ctx)
+	return dbutils.HandlePostgresError(err)
+}
+
+func (s *PostgresStore) PurgeExpired(ctx context.Context, before time.Time) (deleted int64, err error) {
+	result, err := s.qb().Delete(s.table).
+		Where(sq.LtOrEq{"expires_at": before}).
+		RunWith(s.db).
+		ExecContext(ctx)
+	if err != nil {
+		return 0, dbutils.HandlePostgresError(err)
+	}
+	return result.RowsAffected()
+}
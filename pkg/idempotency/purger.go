@@ -0,0 +1,62 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+// Purger deletes the expired keys of a store. Expired keys are already ignored by Reserve, so purging
+// only bounds the size of the table; the module owning the store schedules it as a job.
+type Purger struct {
+	store Store
+	name  string
+}
+
+// NewPurger creates a purger of store. name identifies the store in logs, e.g. the purge job name.
+func NewPurger(store Store, name string) *Purger {
+	return &Purger{
+		store: store,
+		name:  name,
+	}
+}
+
+// PurgeResult contains the results of a purge run.
+type PurgeResult struct {
+	StartTime    time.Time
+	EndTime      time.Time
+	Duration     time.Duration
+	DeletedCount int64
+}
+
+// RunSummary reports the outcome of the run to the job run history.
+func (r *PurgeResult) RunSummary() scheduler.RunSummary {
+	return scheduler.RunSummary{
+		ProcessedCount: int(r.DeletedCount),
+		SuccessCount:   int(r.DeletedCount),
+	}
+}
+
+// Execute deletes every key expired before the run started.
+func (p *Purger) Execute(ctx context.Context) (resultData interface{}, err error) {
+	result := &PurgeResult{StartTime: time.Now()}
+	result.DeletedCount, err = p.store.PurgeExpired(ctx, result.StartTime)
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	if err != nil {
+		return result, fmt.Errorf("failed to purge expired idempotency keys: %w", err)
+	}
+
+	if result.DeletedCount > 0 {
+		log.Info().
+			Str("store", p.name).
+			Dur("duration", result.Duration).
+			Int64("deleted", result.DeletedCount).
+			Msg("Expired idempotency keys purged")
+	}
+	return result, nil
+}
@@ -0,0 +1,48 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/idempotency"
+)
+
+// purgeStore records the cutoff of PurgeExpired; the other methods are not used by the purger.
+type purgeStore struct {
+	idempotency.Store
+	before  time.Time
+	deleted int64
+	err     error
+}
+
+func (s *purgeStore) PurgeExpired(_ context.Context, before time.Time) (int64, error) {
+	s.before = before
+	return s.deleted, s.err
+}
+
+func TestPurger_DeletesKeysExpiredBeforeTheRun(t *testing.T) {
+	store := &purgeStore{deleted: 3}
+	started := time.Now()
+
+	resultData, err := idempotency.NewPurger(store, "test").Execute(context.Background())
+
+	require.NoError(t, err)
+	result := resultData.(*idempotency.PurgeResult)
+	assert.Equal(t, int64(3), result.DeletedCount)
+	assert.Equal(t, 3, result.RunSummary().SuccessCount)
+	assert.False(t, store.before.Before(started))
+	assert.False(t, store.before.After(time.Now()))
+}
+
+func TestPurger_ReportsStoreErrors(t *testing.T) {
+	storeErr := errors.New("connection refused")
+
+	_, err := idempotency.NewPurger(&purgeStore{err: storeErr}, "test").Execute(context.Background())
+
+	assert.ErrorIs(t, err, storeErr)
+}
@@ -0,0 +1,260 @@
+// Package lock provides cluster-wide mutual exclusion for jobs, backed by Postgres advisory locks.
+//
+// A lock is a session-level advisory lock held on a dedicated connection. It is released by
+// Release, and Postgres releases it on its own when the session ends, so a crashed or killed
+// process never leaves a lock behind. Lock names are hashed into the 64-bit advisory lock key.
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Mode decides what Acquire does when the lock is held by someone else.
+type Mode string
+
+const (
+	// ModeSkip gives up immediately.
+	ModeSkip Mode = "skip"
+	// ModeWait retries until the lock is free, WaitTimeout passes or ctx is cancelled.
+	ModeWait Mode = "wait"
+)
+
+// ParseMode parses "skip" or "wait"; an empty string is ModeSkip.
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", ModeSkip:
+		return ModeSkip, nil
+	case ModeWait:
+		return ModeWait, nil
+	}
+	return "", fmt.Errorf("invalid lock mode %q: expected %q or %q", s, ModeSkip, ModeWait)
+}
+
+// ErrLockHeld is matched by the error Acquire returns when the lock could not be obtained.
+var ErrLockHeld = errors.New("lock is held by another instance")
+
+// Options tune a single Acquire call.
+type Options struct {
+	// Mode is ModeSkip (default) or ModeWait.
+	Mode Mode
+	// WaitTimeout bounds ModeWait (0 = until ctx is cancelled).
+	WaitTimeout time.Duration
+	// RetryInterval is the delay between attempts in ModeWait (default 1s).
+	RetryInterval time.Duration
+}
+
+// Holder describes the instance holding a lock.
+type Holder struct {
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
+	// PID is the Postgres backend process holding the lock.
+	PID int `json:"pid"`
+	// AcquiredAt is only known for locks held by this process.
+	AcquiredAt time.Time `json:"acquiredAt,omitempty"`
+}
+
+// HeldError is returned by Acquire when the lock is held. Holder is nil when the holder
+// released the lock between the attempt and the lookup.
+type HeldError struct {
+	Name   string
+	Holder *Holder
+}
+
+func (e *HeldError) Error() string {
+	if e.Holder == nil {
+		return fmt.Sprintf("lock %q is held by another instance", e.Name)
+	}
+	return fmt.Sprintf("lock %q is held by %s (pid %d)", e.Name, e.Holder.Owner, e.Holder.PID)
+}
+
+func (e *HeldError) Is(target error) bool {
+	return target == ErrLockHeld
+}
+
+// Locker acquires named locks.
+type Locker interface {
+	// Acquire obtains the lock named name. The returned Lock must be released by the caller.
+	// When the lock is held, the error matches ErrLockHeld and is a *HeldError.
+	Acquire(ctx context.Context, name string, opts Options) (*Lock, error)
+}
+
+// Lock is an acquired lock.
+type Lock struct {
+	Holder Holder
+
+	key     int64
+	conn    *sql.Conn
+	release sync.Once
+}
+
+// Release unlocks and returns the connection. It is safe to call more than once.
+func (l *Lock) Release() (err error) {
+	l.release.Do(func() {
+		err = release(l.conn, l.key)
+	})
+	return err
+}
+
+type postgresLocker struct {
+	db    *sql.DB
+	owner string
+}
+
+// NewPostgresLocker creates a Locker on db. owner identifies this process in Holder,
+// and defaults to hostname-pid.
+func NewPostgresLocker(db *sql.DB, owner string) Locker {
+	if owner == "" {
+		owner = DefaultOwner()
+	}
+	return &postgresLocker{
+		db:    db,
+		owner: owner,
+	}
+}
+
+// DefaultOwner identifies the current process as hostname-pid.
+func DefaultOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// Key maps a lock name to its advisory lock key.
+func Key(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+func (p *postgresLocker) Acquire(ctx context.Context, name string, opts Options) (lock *Lock, err error) {
+	if opts.Mode == "" {
+		opts.Mode = ModeSkip
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = time.Second
+	}
+	if opts.Mode == ModeWait && opts.WaitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.WaitTimeout)
+		defer cancel()
+	}
+
+	key := Key(name)
+	for {
+		lock, err = p.tryAcquire(ctx, name, key)
+		if err != nil || lock != nil {
+			return lock, err
+		}
+
+		heldErr := &HeldError{Name: name, Holder: p.holder(ctx, name, key)}
+		if opts.Mode != ModeWait {
+			return nil, heldErr
+		}
+
+		log.Info().Str("lock", name).Err(heldErr).Msg("Waiting for lock")
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", heldErr, ctx.Err())
+		case <-time.After(opts.RetryInterval):
+		}
+	}
+}
+
+// tryAcquire returns nil without error when the lock is held by someone else.
+func (p *postgresLocker) tryAcquire(ctx context.Context, name string, key int64) (lock *Lock, err error) {
+	// Session-level advisory locks belong to a connection, so the lock keeps one out of the pool
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection for lock %q: %w", name, err)
+	}
+
+	var acquired bool
+	var pid int
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1), pg_backend_pid()", key).Scan(&acquired, &pid)
+	if err != nil {
+		// The outcome is unknown, so the session must not go back to the pool
+		discard(conn)
+		return nil, fmt.Errorf("failed to acquire lock %q: %w", name, err)
+	}
+	if !acquired {
+		_ = conn.Close()
+		return nil, nil
+	}
+
+	// The owner is published through the session, so other instances can report who holds the lock
+	if _, err = conn.ExecContext(ctx, "SELECT set_config('application_name', $1, false)", truncate(p.owner, 63)); err != nil {
+		log.Warn().Err(err).Str("lock", name).Msg("failed to publish lock owner")
+	}
+
+	return &Lock{
+		Holder: Holder{Name: name, Owner: p.owner, PID: pid, AcquiredAt: time.Now()},
+		key:    key,
+		conn:   conn,
+	}, nil
+}
+
+// holder looks up the session holding the lock. Failures are logged, not returned: the lock
+// being held is the answer that matters.
+func (p *postgresLocker) holder(ctx context.Context, name string, key int64) *Holder {
+	holder := &Holder{Name: name}
+	err := p.db.QueryRowContext(ctx, `
+		SELECT a.pid, a.application_name
+		FROM pg_locks l
+		JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted
+		  AND l.classid::bigint = $1 AND l.objid::bigint = $2 AND l.objsubid = 1`,
+		int64(uint32(uint64(key)>>32)), int64(uint32(uint64(key)))).
+		Scan(&holder.PID, &holder.Owner)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Warn().Err(err).Str("lock", name).Msg("failed to look up lock holder")
+		}
+		return nil
+	}
+	return holder
+}
+
+func release(conn *sql.Conn, key int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var unlocked bool
+	err := conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", key).Scan(&unlocked)
+	if err == nil && unlocked {
+		_, err = conn.ExecContext(ctx, "RESET application_name")
+	}
+	if err != nil || !unlocked {
+		// Closing the session is the only other way to release a session-level lock
+		discard(conn)
+		if err != nil {
+			return fmt.Errorf("failed to release lock: %w", err)
+		}
+		return nil
+	}
+	return conn.Close()
+}
+
+// discard closes the underlying session instead of returning it to the pool.
+func discard(conn *sql.Conn) {
+	_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
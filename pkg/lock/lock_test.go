@@ -0,0 +1,117 @@
+package lock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/lock"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/testutils"
+)
+
+type PostgresLockerTestSuite struct {
+	suite.Suite
+	pgContainer *testutils.PostgresContainer
+	hostA       lock.Locker
+	hostB       lock.Locker
+}
+
+func (s *PostgresLockerTestSuite) SetupSuite() {
+	if testing.Short() {
+		s.T().Skip("Skipping lock integration test in short mode")
+	}
+	s.pgContainer = testutils.SetupPostgres(s.T())
+	s.hostA = lock.NewPostgresLocker(s.pgContainer.DB, "host-a")
+	s.hostB = lock.NewPostgresLocker(s.pgContainer.DB, "host-b")
+}
+
+func (s *PostgresLockerTestSuite) TearDownSuite() {
+	s.pgContainer.Teardown(s.T())
+}
+
+func (s *PostgresLockerTestSuite) TestSkipReportsHolder() {
+	held, err := s.hostA.Acquire(context.Background(), "job", lock.Options{})
+	require.NoError(s.T(), err)
+	defer func() { _ = held.Release() }()
+	assert.Equal(s.T(), "host-a", held.Holder.Owner)
+
+	_, err = s.hostB.Acquire(context.Background(), "job", lock.Options{Mode: lock.ModeSkip})
+	require.ErrorIs(s.T(), err, lock.ErrLockHeld)
+
+	var heldErr *lock.HeldError
+	require.ErrorAs(s.T(), err, &heldErr)
+	require.NotNil(s.T(), heldErr.Holder)
+	assert.Equal(s.T(), "host-a", heldErr.Holder.Owner)
+	assert.Equal(s.T(), held.Holder.PID, heldErr.Holder.PID)
+
+	// Other names are independent
+	other, err := s.hostB.Acquire(context.Background(), "other-job", lock.Options{})
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), other.Release())
+}
+
+func (s *PostgresLockerTestSuite) TestWaitAcquiresAfterRelease() {
+	held, err := s.hostA.Acquire(context.Background(), "job", lock.Options{})
+	require.NoError(s.T(), err)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = held.Release()
+	}()
+
+	waited, err := s.hostB.Acquire(context.Background(), "job", lock.Options{
+		Mode:          lock.ModeWait,
+		WaitTimeout:   5 * time.Second,
+		RetryInterval: 10 * time.Millisecond,
+	})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "host-b", waited.Holder.Owner)
+	require.NoError(s.T(), waited.Release())
+}
+
+func (s *PostgresLockerTestSuite) TestWaitTimesOut() {
+	held, err := s.hostA.Acquire(context.Background(), "job", lock.Options{})
+	require.NoError(s.T(), err)
+	defer func() { _ = held.Release() }()
+
+	_, err = s.hostB.Acquire(context.Background(), "job", lock.Options{
+		Mode:          lock.ModeWait,
+		WaitTimeout:   50 * time.Millisecond,
+		RetryInterval: 10 * time.Millisecond,
+	})
+	assert.ErrorIs(s.T(), err, lock.ErrLockHeld)
+	assert.ErrorIs(s.T(), err, context.DeadlineExceeded)
+}
+
+func (s *PostgresLockerTestSuite) TestGuardReleasesOnPanic() {
+	runner := runnerFunc(func(context.Context) (interface{}, error) { panic("boom") })
+	guarded := lock.Guard(s.hostA, "job", runner, lock.Options{})
+
+	assert.Panics(s.T(), func() { _, _ = guarded.Execute(context.Background()) })
+
+	again, err := s.hostB.Acquire(context.Background(), "job", lock.Options{})
+	require.NoError(s.T(), err, "lock must be released after a panic")
+	require.NoError(s.T(), again.Release())
+}
+
+func (s *PostgresLockerTestSuite) TestGuardExposesHolderToRun() {
+	runner := runnerFunc(func(ctx context.Context) (interface{}, error) {
+		holder, ok := lock.HolderFromContext(ctx)
+		if !ok {
+			return nil, assert.AnError
+		}
+		return holder, nil
+	})
+
+	result, err := lock.Guard(s.hostA, "job", runner, lock.Options{}).Execute(context.Background())
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "host-a", result.(*lock.Holder).Owner)
+}
+
+func TestPostgresLockerTestSuite(t *testing.T) {
+	suite.Run(t, new(PostgresLockerTestSuite))
+}
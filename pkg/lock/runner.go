@@ -0,0 +1,83 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+type holderKey struct{}
+
+// HolderFromContext returns the lock held for the current run, if any.
+func HolderFromContext(ctx context.Context) (*Holder, bool) {
+	holder, ok := ctx.Value(holderKey{}).(*Holder)
+	return holder, ok
+}
+
+// SkippedResult is the result of a run skipped because another instance holds the job's lock.
+type SkippedResult struct {
+	Job    string  `json:"job"`
+	HeldBy *Holder `json:"heldBy"`
+}
+
+// RunSummary reports the skipped run to the job run history.
+func (r *SkippedResult) RunSummary() scheduler.RunSummary {
+	return scheduler.RunSummary{
+		Skipped: true,
+		Errors:  []string{(&HeldError{Name: r.Job, Holder: r.HeldBy}).Error()},
+	}
+}
+
+type guardedRunner struct {
+	locker Locker
+	name   string
+	runner scheduler.Runner
+	opts   Options
+}
+
+// Guard wraps runner so that a run only starts while holding the lock named name, making the job
+// single-instance across hosts. In ModeSkip a held lock turns the run into a SkippedResult without
+// error; in ModeWait a lock that stays held fails the run. The lock is released when the run
+// returns or panics; the holder is available to the run through HolderFromContext.
+func Guard(locker Locker, name string, runner scheduler.Runner, opts Options) scheduler.Runner {
+	return &guardedRunner{
+		locker: locker,
+		name:   name,
+		runner: runner,
+		opts:   opts,
+	}
+}
+
+// GuardJobs applies Guard to every job, using the job name as the lock name.
+func GuardJobs(locker Locker, opts Options, jobs ...scheduler.Job) []scheduler.Job {
+	guarded := make([]scheduler.Job, len(jobs))
+	for i, job := range jobs {
+		job.Runner = Guard(locker, job.Name, job.Runner, opts)
+		guarded[i] = job
+	}
+	return guarded
+}
+
+func (g *guardedRunner) Execute(ctx context.Context) (result interface{}, err error) {
+	lock, err := g.locker.Acquire(ctx, g.name, g.opts)
+	if err != nil {
+		var heldErr *HeldError
+		if errors.As(err, &heldErr) && g.opts.Mode != ModeWait {
+			log.Info().Str("job", g.name).Err(err).Msg("Job is running elsewhere, skipping")
+			return &SkippedResult{Job: g.name, HeldBy: heldErr.Holder}, nil
+		}
+		return nil, fmt.Errorf("failed to acquire lock for job %q: %w", g.name, err)
+	}
+	defer func() {
+		if errRelease := lock.Release(); errRelease != nil {
+			log.Error().Err(errRelease).Str("job", g.name).Msg("failed to release job lock")
+		}
+	}()
+
+	log.Info().Str("job", g.name).Str("owner", lock.Holder.Owner).Msg("Job lock acquired")
+	return g.runner.Execute(context.WithValue(ctx, holderKey{}, &lock.Holder))
+}
@@ -0,0 +1,84 @@
+package lock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/lock"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+// heldLocker always reports the lock as held by holder.
+type heldLocker struct {
+	holder *lock.Holder
+}
+
+func (l heldLocker) Acquire(_ context.Context, name string, _ lock.Options) (*lock.Lock, error) {
+	return nil, &lock.HeldError{Name: name, Holder: l.holder}
+}
+
+type runnerFunc func(ctx context.Context) (interface{}, error)
+
+func (f runnerFunc) Execute(ctx context.Context) (interface{}, error) {
+	return f(ctx)
+}
+
+func TestGuard_SkipsWhenLockIsHeld(t *testing.T) {
+	holder := &lock.Holder{Name: "payment-updater", Owner: "host-a-42", PID: 1234}
+	ran := false
+	runner := runnerFunc(func(context.Context) (interface{}, error) {
+		ran = true
+		return nil, nil
+	})
+
+	result, err := lock.Guard(heldLocker{holder: holder}, "payment-updater", runner, lock.Options{Mode: lock.ModeSkip}).
+		Execute(context.Background())
+
+	require.NoError(t, err)
+	assert.False(t, ran)
+	skipped, ok := result.(*lock.SkippedResult)
+	require.True(t, ok)
+	assert.Equal(t, holder, skipped.HeldBy)
+
+	summary := skipped.RunSummary()
+	assert.True(t, summary.Skipped)
+	assert.Equal(t, []string{`lock "payment-updater" is held by host-a-42 (pid 1234)`}, summary.Errors)
+}
+
+func TestGuard_FailsWhenWaitingTimesOut(t *testing.T) {
+	runner := runnerFunc(func(context.Context) (interface{}, error) { return nil, nil })
+
+	_, err := lock.Guard(heldLocker{}, "payment-updater", runner, lock.Options{Mode: lock.ModeWait}).
+		Execute(context.Background())
+
+	assert.ErrorIs(t, err, lock.ErrLockHeld)
+}
+
+func TestGuardJobs(t *testing.T) {
+	runner := runnerFunc(func(context.Context) (interface{}, error) { return nil, errors.New("not guarded") })
+
+	jobs := lock.GuardJobs(heldLocker{}, lock.Options{}, scheduler.Job{Name: "job-a", Schedule: "@hourly", Runner: runner})
+
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "@hourly", jobs[0].Schedule)
+	result, err := jobs[0].Runner.Execute(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "job-a", result.(*lock.SkippedResult).Job)
+}
+
+func TestParseMode(t *testing.T) {
+	mode, err := lock.ParseMode("")
+	require.NoError(t, err)
+	assert.Equal(t, lock.ModeSkip, mode)
+
+	mode, err = lock.ParseMode("wait")
+	require.NoError(t, err)
+	assert.Equal(t, lock.ModeWait, mode)
+
+	_, err = lock.ParseMode("block")
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+
+	apperrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/idempotency"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotentReplayed  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	defaultIdempotencyKeysTTL = 24 * time.Hour
+)
+
+// Idempotency makes a route retry-safe using the Idempotency-Key request header.
+//
+// The first request with a key runs the handler and stores its response. Repeats with the same key
+// and the same method, path and body replay the stored response. A repeat with a different request
+// returns 422, and a repeat while the first request is still running returns 409. Keys are scoped to the
+// tenant of the request, so tenants never see each other's responses.
+// Server errors (5xx) and panics are not stored so the client can retry them. Requests without the header
+// are passed through unchanged.
+func Idempotency(store idempotency.Store, ttl time.Duration) echo.MiddlewareFunc {
+	if ttl <= 0 {
+		ttl = defaultIdempotencyKeysTTL
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := strings.TrimSpace(c.Request().Header.Get(HeaderIdempotencyKey))
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return apperrors.NewValidationError(fmt.Errorf("idempotency key must not exceed %d characters", maxIdempotencyKeyLength))
+			}
+			// Clients choose their keys, so every tenant has keys of its own
+			if id, ok := tenant.FromContext(c.Request().Context()); ok {
+				key = id + ":" + key
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return err
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			now := time.Now()
+			record := idempotency.Record{
+				Key:         key,
+				RequestHash: idempotency.HashRequest(c.Request().Method, c.Request().URL.RequestURI(), body),
+				CreatedAt:   now,
+				ExpiresAt:   now.Add(ttl),
+			}
+
+			ctx := c.Request().Context()
+			existing, reserved, err := store.Reserve(ctx, record)
+			if err != nil {
+				return err
+			}
+			if !reserved {
+				return replayIdempotentResponse(c, record.RequestHash, existing)
+			}
+
+			// A panicking handler must not hold the key until it expires; the panic goes on to the Recover middleware.
+			defer func() {
+				if r := recover(); r != nil {
+					if releaseErr := store.Release(context.WithoutCancel(ctx), key); releaseErr != nil {
+						log.Error().Err(releaseErr).Str("idempotency_key", key).Msg("Failed to release idempotency key")
+					}
+					panic(r)
+				}
+			}()
+
+			recorder := &responseBodyRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+			if err = next(c); err != nil {
+				c.Error(err)
+			}
+
+			// The response is already written; persisting it must not depend on the request context.
+			storeCtx := context.WithoutCancel(ctx)
+			status := c.Response().Status
+			if !c.Response().Committed || status >= http.StatusInternalServerError {
+				if releaseErr := store.Release(storeCtx, key); releaseErr != nil {
+					log.Error().Err(releaseErr).Str("idempotency_key", key).Msg("Failed to release idempotency key")
+				}
+				return nil
+			}
+
+			completedAt := time.Now()
+			record.StatusCode = status
+			record.ContentType = c.Response().Header().Get(echo.HeaderContentType)
+			record.ResponseBody = recorder.body.Bytes()
+			record.CompletedAt = &completedAt
+			if completeErr := store.Complete(storeCtx, record); completeErr != nil {
+				log.Error().Err(completeErr).Str("idempotency_key", key).Msg("Failed to store idempotent response")
+			}
+			return nil
+		}
+	}
+}
+
+func replayIdempotentResponse(c echo.Context, requestHash string, existing idempotency.Record) error {
+	if existing.RequestHash != requestHash {
+		return apperrors.ErrIdempotencyKeyMismatch
+	}
+	if !existing.IsCompleted() {
+		return apperrors.ErrRequestInProgress
+	}
+
+	c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+	return c.Blob(existing.StatusCode, existing.ContentType, existing.ResponseBody)
+}
+
+// responseBodyRecorder copies the response body while it is written to the client.
+type responseBodyRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseBodyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,217 @@
+package middlewares_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+
+	apperrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/idempotency"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/middlewares"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]idempotency.Record
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: map[string]idempotency.Record{}}
+}
+
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, record idempotency.Record) (idempotency.Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[record.Key]; ok && existing.ExpiresAt.After(record.CreatedAt) {
+		return existing, false, nil
+	}
+	s.records[record.Key] = record
+	return idempotency.Record{}, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(_ context.Context, record idempotency.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.Key] = record
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func (s *memoryIdempotencyStore) PurgeExpired(_ context.Context, _ time.Time) (int64, error) {
+	return 0, nil
+}
+
+func newIdempotentEcho(store idempotency.Store, ttl time.Duration, status *int, calls *int) *echo.Echo {
+	e := echo.New()
+	e.HTTPErrorHandler = middlewares.ErrorHandler
+	e.POST("/payments", func(c echo.Context) error {
+		*calls++
+		if *status >= http.StatusBadRequest {
+			return echo.NewHTTPError(*status, "failed")
+		}
+		return c.JSON(*status, map[string]int{"call": *calls})
+	}, middlewares.Idempotency(store, ttl))
+	return e
+}
+
+func doIdempotentRequest(e *echo.Echo, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(middlewares.HeaderIdempotencyKey, key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotency_ReplaysResponseForSameKey(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	e := newIdempotentEcho(newMemoryIdempotencyStore(), time.Hour, &status, &calls)
+
+	first := doIdempotentRequest(e, "key-1", `{"amount":"10.00"}`)
+	second := doIdempotentRequest(e, "key-1", `{"amount":"10.00"}`)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.JSONEq(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(middlewares.HeaderIdempotentReplayed))
+	assert.Empty(t, first.Header().Get(middlewares.HeaderIdempotentReplayed))
+}
+
+func TestIdempotency_WithoutKeyPassesThrough(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	e := newIdempotentEcho(newMemoryIdempotencyStore(), time.Hour, &status, &calls)
+
+	doIdempotentRequest(e, "", `{"amount":"10.00"}`)
+	doIdempotentRequest(e, "", `{"amount":"10.00"}`)
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_MismatchedBodyIsRejected(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	e := newIdempotentEcho(newMemoryIdempotencyStore(), time.Hour, &status, &calls)
+
+	doIdempotentRequest(e, "key-1", `{"amount":"10.00"}`)
+	rec := doIdempotentRequest(e, "key-1", `{"amount":"20.00"}`)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), apperrors.ErrorCodeIdempotencyMismatch)
+}
+
+func TestIdempotency_InFlightRequestIsRejected(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	status, calls := http.StatusCreated, 0
+	e := newIdempotentEcho(store, time.Hour, &status, &calls)
+
+	body := `{"amount":"10.00"}`
+	now := time.Now()
+	_, _, _ = store.Reserve(context.Background(), idempotency.Record{
+		Key:         "key-1",
+		RequestHash: idempotency.HashRequest(http.MethodPost, "/payments", []byte(body)),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Hour),
+	})
+
+	rec := doIdempotentRequest(e, "key-1", body)
+
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestIdempotency_ServerErrorsAreNotStored(t *testing.T) {
+	status, calls := http.StatusServiceUnavailable, 0
+	e := newIdempotentEcho(newMemoryIdempotencyStore(), time.Hour, &status, &calls)
+
+	doIdempotentRequest(e, "key-1", `{"amount":"10.00"}`)
+	status = http.StatusCreated
+	rec := doIdempotentRequest(e, "key-1", `{"amount":"10.00"}`)
+
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestIdempotency_PanicReleasesKey(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	calls := 0
+	e := echo.New()
+	e.HTTPErrorHandler = middlewares.ErrorHandler
+	e.Use(middleware.Recover())
+	e.POST("/payments", func(c echo.Context) error {
+		calls++
+		if calls == 1 {
+			panic("handler failed")
+		}
+		return c.JSON(http.StatusCreated, map[string]int{"call": calls})
+	}, middlewares.Idempotency(store, time.Hour))
+
+	first := doIdempotentRequest(e, "key-1", `{"amount":"10.00"}`)
+	second := doIdempotentRequest(e, "key-1", `{"amount":"10.00"}`)
+
+	assert.Equal(t, http.StatusInternalServerError, first.Code)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_ClientErrorsAreReplayed(t *testing.T) {
+	status, calls := http.StatusBadRequest, 0
+	e := newIdempotentEcho(newMemoryIdempotencyStore(), time.Hour, &status, &calls)
+
+	doIdempotentRequest(e, "key-1", `{"amount":"10.00"}`)
+	rec := doIdempotentRequest(e, "key-1", `{"amount":"10.00"}`)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestIdempotency_ExpiredKeyRunsAgain(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	e := newIdempotentEcho(newMemoryIdempotencyStore(), time.Nanosecond, &status, &calls)
+
+	doIdempotentRequest(e, "key-1", `{"amount":"10.00"}`)
+	time.Sleep(time.Millisecond)
+	doIdempotentRequest(e, "key-1", `{"amount":"10.00"}`)
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_KeysAreScopedToTheTenant(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	e := newIdempotentEcho(newMemoryIdempotencyStore(), time.Hour, &status, &calls)
+	e.Pre(middlewares.Tenant(nil, ""))
+
+	request := func(tenantID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(`{"amount":"10.00"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(middlewares.HeaderIdempotencyKey, "key-1")
+		req.Header.Set(tenant.HeaderTenantID, tenantID)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	request("merchant_a")
+	replayed := request("merchant_a")
+	other := request("merchant_b")
+
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "true", replayed.Header().Get(middlewares.HeaderIdempotentReplayed))
+	assert.Empty(t, other.Header().Get(middlewares.HeaderIdempotentReplayed))
+}
@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	apperrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+// Tenant resolves the tenant of every request into its context.
+//
+// apiKeys maps each API key to the tenant it authenticates. When there are keys, the tenant is the one of
+// the bearer key in the Authorization header: an unknown key is rejected with 401, and an X-Tenant-ID header
+// naming another tenant with 403. Without keys the X-Tenant-ID header is trusted, which is only safe behind
+// a gateway that authenticates the merchant and sets the header itself.
+//
+// adminAPIKey is the only way to act for tenant.Platform, e.g. to manage the default payment settings every
+// tenant falls back to. A request with the admin key acts for the platform, or for the tenant its
+// X-Tenant-ID header names. An empty adminAPIKey disables platform access over HTTP.
+//
+// A request without a key or header goes through without a tenant: platform routes do not need one, and
+// the repositories of tenant data reject it.
+func Tenant(apiKeys map[string]string, adminAPIKey string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := strings.TrimSpace(c.Request().Header.Get(tenant.HeaderTenantID))
+			id := header
+			key, hasKey := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+			key = strings.TrimSpace(key)
+
+			switch {
+			case hasKey && adminAPIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(adminAPIKey)) == 1:
+				if header == "" {
+					c.SetRequest(c.Request().WithContext(tenant.WithID(c.Request().Context(), tenant.Platform)))
+					return next(c)
+				}
+			case len(apiKeys) > 0:
+				if !hasKey {
+					if header != "" {
+						return apperrors.NewUnauthorizedError(fmt.Errorf("an API key is required to act for a tenant"))
+					}
+					return next(c)
+				}
+				var known bool
+				if id, known = apiKeys[key]; !known {
+					return apperrors.NewUnauthorizedError(fmt.Errorf("unknown API key"))
+				}
+				if header != "" && header != id {
+					return apperrors.NewForbiddenError(fmt.Errorf("the API key does not act for tenant %s", header))
+				}
+			}
+
+			if id == "" {
+				return next(c)
+			}
+			if err := tenant.Validate(id); err != nil {
+				return err
+			}
+			c.SetRequest(c.Request().WithContext(tenant.WithID(c.Request().Context(), id)))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/middlewares"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+func TestTenant(t *testing.T) {
+	apiKeys := map[string]string{"key_a": "merchant_a", "key_b": "merchant_b"}
+
+	const adminKey = "key_admin"
+
+	tests := []struct {
+		name           string
+		apiKeys        map[string]string
+		adminAPIKey    string
+		headers        map[string]string
+		expectedStatus int
+		expectedTenant string
+		// expectedPlatform means the request acts for tenant.Platform
+		expectedPlatform bool
+	}{
+		{name: "trusted header", headers: map[string]string{tenant.HeaderTenantID: "merchant_a"}, expectedStatus: http.StatusOK, expectedTenant: "merchant_a"},
+		{name: "invalid header", headers: map[string]string{tenant.HeaderTenantID: "merchant a"}, expectedStatus: http.StatusBadRequest},
+		{name: "no tenant", expectedStatus: http.StatusOK},
+		{name: "API key", apiKeys: apiKeys, headers: map[string]string{echo.HeaderAuthorization: "Bearer key_b"}, expectedStatus: http.StatusOK, expectedTenant: "merchant_b"},
+		{
+			name:           "API key and header of its tenant",
+			apiKeys:        apiKeys,
+			headers:        map[string]string{echo.HeaderAuthorization: "Bearer key_a", tenant.HeaderTenantID: "merchant_a"},
+			expectedStatus: http.StatusOK,
+			expectedTenant: "merchant_a",
+		},
+		{
+			name:           "API key and header of another tenant",
+			apiKeys:        apiKeys,
+			headers:        map[string]string{echo.HeaderAuthorization: "Bearer key_a", tenant.HeaderTenantID: "merchant_b"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{name: "unknown API key", apiKeys: apiKeys, headers: map[string]string{echo.HeaderAuthorization: "Bearer key_c"}, expectedStatus: http.StatusUnauthorized},
+		{name: "header without API key", apiKeys: apiKeys, headers: map[string]string{tenant.HeaderTenantID: "merchant_a"}, expectedStatus: http.StatusUnauthorized},
+		{name: "no API key", apiKeys: apiKeys, expectedStatus: http.StatusOK},
+		{
+			name:             "admin key",
+			apiKeys:          apiKeys,
+			adminAPIKey:      adminKey,
+			headers:          map[string]string{echo.HeaderAuthorization: "Bearer " + adminKey},
+			expectedStatus:   http.StatusOK,
+			expectedPlatform: true,
+		},
+		{
+			name:           "admin key acting for a tenant",
+			apiKeys:        apiKeys,
+			adminAPIKey:    adminKey,
+			headers:        map[string]string{echo.HeaderAuthorization: "Bearer " + adminKey, tenant.HeaderTenantID: "merchant_b"},
+			expectedStatus: http.StatusOK,
+			expectedTenant: "merchant_b",
+		},
+		{
+			name:             "admin key with trusted headers",
+			adminAPIKey:      adminKey,
+			headers:          map[string]string{echo.HeaderAuthorization: "Bearer " + adminKey},
+			expectedStatus:   http.StatusOK,
+			expectedPlatform: true,
+		},
+		{
+			name:           "admin key disabled",
+			apiKeys:        apiKeys,
+			headers:        map[string]string{echo.HeaderAuthorization: "Bearer " + adminKey},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{name: "wrong admin key", adminAPIKey: adminKey, headers: map[string]string{echo.HeaderAuthorization: "Bearer key_a"}, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = middlewares.ErrorHandler
+			var resolved string
+			var hasTenant bool
+			e.GET("/resource", func(c echo.Context) error {
+				resolved, hasTenant = tenant.FromContext(c.Request().Context())
+				return c.NoContent(http.StatusOK)
+			}, middlewares.Tenant(tt.apiKeys, tt.adminAPIKey))
+
+			req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+			assert.Equal(t, tt.expectedTenant, resolved)
+			assert.Equal(t, tt.expectedTenant != "" || tt.expectedPlatform, hasTenant)
+		})
+	}
+}
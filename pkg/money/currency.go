@@ -0,0 +1,38 @@
+package money
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency with its minor unit exponent,
+// e.g. USD has 2 decimals (cents) and JPY has none.
+type Currency struct {
+	Code     string
+	Exponent int
+}
+
+// currencies lists the supported ISO 4217 currencies and their exponents.
+var currencies = map[string]int{
+	"AED": 2, "AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CLP": 0, "CNY": 2,
+	"CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2,
+	"INR": 2, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3, "MXN": 2, "MYR": 2,
+	"NOK": 2, "NZD": 2, "OMR": 3, "PHP": 2, "PLN": 2, "SAR": 2, "SEK": 2, "SGD": 2,
+	"THB": 2, "TND": 3, "TRY": 2, "TWD": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// LookupCurrency returns the currency for an ISO 4217 code.
+func LookupCurrency(code string) (currency Currency, err error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	exponent, ok := currencies[code]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, code)
+	}
+	return Currency{Code: code, Exponent: exponent}, nil
+}
+
+// IsSupportedCurrency reports whether code is a supported ISO 4217 currency.
+func IsSupportedCurrency(code string) bool {
+	_, err := LookupCurrency(code)
+	return err == nil
+}
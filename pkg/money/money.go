@@ -0,0 +1,194 @@
+// Package money provides an exact monetary value type.
+//
+// Amounts are stored as an integer number of minor units (cents for USD, yen for JPY)
+// together with their ISO 4217 currency, so values never go through binary floating point.
+// Amounts are exchanged as decimal strings ("299.99") at every boundary (database, JSON).
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount       = errors.New("invalid amount")
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrCurrencyMismatch    = errors.New("currency mismatch")
+)
+
+// Money is an amount in minor units of a currency. The zero value has no currency.
+type Money struct {
+	minorUnits int64
+	currency   Currency
+}
+
+// New creates a Money from an amount expressed in minor units.
+func New(minorUnits int64, currencyCode string) (m Money, err error) {
+	currency, err := LookupCurrency(currencyCode)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{minorUnits: minorUnits, currency: currency}, nil
+}
+
+// Parse creates a Money from a decimal string such as "299.99".
+// Trailing zeros beyond the currency exponent are accepted ("8200.0000" JPY),
+// any other extra precision is rejected rather than rounded.
+func Parse(amount, currencyCode string) (m Money, err error) {
+	currency, err := LookupCurrency(currencyCode)
+	if err != nil {
+		return Money{}, err
+	}
+
+	value := strings.TrimSpace(amount)
+	negative := false
+	if strings.HasPrefix(value, "-") {
+		negative = true
+		value = value[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(value, ".")
+	if !isDigits(intPart) || (fracPart != "" && !isDigits(fracPart)) || strings.HasSuffix(value, ".") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+
+	if len(fracPart) > currency.Exponent {
+		if strings.Trim(fracPart[currency.Exponent:], "0") != "" {
+			return Money{}, fmt.Errorf("%w: %q has more than %d decimal places for %s",
+				ErrInvalidAmount, amount, currency.Exponent, currency.Code)
+		}
+		fracPart = fracPart[:currency.Exponent]
+	}
+	fracPart += strings.Repeat("0", currency.Exponent-len(fracPart))
+
+	minorUnits, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, amount)
+	}
+	if negative {
+		minorUnits = -minorUnits
+	}
+
+	return Money{minorUnits: minorUnits, currency: currency}, nil
+}
+
+// MustParse is like Parse but panics on error. Intended for constants and tests.
+func MustParse(amount, currencyCode string) Money {
+	m, err := Parse(amount, currencyCode)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MinorUnits returns the amount in minor units of the currency.
+func (m Money) MinorUnits() int64 {
+	return m.minorUnits
+}
+
+// Currency returns the ISO 4217 currency code.
+func (m Money) Currency() string {
+	return m.currency.Code
+}
+
+// Exponent returns the number of decimal places of the currency.
+func (m Money) Exponent() int {
+	return m.currency.Exponent
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.minorUnits == 0
+}
+
+// IsNegative reports whether the amount is below zero.
+func (m Money) IsNegative() bool {
+	return m.minorUnits < 0
+}
+
+// String formats the amount as a decimal string with the currency exponent, e.g. "299.99".
+func (m Money) String() string {
+	units := m.minorUnits
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	digits := strconv.FormatInt(units, 10)
+	exponent := m.currency.Exponent
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// Format returns the amount followed by its currency code, e.g. "299.99 USD".
+func (m Money) Format() string {
+	return m.String() + " " + m.currency.Code
+}
+
+// Cmp compares two amounts of the same currency and returns -1, 0 or +1.
+func (m Money) Cmp(other Money) (result int, err error) {
+	if m.currency.Code != other.currency.Code {
+		return 0, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency.Code, other.currency.Code)
+	}
+	switch {
+	case m.minorUnits < other.minorUnits:
+		return -1, nil
+	case m.minorUnits > other.minorUnits:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Add returns the sum of two amounts of the same currency.
+func (m Money) Add(other Money) (result Money, err error) {
+	if m.currency.Code != other.currency.Code {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency.Code, other.currency.Code)
+	}
+	return Money{minorUnits: m.minorUnits + other.minorUnits, currency: m.currency}, nil
+}
+
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as a string so clients never lose precision.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.String(), Currency: m.currency.Code})
+}
+
+// UnmarshalJSON decodes the representation produced by MarshalJSON.
+func (m *Money) UnmarshalJSON(data []byte) (err error) {
+	var raw moneyJSON
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	parsed, err := Parse(raw.Amount, raw.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
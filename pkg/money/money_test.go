@@ -0,0 +1,94 @@
+package money_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/money"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name          string
+		amount        string
+		currency      string
+		expectedMinor int64
+		expectedStr   string
+		expectedErr   error
+	}{
+		{name: "two decimals", amount: "299.99", currency: "USD", expectedMinor: 29999, expectedStr: "299.99"},
+		{name: "database scale", amount: "299.9900", currency: "USD", expectedMinor: 29999, expectedStr: "299.99"},
+		{name: "integer amount", amount: "100", currency: "EUR", expectedMinor: 10000, expectedStr: "100.00"},
+		{name: "one decimal", amount: "0.5", currency: "GBP", expectedMinor: 50, expectedStr: "0.50"},
+		{name: "zero exponent", amount: "11500", currency: "JPY", expectedMinor: 11500, expectedStr: "11500"},
+		{name: "zero exponent with zero fraction", amount: "11500.0000", currency: "JPY", expectedMinor: 11500, expectedStr: "11500"},
+		{name: "three decimals", amount: "1.234", currency: "BHD", expectedMinor: 1234, expectedStr: "1.234"},
+		{name: "negative amount", amount: "-5.05", currency: "USD", expectedMinor: -505, expectedStr: "-5.05"},
+		{name: "lowercase currency", amount: "1.00", currency: "usd", expectedMinor: 100, expectedStr: "1.00"},
+		{name: "excess precision", amount: "299.999", currency: "USD", expectedErr: money.ErrInvalidAmount},
+		{name: "fraction for zero exponent", amount: "8200.75", currency: "JPY", expectedErr: money.ErrInvalidAmount},
+		{name: "not a number", amount: "1O.00", currency: "USD", expectedErr: money.ErrInvalidAmount},
+		{name: "empty amount", amount: "", currency: "USD", expectedErr: money.ErrInvalidAmount},
+		{name: "trailing dot", amount: "10.", currency: "USD", expectedErr: money.ErrInvalidAmount},
+		{name: "exponent notation", amount: "1e3", currency: "USD", expectedErr: money.ErrInvalidAmount},
+		{name: "overflow", amount: "999999999999999999999", currency: "USD", expectedErr: money.ErrInvalidAmount},
+		{name: "unsupported currency", amount: "10.00", currency: "XXX", expectedErr: money.ErrUnsupportedCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := money.Parse(tt.amount, tt.currency)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedMinor, m.MinorUnits())
+			assert.Equal(t, tt.expectedStr, m.String())
+		})
+	}
+}
+
+func TestMoney_Cmp(t *testing.T) {
+	tests := []struct {
+		name        string
+		a           money.Money
+		b           money.Money
+		expected    int
+		expectedErr error
+	}{
+		{name: "less", a: money.MustParse("9.99", "USD"), b: money.MustParse("10.00", "USD"), expected: -1},
+		{name: "equal", a: money.MustParse("10", "USD"), b: money.MustParse("10.00", "USD"), expected: 0},
+		{name: "greater", a: money.MustParse("10000.01", "USD"), b: money.MustParse("10000", "USD"), expected: 1},
+		{name: "currency mismatch", a: money.MustParse("10", "USD"), b: money.MustParse("10", "EUR"), expectedErr: money.ErrCurrencyMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.a.Cmp(tt.b)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	m := money.MustParse("299.99", "USD")
+
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"299.99","currency":"USD"}`, string(data))
+
+	var decoded money.Money
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, m, decoded)
+}
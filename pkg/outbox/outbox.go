@@ -0,0 +1,69 @@
+// Package outbox implements the transactional outbox shared by the modules.
+//
+// A module appends its domain events to an outbox table in its own schema, in the same transaction
+// as the change they describe, so an event is recorded if and only if the change commits. A Relay
+// later reads the unpublished events in position order, hands them to a Publisher and marks them
+// published. Delivery is at-least-once: a relay that stops between publishing and marking publishes
+// the same events again on its next run, so consumers deduplicate by Event.ID.
+//
+// Positions grow with every appended event and are never reused. Replay publishes every event from
+// a given position again, published or not, e.g. to rebuild a consumer after data loss.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Event is a domain event recorded in an outbox. Position orders the events of one outbox;
+// ID identifies the event across outboxes and is stable across redeliveries and replays.
+type Event struct {
+	Position    int64           `json:"position"`
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID string          `json:"aggregateId"`
+	Payload     json.RawMessage `json:"payload"`
+	OccurredAt  time.Time       `json:"occurredAt"`
+	PublishedAt *time.Time      `json:"publishedAt,omitempty"`
+}
+
+// Store is the outbound port for outbox persistence.
+type Store interface {
+	// Append records events in the transaction carried by ctx, assigning their ID when empty.
+	Append(ctx context.Context, events ...Event) error
+	// FetchUnpublished returns up to limit unpublished events, lowest position first.
+	FetchUnpublished(ctx context.Context, limit int) (events []Event, err error)
+	// FetchFrom returns up to limit events from position on, published or not, lowest position first.
+	FetchFrom(ctx context.Context, position int64, limit int) (events []Event, err error)
+	// MarkPublished records that the events at the given positions were published.
+	MarkPublished(ctx context.Context, positions []int64, at time.Time) error
+}
+
+// Publisher hands events over to their consumers. An error stops the relay before the event,
+// which is then published again on the next run.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// PublisherFunc adapts a function to a Publisher.
+type PublisherFunc func(ctx context.Context, event Event) error
+
+func (f PublisherFunc) Publish(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// LogPublisher only logs the events. It is the default publisher until a module has consumers.
+type LogPublisher struct{}
+
+func (LogPublisher) Publish(ctx context.Context, event Event) error {
+	log.Info().
+		Int64("position", event.Position).
+		Str("event_id", event.ID).
+		Str("event_type", event.Type).
+		Str("aggregate_id", event.AggregateID).
+		Msg("Outbox event published")
+	return nil
+}
@@ -0,0 +1,134 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/rs/zerolog/log"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/dbutils"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/transaction"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/uniqueid"
+)
+
+// PostgresStore persists events in a module-owned table, e.g. payment_module.outbox.
+type PostgresStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewPostgresStore creates a store backed by the given schema-qualified table.
+func NewPostgresStore(db *sql.DB, table string) *PostgresStore {
+	return &PostgresStore{
+		db:    db,
+		table: table,
+	}
+}
+
+func (s *PostgresStore) qb() sq.StatementBuilderType {
+	return sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+}
+
+// conn joins the transaction carried by ctx, so events commit together with the change they describe.
+func (s *PostgresStore) conn(ctx context.Context) transaction.DBTX {
+	return transaction.Executor(ctx, s.db)
+}
+
+var columns = []string{"position", "event_id", "event_type", "aggregate_id", "payload", "occurred_at", "published_at"}
+
+func (s *PostgresStore) Append(ctx context.Context, events ...Event) (err error) {
+	if len(events) == 0 {
+		return nil
+	}
+
+	query := s.qb().Insert(s.table).Columns("event_id", "event_type", "aggregate_id", "payload", "occurred_at")
+	for _, event := range events {
+		if event.ID == "" {
+			if event.ID, err = uniqueid.GeneratePK("evt"); err != nil {
+				return err
+			}
+		}
+		if event.OccurredAt.IsZero() {
+			event.OccurredAt = time.Now()
+		}
+		// JSONB takes the payload as text; lib/pq would send []byte as bytea
+		query = query.Values(event.ID, event.Type, event.AggregateID, string(event.Payload), event.OccurredAt)
+	}
+
+	if _, err = query.RunWith(s.conn(ctx)).ExecContext(ctx); err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) FetchUnpublished(ctx context.Context, limit int) (events []Event, err error) {
+	return s.fetch(ctx, s.qb().Select(columns...).
+		From(s.table).
+		Where(sq.Eq{"published_at": nil}).
+		OrderBy("position ASC").
+		Limit(uint64(limit)))
+}
+
+func (s *PostgresStore) FetchFrom(ctx context.Context, position int64, limit int) (events []Event, err error) {
+	return s.fetch(ctx, s.qb().Select(columns...).
+		From(s.table).
+		Where(sq.GtOrEq{"position": position}).
+		OrderBy("position ASC").
+		Limit(uint64(limit)))
+}
+
+// MarkPublished keeps the first publication time of replayed events.
+func (s *PostgresStore) MarkPublished(ctx context.Context, positions []int64, at time.Time) (err error) {
+	if len(positions) == 0 {
+		return nil
+	}
+
+	_, err = s.qb().Update(s.table).
+		Set("published_at", at).
+		Where(sq.Eq{"position": positions}).
+		Where(sq.Eq{"published_at": nil}).
+		RunWith(s.conn(ctx)).
+		ExecContext(ctx)
+	if err != nil {
+		return dbutils.HandlePostgresError(err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) fetch(ctx context.Context, query sq.SelectBuilder) (events []Event, err error) {
+	rows, err := query.RunWith(s.conn(ctx)).QueryContext(ctx)
+	if err != nil {
+		return nil, dbutils.HandlePostgresError(err)
+	}
+	defer func() {
+		errClose := rows.Close()
+		if errClose != nil {
+			log.Error().Err(errClose).Msg("failed to close rows")
+		}
+	}()
+
+	events = make([]Event, 0)
+	for rows.Next() {
+		var (
+			event       Event
+			payload     []byte
+			publishedAt sql.NullTime
+		)
+		err = rows.Scan(&event.Position, &event.ID, &event.Type, &event.AggregateID, &payload, &event.OccurredAt, &publishedAt)
+		if err != nil {
+			return nil, dbutils.HandlePostgresError(err)
+		}
+		event.Payload = payload
+		if publishedAt.Valid {
+			event.PublishedAt = &publishedAt.Time
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, dbutils.HandlePostgresError(err)
+	}
+	return events, nil
+}
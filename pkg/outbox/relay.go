@@ -0,0 +1,157 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+// RelayConfig tunes a Relay.
+type RelayConfig struct {
+	// Name identifies the outbox in logs, e.g. the relay job name.
+	Name string
+	// BatchSize is the number of events read at a time (default 100).
+	BatchSize int
+}
+
+// Relay publishes the events of one outbox in position order. Only one relay may run per outbox
+// at a time, or events may be published out of order; the scheduler runs it under the job lock.
+type Relay struct {
+	store     Store
+	publisher Publisher
+	config    RelayConfig
+}
+
+// NewRelay creates a relay publishing the events of store to publisher.
+func NewRelay(store Store, publisher Publisher, config RelayConfig) *Relay {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		config:    config,
+	}
+}
+
+// RelayResult contains the results of a relay or replay run.
+type RelayResult struct {
+	StartTime time.Time
+	EndTime   time.Time
+	Duration  time.Duration
+	// ReplayFrom is the position a replay started from; nil for a relay run.
+	ReplayFrom     *int64
+	PublishedCount int
+	// LastPosition is the position of the last event published in this run.
+	LastPosition int64
+	Errors       []error
+}
+
+// RunSummary reports the outcome of the run to the job run history.
+func (r *RelayResult) RunSummary() scheduler.RunSummary {
+	errs := make([]string, len(r.Errors))
+	for i, err := range r.Errors {
+		errs[i] = err.Error()
+	}
+	return scheduler.RunSummary{
+		ProcessedCount: r.PublishedCount + len(r.Errors),
+		SuccessCount:   r.PublishedCount,
+		ErrorCount:     len(r.Errors),
+		Errors:         errs,
+	}
+}
+
+// Execute publishes unpublished events until none are left. Publishing stops at the first event
+// the publisher rejects, so no later event overtakes it; it is retried on the next run.
+func (r *Relay) Execute(ctx context.Context) (resultData interface{}, err error) {
+	result := &RelayResult{StartTime: time.Now()}
+	err = r.run(ctx, result, func(ctx context.Context, _ int64) ([]Event, error) {
+		return r.store.FetchUnpublished(ctx, r.config.BatchSize)
+	})
+	return result, err
+}
+
+// Replay publishes every event from position on again, published or not, then stops at the end of
+// the outbox. Events that were never published are marked published along the way.
+func (r *Relay) Replay(ctx context.Context, position int64) (result *RelayResult, err error) {
+	result = &RelayResult{StartTime: time.Now(), ReplayFrom: &position}
+	err = r.run(ctx, result, func(ctx context.Context, next int64) ([]Event, error) {
+		if next < position {
+			next = position
+		}
+		return r.store.FetchFrom(ctx, next, r.config.BatchSize)
+	})
+	return result, err
+}
+
+// run publishes the batches returned by fetch, which receives the position after the last published event.
+func (r *Relay) run(ctx context.Context, result *RelayResult, fetch func(ctx context.Context, next int64) ([]Event, error)) (err error) {
+	defer r.finish(result)
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return fmt.Errorf("outbox relay interrupted: %w", err)
+		}
+
+		events, err := fetch(ctx, result.LastPosition+1)
+		if err != nil {
+			return fmt.Errorf("failed to fetch outbox events: %w", err)
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		published := make([]int64, 0, len(events))
+		var publishErr error
+		for _, event := range events {
+			if publishErr = r.publisher.Publish(ctx, event); publishErr != nil {
+				publishErr = fmt.Errorf("event %s at position %d: %w", event.ID, event.Position, publishErr)
+				break
+			}
+			published = append(published, event.Position)
+			result.LastPosition = event.Position
+		}
+
+		// Marking survives cancellation, so published events are not published again needlessly
+		if err = r.store.MarkPublished(context.WithoutCancel(ctx), published, time.Now()); err != nil {
+			return fmt.Errorf("failed to mark outbox events published: %w", err)
+		}
+		result.PublishedCount += len(published)
+
+		if publishErr != nil {
+			result.Errors = append(result.Errors, publishErr)
+			return fmt.Errorf("failed to publish outbox event: %w", publishErr)
+		}
+		if len(events) < r.config.BatchSize {
+			return nil
+		}
+	}
+}
+
+// finish stamps the end of the run and logs the summary
+func (r *Relay) finish(result *RelayResult) {
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+
+	if result.PublishedCount == 0 && len(result.Errors) == 0 {
+		return
+	}
+	event := log.Info()
+	if len(result.Errors) > 0 {
+		event = log.Warn()
+	}
+	if result.ReplayFrom != nil {
+		event = event.Int64("replay_from", *result.ReplayFrom)
+	}
+	event.
+		Str("outbox", r.config.Name).
+		Dur("duration", result.Duration).
+		Int("published", result.PublishedCount).
+		Int64("last_position", result.LastPosition).
+		Int("errors", len(result.Errors)).
+		Msg("Outbox relay run completed")
+}
@@ -0,0 +1,156 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/outbox"
+)
+
+// memoryStore is an in-memory outbox.
+type memoryStore struct {
+	mu     sync.Mutex
+	events []outbox.Event
+}
+
+func (s *memoryStore) Append(_ context.Context, events ...outbox.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, event := range events {
+		event.Position = int64(len(s.events) + 1)
+		if event.ID == "" {
+			event.ID = fmt.Sprintf("evt_%d", event.Position)
+		}
+		s.events = append(s.events, event)
+	}
+	return nil
+}
+
+func (s *memoryStore) FetchUnpublished(_ context.Context, limit int) ([]outbox.Event, error) {
+	return s.filter(limit, func(e outbox.Event) bool { return e.PublishedAt == nil }), nil
+}
+
+func (s *memoryStore) FetchFrom(_ context.Context, position int64, limit int) ([]outbox.Event, error) {
+	return s.filter(limit, func(e outbox.Event) bool { return e.Position >= position }), nil
+}
+
+func (s *memoryStore) MarkPublished(_ context.Context, positions []int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, position := range positions {
+		if s.events[position-1].PublishedAt == nil {
+			s.events[position-1].PublishedAt = &at
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) filter(limit int, keep func(outbox.Event) bool) []outbox.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]outbox.Event, 0)
+	for _, event := range s.events {
+		if keep(event) && len(result) < limit {
+			result = append(result, event)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Position < result[j].Position })
+	return result
+}
+
+// recordingPublisher records published positions and fails the events in failAt.
+type recordingPublisher struct {
+	published []int64
+	failAt    map[int64]bool
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event outbox.Event) error {
+	if p.failAt[event.Position] {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, event.Position)
+	return nil
+}
+
+func appendEvents(t *testing.T, store *memoryStore, n int) {
+	for i := 0; i < n; i++ {
+		require.NoError(t, store.Append(context.Background(), outbox.Event{Type: "payment.created", AggregateID: fmt.Sprintf("pay_%d", i)}))
+	}
+}
+
+func TestRelay_Execute_PublishesInOrder(t *testing.T) {
+	store := &memoryStore{}
+	appendEvents(t, store, 5)
+	publisher := &recordingPublisher{}
+
+	relay := outbox.NewRelay(store, publisher, outbox.RelayConfig{BatchSize: 2})
+	resultData, err := relay.Execute(context.Background())
+
+	require.NoError(t, err)
+	result := resultData.(*outbox.RelayResult)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, publisher.published)
+	assert.Equal(t, 5, result.PublishedCount)
+	assert.Equal(t, int64(5), result.LastPosition)
+
+	// Published events are not published again
+	_, err = relay.Execute(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, publisher.published, 5)
+}
+
+func TestRelay_Execute_StopsAtFailedEvent(t *testing.T) {
+	store := &memoryStore{}
+	appendEvents(t, store, 4)
+	publisher := &recordingPublisher{failAt: map[int64]bool{3: true}}
+
+	relay := outbox.NewRelay(store, publisher, outbox.RelayConfig{})
+	resultData, err := relay.Execute(context.Background())
+
+	assert.ErrorContains(t, err, "broker unavailable")
+	assert.Equal(t, []int64{1, 2}, publisher.published, "no event may overtake the failed one")
+	assert.Equal(t, 1, resultData.(*outbox.RelayResult).RunSummary().ErrorCount)
+
+	// The failed event and the ones after it are published on the next run
+	publisher.failAt = nil
+	_, err = relay.Execute(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3, 4}, publisher.published)
+}
+
+func TestRelay_Replay(t *testing.T) {
+	store := &memoryStore{}
+	appendEvents(t, store, 5)
+	publisher := &recordingPublisher{}
+	relay := outbox.NewRelay(store, publisher, outbox.RelayConfig{BatchSize: 2})
+
+	_, err := relay.Execute(context.Background())
+	require.NoError(t, err)
+	publisher.published = nil
+
+	result, err := relay.Replay(context.Background(), 3)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 4, 5}, publisher.published)
+	assert.Equal(t, 3, result.PublishedCount)
+	require.NotNil(t, result.ReplayFrom)
+	assert.Equal(t, int64(3), *result.ReplayFrom)
+}
+
+func TestRelay_Execute_Cancelled(t *testing.T) {
+	store := &memoryStore{}
+	appendEvents(t, store, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := outbox.NewRelay(store, &recordingPublisher{}, outbox.RelayConfig{}).Execute(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,63 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+func TestParse_Next(t *testing.T) {
+	// Wednesday
+	from := time.Date(2025, time.January, 15, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expr     string
+		expected time.Time
+	}{
+		{name: "every minute", expr: "* * * * *", expected: time.Date(2025, time.January, 15, 10, 8, 0, 0, time.UTC)},
+		{name: "step", expr: "*/5 * * * *", expected: time.Date(2025, time.January, 15, 10, 10, 0, 0, time.UTC)},
+		{name: "list", expr: "0,30 * * * *", expected: time.Date(2025, time.January, 15, 10, 30, 0, 0, time.UTC)},
+		{name: "range with step", expr: "0 9-17/4 * * *", expected: time.Date(2025, time.January, 15, 13, 0, 0, 0, time.UTC)},
+		{name: "rolls over to the next day", expr: "0 2 * * *", expected: time.Date(2025, time.January, 16, 2, 0, 0, 0, time.UTC)},
+		{name: "day of week", expr: "0 0 * * 1", expected: time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC)},
+		{name: "sunday as 7", expr: "0 0 * * 7", expected: time.Date(2025, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{name: "day of month or day of week", expr: "0 0 17 * 5", expected: time.Date(2025, time.January, 17, 0, 0, 0, 0, time.UTC)},
+		{name: "leap day", expr: "0 0 29 2 *", expected: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "hourly", expr: "@hourly", expected: time.Date(2025, time.January, 15, 11, 0, 0, 0, time.UTC)},
+		{name: "monthly", expr: "@monthly", expected: time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "every", expr: "@every 90s", expected: from.Add(90 * time.Second)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := scheduler.Parse(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, schedule.Next(from))
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@fortnightly",
+		"@every -1m",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := scheduler.Parse(expr)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,209 @@
+// Package scheduler runs named jobs on cron expressions inside a single long-lived process.
+//
+// Modules contribute jobs through their Module struct; the scheduler command collects them,
+// applies schedule overrides from configuration and hands them to a Scheduler. A job never
+// overlaps with itself: an activation that fires while the previous run is still in flight is
+// skipped. On shutdown no new runs start, in-flight runs get ShutdownTimeout to finish on their
+// own and are then cancelled through their context.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Runner is the work performed by a job. Every module CronAdapter satisfies it.
+type Runner interface {
+	Execute(ctx context.Context) (interface{}, error)
+}
+
+// RunSummary is the outcome of a run in a form shared by all jobs, e.g. for the run history.
+type RunSummary struct {
+	ProcessedCount int
+	SuccessCount   int
+	ErrorCount     int
+	Errors         []string
+	DryRun         bool
+	// Skipped marks a run that did no work, e.g. because another instance was running the job.
+	Skipped bool
+}
+
+// Summarizer is implemented by run results that can describe themselves as a RunSummary.
+type Summarizer interface {
+	RunSummary() RunSummary
+}
+
+// Job is a named Runner with its default cron expression.
+type Job struct {
+	Name     string
+	Schedule string
+	Runner   Runner
+}
+
+// Config tunes the Scheduler.
+type Config struct {
+	// Schedules overrides the cron expression of jobs by name.
+	Schedules map[string]string
+	// ShutdownTimeout is how long in-flight runs may continue after shutdown starts (default 30s).
+	ShutdownTimeout time.Duration
+}
+
+type entry struct {
+	job      Job
+	schedule Schedule
+	running  atomic.Bool
+}
+
+// Scheduler runs registered jobs on their schedules.
+type Scheduler struct {
+	config  Config
+	entries []*entry
+	names   map[string]bool
+}
+
+// New creates a Scheduler without jobs.
+func New(config Config) *Scheduler {
+	if config.ShutdownTimeout == 0 {
+		config.ShutdownTimeout = 30 * time.Second
+	}
+	return &Scheduler{
+		config: config,
+		names:  map[string]bool{},
+	}
+}
+
+// Register adds jobs to the scheduler. It fails on duplicate names and invalid cron expressions,
+// so a misconfigured deployment stops at startup instead of silently never running a job.
+func (s *Scheduler) Register(jobs ...Job) (err error) {
+	for _, job := range jobs {
+		if job.Name == "" || job.Runner == nil {
+			return fmt.Errorf("job %q must have a name and a runner", job.Name)
+		}
+		if s.names[job.Name] {
+			return fmt.Errorf("job %q is already registered", job.Name)
+		}
+
+		expr := job.Schedule
+		if override, ok := s.config.Schedules[job.Name]; ok {
+			expr = override
+		}
+		schedule, err := Parse(expr)
+		if err != nil {
+			return fmt.Errorf("job %q: %w", job.Name, err)
+		}
+		if schedule.Next(time.Now()).IsZero() {
+			return fmt.Errorf("job %q: schedule %q never fires", job.Name, expr)
+		}
+
+		job.Schedule = expr
+		s.names[job.Name] = true
+		s.entries = append(s.entries, &entry{job: job, schedule: schedule})
+	}
+	return nil
+}
+
+// Jobs returns the registered jobs with their effective schedules.
+func (s *Scheduler) Jobs() []Job {
+	jobs := make([]Job, len(s.entries))
+	for i, e := range s.entries {
+		jobs[i] = e.job
+	}
+	return jobs
+}
+
+// Run blocks until ctx is cancelled, then waits for in-flight runs before returning.
+// It fails right away when a schedule override names no registered job, e.g. because of a typo
+// that would otherwise leave the job on its default schedule.
+func (s *Scheduler) Run(ctx context.Context) error {
+	for name := range s.config.Schedules {
+		if !s.names[name] {
+			return fmt.Errorf("schedule override for unknown job %q", name)
+		}
+	}
+
+	// Runs outlive ctx so they can finish during the shutdown grace period
+	runCtx, cancelRuns := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelRuns()
+
+	var runs sync.WaitGroup
+	var loops sync.WaitGroup
+	for _, e := range s.entries {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			s.loop(ctx, runCtx, e, &runs)
+		}()
+	}
+
+	<-ctx.Done()
+	loops.Wait()
+	log.Info().Dur("timeout", s.config.ShutdownTimeout).Msg("Scheduler stopping, waiting for running jobs")
+
+	done := make(chan struct{})
+	go func() {
+		runs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(s.config.ShutdownTimeout):
+		log.Warn().Msg("Shutdown timeout reached, cancelling running jobs")
+		cancelRuns()
+		<-done
+	}
+
+	log.Info().Msg("Scheduler stopped")
+	return nil
+}
+
+func (s *Scheduler) loop(ctx, runCtx context.Context, e *entry, runs *sync.WaitGroup) {
+	for {
+		next := e.schedule.Next(time.Now())
+		log.Debug().Str("job", e.job.Name).Time("next_run", next).Msg("Job scheduled")
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !e.running.CompareAndSwap(false, true) {
+			log.Warn().Str("job", e.job.Name).Msg("Previous run still in progress, skipping")
+			continue
+		}
+
+		runs.Add(1)
+		go func() {
+			defer runs.Done()
+			defer e.running.Store(false)
+			s.execute(runCtx, e.job)
+		}()
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, job Job) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error().Str("job", job.Name).Interface("panic", p).Msg("Job panicked")
+		}
+	}()
+
+	start := time.Now()
+	log.Info().Str("job", job.Name).Msg("Job started")
+
+	result, err := job.Runner.Execute(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("job", job.Name).Dur("duration", time.Since(start)).Interface("result", result).Msg("Job failed")
+		return
+	}
+
+	log.Info().Str("job", job.Name).Dur("duration", time.Since(start)).Interface("result", result).Msg("Job completed")
+}
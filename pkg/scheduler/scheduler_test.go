@@ -0,0 +1,126 @@
+package scheduler_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/scheduler"
+)
+
+type runnerFunc func(ctx context.Context) (interface{}, error)
+
+func (f runnerFunc) Execute(ctx context.Context) (interface{}, error) {
+	return f(ctx)
+}
+
+func TestScheduler_Register(t *testing.T) {
+	noop := runnerFunc(func(context.Context) (interface{}, error) { return nil, nil })
+
+	s := scheduler.New(scheduler.Config{Schedules: map[string]string{"job-a": "@hourly"}})
+	require.NoError(t, s.Register(scheduler.Job{Name: "job-a", Schedule: "* * * * *", Runner: noop}))
+	assert.Equal(t, "@hourly", s.Jobs()[0].Schedule, "configured schedule overrides the default")
+
+	assert.Error(t, s.Register(scheduler.Job{Name: "job-a", Schedule: "@daily", Runner: noop}), "duplicate name")
+	assert.Error(t, s.Register(scheduler.Job{Name: "job-b", Schedule: "nope", Runner: noop}), "invalid expression")
+	assert.Error(t, s.Register(scheduler.Job{Name: "job-c", Schedule: "0 0 31 2 *", Runner: noop}), "never fires")
+	assert.Error(t, s.Register(scheduler.Job{Name: "job-d", Schedule: "@daily"}), "missing runner")
+}
+
+func TestScheduler_Run_UnknownScheduleOverride(t *testing.T) {
+	noop := runnerFunc(func(context.Context) (interface{}, error) { return nil, nil })
+
+	s := scheduler.New(scheduler.Config{Schedules: map[string]string{"payment-updatr": "@hourly"}})
+	require.NoError(t, s.Register(scheduler.Job{Name: "payment-updater", Schedule: "@daily", Runner: noop}))
+
+	// The typo is reported at startup instead of leaving the job on its default schedule
+	err := s.Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"payment-updatr"`)
+}
+
+func TestScheduler_Run_SkipsOverlappingRuns(t *testing.T) {
+	var started, concurrent, maxConcurrent int32
+	release := make(chan struct{})
+	job := runnerFunc(func(context.Context) (interface{}, error) {
+		atomic.AddInt32(&started, 1)
+		if n := atomic.AddInt32(&concurrent, 1); n > atomic.LoadInt32(&maxConcurrent) {
+			atomic.StoreInt32(&maxConcurrent, n)
+		}
+		<-release
+		atomic.AddInt32(&concurrent, -1)
+		return nil, nil
+	})
+
+	s := scheduler.New(scheduler.Config{})
+	require.NoError(t, s.Register(scheduler.Job{Name: "slow", Schedule: "@every 5ms", Runner: job}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	// Many activations pass while the first run is blocked
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&started))
+
+	close(release)
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	assert.Greater(t, atomic.LoadInt32(&started), int32(1), "runs resume once the previous one finished")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxConcurrent))
+}
+
+func TestScheduler_Run_WaitsForRunningJobs(t *testing.T) {
+	running := make(chan struct{})
+	var finished atomic.Bool
+	job := runnerFunc(func(ctx context.Context) (interface{}, error) {
+		if finished.Load() {
+			return nil, nil
+		}
+		close(running)
+		time.Sleep(30 * time.Millisecond)
+		finished.Store(true)
+		return nil, ctx.Err()
+	})
+
+	s := scheduler.New(scheduler.Config{ShutdownTimeout: time.Second})
+	require.NoError(t, s.Register(scheduler.Job{Name: "job", Schedule: "@every 5ms", Runner: job}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	<-running
+	cancel()
+	require.NoError(t, <-done)
+	assert.True(t, finished.Load(), "Run returned before the running job finished")
+}
+
+func TestScheduler_Run_CancelsJobsAfterShutdownTimeout(t *testing.T) {
+	running := make(chan struct{})
+	var cancelled atomic.Bool
+	job := runnerFunc(func(ctx context.Context) (interface{}, error) {
+		close(running)
+		<-ctx.Done()
+		cancelled.Store(true)
+		return nil, ctx.Err()
+	})
+
+	s := scheduler.New(scheduler.Config{ShutdownTimeout: 10 * time.Millisecond})
+	require.NoError(t, s.Register(scheduler.Job{Name: "stuck", Schedule: "@every 5ms", Runner: job}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	<-running
+	cancel()
+	require.NoError(t, <-done)
+	assert.True(t, cancelled.Load())
+}
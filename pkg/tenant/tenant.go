@@ -0,0 +1,78 @@
+// Package tenant carries the merchant a unit of work acts for in its context.
+//
+// The tenant is resolved once per request, from the API key or the X-Tenant-ID header, and the
+// repositories of tenant data scope every query to the tenant of the context. A context without a
+// tenant is rejected, so the data of another tenant cannot be read or written, not even with a guessed ID.
+// Background jobs working for every tenant say so explicitly with WithAllTenants.
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	apperrors "github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/errors"
+)
+
+// HeaderTenantID names the tenant of a request when no API key does.
+const HeaderTenantID = "X-Tenant-ID"
+
+// Platform is the tenant of platform-wide data, e.g. the default payment settings every tenant falls back to.
+// It is never resolved from a request.
+const Platform = ""
+
+// ErrRequired is returned for tenant data accessed without a tenant in the context.
+var ErrRequired = apperrors.NewUnauthorizedError(fmt.Errorf("tenant is required, see the %s header", HeaderTenantID))
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// Validate checks that id is a tenant ID: up to 64 letters, digits, '-' and '_', starting with a letter or digit.
+func Validate(id string) error {
+	if !idPattern.MatchString(id) {
+		return apperrors.NewValidationError(fmt.Errorf("invalid tenant ID %q", id))
+	}
+	return nil
+}
+
+type scope struct {
+	id  string
+	all bool
+}
+
+type scopeKey struct{}
+
+// WithID returns a context acting for the tenant id, Platform included. It replaces any scope of ctx.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope{id: id})
+}
+
+// WithAllTenants returns a context acting for every tenant, for background jobs such as the payment updater
+// and the webhook dispatcher. It must never be derived from a request.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope{all: true})
+}
+
+// FromContext returns the tenant ctx acts for. ok is false without one, and under WithAllTenants.
+func FromContext(ctx context.Context) (id string, ok bool) {
+	s, found := ctx.Value(scopeKey{}).(scope)
+	if !found || s.all {
+		return "", false
+	}
+	return s.id, true
+}
+
+// IsAllTenants reports whether ctx acts for every tenant.
+func IsAllTenants(ctx context.Context) bool {
+	s, _ := ctx.Value(scopeKey{}).(scope)
+	return s.all
+}
+
+// Required returns the tenant ctx acts for, or ErrRequired. Data is always written for a single tenant,
+// so a context acting for every tenant is rejected as well.
+func Required(ctx context.Context) (id string, err error) {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return "", ErrRequired
+	}
+	return id, nil
+}
@@ -0,0 +1,49 @@
+package tenant_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+func TestScope(t *testing.T) {
+	ctx := context.Background()
+	_, err := tenant.Required(ctx)
+	assert.ErrorIs(t, err, tenant.ErrRequired)
+
+	merchant := tenant.WithID(ctx, "merchant_a")
+	id, err := tenant.Required(merchant)
+	require.NoError(t, err)
+	assert.Equal(t, "merchant_a", id)
+	assert.False(t, tenant.IsAllTenants(merchant))
+
+	platform := tenant.WithID(ctx, tenant.Platform)
+	id, ok := tenant.FromContext(platform)
+	assert.True(t, ok)
+	assert.Equal(t, tenant.Platform, id)
+
+	all := tenant.WithAllTenants(merchant)
+	assert.True(t, tenant.IsAllTenants(all))
+	_, err = tenant.Required(all)
+	assert.ErrorIs(t, err, tenant.ErrRequired, "data is written for a single tenant")
+
+	// A job acting for every tenant narrows down to the tenant of the data it works on
+	narrowed := tenant.WithID(all, "merchant_b")
+	assert.False(t, tenant.IsAllTenants(narrowed))
+	id, err = tenant.Required(narrowed)
+	require.NoError(t, err)
+	assert.Equal(t, "merchant_b", id)
+}
+
+func TestValidate(t *testing.T) {
+	for _, id := range []string{"merchant_a", "m-1", "ACME", "0"} {
+		assert.NoError(t, tenant.Validate(id), id)
+	}
+	for _, id := range []string{"", "-merchant", "merchant a", "merchant:a", string(make([]byte, 65))} {
+		assert.Error(t, tenant.Validate(id), id)
+	}
+}
@@ -11,9 +11,25 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/require"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/middlewares"
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
 )
 
 func MakeRequest(t *testing.T, e *echo.Echo, method, path string, body interface{}) *httptest.ResponseRecorder {
+	return MakeRequestWithHeaders(t, e, method, path, body, nil)
+}
+
+func MakeRequestWithHeaders(t *testing.T, e *echo.Echo, method, path string, body interface{}, headers map[string]string) *httptest.ResponseRecorder {
+	return serve(t, e, method, path, body, map[string]string{tenant.HeaderTenantID: TestTenantID}, headers)
+}
+
+// MakeAdminRequest makes a request with TestAdminAPIKey, acting for tenant.Platform.
+func MakeAdminRequest(t *testing.T, e *echo.Echo, method, path string, body interface{}) *httptest.ResponseRecorder {
+	return serve(t, e, method, path, body, map[string]string{echo.HeaderAuthorization: "Bearer " + TestAdminAPIKey}, nil)
+}
+
+func serve(t *testing.T, e *echo.Echo, method, path string, body interface{}, defaultHeaders, headers map[string]string) *httptest.ResponseRecorder {
 	var reqBody io.Reader
 	if body != nil {
 		jsonBody, err := json.Marshal(body)
@@ -25,6 +41,12 @@ func MakeRequest(t *testing.T, e *echo.Echo, method, path string, body interface
 	if body != nil {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	}
+	for key, value := range defaultHeaders {
+		req.Header.Set(key, value)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 	rec := httptest.NewRecorder()
 
 	e.ServeHTTP(rec, req)
@@ -81,5 +103,6 @@ func NewEchoForTest() *echo.Echo {
 
 		_ = c.JSON(code, map[string]string{"error": message})
 	}
+	e.Use(middlewares.Tenant(nil, TestAdminAPIKey))
 	return e
 }
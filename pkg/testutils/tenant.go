@@ -0,0 +1,18 @@
+package testutils
+
+import (
+	"context"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/tenant"
+)
+
+// TestTenantID is the tenant tests act for, and the tenant of the requests made with MakeRequest.
+const TestTenantID = "test_tenant"
+
+// TestAdminAPIKey is the admin API key of the echo instances made with NewEchoForTest, used by MakeAdminRequest.
+const TestAdminAPIKey = "test_admin_key"
+
+// TenantContext returns a context acting for TestTenantID.
+func TenantContext() context.Context {
+	return tenant.WithID(context.Background(), TestTenantID)
+}
@@ -0,0 +1,169 @@
+// Package transaction provides the Unit of Work port shared by all modules.
+//
+// The modules of the monolith share one Postgres database. A service that needs several writes
+// (or a read and a write) to be atomic runs them inside Manager.WithinTransaction. The active
+// *sql.Tx travels in the context, and repositories obtain their query runner through Executor,
+// so they join the transaction without knowing about it. This also works across modules: a
+// payment write and a payment-settings read inside the same function share the same transaction.
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// Manager runs a function inside a database transaction.
+type Manager interface {
+	// WithinTransaction runs fn in a transaction carried by the ctx passed to fn. The transaction
+	// commits when fn returns nil and rolls back otherwise. When ctx already carries a transaction,
+	// fn joins it and the outermost call decides the outcome.
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error)
+}
+
+// DBTX is the query runner used by repositories. Both *sql.DB and *sql.Tx implement it.
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type txKey struct{}
+
+// Executor returns the transaction carried by ctx, or db when there is none.
+func Executor(ctx context.Context, db *sql.DB) DBTX {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// InTransaction reports whether ctx carries an active transaction.
+func InTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return ok
+}
+
+type afterCommitKey struct{}
+
+// afterCommitHooks collects the functions to run once the outermost transaction committed.
+type afterCommitHooks struct {
+	fns []func(ctx context.Context)
+}
+
+// AfterCommit runs fn once the transaction carried by ctx committed, or right away when ctx carries
+// no transaction. fn is dropped when the transaction rolls back, e.g. to notify in-process listeners
+// only of changes that actually happened. The hooks run in registration order with the ctx of the
+// outermost WithinTransaction call, and only once even if the transaction was retried.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if hooks, ok := ctx.Value(afterCommitKey{}).(*afterCommitHooks); ok {
+		hooks.fns = append(hooks.fns, fn)
+		return
+	}
+	fn(ctx)
+}
+
+// Config tunes the SQL transaction manager.
+type Config struct {
+	// Isolation is the isolation level of new transactions (default: database default, read committed).
+	Isolation sql.IsolationLevel
+	// MaxRetries is how many times a transaction is retried after a serialization failure or deadlock (default 3).
+	MaxRetries int
+	// RetryBackoff is the base delay between retries, multiplied by the attempt number (default 20ms).
+	RetryBackoff time.Duration
+}
+
+type sqlManager struct {
+	db     *sql.DB
+	config Config
+}
+
+// NewManager creates a Manager backed by db.
+func NewManager(db *sql.DB, config Config) Manager {
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	}
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = 20 * time.Millisecond
+	}
+	return &sqlManager{
+		db:     db,
+		config: config,
+	}
+}
+
+func (m *sqlManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	// Nested call: join the outer transaction
+	if InTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		hooks := &afterCommitHooks{}
+		err = m.run(context.WithValue(ctx, afterCommitKey{}, hooks), fn)
+		if err == nil {
+			for _, hook := range hooks.fns {
+				hook(ctx)
+			}
+			return nil
+		}
+		if !IsRetryable(err) || attempt >= m.config.MaxRetries {
+			return err
+		}
+
+		log.Warn().
+			Err(err).
+			Int("attempt", attempt+1).
+			Msg("Retrying transaction after serialization failure")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * m.config.RetryBackoff):
+		}
+	}
+}
+
+func (m *sqlManager) run(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: m.config.Isolation})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		if errRollback := tx.Rollback(); errRollback != nil && !errors.Is(errRollback, sql.ErrTxDone) {
+			log.Error().Err(errRollback).Msg("failed to rollback transaction")
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// IsRetryable reports whether err is a serialization failure or deadlock, after which
+// the whole transaction can safely be run again.
+func IsRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
@@ -0,0 +1,49 @@
+package transaction_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/transaction"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "serialization failure", err: &pq.Error{Code: "40001"}, expected: true},
+		{name: "deadlock", err: &pq.Error{Code: "40P01"}, expected: true},
+		{name: "wrapped serialization failure", err: fmt.Errorf("update: %w", &pq.Error{Code: "40001"}), expected: true},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}, expected: false},
+		{name: "plain error", err: errors.New("boom"), expected: false},
+		{name: "nil", err: nil, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, transaction.IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestExecutor_WithoutTransaction(t *testing.T) {
+	db := &sql.DB{}
+
+	assert.False(t, transaction.InTransaction(context.Background()))
+	assert.Same(t, db, transaction.Executor(context.Background(), db))
+}
+
+func TestAfterCommit_WithoutTransaction(t *testing.T) {
+	var calls int
+	transaction.AfterCommit(context.Background(), func(context.Context) { calls++ })
+
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,94 @@
+// Package webhook signs and verifies webhook payloads.
+//
+// A signature header has the form "t=<unix seconds>,v1=<hex HMAC-SHA256>", where the HMAC is
+// computed with the shared secret over "<t>.<body>". Signing the timestamp together with the body
+// lets the receiver reject replays of old deliveries. Several v1 entries may be present, e.g. while
+// a secret is being rotated; the signature is valid when any of them matches.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of a webhook delivery.
+const (
+	// HeaderSignature carries the signature of the delivery.
+	HeaderSignature = "Webhook-Signature"
+	// HeaderEventID identifies the event; redeliveries of an event carry the same ID.
+	HeaderEventID = "Webhook-Id"
+	// HeaderEventType is the type of the event, e.g. "payment.status_changed".
+	HeaderEventType = "Webhook-Event"
+)
+
+// DefaultTolerance is how far a signature timestamp may be from the receiver's clock.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	// ErrInvalidSignature is returned when the header is malformed or no signature matches.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrTimestampOutOfTolerance is returned when the signature is too old or too far in the future.
+	ErrTimestampOutOfTolerance = errors.New("webhook timestamp is outside the tolerance")
+)
+
+// Sign returns the signature header value for body, sent at timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := timestamp.Unix()
+	return fmt.Sprintf("t=%d,v1=%s", t, compute(secret, t, body))
+}
+
+// Verify checks a signature header against body. tolerance bounds the distance between the signed
+// timestamp and now (DefaultTolerance when 0).
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) (err error) {
+	if secret == "" {
+		return fmt.Errorf("%w: no secret configured", ErrInvalidSignature)
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "t":
+			if timestamp, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+			}
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return fmt.Errorf("%w: missing timestamp or signature", ErrInvalidSignature)
+	}
+
+	if skew := now.Sub(time.Unix(timestamp, 0)); skew > tolerance || skew < -tolerance {
+		return ErrTimestampOutOfTolerance
+	}
+
+	expected := []byte(compute(secret, timestamp, body))
+	for _, signature := range signatures {
+		if hmac.Equal(expected, []byte(signature)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func compute(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = fmt.Fprintf(mac, "%d.", timestamp)
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,47 @@
+package webhook_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bxcodec/golang-ddd-modular-monolith-with-hexagonal/pkg/webhook"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"eventId":"evt_1"}`)
+	valid := webhook.Sign("secret", now, body)
+
+	tests := []struct {
+		name          string
+		secret        string
+		header        string
+		body          []byte
+		now           time.Time
+		expectedError error
+	}{
+		{name: "valid signature", secret: "secret", header: valid, body: body, now: now},
+		{name: "within tolerance", secret: "secret", header: valid, body: body, now: now.Add(4 * time.Minute)},
+		{name: "rotated secret", secret: "secret", header: valid + ",v1=deadbeef", body: body, now: now},
+		{name: "wrong secret", secret: "other", header: valid, body: body, now: now, expectedError: webhook.ErrInvalidSignature},
+		{name: "tampered body", secret: "secret", header: valid, body: []byte(`{"eventId":"evt_2"}`), now: now, expectedError: webhook.ErrInvalidSignature},
+		{name: "expired timestamp", secret: "secret", header: valid, body: body, now: now.Add(6 * time.Minute), expectedError: webhook.ErrTimestampOutOfTolerance},
+		{name: "timestamp in the future", secret: "secret", header: valid, body: body, now: now.Add(-6 * time.Minute), expectedError: webhook.ErrTimestampOutOfTolerance},
+		{name: "missing header", secret: "secret", header: "", body: body, now: now, expectedError: webhook.ErrInvalidSignature},
+		{name: "malformed timestamp", secret: "secret", header: "t=abc,v1=00", body: body, now: now, expectedError: webhook.ErrInvalidSignature},
+		{name: "no secret configured", secret: "", header: valid, body: body, now: now, expectedError: webhook.ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := webhook.Verify(tt.secret, tt.header, tt.body, 0, tt.now)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}